@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/pflag"
+
+	appConfig "github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/password"
+)
+
+type Config struct {
+	Memory      uint32
+	Parallelism uint8
+	Target      time.Duration
+}
+
+func New() *Config {
+	c := appConfig.New().Password
+	return &Config{
+		Memory:      c.Argon2Memory,
+		Parallelism: c.Argon2Parallelism,
+		Target:      500 * time.Millisecond,
+	}
+}
+
+const (
+	Memory      = "memory"
+	Parallelism = "parallelism"
+	Target      = "target"
+)
+
+func (c *Config) addFlags(fs *pflag.FlagSet) {
+	fs.Uint32Var(&c.Memory, Memory, c.Memory, "Memory the host can spare for each concurrent login in KiB")
+	fs.Uint8Var(&c.Parallelism, Parallelism, c.Parallelism, "Threads used for each hash")
+	fs.DurationVar(&c.Target, Target, c.Target, "How long hashing a password should take")
+}
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+	c := New()
+	c.addFlags(pflag.CommandLine)
+	pflag.Parse()
+
+	log.Info().
+		Uint32("memory", c.Memory).
+		Uint8("parallelism", c.Parallelism).
+		Stringer("target", c.Target).
+		Msg("benchmarking argon2")
+
+	params, d := password.Recommend(c.Target, c.Memory, c.Parallelism)
+	if d > c.Target {
+		log.Warn().Stringer("duration", d).Msg("a single pass is slower than the target, lower the memory")
+	} else {
+		log.Info().Stringer("duration", d).Msg("done")
+	}
+
+	fmt.Printf("--%s %d --%s %d --%s %d\n",
+		appConfig.PasswordArgon2Memory, params.Memory,
+		appConfig.PasswordArgon2Parallelism, params.Parallelism,
+		appConfig.PasswordArgon2Time, params.Time,
+	)
+}
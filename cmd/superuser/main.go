@@ -13,16 +13,19 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	appConfig "github.com/alexferl/echo-boilerplate/config"
 	"github.com/alexferl/echo-boilerplate/data"
 	"github.com/alexferl/echo-boilerplate/mappers"
 	"github.com/alexferl/echo-boilerplate/models"
 	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/password"
 )
 
 type Config struct {
-	Config  *config.Config
-	MongoDB *mongodb.Config
-	Super   *Super
+	Config   *config.Config
+	MongoDB  *mongodb.Config
+	Password *appConfig.Password
+	Super    *Super
 }
 
 type Super struct {
@@ -34,8 +37,9 @@ type Super struct {
 
 func New() *Config {
 	return &Config{
-		Config:  config.New("APP"),
-		MongoDB: mongodb.DefaultConfig,
+		Config:   config.New("APP"),
+		MongoDB:  mongodb.DefaultConfig,
+		Password: appConfig.New().Password,
 		Super: &Super{
 			Email:    "super@example.com",
 			Name:     "Super",
@@ -62,6 +66,7 @@ func (c *Config) addFlags(fs *pflag.FlagSet) {
 func (c *Config) BindFlags() {
 	c.addFlags(pflag.CommandLine)
 	c.MongoDB.BindFlags(pflag.CommandLine)
+	c.Password.BindFlags(pflag.CommandLine)
 
 	err := c.Config.BindFlags()
 	if err != nil {
@@ -79,6 +84,14 @@ func main() {
 	c := New()
 	c.BindFlags()
 
+	if path := viper.GetString(appConfig.PasswordBreachedFile); path != "" {
+		breached, err := password.LoadBreached(path)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed loading breached passwords")
+		}
+		password.SetBreached(breached)
+	}
+
 	client, err := data.MewMongoClient()
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed creating mongo client")
@@ -110,9 +123,14 @@ func main() {
 
 				user := models.NewUserWithRole(email, username, models.SuperRole)
 				user.Name = name
+				user.VerifyEmail()
 
 				err = user.SetPassword(viper.GetString(SuperPassword))
 				if err != nil {
+					var me *models.Error
+					if errors.As(err, &me) && len(me.Errors) > 0 {
+						log.Fatal().Strs("errors", me.Errors).Msg(me.Message)
+					}
 					log.Fatal().Err(err).Msg("failed setting superuser password")
 				}
 
@@ -22,18 +22,30 @@ type Config struct {
 
 	BaseURL string
 
-	Casbin       *Casbin
-	Cookies      *Cookies
-	CSRF         *CSRF
-	JWT          *JWT
-	OAuth2       *OAuth2
-	OAuth2Google *OAuth2Google
-	OpenAPI      *OpenAPI
+	Casbin               *Casbin
+	Cookies              *Cookies
+	CSRF                 *CSRF
+	EmailVerification    *EmailVerification
+	Impersonation        *Impersonation
+	JWT                  *JWT
+	LoginThrottle        *LoginThrottle
+	MagicLink            *MagicLink
+	MFA                  *MFA
+	OAuth2               *OAuth2
+	OAuth2Google         *OAuth2Google
+	OpenAPI              *OpenAPI
+	Password             *Password
+	PasswordReset        *PasswordReset
+	PersonalAccessTokens *PersonalAccessTokens
+	Restrictions         *Restrictions
+	SMTP                 *SMTP
+	WebAuthn             *WebAuthn
 }
 
 type Casbin struct {
-	Model  string
-	Policy string
+	Model       string
+	Policy      string
+	ScopePolicy string
 }
 
 type Cookies struct {
@@ -49,6 +61,15 @@ type CSRF struct {
 	HeaderName   string
 }
 
+type EmailVerification struct {
+	Policy      string
+	TokenExpiry time.Duration
+}
+
+type Impersonation struct {
+	Expiry time.Duration
+}
+
 type JWT struct {
 	AccessTokenExpiry      time.Duration
 	AccessTokenCookieName  string
@@ -56,10 +77,38 @@ type JWT struct {
 	RefreshTokenCookieName string
 	PrivateKey             string
 	Issuer                 string
+	KeyEncryptionKey       string
+	KeyRotationInterval    time.Duration
+	KeyRetention           time.Duration
+}
+
+type LoginThrottle struct {
+	AccountMaxAttempts int
+	BackoffBase        time.Duration
+	BackoffMax         time.Duration
+	IPMaxAttempts      int
+	LockDuration       time.Duration
+	Window             time.Duration
+}
+
+type MagicLink struct {
+	MaxEmails   int
+	TokenExpiry time.Duration
+	Window      time.Duration
+}
+
+type MFA struct {
+	ChallengeExpiry     time.Duration
+	MaxAttempts         int
+	RequireAdmin        bool
+	SecretEncryptionKey string
 }
 
 type OAuth2 struct {
-	Providers []string
+	Clients           map[string]string
+	Providers         []string
+	RedirectAllowList []string
+	StateExpiry       time.Duration
 }
 
 type OAuth2Google struct {
@@ -71,6 +120,48 @@ type OpenAPI struct {
 	Schema string
 }
 
+type Password struct {
+	Argon2Memory      uint32
+	Argon2Parallelism uint8
+	Argon2Time        uint32
+	BreachedFile      string
+	MinClasses        int
+	MinLength         int
+	RejectUserInfo    bool
+}
+
+type PasswordReset struct {
+	IPMaxRequests int
+	MaxEmails     int
+	TokenExpiry   time.Duration
+	Window        time.Duration
+}
+
+type PersonalAccessTokens struct {
+	CleanupInterval time.Duration
+	ExpiryReminder  time.Duration
+	Retention       time.Duration
+}
+
+type Restrictions struct {
+	SweepInterval time.Duration
+}
+
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type WebAuthn struct {
+	RPDisplayName string
+	RPID          string
+	RPOrigins     []string
+	Timeout       time.Duration
+}
+
 // New creates a Config instance
 func New() *Config {
 	c := &Config{
@@ -80,8 +171,9 @@ func New() *Config {
 		MongoDB: libMongo.DefaultConfig,
 		BaseURL: "http://localhost:1323",
 		Casbin: &Casbin{
-			Model:  "./casbin/model.conf",
-			Policy: "./casbin/policy.csv",
+			Model:       "./casbin/model.conf",
+			Policy:      "./casbin/policy.csv",
+			ScopePolicy: "./casbin/scopes.csv",
 		},
 		Cookies: &Cookies{
 			Enabled: false,
@@ -94,15 +186,45 @@ func New() *Config {
 			HeaderName:   "X-CSRF-Token",
 			SecretKey:    "",
 		},
+		EmailVerification: &EmailVerification{
+			Policy:      EmailVerificationPolicyNone,
+			TokenExpiry: 24 * time.Hour,
+		},
+		Impersonation: &Impersonation{
+			Expiry: 15 * time.Minute,
+		},
 		JWT: &JWT{
 			AccessTokenCookieName:  "access_token",
 			AccessTokenExpiry:      60 * time.Minute,
+			KeyRetention:           (30 * 24) * time.Hour,
 			PrivateKey:             "./private-key.pem",
 			RefreshTokenCookieName: "refresh_token",
 			RefreshTokenExpiry:     (30 * 24) * time.Hour,
 		},
+		LoginThrottle: &LoginThrottle{
+			AccountMaxAttempts: 5,
+			BackoffBase:        time.Second,
+			BackoffMax:         30 * time.Second,
+			IPMaxAttempts:      50,
+			LockDuration:       15 * time.Minute,
+			Window:             60 * time.Minute,
+		},
+		MagicLink: &MagicLink{
+			MaxEmails:   3,
+			TokenExpiry: 15 * time.Minute,
+			Window:      60 * time.Minute,
+		},
+		MFA: &MFA{
+			ChallengeExpiry:     5 * time.Minute,
+			MaxAttempts:         5,
+			RequireAdmin:        false,
+			SecretEncryptionKey: "",
+		},
 		OAuth2: &OAuth2{
-			Providers: []string{""},
+			Clients:           map[string]string{},
+			Providers:         []string{""},
+			RedirectAllowList: []string{},
+			StateExpiry:       10 * time.Minute,
 		},
 		OAuth2Google: &OAuth2Google{
 			ClientId:     "",
@@ -111,6 +233,42 @@ func New() *Config {
 		OpenAPI: &OpenAPI{
 			Schema: "./openapi/openapi.yaml",
 		},
+		Password: &Password{
+			Argon2Memory:      64 * 1024,
+			Argon2Parallelism: 4,
+			Argon2Time:        3,
+			BreachedFile:      "",
+			MinClasses:        1,
+			MinLength:         12,
+			RejectUserInfo:    true,
+		},
+		PasswordReset: &PasswordReset{
+			IPMaxRequests: 20,
+			MaxEmails:     3,
+			TokenExpiry:   60 * time.Minute,
+			Window:        60 * time.Minute,
+		},
+		PersonalAccessTokens: &PersonalAccessTokens{
+			CleanupInterval: 60 * time.Minute,
+			ExpiryReminder:  (7 * 24) * time.Hour,
+			Retention:       (30 * 24) * time.Hour,
+		},
+		Restrictions: &Restrictions{
+			SweepInterval: 5 * time.Minute,
+		},
+		SMTP: &SMTP{
+			Host:     "",
+			Port:     587,
+			Username: "",
+			Password: "",
+			From:     "no-reply@example.com",
+		},
+		WebAuthn: &WebAuthn{
+			RPDisplayName: "",
+			RPID:          "localhost",
+			RPOrigins:     []string{"http://localhost:1323"},
+			Timeout:       5 * time.Minute,
+		},
 	}
 	c.JWT.Issuer = c.BaseURL
 	return c
@@ -125,8 +283,9 @@ const (
 
 	BaseURL = "base-url"
 
-	CasbinModel  = "casbin-model"
-	CasbinPolicy = "casbin-policy"
+	CasbinModel       = "casbin-model"
+	CasbinPolicy      = "casbin-policy"
+	CasbinScopePolicy = "casbin-scope-policy"
 
 	CookiesEnabled = "cookies-enabled"
 	CookiesDomain  = "cookies-domain"
@@ -137,19 +296,104 @@ const (
 	CSRFHeaderName   = "csrf-header-name"
 	CSRFSecretKey    = "csrf-secret-key"
 
+	EmailVerificationPolicy      = "email-verification-policy"
+	EmailVerificationTokenExpiry = "email-verification-token-expiry"
+
+	ImpersonationExpiry = "impersonation-expiry"
+
 	JWTAccessTokenCookieName  = "jwt-access-token-cookie-name"
 	JWTAccessTokenExpiry      = "jwt-access-token-expiry"
 	JWTIssuer                 = "jwt-issuer"
+	JWTKeyEncryptionKey       = "jwt-key-encryption-key"
+	JWTKeyRetention           = "jwt-key-retention"
+	JWTKeyRotationInterval    = "jwt-key-rotation-interval"
 	JWTPrivateKey             = "jwt-private-key"
 	JWTRefreshTokenCookieName = "jwt-refresh-token-cookie-name"
 	JWTRefreshTokenExpiry     = "jwt-refresh-token-expiry"
 
-	OAuth2Providers = "oauth2-providers"
+	LoginThrottleAccountMaxAttempts = "login-throttle-account-max-attempts"
+	LoginThrottleBackoffBase        = "login-throttle-backoff-base"
+	LoginThrottleBackoffMax         = "login-throttle-backoff-max"
+	LoginThrottleIPMaxAttempts      = "login-throttle-ip-max-attempts"
+	LoginThrottleLockDuration       = "login-throttle-lock-duration"
+	LoginThrottleWindow             = "login-throttle-window"
+
+	MagicLinkMaxEmails   = "magic-link-max-emails"
+	MagicLinkTokenExpiry = "magic-link-token-expiry"
+	MagicLinkWindow      = "magic-link-window"
+
+	MFAChallengeExpiry     = "mfa-challenge-expiry"
+	MFAMaxAttempts         = "mfa-max-attempts"
+	MFARequireAdmin        = "mfa-require-admin"
+	MFASecretEncryptionKey = "mfa-secret-encryption-key"
+
+	OAuth2Clients           = "oauth2-clients"
+	OAuth2Providers         = "oauth2-providers"
+	OAuth2RedirectAllowList = "oauth2-redirect-allow-list"
+	OAuth2StateExpiry       = "oauth2-state-expiry"
+
+	// settings of each OAuth2 provider, see OAuth2ProviderKey
+	OAuth2ProviderAuthURL            = "auth-url"
+	OAuth2ProviderClientId           = "client-id"
+	OAuth2ProviderClientSecret       = "client-secret"
+	OAuth2ProviderDiscoveryURL       = "discovery-url"
+	OAuth2ProviderEmailClaim         = "email-claim"
+	OAuth2ProviderEmailsURL          = "emails-url"
+	OAuth2ProviderEmailVerifiedClaim = "email-verified-claim"
+	OAuth2ProviderNameClaim          = "name-claim"
+	OAuth2ProviderScopes             = "scopes"
+	OAuth2ProviderSubjectClaim       = "subject-claim"
+	OAuth2ProviderTokenURL           = "token-url"
+	OAuth2ProviderUserInfoURL        = "userinfo-url"
 
 	OAuth2GoogleClientId     = "oauth2-google-client-id"
 	OAuth2GoogleClientSecret = "oauth2-google-client-secret"
 
 	OpenAPISchema = "openapi-schema"
+
+	PasswordArgon2Memory      = "password-argon2-memory"
+	PasswordArgon2Parallelism = "password-argon2-parallelism"
+	PasswordArgon2Time        = "password-argon2-time"
+	PasswordBreachedFile      = "password-breached-file"
+	PasswordMinClasses        = "password-min-classes"
+	PasswordMinLength         = "password-min-length"
+	PasswordRejectUserInfo    = "password-reject-user-info"
+
+	PasswordResetIPMaxRequests = "password-reset-ip-max-requests"
+	PasswordResetMaxEmails     = "password-reset-max-emails"
+	PasswordResetTokenExpiry   = "password-reset-token-expiry"
+	PasswordResetWindow        = "password-reset-window"
+
+	PersonalAccessTokensCleanupInterval = "personal-access-tokens-cleanup-interval"
+	PersonalAccessTokensExpiryReminder  = "personal-access-tokens-expiry-reminder"
+	PersonalAccessTokensRetention       = "personal-access-tokens-retention"
+
+	RestrictionsSweepInterval = "restrictions-sweep-interval"
+
+	SMTPHost     = "smtp-host"
+	SMTPPort     = "smtp-port"
+	SMTPUsername = "smtp-username"
+	SMTPPassword = "smtp-password"
+	SMTPFrom     = "smtp-from"
+
+	WebAuthnRPDisplayName = "webauthn-rp-display-name"
+	WebAuthnRPID          = "webauthn-rp-id"
+	WebAuthnRPOrigins     = "webauthn-rp-origins"
+	WebAuthnTimeout       = "webauthn-timeout"
+)
+
+// OAuth2ProviderKey returns the key of a setting of an OAuth2 provider, e.g. 'oauth2-github-client-id'.
+// Providers other than Google don't have flags, they're configured in config files or with
+// environment variables such as APP_OAUTH2_GITHUB_CLIENT_ID.
+func OAuth2ProviderKey(provider string, setting string) string {
+	return fmt.Sprintf("oauth2-%s-%s", provider, setting)
+}
+
+// Email verification policies, what unverified users are allowed to do.
+const (
+	EmailVerificationPolicyNone  = "none"  // no restrictions
+	EmailVerificationPolicyLogin = "login" // can't log in
+	EmailVerificationPolicyRoles = "roles" // only get the 'any' role
 )
 
 // addFlags adds all the flags from the command line
@@ -158,6 +402,8 @@ func (c *Config) addFlags(fs *pflag.FlagSet) {
 
 	fs.StringVar(&c.Casbin.Model, CasbinModel, c.Casbin.Model, "Casbin model file")
 	fs.StringVar(&c.Casbin.Policy, CasbinPolicy, c.Casbin.Policy, "Casbin policy file")
+	fs.StringVar(&c.Casbin.ScopePolicy, CasbinScopePolicy, c.Casbin.ScopePolicy,
+		"Casbin policy file of the scopes of personal access tokens")
 
 	fs.BoolVar(&c.Cookies.Enabled, CookiesEnabled, c.Cookies.Enabled, "Send cookies with authentication requests")
 	fs.StringVar(&c.Cookies.Domain, CookiesDomain, c.Cookies.Domain, "Cookies domain")
@@ -168,23 +414,122 @@ func (c *Config) addFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&c.CSRF.CookieDomain, CSRFCookieDomain, c.CSRF.CookieDomain, "CSRF cookie domain")
 	fs.StringVar(&c.CSRF.HeaderName, CSRFHeaderName, c.CSRF.HeaderName, "CSRF header name")
 
+	fs.StringVar(&c.EmailVerification.Policy, EmailVerificationPolicy, c.EmailVerification.Policy,
+		"What unverified users are allowed to do. Valid policies: 'none', 'login' (can't log in), 'roles' (only public routes)")
+	fs.DurationVar(&c.EmailVerification.TokenExpiry, EmailVerificationTokenExpiry, c.EmailVerification.TokenExpiry,
+		"Email verification token expiry")
+
+	fs.DurationVar(&c.Impersonation.Expiry, ImpersonationExpiry, c.Impersonation.Expiry,
+		"How long the access tokens of impersonation sessions last, they can't be refreshed")
+
 	fs.StringVar(&c.JWT.AccessTokenCookieName, JWTAccessTokenCookieName, c.JWT.AccessTokenCookieName,
 		"JWT access token cookie name")
 	fs.DurationVar(&c.JWT.AccessTokenExpiry, JWTAccessTokenExpiry, c.JWT.AccessTokenExpiry,
 		"JWT access token expiry")
 	fs.StringVar(&c.JWT.Issuer, JWTIssuer, c.JWT.Issuer, "JWT issuer")
+	fs.StringVar(&c.JWT.KeyEncryptionKey, JWTKeyEncryptionKey, c.JWT.KeyEncryptionKey,
+		"Base64 encoded 32 bytes key encrypting the signing keys stored in the database, required with --jwt-key-rotation-interval")
+	fs.DurationVar(&c.JWT.KeyRetention, JWTKeyRetention, c.JWT.KeyRetention,
+		"How long retired signing keys still verify the tokens they signed")
+	fs.DurationVar(&c.JWT.KeyRotationInterval, JWTKeyRotationInterval, c.JWT.KeyRotationInterval,
+		"How often the signing key is replaced, keys are stored in the database when set. 0 only uses the private key file")
 	fs.StringVar(&c.JWT.PrivateKey, JWTPrivateKey, c.JWT.PrivateKey, "JWT private key file path")
 	fs.StringVar(&c.JWT.RefreshTokenCookieName, JWTRefreshTokenCookieName, c.JWT.RefreshTokenCookieName,
 		"JWT refresh token cookie name")
 	fs.DurationVar(&c.JWT.RefreshTokenExpiry, JWTRefreshTokenExpiry, c.JWT.RefreshTokenExpiry,
 		"JWT refresh token expiry")
 
-	fs.StringSliceVar(&c.OAuth2.Providers, OAuth2Providers, c.OAuth2.Providers, "OAuth2 providers")
+	fs.IntVar(&c.LoginThrottle.AccountMaxAttempts, LoginThrottleAccountMaxAttempts, c.LoginThrottle.AccountMaxAttempts,
+		"Failed logins after which an account is temporarily locked, 0 to disable")
+	fs.DurationVar(&c.LoginThrottle.BackoffBase, LoginThrottleBackoffBase, c.LoginThrottle.BackoffBase,
+		"Delay required after the first failed login, doubled on each failure, 0 to disable")
+	fs.DurationVar(&c.LoginThrottle.BackoffMax, LoginThrottleBackoffMax, c.LoginThrottle.BackoffMax,
+		"Maximum delay required between failed logins")
+	fs.IntVar(&c.LoginThrottle.IPMaxAttempts, LoginThrottleIPMaxAttempts, c.LoginThrottle.IPMaxAttempts,
+		"Failed logins after which an IP address is temporarily locked, 0 to disable")
+	fs.DurationVar(&c.LoginThrottle.LockDuration, LoginThrottleLockDuration, c.LoginThrottle.LockDuration,
+		"How long accounts and IP addresses stay locked")
+	fs.DurationVar(&c.LoginThrottle.Window, LoginThrottleWindow, c.LoginThrottle.Window,
+		"Failed logins are forgotten after this long without a new one")
+
+	fs.IntVar(&c.MagicLink.MaxEmails, MagicLinkMaxEmails, c.MagicLink.MaxEmails,
+		"Magic links emailed to an address per magic-link-window, further requests are ignored")
+	fs.DurationVar(&c.MagicLink.TokenExpiry, MagicLinkTokenExpiry, c.MagicLink.TokenExpiry, "Magic link expiry")
+	fs.DurationVar(&c.MagicLink.Window, MagicLinkWindow, c.MagicLink.Window,
+		"Period over which the magic links emailed to an address are counted")
+
+	fs.DurationVar(&c.MFA.ChallengeExpiry, MFAChallengeExpiry, c.MFA.ChallengeExpiry,
+		"Time allowed to enter the MFA code after the password")
+	fs.IntVar(&c.MFA.MaxAttempts, MFAMaxAttempts, c.MFA.MaxAttempts,
+		"Wrong MFA codes after which the login challenge is invalidated, 0 to disable")
+	fs.BoolVar(&c.MFA.RequireAdmin, MFARequireAdmin, c.MFA.RequireAdmin, "Require MFA for admins and supers")
+	fs.StringVar(&c.MFA.SecretEncryptionKey, MFASecretEncryptionKey, c.MFA.SecretEncryptionKey,
+		"Base64 encoded 32 bytes key encrypting the TOTP secrets stored in the database, required to enable TOTP")
+
+	fs.StringToStringVar(&c.OAuth2.Clients, OAuth2Clients, c.OAuth2.Clients,
+		"Clients allowed to introspect and revoke tokens, as client_id=client_secret pairs")
+	fs.StringSliceVar(&c.OAuth2.Providers, OAuth2Providers, c.OAuth2.Providers,
+		"OAuth2 providers, either 'github', 'gitlab', 'google', 'microsoft' or any name configured with a discovery URL")
+	fs.StringSliceVar(&c.OAuth2.RedirectAllowList, OAuth2RedirectAllowList, c.OAuth2.RedirectAllowList,
+		"URLs users can be redirected to after logging in with an OAuth2 provider, matched by origin and path prefix. "+
+			"Requires cookies to be enabled")
+	fs.DurationVar(&c.OAuth2.StateExpiry, OAuth2StateExpiry, c.OAuth2.StateExpiry,
+		"Time allowed to log in with an OAuth2 provider")
 
 	fs.StringVar(&c.OAuth2Google.ClientId, OAuth2GoogleClientId, c.OAuth2Google.ClientId, "OAuth2 Google client id")
 	fs.StringVar(&c.OAuth2Google.ClientSecret, OAuth2GoogleClientSecret, c.OAuth2Google.ClientSecret, "OAuth2 Google client secret")
 
 	fs.StringVar(&c.OpenAPI.Schema, OpenAPISchema, c.OpenAPI.Schema, "OpenAPI schema file")
+
+	fs.IntVar(&c.PasswordReset.IPMaxRequests, PasswordResetIPMaxRequests, c.PasswordReset.IPMaxRequests,
+		"Password resets an IP address can request per password-reset-window, 0 to disable")
+	fs.IntVar(&c.PasswordReset.MaxEmails, PasswordResetMaxEmails, c.PasswordReset.MaxEmails,
+		"Password reset emails sent to an address per password-reset-window, further requests are ignored")
+	fs.DurationVar(&c.PasswordReset.TokenExpiry, PasswordResetTokenExpiry, c.PasswordReset.TokenExpiry,
+		"Password reset token expiry")
+	fs.DurationVar(&c.PasswordReset.Window, PasswordResetWindow, c.PasswordReset.Window,
+		"Period over which the password resets requested are counted")
+
+	fs.DurationVar(&c.PersonalAccessTokens.CleanupInterval, PersonalAccessTokensCleanupInterval, c.PersonalAccessTokens.CleanupInterval,
+		"How often owners of expiring personal access tokens are emailed and old tokens deleted, 0 to disable")
+	fs.DurationVar(&c.PersonalAccessTokens.ExpiryReminder, PersonalAccessTokensExpiryReminder, c.PersonalAccessTokens.ExpiryReminder,
+		"How long before their personal access tokens expire owners are emailed, 0 to disable")
+	fs.DurationVar(&c.PersonalAccessTokens.Retention, PersonalAccessTokensRetention, c.PersonalAccessTokens.Retention,
+		"How long revoked and expired personal access tokens are kept before being deleted")
+
+	fs.DurationVar(&c.Restrictions.SweepInterval, RestrictionsSweepInterval, c.Restrictions.SweepInterval,
+		"How often the bans and locks past their end date are lifted, 0 to only lift them when the users are read")
+
+	fs.StringVar(&c.SMTP.Host, SMTPHost, c.SMTP.Host, "SMTP host, emails are logged instead of sent when unset")
+	fs.IntVar(&c.SMTP.Port, SMTPPort, c.SMTP.Port, "SMTP port")
+	fs.StringVar(&c.SMTP.Username, SMTPUsername, c.SMTP.Username, "SMTP username")
+	fs.StringVar(&c.SMTP.Password, SMTPPassword, c.SMTP.Password, "SMTP password")
+	fs.StringVar(&c.SMTP.From, SMTPFrom, c.SMTP.From, "SMTP sender address")
+
+	fs.StringVar(&c.WebAuthn.RPDisplayName, WebAuthnRPDisplayName, c.WebAuthn.RPDisplayName,
+		"WebAuthn relying party name shown by authenticators, defaults to the app name")
+	fs.StringVar(&c.WebAuthn.RPID, WebAuthnRPID, c.WebAuthn.RPID, "WebAuthn relying party id, the domain of the app")
+	fs.StringSliceVar(&c.WebAuthn.RPOrigins, WebAuthnRPOrigins, c.WebAuthn.RPOrigins, "WebAuthn allowed origins")
+	fs.DurationVar(&c.WebAuthn.Timeout, WebAuthnTimeout, c.WebAuthn.Timeout,
+		"Time allowed to complete a WebAuthn registration or login")
+}
+
+// BindFlags adds the flags of the password policy and hashing to fs, cmd/superuser shares them.
+func (p *Password) BindFlags(fs *pflag.FlagSet) {
+	fs.Uint32Var(&p.Argon2Memory, PasswordArgon2Memory, p.Argon2Memory,
+		"Memory used to hash passwords and tokens with argon2 in KiB, see cmd/argon2 to pick the argon2 parameters")
+	fs.Uint8Var(&p.Argon2Parallelism, PasswordArgon2Parallelism, p.Argon2Parallelism,
+		"Threads used to hash passwords and tokens with argon2")
+	fs.Uint32Var(&p.Argon2Time, PasswordArgon2Time, p.Argon2Time,
+		"Passes over the memory to hash passwords and tokens with argon2, "+
+			"hashes made with other parameters are replaced when next used")
+	fs.StringVar(&p.BreachedFile, PasswordBreachedFile, p.BreachedFile,
+		"File of the SHA-1 hashes of breached passwords users can't pick, one per line like 'HASH:COUNT'")
+	fs.IntVar(&p.MinClasses, PasswordMinClasses, p.MinClasses,
+		"Minimum number of character classes in passwords: lowercase, uppercase, digits and symbols")
+	fs.IntVar(&p.MinLength, PasswordMinLength, p.MinLength, "Minimum length of passwords")
+	fs.BoolVar(&p.RejectUserInfo, PasswordRejectUserInfo, p.RejectUserInfo,
+		"Reject passwords containing the username or email of the user")
 }
 
 func (c *Config) BindFlags() {
@@ -193,6 +538,7 @@ func (c *Config) BindFlags() {
 	}
 
 	c.addFlags(pflag.CommandLine)
+	c.Password.BindFlags(pflag.CommandLine)
 	c.Logging.BindFlags(pflag.CommandLine)
 	c.HTTP.BindFlags(pflag.CommandLine)
 	c.MongoDB.BindFlags(pflag.CommandLine)
@@ -215,6 +561,12 @@ func (c *Config) BindFlags() {
 		log.Panic().Msg("CSRF: secret key is unset!")
 	}
 
+	switch viper.GetString(EmailVerificationPolicy) {
+	case EmailVerificationPolicyNone, EmailVerificationPolicyLogin, EmailVerificationPolicyRoles:
+	default:
+		log.Panic().Msgf("email verification: invalid policy '%s'", viper.GetString(EmailVerificationPolicy))
+	}
+
 	if viper.GetBool(libHttp.HTTPCORSEnabled) {
 		for _, origin := range viper.GetStringSlice(libHttp.HTTPCORSAllowOrigins) {
 			if origin == "*" {
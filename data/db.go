@@ -59,6 +59,30 @@ func createIndexes(client *mongo.Client) error {
 				Unique: &t,
 			},
 		},
+		{
+			// an account at a provider can only be linked to one user
+			Keys: bson.D{
+				{"identities.provider", 1},
+				{"identities.subject", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique:                  &t,
+				PartialFilterExpression: bson.D{{"identities.subject", bson.D{{"$type", "string"}}}},
+			},
+		},
+		{
+			// bans and locks with an end date, for the sweeper
+			Keys: bson.D{{"banned_until", 1}},
+			Options: &options.IndexOptions{
+				PartialFilterExpression: bson.D{{"banned_until", bson.D{{"$type", "date"}}}},
+			},
+		},
+		{
+			Keys: bson.D{{"locked_until", 1}},
+			Options: &options.IndexOptions{
+				PartialFilterExpression: bson.D{{"locked_until", bson.D{{"$type", "date"}}}},
+			},
+		},
 	}
 
 	indexes["tasks"] = []mongo.IndexModel{
@@ -100,6 +124,215 @@ func createIndexes(client *mongo.Client) error {
 				Unique: &t,
 			},
 		},
+		{
+			Keys: bson.D{
+				{"expires_at", 1},
+			},
+		},
+	}
+
+	indexes["service_accounts"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			// deleted service accounts give up their name
+			Keys: bson.D{{"name", 1}},
+			Options: &options.IndexOptions{
+				Unique:                  &t,
+				Collation:               &options.Collation{Locale: "en", Strength: 2},
+				PartialFilterExpression: bson.D{{"deleted_at", bson.D{{"$type", "null"}}}},
+			},
+		},
+		{
+			Keys: bson.D{
+				{"credentials.client_id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique:                  &t,
+				PartialFilterExpression: bson.D{{"credentials.client_id", bson.D{{"$type", "string"}}}},
+			},
+		},
+	}
+
+	var expireAfter int32 = 0
+	indexes["sessions"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{"expires_at", 1},
+			},
+			Options: &options.IndexOptions{
+				ExpireAfterSeconds: &expireAfter,
+			},
+		},
+	}
+
+	indexes["email_verifications"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"expires_at", 1},
+			},
+			Options: &options.IndexOptions{
+				ExpireAfterSeconds: &expireAfter,
+			},
+		},
+	}
+
+	indexes["magic_links"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+				{"created_at", 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{"delete_at", 1},
+			},
+			Options: &options.IndexOptions{
+				ExpireAfterSeconds: &expireAfter,
+			},
+		},
+	}
+
+	indexes["password_resets"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+				{"created_at", 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{"expires_at", 1},
+			},
+			Options: &options.IndexOptions{
+				ExpireAfterSeconds: &expireAfter,
+			},
+		},
+	}
+
+	indexes["login_attempts"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"expires_at", 1},
+			},
+			Options: &options.IndexOptions{
+				ExpireAfterSeconds: &expireAfter,
+			},
+		},
+	}
+
+	indexes["signing_keys"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"expires_at", 1},
+			},
+			Options: &options.IndexOptions{
+				ExpireAfterSeconds: &expireAfter,
+			},
+		},
+	}
+
+	indexes["webauthn_credentials"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"credential_id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"user_id", 1},
+			},
+		},
+	}
+
+	indexes["webauthn_challenges"] = []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{"id", 1},
+			},
+			Options: &options.IndexOptions{
+				Unique: &t,
+			},
+		},
+		{
+			Keys: bson.D{
+				{"expires_at", 1},
+			},
+			Options: &options.IndexOptions{
+				ExpireAfterSeconds: &expireAfter,
+			},
+		},
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
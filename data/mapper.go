@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson"
@@ -15,15 +16,22 @@ import (
 
 var ErrNoDocuments = errors.New("no documents in result")
 
+// illegalOperation is the code of the error standalone servers return to transactions.
+const illegalOperation = 20
+
 type Mapper interface {
 	Aggregate(ctx context.Context, pipeline mongo.Pipeline, results any, opts ...*options.AggregateOptions) (any, error)
 	Count(ctx context.Context, filter any, opts ...*options.CountOptions) (int64, error)
+	DeleteMany(ctx context.Context, filter any, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
 	Find(ctx context.Context, filter any, results any, opts ...*options.FindOptions) (any, error)
 	FindOne(ctx context.Context, filter any, result any, opts ...*options.FindOneOptions) (any, error)
 	FindOneAndUpdate(ctx context.Context, filter any, update any, result any, opts ...*options.FindOneAndUpdateOptions) (any, error)
+	FindOneAndModify(ctx context.Context, filter any, update any, result any, opts ...*options.FindOneAndUpdateOptions) (any, error)
 	InsertOne(ctx context.Context, document any, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
 	UpdateOne(ctx context.Context, filter any, update any, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(ctx context.Context, filter any, update any, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
 	GetNextSequence(ctx context.Context, name string) (*Sequence, error)
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type mapper struct {
@@ -82,6 +90,15 @@ func (m *mapper) Count(ctx context.Context, filter any, opts ...*options.CountOp
 	return count, nil
 }
 
+// DeleteMany removes documents for good, most collections are soft deleted instead.
+func (m *mapper) DeleteMany(ctx context.Context, filter any, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	res, err := m.collection.DeleteMany(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (m *mapper) Find(ctx context.Context, filter any, results any, opts ...*options.FindOptions) (any, error) {
 	if filter == nil {
 		filter = bson.D{}
@@ -125,7 +142,9 @@ func (m *mapper) FindOne(ctx context.Context, filter any, result any, opts ...*o
 func (m *mapper) FindOneAndUpdate(ctx context.Context, filter any, update any, result any, opts ...*options.FindOneAndUpdateOptions) (any, error) {
 	opts = append(opts, options.FindOneAndUpdate().SetReturnDocument(options.After))
 	res := m.collection.FindOneAndUpdate(ctx, filter, bson.D{{"$set", update}}, opts...)
-	if res.Err() != nil {
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, ErrNoDocuments
+	} else if res.Err() != nil {
 		return nil, res.Err()
 	}
 
@@ -139,6 +158,25 @@ func (m *mapper) FindOneAndUpdate(ctx context.Context, filter any, update any, r
 	return result, nil
 }
 
+// FindOneAndModify is FindOneAndUpdate with update used as is rather than as
+// the fields to $set, so it can use any update operator.
+func (m *mapper) FindOneAndModify(ctx context.Context, filter any, update any, result any, opts ...*options.FindOneAndUpdateOptions) (any, error) {
+	opts = append(opts, options.FindOneAndUpdate().SetReturnDocument(options.After))
+	res := m.collection.FindOneAndUpdate(ctx, filter, update, opts...)
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, ErrNoDocuments
+	} else if res.Err() != nil {
+		return nil, res.Err()
+	}
+
+	err := res.Decode(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (m *mapper) InsertOne(ctx context.Context, document any, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
 	res, err := m.collection.InsertOne(ctx, document, opts...)
 	return res, err
@@ -152,6 +190,14 @@ func (m *mapper) UpdateOne(ctx context.Context, filter any, update any, opts ...
 	return res, nil
 }
 
+func (m *mapper) UpdateMany(ctx context.Context, filter any, update any, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	res, err := m.collection.UpdateMany(ctx, filter, update, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 type Sequence struct {
 	Seq int `bson:"seq"`
 }
@@ -193,3 +239,36 @@ func (m *mapper) getSession() (mongo.Session, *options.TransactionOptions, error
 
 	return session, txnOpts, nil
 }
+
+// transactionsUnsupported is set once the server turned out to be a standalone
+// one, transactions need a replica set or a sharded cluster.
+var transactionsUnsupported atomic.Bool
+
+// WithTransaction runs fn in a transaction. What's written with the ctx passed to fn,
+// through any mapper, is committed together or not at all. fn can be retried
+// on transient errors so it shouldn't have other side effects. On a standalone
+// server fn runs without a transaction instead, and can be left half done.
+func (m *mapper) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if transactionsUnsupported.Load() {
+		return fn(ctx)
+	}
+
+	session, txnOpts, err := m.getSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(sc)
+	}, txnOpts)
+
+	var se mongo.ServerError
+	if errors.As(err, &se) && se.HasErrorCodeWithMessage(illegalOperation, "Transaction numbers") {
+		transactionsUnsupported.Store(true)
+		log.Warn().Msg("transactions aren't supported by standalone servers, writing without them")
+		return fn(ctx)
+	}
+
+	return err
+}
@@ -3,8 +3,9 @@ package handlers
 import (
 	"context"
 	"errors"
+	"math"
 	"net/http"
-	"slices"
+	"strconv"
 	"time"
 
 	"github.com/alexferl/echo-openapi"
@@ -18,37 +19,59 @@ import (
 	"github.com/alexferl/echo-boilerplate/models"
 	"github.com/alexferl/echo-boilerplate/services"
 	"github.com/alexferl/echo-boilerplate/util/cookie"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
 )
 
+// LoginAttemptService defines the service keeping track of failed logins.
+type LoginAttemptService interface {
+	Read(ctx context.Context, kind string, value string) (*models.LoginAttempt, error)
+	Fail(ctx context.Context, model *models.LoginAttempt) (*models.LoginAttempt, error)
+	Reset(ctx context.Context, model *models.LoginAttempt) error
+}
+
+var ErrLoginThrottled = errors.New("too many failed login attempts, try again later")
+
 type AuthHandler struct {
 	*openapi.Handler
-	svc UserService
+	svc                  UserService
+	sessionSvc           SessionService
+	emailVerificationSvc EmailVerificationService
+	loginAttemptSvc      LoginAttemptService
+	mailer               Mailer
 }
 
-func NewAuthHandler(openapi *openapi.Handler, svc UserService) *AuthHandler {
+func NewAuthHandler(
+	openapi *openapi.Handler,
+	svc UserService,
+	sessionSvc SessionService,
+	emailVerificationSvc EmailVerificationService,
+	loginAttemptSvc LoginAttemptService,
+	mailer Mailer,
+) *AuthHandler {
 	return &AuthHandler{
-		Handler: openapi,
-		svc:     svc,
+		Handler:              openapi,
+		svc:                  svc,
+		sessionSvc:           sessionSvc,
+		emailVerificationSvc: emailVerificationSvc,
+		loginAttemptSvc:      loginAttemptSvc,
+		mailer:               mailer,
 	}
 }
 
 func (h *AuthHandler) Register(s *server.Server) {
 	s.Add(http.MethodPost, "/auth/login", h.login)
+	s.Add(http.MethodPost, "/auth/login/mfa", h.loginMFA)
 	s.Add(http.MethodPost, "/auth/logout", h.logout)
 	s.Add(http.MethodPost, "/auth/refresh", h.refresh)
 	s.Add(http.MethodPost, "/auth/signup", h.signup)
 	s.Add(http.MethodGet, "/auth/token", h.token)
-
-	if slices.Contains(viper.GetStringSlice(config.OAuth2Providers), "google") {
-		s.Add(http.MethodGet, "/oauth2/google/callback", h.oauth2GoogleCallback)
-		s.Add(http.MethodGet, "/oauth2/google/login", h.oauth2GoogleLogin)
-	}
 }
 
 type LoginRequest struct {
-	Email    string `json:"email,omitempty"`
-	Password string `json:"password"`
-	Username string `json:"username,omitempty"`
+	DeviceName string `json:"device_name,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Password   string `json:"password"`
+	Username   string `json:"username,omitempty"`
 }
 
 type LoginResponse struct {
@@ -68,47 +91,250 @@ func (h *AuthHandler) login(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
+	ip, err := h.loginAttemptSvc.Read(ctx, models.LoginAttemptIP, c.RealIP())
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting login attempt")
+		return err
+	}
+
+	if d := ip.RetryAfter(); d > 0 {
+		return loginThrottled(c, h.Handler, d)
+	}
+
 	user, err := h.svc.FindOneByEmailOrUsername(ctx, body.Email, body.Username)
 	if err != nil {
 		var se *services.Error
 		if errors.As(err, &se) {
-			if se.Kind == services.NotExist {
-				return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "invalid email or password"})
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.loginFailed(ctx, c, ip)
 			}
 		}
 		log.Error().Err(err).Msg("failed finding user")
 		return err
 	}
 
+	account, err := h.loginAttemptSvc.Read(ctx, models.LoginAttemptAccount, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting login attempt")
+		return err
+	}
+
+	if d := account.RetryAfter(); d > 0 {
+		return loginThrottled(c, h.Handler, d)
+	}
+
 	err = user.ValidatePassword(body.Password)
 	if err != nil {
-		return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "invalid email or password"})
+		return h.loginFailed(ctx, c, ip, account)
 	}
 
-	access, refresh, err := user.Login()
+	// upgrade hashes made with older argon2 parameters while the password is known
+	rehashed, err := user.RehashPassword(body.Password)
 	if err != nil {
-		log.Error().Err(err).Msg("failed generating tokens")
+		log.Error().Err(err).Msg("failed rehashing password")
 		return err
 	}
 
-	_, err = h.svc.Update(ctx, "", user)
+	if rehashed {
+		_, err = h.svc.Update(ctx, "", user)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating user")
+			return err
+		}
+	}
+
+	// the IP isn't reset, otherwise logging into an account of their own
+	// would let an attacker keep guessing the passwords of others. With MFA
+	// the account is only reset once the code is valid, otherwise the password
+	// would give a fresh set of attempts at guessing codes.
+	if account.Failures > 0 && !user.IsMFAEnabled() {
+		err = h.loginAttemptSvc.Reset(ctx, account)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating login attempt")
+			return err
+		}
+	}
+
+	if emailVerificationRequired(user) {
+		return h.Validate(c, http.StatusForbidden, echo.Map{"message": ErrEmailNotVerified.Error()})
+	}
+
+	if user.IsMFAEnabled() {
+		resp, err := newMFAChallenge(user, body.DeviceName)
+		if err != nil {
+			log.Error().Err(err).Msg("failed generating mfa token")
+			return err
+		}
+
+		return h.Validate(c, http.StatusOK, resp)
+	}
+
+	return createSession(ctx, c, h.Handler, h.svc, h.sessionSvc, user, body.DeviceName)
+}
+
+// loginFailed records a failed login for each of attempts.
+func (h *AuthHandler) loginFailed(ctx context.Context, c echo.Context, attempts ...*models.LoginAttempt) error {
+	for _, attempt := range attempts {
+		_, err := h.loginAttemptSvc.Fail(ctx, attempt)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating login attempt")
+			return err
+		}
+	}
+
+	return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "invalid email or password"})
+}
+
+func loginThrottled(c echo.Context, h *openapi.Handler, retryAfter time.Duration) error {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return h.Validate(c, http.StatusTooManyRequests, echo.Map{"message": ErrLoginThrottled.Error()})
+}
+
+// reauthFailed reports whether err is a wrong password or MFA code given
+// by a signed in user, which counts as a failed login of their account.
+func reauthFailed(err error) bool {
+	var me *models.Error
+	if !errors.As(err, &me) {
+		return false
+	}
+	return me.Message == models.ErrPasswordInvalid.Error() || me.Message == models.ErrMFACodeInvalid.Error()
+}
+
+type LoginMFARequest struct {
+	Code     string `json:"code"`
+	MFAToken string `json:"mfa_token"`
+}
+
+func (h *AuthHandler) loginMFA(c echo.Context) error {
+	body := &LoginMFARequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	token, err := jwt.ParseEncoded([]byte(body.MFAToken))
+	if err != nil || token.PrivateClaims()["type"] != jwt.MFAToken.String() {
+		return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token invalid"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.svc.Read(ctx, token.Subject())
 	if err != nil {
-		log.Error().Err(err).Msg("failed updating user")
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token invalid"})
+			}
+		}
+		log.Error().Err(err).Msg("failed getting user")
 		return err
 	}
 
+	account, err := h.loginAttemptSvc.Read(ctx, models.LoginAttemptAccount, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting login attempt")
+		return err
+	}
+
+	if d := account.RetryAfter(); d > 0 {
+		return loginThrottled(c, h.Handler, d)
+	}
+
+	// wrong codes are counted per token as well, the token is
+	// invalidated once they reach the maximum
+	challenge, err := h.loginAttemptSvc.Read(ctx, models.LoginAttemptMFA, token.JwtID())
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting login attempt")
+		return err
+	}
+
+	if challenge.Locked() {
+		return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token invalid"})
+	}
+
+	if err = user.ValidateMFA(body.Code); err != nil {
+		for _, attempt := range []*models.LoginAttempt{account, challenge} {
+			_, err = h.loginAttemptSvc.Fail(ctx, attempt)
+			if err != nil {
+				log.Error().Err(err).Msg("failed updating login attempt")
+				return err
+			}
+		}
+		return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": models.ErrMFACodeInvalid.Error()})
+	}
+
+	if account.Failures > 0 {
+		err = h.loginAttemptSvc.Reset(ctx, account)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating login attempt")
+			return err
+		}
+	}
+
+	deviceName, _ := token.PrivateClaims()["device_name"].(string)
+
+	return createSession(ctx, c, h.Handler, h.svc, h.sessionSvc, user, deviceName)
+}
+
+// createSession logs in user on a new session and responds with its tokens.
+// Every login method ends here.
+func createSession(
+	ctx context.Context,
+	c echo.Context,
+	h *openapi.Handler,
+	svc UserService,
+	sessionSvc SessionService,
+	user *models.User,
+	deviceName string,
+) error {
+	resp, err := startSession(ctx, c, svc, sessionSvc, user, deviceName)
+	if err != nil {
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, resp)
+}
+
+// startSession logs in user and sets the token cookies if they're enabled.
+func startSession(
+	ctx context.Context,
+	c echo.Context,
+	svc UserService,
+	sessionSvc SessionService,
+	user *models.User,
+	deviceName string,
+) (*LoginResponse, error) {
+	session := newSession(c, user.Id, deviceName)
+	access, refresh, err := user.Login(session)
+	if err != nil {
+		log.Error().Err(err).Msg("failed generating tokens")
+		return nil, err
+	}
+
+	_, err = sessionSvc.Create(ctx, session)
+	if err != nil {
+		log.Error().Err(err).Msg("failed inserting session")
+		return nil, err
+	}
+
+	_, err = svc.Update(ctx, "", user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating user")
+		return nil, err
+	}
+
 	if viper.GetBool(config.CookiesEnabled) {
 		cookie.SetToken(c, access, refresh)
 	}
 
-	resp := &LoginResponse{
+	return &LoginResponse{
 		AccessToken:  string(access),
 		ExpiresIn:    int64(viper.GetDuration(config.JWTAccessTokenExpiry).Seconds()),
 		RefreshToken: string(refresh),
 		TokenType:    "Bearer",
-	}
-
-	return h.Validate(c, http.StatusOK, resp)
+	}, nil
 }
 
 type LogoutRequest struct {
@@ -117,6 +343,7 @@ type LogoutRequest struct {
 
 func (h *AuthHandler) logout(c echo.Context) error {
 	currentUser := c.Get("user").(*models.User)
+	token := c.Get("refresh_token").(jwx.Token)
 	encodedToken := c.Get("refresh_token_encoded").(string)
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
@@ -134,11 +361,27 @@ func (h *AuthHandler) logout(c echo.Context) error {
 		return err
 	}
 
-	if err = user.ValidateRefreshToken(encodedToken); err != nil {
-		return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token mismatch"})
+	sid, _ := token.PrivateClaims()["sid"].(string)
+	session, err := h.sessionSvc.Read(ctx, user.Id, sid)
+	if err != nil {
+		return h.readSession(c, err)
+	}
+
+	if session.IsRevoked {
+		return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token revoked"})
 	}
 
-	user.Logout()
+	if err = session.ValidateRefreshToken(encodedToken); err != nil {
+		return h.reusedToken(ctx, c, session)
+	}
+
+	previous := session.RefreshToken
+	user.Logout(session)
+
+	err = h.sessionSvc.CompareAndSwap(ctx, session, previous)
+	if err != nil {
+		return h.swapSession(ctx, c, session, err)
+	}
 
 	_, err = h.svc.Update(ctx, "", user)
 	if err != nil {
@@ -182,16 +425,34 @@ func (h *AuthHandler) refresh(c echo.Context) error {
 		return err
 	}
 
-	if err = user.ValidateRefreshToken(encodedToken); err != nil {
-		return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token mismatch"})
+	sid, _ := token.PrivateClaims()["sid"].(string)
+	session, err := h.sessionSvc.Read(ctx, user.Id, sid)
+	if err != nil {
+		return h.readSession(c, err)
+	}
+
+	if session.IsRevoked {
+		return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token revoked"})
+	}
+
+	if err = session.ValidateRefreshToken(encodedToken); err != nil {
+		return h.reusedToken(ctx, c, session)
 	}
 
-	access, refresh, err := user.Refresh()
+	previous := session.RefreshToken
+	session.IP = c.RealIP()
+	session.UserAgent = c.Request().UserAgent()
+	access, refresh, err := user.Refresh(session)
 	if err != nil {
 		log.Error().Err(err).Msg("failed generating tokens")
 		return err
 	}
 
+	err = h.sessionSvc.CompareAndSwap(ctx, session, previous)
+	if err != nil {
+		return h.swapSession(ctx, c, session, err)
+	}
+
 	_, err = h.svc.Update(ctx, "", user)
 	if err != nil {
 		log.Error().Err(err).Msg("failed updating user")
@@ -212,6 +473,52 @@ func (h *AuthHandler) refresh(c echo.Context) error {
 	return h.Validate(c, http.StatusOK, resp)
 }
 
+func (h *AuthHandler) readSession(c echo.Context, err error) error {
+	var se *services.Error
+	if errors.As(err, &se) {
+		if se.Kind == services.NotExist {
+			return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token not found"})
+		}
+	}
+	log.Error().Err(err).Msg("failed getting session")
+	return err
+}
+
+// reusedToken revokes the session when a refresh token that was already
+// rotated is presented again since it's assumed to be stolen.
+func (h *AuthHandler) reusedToken(ctx context.Context, c echo.Context, session *models.Session) error {
+	log.Warn().Str("session_id", session.Id).Msg("refresh token reuse detected, revoking session")
+
+	session.Revoke("", models.SessionRevokedReuse)
+	_, err := h.sessionSvc.Update(ctx, session)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating session")
+		return err
+	}
+
+	return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": "token mismatch"})
+}
+
+// swapSession handles err from replacing the refresh token of session, it changing
+// in the meantime means the token was used concurrently so it was reused.
+func (h *AuthHandler) swapSession(ctx context.Context, c echo.Context, session *models.Session, err error) error {
+	var se *services.Error
+	if errors.As(err, &se) && se.Kind == services.Conflict {
+		return h.reusedToken(ctx, c, session)
+	}
+
+	log.Error().Err(err).Msg("failed updating session")
+	return err
+}
+
+func newSession(c echo.Context, userId string, deviceName string) *models.Session {
+	session := models.NewSession(userId)
+	session.DeviceName = deviceName
+	session.IP = c.RealIP()
+	session.UserAgent = c.Request().UserAgent()
+	return session
+}
+
 type SignUpRequest struct {
 	Email    string `json:"email"`
 	Username string `json:"username"`
@@ -247,6 +554,9 @@ func (h *AuthHandler) signup(c echo.Context) error {
 	user.Bio = body.Bio
 	err = user.SetPassword(body.Password)
 	if err != nil {
+		if m, ok := passwordPolicyErrors(err); ok {
+			return h.Validate(c, http.StatusUnprocessableEntity, m)
+		}
 		log.Error().Err(err).Msg("failed setting password")
 		return err
 	}
@@ -266,6 +576,11 @@ func (h *AuthHandler) signup(c echo.Context) error {
 		return err
 	}
 
+	err = sendEmailVerification(ctx, h.emailVerificationSvc, h.mailer, res)
+	if err != nil {
+		log.Error().Err(err).Msg("failed sending email verification")
+	}
+
 	return h.Validate(c, http.StatusOK, res.Response())
 }
 
@@ -273,8 +588,10 @@ type TokenResponse struct {
 	Exp   time.Time `json:"exp"`
 	Iat   time.Time `json:"iat"`
 	Iss   string    `json:"iss"`
+	Jti   string    `json:"jti"`
 	Nbf   time.Time `json:"nbf"`
 	Roles []string  `json:"roles"`
+	Sid   string    `json:"sid,omitempty"`
 	Sub   string    `json:"sub"`
 	Type  string    `json:"type"`
 }
@@ -2,11 +2,14 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	jwtMw "github.com/alexferl/echo-jwt"
 	"github.com/alexferl/echo-openapi"
@@ -22,19 +25,32 @@ import (
 	"github.com/alexferl/echo-boilerplate/services"
 	"github.com/alexferl/echo-boilerplate/util/cookie"
 	"github.com/alexferl/echo-boilerplate/util/jwt"
+	"github.com/alexferl/echo-boilerplate/util/totp"
 )
 
 type AuthHandlerTestSuite struct {
 	suite.Suite
-	svc    *handlers.MockUserService
-	server *api.Server
+	svc                  *handlers.MockUserService
+	sessionSvc           *handlers.MockSessionService
+	emailVerificationSvc *handlers.MockEmailVerificationService
+	loginAttemptSvc      *handlers.MockLoginAttemptService
+	mailer               *handlers.MockMailer
+	server               *api.Server
 }
 
 func (s *AuthHandlerTestSuite) SetupTest() {
 	svc := handlers.NewMockUserService(s.T())
 	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
-	h := handlers.NewAuthHandler(openapi.NewHandler(), svc)
+	sessionSvc := handlers.NewMockSessionService(s.T())
+	emailVerificationSvc := handlers.NewMockEmailVerificationService(s.T())
+	loginAttemptSvc := handlers.NewMockLoginAttemptService(s.T())
+	m := handlers.NewMockMailer(s.T())
+	h := handlers.NewAuthHandler(openapi.NewHandler(), svc, sessionSvc, emailVerificationSvc, loginAttemptSvc, m)
 	s.svc = svc
+	s.sessionSvc = sessionSvc
+	s.emailVerificationSvc = emailVerificationSvc
+	s.loginAttemptSvc = loginAttemptSvc
+	s.mailer = m
 	s.server = getServer(svc, patSvc, h)
 }
 
@@ -42,6 +58,15 @@ func TestAuthHandlerTestSuite(t *testing.T) {
 	suite.Run(t, new(AuthHandlerTestSuite))
 }
 
+// expectLoginAttempts makes the account and IP of logins have no previous failures.
+func (s *AuthHandlerTestSuite) expectLoginAttempts() {
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, kind string, value string) (*models.LoginAttempt, error) {
+			return models.NewLoginAttempt(kind, value), nil
+		})
+}
+
 func (s *AuthHandlerTestSuite) TestAuthHandler_Login_200() {
 	pwd := "abcdefghijkl"
 	user := models.NewUser("test@example.com", "test")
@@ -64,10 +89,16 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Login_200() {
 			req.Header.Set("Content-Type", "application/json")
 			resp := httptest.NewRecorder()
 
+			s.expectLoginAttempts()
+
 			s.svc.EXPECT().
 				FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
 				Return(user, nil)
 
+			s.sessionSvc.EXPECT().
+				Create(mock.Anything, mock.Anything).
+				Return(nil, nil)
+
 			s.svc.EXPECT().
 				Update(mock.Anything, mock.Anything, mock.Anything).
 				Return(user, nil)
@@ -115,11 +146,15 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Login_401() {
 	wrongEmail, _ := json.Marshal(&handlers.LoginRequest{Email: "wrong", Password: user.Password})
 
 	testCases := []struct {
-		name    string
-		payload []byte
+		name     string
+		payload  []byte
+		user     *models.User
+		kind     services.Kind
+		attempts []string
 	}{
-		{"user does not exist", wrongEmail},
-		{"wrong password", wrongPwd},
+		{"user does not exist", wrongEmail, nil, services.NotExist, []string{models.LoginAttemptIP}},
+		{"user deleted", wrongEmail, nil, services.Deleted, []string{models.LoginAttemptIP}},
+		{"wrong password", wrongPwd, user, 0, []string{models.LoginAttemptIP, models.LoginAttemptAccount}},
 	}
 
 	for _, tc := range testCases {
@@ -128,11 +163,29 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Login_401() {
 			req.Header.Set("Content-Type", "application/json")
 			resp := httptest.NewRecorder()
 
-			s.svc.EXPECT().
-				FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
-				Return(nil, &services.Error{
-					Kind: services.NotExist,
-				})
+			s.loginAttemptSvc.EXPECT().
+				Read(mock.Anything, mock.Anything, mock.Anything).
+				RunAndReturn(func(ctx context.Context, kind string, value string) (*models.LoginAttempt, error) {
+					return models.NewLoginAttempt(kind, value), nil
+				}).Times(len(tc.attempts))
+
+			if tc.user != nil {
+				s.svc.EXPECT().
+					FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+					Return(tc.user, nil).Once()
+			} else {
+				s.svc.EXPECT().
+					FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+					Return(nil, &services.Error{
+						Kind: tc.kind,
+					}).Once()
+			}
+
+			var failed []*models.LoginAttempt
+			s.loginAttemptSvc.EXPECT().
+				Fail(mock.Anything, mock.Anything).
+				Run(func(ctx context.Context, model *models.LoginAttempt) { failed = append(failed, model) }).
+				Return(nil, nil).Times(len(tc.attempts))
 
 			s.server.ServeHTTP(resp, req)
 
@@ -141,10 +194,379 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Login_401() {
 
 			s.Assert().Equal(http.StatusUnauthorized, resp.Code)
 			s.Assert().Contains("invalid email or password", result.Message)
+			if s.Assert().Len(failed, len(tc.attempts)) {
+				for i, kind := range tc.attempts {
+					s.Assert().Equal(kind, failed[i].Kind)
+				}
+			}
+		})
+	}
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_Login_429() {
+	pwd := "abcdefghijkl"
+	user := models.NewUser("test@example.com", "test")
+	_ = user.SetPassword(pwd)
+
+	b, _ := json.Marshal(&handlers.LoginRequest{Email: user.Email, Password: pwd})
+
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	lastFailureAt := time.Now()
+
+	locked := models.NewLoginAttempt(models.LoginAttemptAccount, user.Id)
+	locked.Failures = 5
+	locked.LastFailureAt = &lastFailureAt
+	locked.LockedUntil = &lockedUntil
+
+	backoff := models.NewLoginAttempt(models.LoginAttemptAccount, user.Id)
+	backoff.Failures = 1
+	backoff.LastFailureAt = &lastFailureAt
+
+	testCases := []struct {
+		name       string
+		kind       string
+		attempt    *models.LoginAttempt
+		retryAfter string
+	}{
+		{"ip locked", models.LoginAttemptIP, locked, "600"},
+		{"account locked", models.LoginAttemptAccount, locked, "600"},
+		{"account backoff", models.LoginAttemptAccount, backoff, "1"},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(b))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			if tc.kind == models.LoginAttemptIP {
+				s.loginAttemptSvc.EXPECT().
+					Read(mock.Anything, models.LoginAttemptIP, mock.Anything).
+					Return(tc.attempt, nil).Once()
+			} else {
+				s.loginAttemptSvc.EXPECT().
+					Read(mock.Anything, models.LoginAttemptIP, mock.Anything).
+					Return(models.NewLoginAttempt(models.LoginAttemptIP, "192.0.2.1"), nil).Once()
+
+				s.svc.EXPECT().
+					FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+					Return(user, nil).Once()
+
+				s.loginAttemptSvc.EXPECT().
+					Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+					Return(tc.attempt, nil).Once()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusTooManyRequests, resp.Code)
+			s.Assert().Equal(handlers.ErrLoginThrottled.Error(), result.Message)
+			s.Assert().Equal(tc.retryAfter, resp.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_Login_200_Reset_Failures() {
+	pwd := "abcdefghijkl"
+	user := models.NewUser("test@example.com", "test")
+	_ = user.SetPassword(pwd)
+
+	b, _ := json.Marshal(&handlers.LoginRequest{Email: user.Email, Password: pwd})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	lastFailureAt := time.Now().Add(-time.Minute)
+	account := models.NewLoginAttempt(models.LoginAttemptAccount, user.Id)
+	account.Failures = 2
+	account.LastFailureAt = &lastFailureAt
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptIP, mock.Anything).
+		Return(models.NewLoginAttempt(models.LoginAttemptIP, "192.0.2.1"), nil)
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(account, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Reset(mock.Anything, account).
+		Return(nil)
+
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_Login_403_Email_Not_Verified() {
+	viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyLogin)
+	defer viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyNone)
+
+	pwd := "abcdefghijkl"
+	user := models.NewUser("test@example.com", "test")
+	_ = user.SetPassword(pwd)
+
+	b, _ := json.Marshal(&handlers.LoginRequest{Email: user.Email, Password: pwd})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.expectLoginAttempts()
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Equal(handlers.ErrEmailNotVerified.Error(), result.Message)
+}
+
+func getMFAUser(pwd string) (*models.User, string) {
+	user := models.NewUser("test@example.com", "test")
+	_ = user.SetPassword(pwd)
+	secret, _, _ := user.EnrollTOTP()
+	code, _ := totp.Code(secret, totp.Counter(time.Now())-1)
+	_, _ = user.ConfirmTOTP(code)
+	return user, secret
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_Login_200_MFA_Required() {
+	pwd := "abcdefghijkl"
+	user, _ := getMFAUser(pwd)
+
+	b, _ := json.Marshal(&handlers.LoginRequest{Email: user.Email, Password: pwd})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.expectLoginAttempts()
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.MFAChallengeResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Empty(resp.Result().Cookies())
+	s.Assert().True(result.MFARequired)
+	s.Assert().NotEqual("", result.MFAToken)
+
+	token, err := jwt.ParseEncoded([]byte(result.MFAToken))
+	if s.Assert().NoError(err) {
+		s.Assert().Equal(jwt.MFAToken.String(), token.PrivateClaims()["type"])
+	}
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_Login_200_Rehash() {
+	pwd := "abcdefghijkl"
+	viper.Set(config.PasswordArgon2Time, 1)
+	user, _ := getMFAUser(pwd)
+	viper.Set(config.PasswordArgon2Time, 3)
+
+	b, _ := json.Marshal(&handlers.LoginRequest{Email: user.Email, Password: pwd})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.expectLoginAttempts()
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	// saved before the MFA challenge which doesn't update the user otherwise
+	s.svc.EXPECT().
+		Update(mock.Anything, mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+			return strings.Contains(u.Password, ",t=3,") && u.ValidatePassword(pwd) == nil
+		})).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_LoginMFA_200() {
+	pwd := "abcdefghijkl"
+	user, secret := getMFAUser(pwd)
+	mfaToken, _ := jwt.GenerateMFAToken(user.Id, map[string]any{"device_name": "My Laptop"})
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+
+	b, _ := json.Marshal(&handlers.LoginMFARequest{Code: code, MFAToken: string(mfaToken)})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login/mfa", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.expectLoginAttempts()
+
+	var session *models.Session
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.Session) { session = model }).
+		Return(nil, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, mock.Anything, user).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.LoginResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotEqual("", result.AccessToken)
+	s.Assert().NotEqual("", result.RefreshToken)
+	if s.Assert().NotNil(session) {
+		s.Assert().Equal("My Laptop", session.DeviceName)
+	}
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_LoginMFA_401() {
+	pwd := "abcdefghijkl"
+	user, secret := getMFAUser(pwd)
+	mfaToken, _ := jwt.GenerateMFAToken(user.Id, nil)
+	access, _ := jwt.GenerateAccessToken(user.Id, nil)
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+
+	testCases := []struct {
+		name     string
+		token    string
+		code     string
+		readUser bool
+		msg      string
+	}{
+		{"malformed token", "invalid", code, false, "token invalid"},
+		{"access token", string(access), code, false, "token invalid"},
+		{"wrong code", string(mfaToken), "000000", true, models.ErrMFACodeInvalid.Error()},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			b, _ := json.Marshal(&handlers.LoginMFARequest{Code: tc.code, MFAToken: tc.token})
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/login/mfa", bytes.NewBuffer(b))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			if tc.readUser {
+				s.svc.EXPECT().
+					Read(mock.Anything, user.Id).
+					Return(user, nil).Once()
+
+				s.expectLoginAttempts()
+
+				s.loginAttemptSvc.EXPECT().
+					Fail(mock.Anything, mock.Anything).
+					Return(nil, nil).Twice()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+			s.Assert().Equal(tc.msg, result.Message)
 		})
 	}
 }
 
+func (s *AuthHandlerTestSuite) TestAuthHandler_LoginMFA_401_Exhausted() {
+	viper.Set(config.LoginThrottleBackoffBase, 0)
+	viper.Set(config.LoginThrottleAccountMaxAttempts, 0)
+	defer viper.Set(config.LoginThrottleBackoffBase, time.Second)
+	defer viper.Set(config.LoginThrottleAccountMaxAttempts, 5)
+
+	pwd := "abcdefghijkl"
+	user, secret := getMFAUser(pwd)
+	mfaToken, _ := jwt.GenerateMFAToken(user.Id, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	// keeps the failures like the datastore would
+	attempts := map[string]*models.LoginAttempt{}
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, kind string, value string) (*models.LoginAttempt, error) {
+			if a, ok := attempts[models.LoginAttemptId(kind, value)]; ok {
+				return a, nil
+			}
+			return models.NewLoginAttempt(kind, value), nil
+		})
+
+	s.loginAttemptSvc.EXPECT().
+		Fail(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, a *models.LoginAttempt) (*models.LoginAttempt, error) {
+			now := time.Now()
+			a.Failures++
+			a.LastFailureAt = &now
+			a.Lock()
+			attempts[a.Id] = a
+			return a, nil
+		})
+
+	login := func(code string) (int, string) {
+		b, _ := json.Marshal(&handlers.LoginMFARequest{Code: code, MFAToken: string(mfaToken)})
+
+		req := httptest.NewRequest(http.MethodPost, "/auth/login/mfa", bytes.NewBuffer(b))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+
+		s.server.ServeHTTP(resp, req)
+
+		var result echo.HTTPError
+		_ = json.Unmarshal(resp.Body.Bytes(), &result)
+		return resp.Code, result.Message.(string)
+	}
+
+	for i := 0; i < viper.GetInt(config.MFAMaxAttempts); i++ {
+		code, msg := login("000000")
+		s.Assert().Equal(http.StatusUnauthorized, code)
+		s.Assert().Equal(models.ErrMFACodeInvalid.Error(), msg)
+	}
+
+	valid, _ := totp.Code(secret, totp.Counter(time.Now()))
+	code, msg := login(valid)
+	s.Assert().Equal(http.StatusUnauthorized, code)
+	s.Assert().Equal("token invalid", msg)
+}
+
 func (s *AuthHandlerTestSuite) TestAuthHandler_Login_400() {
 	b, _ := json.Marshal(&handlers.LoginRequest{})
 
@@ -159,7 +581,8 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Login_400() {
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_204_Cookie() {
 	user := models.NewUser("test@example.com", "test")
-	_, refresh, _ := user.Login()
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
 
 	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -170,6 +593,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_204_Cookie() {
 		Read(mock.Anything, mock.Anything).
 		Return(user, nil)
 
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		CompareAndSwap(mock.Anything, session, session.RefreshToken).
+		Return(nil)
+
 	s.svc.EXPECT().
 		Update(mock.Anything, mock.Anything, mock.Anything).
 		Return(nil, nil)
@@ -181,7 +612,7 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_204_Cookie() {
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Cookie_Invalid() {
 	user := models.NewUser("test@example.com", "test")
-	_, _, _ = user.Login()
+	_, _, _ = user.Login(models.NewSession(user.Id))
 
 	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -199,8 +630,9 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Cookie_Invalid() {
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Cookie_Mismatch() {
 	user := models.NewUser("test@example.com", "test")
-	_, refresh, _ := user.Login()
-	user.Logout()
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
+	_, _, _ = user.Refresh(session)
 
 	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -211,6 +643,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Cookie_Mismatch() {
 		Read(mock.Anything, mock.Anything).
 		Return(user, nil)
 
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
 	s.server.ServeHTTP(resp, req)
 
 	var result echo.HTTPError
@@ -218,11 +658,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Cookie_Mismatch() {
 
 	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
 	s.Assert().Equal("token mismatch", result.Message)
+	s.Assert().True(session.IsRevoked)
+	s.Assert().Equal(models.SessionRevokedReuse, session.RevokedReason)
 }
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_204_Token() {
 	user := models.NewUser("test@example.com", "test")
-	_, refresh, _ := user.Login()
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
 
 	payload := &handlers.LogoutRequest{
 		RefreshToken: string(refresh),
@@ -237,6 +680,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_204_Token() {
 		Read(mock.Anything, mock.Anything).
 		Return(user, nil)
 
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		CompareAndSwap(mock.Anything, session, session.RefreshToken).
+		Return(nil)
+
 	s.svc.EXPECT().
 		Update(mock.Anything, mock.Anything, mock.Anything).
 		Return(nil, nil)
@@ -278,8 +729,9 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_400_Token_Missing() {
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Token_Mismatch() {
 	user := models.NewUser("test@example.com", "test")
-	_, refresh, _ := user.Login()
-	user.Logout()
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
+	_, _, _ = user.Refresh(session)
 
 	payload := &handlers.LogoutRequest{
 		RefreshToken: string(refresh),
@@ -294,6 +746,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Token_Mismatch() {
 		Read(mock.Anything, mock.Anything).
 		Return(user, nil)
 
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
 	s.server.ServeHTTP(resp, req)
 
 	var result echo.HTTPError
@@ -301,11 +761,80 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Token_Mismatch() {
 
 	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
 	s.Assert().Equal("token mismatch", result.Message)
+	s.Assert().True(session.IsRevoked)
+	s.Assert().Equal(models.SessionRevokedReuse, session.RevokedReason)
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_Logout_401_Token_Revoked() {
+	user := models.NewUser("test@example.com", "test")
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
+	user.Logout(session)
+
+	payload := &handlers.LogoutRequest{
+		RefreshToken: string(refresh),
+	}
+	b, _ := json.Marshal(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Equal("token revoked", result.Message)
+}
+
+func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Session_Not_Found() {
+	user := models.NewUser("test@example.com", "test")
+	_, refresh, _ := user.Login(models.NewSession(user.Id))
+
+	payload := &handlers.RefreshRequest{
+		GrantType:    "refresh_token",
+		RefreshToken: string(refresh),
+	}
+	b, _ := json.Marshal(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, &services.Error{
+			Kind:    services.NotExist,
+			Message: services.ErrSessionNotFound.Error(),
+		})
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Equal("token not found", result.Message)
 }
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_200_Cookie() {
 	user := models.NewUser("test@example.com", "test")
-	_, refresh, _ := user.Login()
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
 
 	payload := &handlers.RefreshRequest{
 		GrantType: "refresh_token",
@@ -321,6 +850,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_200_Cookie() {
 		Read(mock.Anything, mock.Anything).
 		Return(user, nil)
 
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		CompareAndSwap(mock.Anything, session, session.RefreshToken).
+		Return(nil)
+
 	s.svc.EXPECT().
 		Update(mock.Anything, mock.Anything, mock.Anything).
 		Return(nil, nil)
@@ -373,8 +910,9 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_400_Cookie_Missing() {
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Cookie_Invalid() {
 	user := models.NewUser("test@example.com", "test")
-	_, refresh, _ := user.Login()
-	user.Logout()
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
+	_, _, _ = user.Refresh(session)
 
 	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -385,6 +923,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Cookie_Invalid() {
 		Read(mock.Anything, mock.Anything).
 		Return(user, nil)
 
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
 	s.server.ServeHTTP(resp, req)
 
 	var result echo.HTTPError
@@ -392,11 +938,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Cookie_Invalid() {
 
 	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
 	s.Assert().Equal("token mismatch", result.Message)
+	s.Assert().True(session.IsRevoked)
+	s.Assert().Equal(models.SessionRevokedReuse, session.RevokedReason)
 }
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_200_Token() {
 	user := models.NewUser("test@example.com", "test")
-	_, refresh, _ := user.Login()
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
 
 	payload := &handlers.RefreshRequest{
 		GrantType:    "refresh_token",
@@ -412,6 +961,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_200_Token() {
 		Read(mock.Anything, mock.Anything).
 		Return(user, nil)
 
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		CompareAndSwap(mock.Anything, session, session.RefreshToken).
+		Return(nil)
+
 	s.svc.EXPECT().
 		Update(mock.Anything, mock.Anything, mock.Anything).
 		Return(nil, nil)
@@ -448,6 +1005,49 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_200_Token() {
 	s.Assert().NotEqual("", result.TokenType)
 }
 
+func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Concurrent() {
+	user := models.NewUser("test@example.com", "test")
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
+
+	payload := &handlers.RefreshRequest{
+		GrantType:    "refresh_token",
+		RefreshToken: string(refresh),
+	}
+	b, _ := json.Marshal(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	// another request rotated the token after it was read
+	s.sessionSvc.EXPECT().
+		CompareAndSwap(mock.Anything, session, session.RefreshToken).
+		Return(&services.Error{Kind: services.Conflict})
+
+	s.sessionSvc.EXPECT().
+		Update(mock.Anything, session).
+		Return(nil, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Equal("token mismatch", result.Message)
+	s.Assert().True(session.IsRevoked)
+	s.Assert().Equal(models.SessionRevokedReuse, session.RevokedReason)
+}
+
 func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_400_Token_Missing() {
 	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -484,8 +1084,9 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Token_Invalid() {
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Token_Mismatch() {
 	user := models.NewUser("test@example.com", "test")
-	_, refresh, _ := user.Login()
-	user.Logout()
+	session := models.NewSession(user.Id)
+	_, refresh, _ := user.Login(session)
+	_, _, _ = user.Refresh(session)
 
 	payload := &handlers.RefreshRequest{
 		GrantType:    "refresh_token",
@@ -501,6 +1102,14 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Token_Mismatch() {
 		Read(mock.Anything, mock.Anything).
 		Return(user, nil)
 
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
 	s.server.ServeHTTP(resp, req)
 
 	var result echo.HTTPError
@@ -508,6 +1117,8 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Refresh_401_Token_Mismatch() {
 
 	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
 	s.Assert().Equal("token mismatch", result.Message)
+	s.Assert().True(session.IsRevoked)
+	s.Assert().Equal(models.SessionRevokedReuse, session.RevokedReason)
 }
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Signup_200() {
@@ -536,9 +1147,23 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Signup_200() {
 			Name:     payload.Name,
 		}, nil)
 
+	var ev *models.EmailVerification
+	s.emailVerificationSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.EmailVerification) { ev = model }).
+		Return(nil, nil)
+
+	s.mailer.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		Return(nil)
+
 	s.server.ServeHTTP(resp, req)
 
 	s.Assert().Equal(http.StatusOK, resp.Code)
+	if s.Assert().NotNil(ev) {
+		s.Assert().Equal("1", ev.UserId)
+		s.Assert().Equal(payload.Email, ev.Email)
+	}
 }
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Signup_409() {
@@ -583,9 +1208,39 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Signup_422() {
 	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
 }
 
+func (s *AuthHandlerTestSuite) TestAuthHandler_Signup_422_Password_Policy() {
+	payload := &handlers.SignUpRequest{
+		Email:    "tester@example.com",
+		Username: "tester",
+		Name:     "Tester",
+		Password: "tester-password",
+	}
+	b, _ := json.Marshal(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/signup", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result struct {
+		Message string   `json:"message"`
+		Errors  []string `json:"errors"`
+	}
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+	s.Assert().Equal("validation error", result.Message)
+	s.Assert().Equal([]string{"password can't contain the username or email"}, result.Errors)
+}
+
 func (s *AuthHandlerTestSuite) TestAuthHandler_Token_200() {
 	user := models.NewUser("test@example.com", "test")
-	access, _, _ := user.Login()
+	access, _, _ := user.Login(models.NewSession(user.Id))
 	token, _ := jwt.ParseEncoded(access)
 
 	req := httptest.NewRequest(http.MethodGet, "/auth/token", nil)
@@ -630,7 +1285,7 @@ func (s *AuthHandlerTestSuite) TestAuthHandler_Token_401() {
 
 func (s *AuthHandlerTestSuite) TestAuthHandler_Cookie_200() {
 	user := models.NewUser("test@example.com", "test")
-	access, _, _ := user.Login()
+	access, _, _ := user.Login(models.NewSession(user.Id))
 	token, _ := jwt.ParseEncoded(access)
 
 	req := httptest.NewRequest(http.MethodGet, "/auth/token", nil)
@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/mailer"
+)
+
+type EmailVerificationService interface {
+	Create(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error)
+	Read(ctx context.Context, id string) (*models.EmailVerification, error)
+	Use(ctx context.Context, model *models.EmailVerification) error
+}
+
+var (
+	ErrEmailNotVerified              = errors.New("email not verified")
+	ErrEmailVerificationTokenInvalid = errors.New("invalid or expired token")
+)
+
+type EmailVerificationHandler struct {
+	*openapi.Handler
+	svc             EmailVerificationService
+	userSvc         UserService
+	loginAttemptSvc LoginAttemptService
+	mailer          Mailer
+}
+
+func NewEmailVerificationHandler(
+	openapi *openapi.Handler,
+	svc EmailVerificationService,
+	userSvc UserService,
+	loginAttemptSvc LoginAttemptService,
+	mailer Mailer,
+) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		Handler:         openapi,
+		svc:             svc,
+		userSvc:         userSvc,
+		loginAttemptSvc: loginAttemptSvc,
+		mailer:          mailer,
+	}
+}
+
+func (h *EmailVerificationHandler) Register(s *server.Server) {
+	s.Add(http.MethodPost, "/auth/verify-email", h.verify)
+	s.Add(http.MethodPost, "/auth/verify-email/resend", h.resend)
+	s.Add(http.MethodPut, "/me/email", h.change)
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func (h *EmailVerificationHandler) verify(c echo.Context) error {
+	body := &VerifyEmailRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	invalid := echo.Map{"message": ErrEmailVerificationTokenInvalid.Error()}
+
+	id, err := models.ParseEmailVerificationToken(body.Token)
+	if err != nil {
+		return h.Validate(c, http.StatusBadRequest, invalid)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	ev, err := h.svc.Read(ctx, id)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist {
+				return h.Validate(c, http.StatusBadRequest, invalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting email verification")
+		return err
+	}
+
+	if err = ev.Validate(body.Token); err != nil {
+		return h.Validate(c, http.StatusBadRequest, invalid)
+	}
+
+	user, err := h.userSvc.Read(ctx, ev.UserId)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.Validate(c, http.StatusBadRequest, invalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	if err = ev.Verify(user); err != nil {
+		return h.Validate(c, http.StatusBadRequest, invalid)
+	}
+
+	// the token is consumed along with updating the user so it can't be used twice,
+	// the pending email of user could have been taken since they asked for it,
+	// the token stays usable until then
+	err = h.userSvc.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.svc.Use(ctx, ev); err != nil {
+			return err
+		}
+
+		_, err := h.userSvc.Update(ctx, "", user)
+		return err
+	})
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			switch se.Kind {
+			case services.Conflict:
+				return h.Validate(c, http.StatusBadRequest, invalid)
+			case services.Exist:
+				return h.Validate(c, http.StatusConflict, echo.Map{"message": se.Message})
+			}
+		}
+		log.Error().Err(err).Msg("failed updating user")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+type ResendEmailVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// resend always responds the same way to avoid
+// disclosing which emails have an account.
+func (h *EmailVerificationHandler) resend(c echo.Context) error {
+	body := &ResendEmailVerificationRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.FindOneByEmailOrUsername(ctx, body.Email, "")
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.Validate(c, http.StatusNoContent, nil)
+			}
+		}
+		log.Error().Err(err).Msg("failed finding user")
+		return err
+	}
+
+	if user.IsBanned || user.IsEmailVerified() {
+		return h.Validate(c, http.StatusNoContent, nil)
+	}
+
+	err = sendEmailVerification(ctx, h.svc, h.mailer, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed sending email verification")
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+type ChangeEmailRequest struct {
+	Code     string `json:"code,omitempty"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// change sends a verification token to the new email of the current user,
+// it only replaces their current one once verified with /auth/verify-email.
+func (h *EmailVerificationHandler) change(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	body := &ChangeEmailRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.Read(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	// the password and MFA code are throttled like a login, otherwise
+	// a stolen session could be used to guess them without limit
+	account, err := h.loginAttemptSvc.Read(ctx, models.LoginAttemptAccount, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting login attempt")
+		return err
+	}
+
+	if d := account.RetryAfter(); d > 0 {
+		return loginThrottled(c, h.Handler, d)
+	}
+
+	err = user.ChangeEmail(body.Password, body.Code, body.Email)
+	if err != nil {
+		if reauthFailed(err) {
+			if _, err := h.loginAttemptSvc.Fail(ctx, account); err != nil {
+				log.Error().Err(err).Msg("failed updating login attempt")
+				return err
+			}
+		}
+		var me *models.Error
+		if errors.As(err, &me) {
+			msg := echo.Map{"message": me.Message}
+			if me.Kind == models.Conflict {
+				return h.Validate(c, http.StatusConflict, msg)
+			}
+			return h.Validate(c, http.StatusBadRequest, msg)
+		}
+		log.Error().Err(err).Msg("failed changing email")
+		return err
+	}
+
+	// lookups use the collation of the unique index so this also catches
+	// emails differing only by case, deleted users keep theirs in it
+	_, err = h.userSvc.FindOneByEmailOrUsername(ctx, body.Email, "")
+	var se *services.Error
+	if err == nil || errors.As(err, &se) && se.Kind == services.Deleted {
+		return h.Validate(c, http.StatusConflict, echo.Map{"message": services.ErrUserExist.Error()})
+	}
+	if !errors.As(err, &se) || se.Kind != services.NotExist {
+		log.Error().Err(err).Msg("failed finding user")
+		return err
+	}
+
+	if account.Failures > 0 {
+		err = h.loginAttemptSvc.Reset(ctx, account)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating login attempt")
+			return err
+		}
+	}
+
+	_, err = h.userSvc.Update(ctx, user.Id, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating user")
+		return err
+	}
+
+	err = sendEmailChange(ctx, h.svc, h.mailer, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed sending email verification")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+// sendEmailVerification stores a new verification token for the current
+// email of user and sends it to that address.
+func sendEmailVerification(ctx context.Context, svc EmailVerificationService, m Mailer, user *models.User) error {
+	token, err := createEmailVerification(ctx, svc, user.Id, user.Email)
+	if err != nil {
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Use the following token to verify your email, it expires in %s:\n\n%s\n\n"+
+				"If you didn't create an account, you can ignore this email.\n",
+			user.Username, viper.GetDuration(config.EmailVerificationTokenExpiry), token,
+		),
+	}
+
+	return m.Send(ctx, msg)
+}
+
+// sendEmailChange stores a new verification token for the pending email of
+// user and sends it to that address, the current one is told about the change.
+func sendEmailChange(ctx context.Context, svc EmailVerificationService, m Mailer, user *models.User) error {
+	token, err := createEmailVerification(ctx, svc, user.Id, user.PendingEmail)
+	if err != nil {
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      user.PendingEmail,
+		Subject: "Verify your new email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Use the following token to make this your new email, it expires in %s:\n\n%s\n\n"+
+				"If you didn't ask for this, you can ignore this email.\n",
+			user.Username, viper.GetDuration(config.EmailVerificationTokenExpiry), token,
+		),
+	}
+	if err = m.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	if user.Email == "" {
+		return nil
+	}
+
+	// failing to warn the current email shouldn't fail the change
+	notice := &mailer.Message{
+		To:      user.Email,
+		Subject: "Your email is being changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"A change of the email of your account to %s was requested, "+
+				"it will replace this one once verified.\n\n"+
+				"If you didn't ask for this, reset your password.\n",
+			user.Username, user.PendingEmail,
+		),
+	}
+	if err = m.Send(ctx, notice); err != nil {
+		log.Error().Err(err).Msg("failed sending email change notice")
+	}
+
+	return nil
+}
+
+// createEmailVerification stores a new verification token
+// for email and returns it before it's hashed.
+func createEmailVerification(ctx context.Context, svc EmailVerificationService, userId string, email string) (string, error) {
+	ev, err := models.NewEmailVerification(userId, email)
+	if err != nil {
+		return "", err
+	}
+
+	token := ev.Token
+	err = ev.Encrypt()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = svc.Create(ctx, ev)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// emailVerificationRequired reports whether user isn't allowed to log in yet.
+func emailVerificationRequired(user *models.User) bool {
+	return viper.GetString(config.EmailVerificationPolicy) == config.EmailVerificationPolicyLogin &&
+		!user.IsEmailVerified()
+}
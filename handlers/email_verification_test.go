@@ -0,0 +1,530 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/mailer"
+)
+
+type EmailVerificationHandlerTestSuite struct {
+	suite.Suite
+	svc             *handlers.MockEmailVerificationService
+	userSvc         *handlers.MockUserService
+	loginAttemptSvc *handlers.MockLoginAttemptService
+	mailer          *handlers.MockMailer
+	server          *api.Server
+}
+
+func (s *EmailVerificationHandlerTestSuite) SetupTest() {
+	svc := handlers.NewMockEmailVerificationService(s.T())
+	userSvc := handlers.NewMockUserService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	loginAttemptSvc := handlers.NewMockLoginAttemptService(s.T())
+	m := handlers.NewMockMailer(s.T())
+	h := handlers.NewEmailVerificationHandler(openapi.NewHandler(), svc, userSvc, loginAttemptSvc, m)
+	s.svc = svc
+	s.userSvc = userSvc
+	s.loginAttemptSvc = loginAttemptSvc
+	s.mailer = m
+	s.server = getServer(userSvc, patSvc, h)
+}
+
+func TestEmailVerificationHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(EmailVerificationHandlerTestSuite))
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Verify_204() {
+	user := getUser()
+	ev, _ := models.NewEmailVerification(user.Id, user.Email)
+	token := ev.Token
+	_ = ev.Encrypt()
+
+	b, _ := json.Marshal(&handlers.VerifyEmailRequest{Token: token})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ev.Id).
+		Return(ev, nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.userSvc.EXPECT().
+		WithTransaction(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) })
+
+	s.svc.EXPECT().
+		Use(mock.Anything, ev).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, "", user).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().NotNil(ev.UsedAt)
+	s.Assert().True(user.IsEmailVerified())
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Verify_400() {
+	user := getUser()
+
+	used, _ := models.NewEmailVerification(user.Id, user.Email)
+	usedToken := used.Token
+	_ = used.Encrypt()
+	_ = used.Verify(getUser())
+
+	expired, _ := models.NewEmailVerification(user.Id, user.Email)
+	expiredToken := expired.Token
+	_ = expired.Encrypt()
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+
+	mismatch, _ := models.NewEmailVerification(user.Id, user.Email)
+	_ = mismatch.Encrypt()
+
+	testCases := []struct {
+		name  string
+		token string
+		ev    *models.EmailVerification
+		err   error
+	}{
+		{"malformed", "invalid", nil, nil},
+		{"not found", "abc.def", nil, &services.Error{Kind: services.NotExist}},
+		{"used", usedToken, used, nil},
+		{"expired", expiredToken, expired, nil},
+		{"mismatch", mismatch.Id + ".wrong", mismatch, nil},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			b, _ := json.Marshal(&handlers.VerifyEmailRequest{Token: tc.token})
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", bytes.NewBuffer(b))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			if tc.ev != nil || tc.err != nil {
+				s.svc.EXPECT().
+					Read(mock.Anything, mock.Anything).
+					Return(tc.ev, tc.err).Once()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusBadRequest, resp.Code)
+			s.Assert().Equal(handlers.ErrEmailVerificationTokenInvalid.Error(), result.Message)
+		})
+	}
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Verify_400_Email_Changed() {
+	user := getUser()
+	ev, _ := models.NewEmailVerification(user.Id, user.Email)
+	token := ev.Token
+	_ = ev.Encrypt()
+	user.SetEmail("new@example.com")
+
+	b, _ := json.Marshal(&handlers.VerifyEmailRequest{Token: token})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ev.Id).
+		Return(ev, nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().False(user.IsEmailVerified())
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Resend_204() {
+	user := getUser()
+	b, _ := json.Marshal(&handlers.ResendEmailVerificationRequest{Email: user.Email})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email/resend", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, user.Email, "").
+		Return(user, nil)
+
+	var created *models.EmailVerification
+	s.svc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.EmailVerification) { created = model }).
+		Return(nil, nil)
+
+	var sent *mailer.Message
+	s.mailer.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, msg *mailer.Message) { sent = msg }).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	if s.Assert().NotNil(created) && s.Assert().NotNil(sent) {
+		s.Assert().Equal(user.Email, created.Email)
+		s.Assert().Equal(user.Email, sent.To)
+		s.Assert().Contains(sent.Body, created.Id+".")
+	}
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Resend_204_Skipped() {
+	verified := getUser()
+	verified.VerifyEmail()
+
+	testCases := []struct {
+		name string
+		user *models.User
+		err  error
+	}{
+		{"not found", nil, &services.Error{Kind: services.NotExist}},
+		{"already verified", verified, nil},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			b, _ := json.Marshal(&handlers.ResendEmailVerificationRequest{Email: "test@example.com"})
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/verify-email/resend", bytes.NewBuffer(b))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			s.userSvc.EXPECT().
+				FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+				Return(tc.user, tc.err).Once()
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusNoContent, resp.Code)
+			s.Assert().Empty(resp.Body.Bytes())
+		})
+	}
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_422() {
+	testCases := []struct {
+		endpoint string
+	}{
+		{"/auth/verify-email"},
+		{"/auth/verify-email/resend"},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.endpoint, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tc.endpoint, bytes.NewBuffer([]byte(`{}`)))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+		})
+	}
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Verify_204_Pending_Email() {
+	user := getUser()
+	user.VerifyEmail()
+	user.PendingEmail = "new@example.com"
+	ev, _ := models.NewEmailVerification(user.Id, user.PendingEmail)
+	token := ev.Token
+	_ = ev.Encrypt()
+
+	b, _ := json.Marshal(&handlers.VerifyEmailRequest{Token: token})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ev.Id).
+		Return(ev, nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.userSvc.EXPECT().
+		WithTransaction(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) })
+
+	s.svc.EXPECT().
+		Use(mock.Anything, ev).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, "", user).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().Equal("new@example.com", user.Email)
+	s.Assert().Empty(user.PendingEmail)
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Verify_409() {
+	user := getUser()
+	user.PendingEmail = "taken@example.com"
+	ev, _ := models.NewEmailVerification(user.Id, user.PendingEmail)
+	token := ev.Token
+	_ = ev.Encrypt()
+
+	b, _ := json.Marshal(&handlers.VerifyEmailRequest{Token: token})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ev.Id).
+		Return(ev, nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.userSvc.EXPECT().
+		WithTransaction(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) })
+
+	s.svc.EXPECT().
+		Use(mock.Anything, ev).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, "", user).
+		Return(nil, &services.Error{Kind: services.Exist, Message: services.ErrUserExist.Error()})
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Verify_400_Used_Concurrently() {
+	user := getUser()
+	ev, _ := models.NewEmailVerification(user.Id, user.Email)
+	token := ev.Token
+	_ = ev.Encrypt()
+
+	b, _ := json.Marshal(&handlers.VerifyEmailRequest{Token: token})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ev.Id).
+		Return(ev, nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.userSvc.EXPECT().
+		WithTransaction(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error { return fn(ctx) })
+
+	// another request used it after it was read
+	s.svc.EXPECT().
+		Use(mock.Anything, ev).
+		Return(&services.Error{Kind: services.Conflict})
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Equal(handlers.ErrEmailVerificationTokenInvalid.Error(), result.Message)
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Change_204() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	b, _ := json.Marshal(&handlers.ChangeEmailRequest{Email: "new@example.com", Password: "current-password"})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(models.NewLoginAttempt(models.LoginAttemptAccount, user.Id), nil)
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "new@example.com", "").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, user.Id, user).
+		Return(user, nil)
+
+	var created *models.EmailVerification
+	s.svc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.EmailVerification) { created = model }).
+		Return(nil, nil)
+
+	var sent []*mailer.Message
+	s.mailer.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, msg *mailer.Message) { sent = append(sent, msg) }).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().Equal("new@example.com", user.PendingEmail)
+	s.Assert().Equal("new@example.com", created.Email)
+	s.Assert().Len(sent, 2)
+	s.Assert().Equal("new@example.com", sent[0].To)
+	s.Assert().Equal(user.Email, sent[1].To)
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Change_400() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	b, _ := json.Marshal(&handlers.ChangeEmailRequest{Email: "new@example.com", Password: "wrong-password"})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(models.NewLoginAttempt(models.LoginAttemptAccount, user.Id), nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Fail(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Equal(models.ErrPasswordInvalid.Error(), result.Message)
+	s.Assert().Empty(user.PendingEmail)
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Change_409() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	b, _ := json.Marshal(&handlers.ChangeEmailRequest{Email: "admin@example.com", Password: "current-password"})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(models.NewLoginAttempt(models.LoginAttemptAccount, user.Id), nil)
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "admin@example.com", "").
+		Return(getAdmin(), nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(services.ErrUserExist.Error(), result.Message)
+}
+
+func (s *EmailVerificationHandlerTestSuite) TestEmailVerificationHandler_Change_429() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	b, _ := json.Marshal(&handlers.ChangeEmailRequest{Email: "new@example.com", Password: "current-password"})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/email", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	lastFailureAt := time.Now()
+	locked := models.NewLoginAttempt(models.LoginAttemptAccount, user.Id)
+	locked.Failures = 5
+	locked.LastFailureAt = &lastFailureAt
+	locked.LockedUntil = &lockedUntil
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(locked, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusTooManyRequests, resp.Code)
+	s.Assert().Equal(handlers.ErrLoginThrottled.Error(), result.Message)
+	s.Assert().Equal("600", resp.Header().Get("Retry-After"))
+	s.Assert().Empty(user.PendingEmail)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/util/idp"
+)
+
+var ErrIdentityNotFound = errors.New("identity not found")
+
+type IdentityHandler struct {
+	*openapi.Handler
+	providers     *idp.Registry
+	svc           UserService
+	credentialSvc WebAuthnCredentialService
+}
+
+func NewIdentityHandler(
+	openapi *openapi.Handler,
+	providers *idp.Registry,
+	svc UserService,
+	credentialSvc WebAuthnCredentialService,
+) *IdentityHandler {
+	return &IdentityHandler{
+		Handler:       openapi,
+		providers:     providers,
+		svc:           svc,
+		credentialSvc: credentialSvc,
+	}
+}
+
+func (h *IdentityHandler) Register(s *server.Server) {
+	s.Add(http.MethodGet, "/me/identities", h.list)
+	s.Add(http.MethodPost, "/me/identities/:provider", h.link)
+	s.Add(http.MethodDelete, "/me/identities/:provider", h.unlink)
+}
+
+func (h *IdentityHandler) list(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.svc.Read(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, user.IdentitiesResponse())
+}
+
+type LinkIdentityRequest struct {
+	Redirect string `json:"redirect"`
+}
+
+type LinkIdentityResponse struct {
+	URL string `json:"url"`
+}
+
+// link starts linking an account at a provider, the user has to be sent to the
+// returned URL and the provider's callback links the account once they consent.
+func (h *IdentityHandler) link(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	body := &LinkIdentityRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	provider, ok := h.providers.Get(c.Param("provider"))
+	if !ok {
+		return h.Validate(c, http.StatusNotFound, echo.Map{"message": ErrOAuth2ProviderNotFound.Error()})
+	}
+
+	if body.Redirect != "" && (!viper.GetBool(config.CookiesEnabled) || !redirectAllowed(body.Redirect)) {
+		return h.Validate(c, http.StatusBadRequest, echo.Map{"message": ErrOAuth2RedirectInvalid.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	url, err := authorize(ctx, c, provider, &oauth2State{Link: currentUser.Id, Redirect: body.Redirect})
+	if err != nil {
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, &LinkIdentityResponse{URL: url})
+}
+
+func (h *IdentityHandler) unlink(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.svc.Read(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	provider := c.Param("provider")
+	if user.Identity(provider) == nil {
+		return h.Validate(c, http.StatusNotFound, echo.Map{"message": ErrIdentityNotFound.Error()})
+	}
+
+	credentials, err := h.credentialSvc.Find(ctx, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting webauthn credentials")
+		return err
+	}
+
+	err = user.UnlinkIdentity(provider, len(credentials) > 0)
+	if err != nil {
+		var me *models.Error
+		if errors.As(err, &me) && me.Kind == models.Conflict {
+			return h.Validate(c, http.StatusConflict, echo.Map{"message": me.Message})
+		}
+		return err
+	}
+
+	_, err = h.svc.Update(ctx, user.Id, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating user")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
@@ -0,0 +1,368 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/idp"
+	"github.com/alexferl/echo-boilerplate/util/idp/idptest"
+)
+
+type IdentityHandlerTestSuite struct {
+	suite.Suite
+	svc           *handlers.MockUserService
+	credentialSvc *handlers.MockWebAuthnCredentialService
+	server        *api.Server
+	idp           *idptest.Server
+	user          *models.User
+	accessToken   []byte
+}
+
+func (s *IdentityHandlerTestSuite) SetupTest() {
+	s.idp = idptest.NewServer()
+
+	viper.Set(config.OAuth2Providers, []string{"fake"})
+	viper.Set(config.OAuth2ProviderKey("fake", config.OAuth2ProviderClientId), idptest.ClientId)
+	viper.Set(config.OAuth2ProviderKey("fake", config.OAuth2ProviderClientSecret), idptest.ClientSecret)
+	viper.Set(config.OAuth2ProviderKey("fake", config.OAuth2ProviderDiscoveryURL), s.idp.DiscoveryURL())
+
+	providers, err := idp.NewRegistry()
+	s.Require().NoError(err)
+
+	svc := handlers.NewMockUserService(s.T())
+	credentialSvc := handlers.NewMockWebAuthnCredentialService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	sessionSvc := handlers.NewMockSessionService(s.T())
+	emailVerificationSvc := handlers.NewMockEmailVerificationService(s.T())
+	m := handlers.NewMockMailer(s.T())
+	h := handlers.NewIdentityHandler(openapi.NewHandler(), providers, svc, credentialSvc)
+	oauth2 := handlers.NewOAuth2Handler(openapi.NewHandler(), providers, svc, sessionSvc, emailVerificationSvc, m)
+	user := getUser()
+	user.Password = "hash"
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	s.svc = svc
+	s.credentialSvc = credentialSvc
+	s.server = getServer(svc, patSvc, h, oauth2)
+	s.user = user
+	s.accessToken = access
+}
+
+func (s *IdentityHandlerTestSuite) TearDownTest() {
+	s.idp.Close()
+	viper.Set(config.OAuth2Providers, []string{""})
+}
+
+func TestIdentityHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(IdentityHandlerTestSuite))
+}
+
+// link starts linking the fake provider and returns the request the provider redirects the user back with.
+func (s *IdentityHandlerTestSuite) link(redirect string) *http.Request {
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	b, _ := json.Marshal(&handlers.LinkIdentityRequest{Redirect: redirect})
+	req := httptest.NewRequest(http.MethodPost, "/me/identities/fake", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.LinkIdentityResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Require().Equal(http.StatusOK, resp.Code)
+
+	callback, err := s.idp.Authorize(result.URL)
+	s.Require().NoError(err)
+
+	req = httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, c := range resp.Result().Cookies() {
+		if c.Name == "state" {
+			req.AddCookie(c)
+		}
+	}
+
+	return req
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_List_200() {
+	_ = s.user.LinkIdentity("fake", "1234567890", "test@example.com")
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/me/identities", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.IdentitiesResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	if s.Assert().Len(result.Identities, 1) {
+		s.Assert().Equal("fake", result.Identities[0].Provider)
+		s.Assert().Equal("test@example.com", result.Identities[0].Email)
+	}
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Link_200() {
+	req := s.link("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "test@example.com", "").
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, s.user.Id, s.user).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.IdentitiesResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Len(result.Identities, 1)
+	if identity := s.user.Identity("fake"); s.Assert().NotNil(identity) {
+		s.Assert().Equal("1234567890", identity.Subject)
+	}
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Link_303_Redirect() {
+	viper.Set(config.OAuth2RedirectAllowList, []string{"https://example.com/app/"})
+	defer viper.Set(config.OAuth2RedirectAllowList, []string{})
+
+	req := s.link("https://example.com/app/settings")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "test@example.com", "").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		Update(mock.Anything, s.user.Id, s.user).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusSeeOther, resp.Code)
+	s.Assert().Equal("https://example.com/app/settings", resp.Header().Get("Location"))
+	s.Assert().NotNil(s.user.Identity("fake"))
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Link_400_Redirect() {
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	b, _ := json.Marshal(&handlers.LinkIdentityRequest{Redirect: "https://evil.com/"})
+	req := httptest.NewRequest(http.MethodPost, "/me/identities/fake", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Link_404() {
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/me/identities/unknown", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusNotFound, resp.Code)
+	s.Assert().Equal(handlers.ErrOAuth2ProviderNotFound.Error(), result.Message)
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Link_409_Identity_Exist() {
+	other := getAdmin()
+	_ = other.LinkIdentity("fake", "1234567890", "test@example.com")
+	req := s.link("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(other, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(handlers.ErrOAuth2IdentityExist.Error(), result.Message)
+	s.Assert().Nil(s.user.Identity("fake"))
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Link_409_Email_Exist() {
+	req := s.link("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "test@example.com", "").
+		Return(getAdmin(), nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(handlers.ErrOAuth2EmailExist.Error(), result.Message)
+	s.Assert().Nil(s.user.Identity("fake"))
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Link_409_Provider_Linked() {
+	_ = s.user.LinkIdentity("fake", "other", "test@example.com")
+	req := s.link("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "test@example.com", "").
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(models.ErrIdentityExist.Error(), result.Message)
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Unlink_204() {
+	_ = s.user.LinkIdentity("fake", "1234567890", "test@example.com")
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.credentialSvc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.WebAuthnCredentials{}, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, s.user.Id, s.user).
+		Return(s.user, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/me/identities/fake", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().Nil(s.user.Identity("fake"))
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Unlink_404() {
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/me/identities/fake", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusNotFound, resp.Code)
+	s.Assert().Equal(handlers.ErrIdentityNotFound.Error(), result.Message)
+}
+
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Unlink_409_Last_Login() {
+	s.user.Password = ""
+	_ = s.user.LinkIdentity("fake", "1234567890", "test@example.com")
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.credentialSvc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.WebAuthnCredentials{}, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/me/identities/fake", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(models.ErrIdentityLastLogin.Error(), result.Message)
+	s.Assert().NotNil(s.user.Identity("fake"))
+}
+
+// linking doesn't log in, even when the account is already linked
+func (s *IdentityHandlerTestSuite) TestIdentityHandler_Link_200_Already_Linked() {
+	req := s.link("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result map[string]any
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Contains(result, "identities")
+	s.Assert().NotContains(result, "access_token")
+}
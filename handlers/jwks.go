@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+type JWKSHandler struct {
+	*openapi.Handler
+}
+
+func NewJWKSHandler(openapi *openapi.Handler) *JWKSHandler {
+	return &JWKSHandler{
+		Handler: openapi,
+	}
+}
+
+func (h *JWKSHandler) Register(s *server.Server) {
+	s.Add(http.MethodGet, "/.well-known/jwks.json", h.JWKS)
+}
+
+// JWKS returns the public keys verifying tokens, the active one and the retired ones.
+func (h *JWKSHandler) JWKS(c echo.Context) error {
+	return h.Validate(c, http.StatusOK, jwt.PublicKeys())
+}
@@ -0,0 +1,44 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/handlers"
+	_ "github.com/alexferl/echo-boilerplate/testing"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+func TestHandler_JWKS(t *testing.T) {
+	h := handlers.NewJWKSHandler(openapi.NewHandler())
+	userSvc := handlers.NewMockUserService(t)
+	patSvc := handlers.NewMockPersonalAccessTokenService(t)
+	s := getServer(userSvc, patSvc, h)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	resp := httptest.NewRecorder()
+
+	s.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	set, err := jwk.Parse(resp.Body.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, set.Len())
+
+	key, _ := set.Key(0)
+	_, ok := key.(jwk.RSAPublicKey)
+	assert.True(t, ok)
+
+	// the keys verify tokens
+	token, err := jwt.GenerateAccessToken("123", nil)
+	assert.NoError(t, err)
+	_, err = jws.Verify(token, jws.WithKeySet(set))
+	assert.NoError(t, err)
+}
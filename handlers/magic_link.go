@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/cookie"
+	"github.com/alexferl/echo-boilerplate/util/mailer"
+	"github.com/alexferl/echo-boilerplate/util/rand"
+)
+
+type MagicLinkService interface {
+	Create(ctx context.Context, model *models.MagicLink) (*models.MagicLink, error)
+	CountSince(ctx context.Context, userId string, t time.Time) (int64, error)
+	Read(ctx context.Context, id string) (*models.MagicLink, error)
+	Use(ctx context.Context, model *models.MagicLink) error
+}
+
+var (
+	ErrMagicLinkInvalid      = errors.New("invalid or expired link")
+	ErrMagicLinkOtherBrowser = errors.New("link must be opened in the browser it was requested from")
+)
+
+const magicLinkCookieName = "magic_link"
+
+type MagicLinkHandler struct {
+	*openapi.Handler
+	svc        MagicLinkService
+	userSvc    UserService
+	sessionSvc SessionService
+	mailer     Mailer
+}
+
+func NewMagicLinkHandler(
+	openapi *openapi.Handler,
+	svc MagicLinkService,
+	userSvc UserService,
+	sessionSvc SessionService,
+	mailer Mailer,
+) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		Handler:    openapi,
+		svc:        svc,
+		userSvc:    userSvc,
+		sessionSvc: sessionSvc,
+		mailer:     mailer,
+	}
+}
+
+func (h *MagicLinkHandler) Register(s *server.Server) {
+	s.Add(http.MethodPost, "/auth/magic-link", h.request)
+	s.Add(http.MethodGet, "/auth/magic-link/callback", h.callback)
+}
+
+type MagicLinkRequest struct {
+	Email    string `json:"email"`
+	Redirect string `json:"redirect"`
+}
+
+// request always responds the same way to avoid disclosing which emails
+// have an account, or that too many links were sent to one.
+func (h *MagicLinkHandler) request(c echo.Context) error {
+	body := &MagicLinkRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	// the tokens can only be handed over with cookies when redirecting
+	if body.Redirect != "" && (!viper.GetBool(config.CookiesEnabled) || !redirectAllowed(body.Redirect)) {
+		return h.Validate(c, http.StatusBadRequest, echo.Map{"message": ErrOAuth2RedirectInvalid.Error()})
+	}
+
+	nonce, err := rand.GenerateRandomString(32)
+	if err != nil {
+		log.Error().Err(err).Msg("failed generating nonce")
+		return err
+	}
+
+	c.SetCookie(magicLinkCookie(nonce, int(viper.GetDuration(config.MagicLinkTokenExpiry).Seconds())))
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.FindOneByEmailOrUsername(ctx, body.Email, "")
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.Validate(c, http.StatusNoContent, nil)
+			}
+		}
+		log.Error().Err(err).Msg("failed finding user")
+		return err
+	}
+
+	if user.IsBanned {
+		return h.Validate(c, http.StatusNoContent, nil)
+	}
+
+	since := time.Now().Add(-viper.GetDuration(config.MagicLinkWindow))
+	count, err := h.svc.CountSince(ctx, user.Id, since)
+	if err != nil {
+		log.Error().Err(err).Msg("failed counting magic links")
+		return err
+	}
+
+	if count >= int64(viper.GetInt(config.MagicLinkMaxEmails)) {
+		log.Warn().Str("user_id", user.Id).Msg("too many magic links requested")
+		return h.Validate(c, http.StatusNoContent, nil)
+	}
+
+	ml, err := models.NewMagicLink(user.Id, nonce, body.Redirect)
+	if err != nil {
+		log.Error().Err(err).Msg("failed generating magic link")
+		return err
+	}
+
+	token := ml.Token
+	err = ml.Encrypt()
+	if err != nil {
+		log.Error().Err(err).Msg("failed encrypting magic link")
+		return err
+	}
+
+	_, err = h.svc.Create(ctx, ml)
+	if err != nil {
+		log.Error().Err(err).Msg("failed inserting magic link")
+		return err
+	}
+
+	link := fmt.Sprintf("%s/auth/magic-link/callback?%s",
+		viper.GetString(config.BaseURL), url.Values{"token": {token}}.Encode())
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Open the following link in the browser you requested it from to log in, "+
+				"it expires in %s and can only be used once:\n\n%s\n\n"+
+				"If you didn't request this, you can ignore this email.\n",
+			user.Username, viper.GetDuration(config.MagicLinkTokenExpiry), link,
+		),
+	}
+	if err = h.mailer.Send(ctx, msg); err != nil {
+		log.Error().Err(err).Msg("failed sending magic link email")
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+// callback logs in the user of the link. Links only work along with the
+// cookie set when they were requested, and aren't used up otherwise.
+func (h *MagicLinkHandler) callback(c echo.Context) error {
+	invalid := echo.Map{"message": ErrMagicLinkInvalid.Error()}
+	otherBrowser := echo.Map{"message": ErrMagicLinkOtherBrowser.Error()}
+
+	nonce, err := c.Cookie(magicLinkCookieName)
+	if err != nil {
+		return h.Validate(c, http.StatusUnauthorized, otherBrowser)
+	}
+
+	token := c.QueryParam("token")
+	id, err := models.ParseMagicLinkToken(token)
+	if err != nil {
+		return h.Validate(c, http.StatusUnauthorized, invalid)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	ml, err := h.svc.Read(ctx, id)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist {
+				return h.Validate(c, http.StatusUnauthorized, invalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting magic link")
+		return err
+	}
+
+	if err = ml.Validate(token, nonce.Value); err != nil {
+		if errors.Is(err, models.ErrMagicLinkNonceInvalid) {
+			return h.Validate(c, http.StatusUnauthorized, otherBrowser)
+		}
+		return h.Validate(c, http.StatusUnauthorized, invalid)
+	}
+
+	// consume the link before anything else so it can't be used twice
+	err = h.svc.Use(ctx, ml)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.Conflict {
+				return h.Validate(c, http.StatusUnauthorized, invalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed updating magic link")
+		return err
+	}
+	c.SetCookie(magicLinkCookie("", -1))
+
+	user, err := h.userSvc.Read(ctx, ml.UserId)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.Validate(c, http.StatusUnauthorized, invalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	// receiving the link proves the user owns the email
+	if !user.IsEmailVerified() {
+		user.VerifyEmail()
+		_, err = h.userSvc.Update(ctx, user.Id, user)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating user")
+			return err
+		}
+	}
+
+	return completeLogin(ctx, c, h.Handler, h.userSvc, h.sessionSvc, user, ml.Redirect)
+}
+
+func magicLinkCookie(value string, maxAge int) *http.Cookie {
+	return cookie.New(&cookie.Options{
+		Name:     magicLinkCookieName,
+		Value:    value,
+		Path:     "/auth/magic-link/callback",
+		SameSite: http.SameSiteLaxMode, // needs to be Lax since it's opened from emails
+		HttpOnly: true,
+		MaxAge:   maxAge,
+	})
+}
@@ -0,0 +1,441 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/mailer"
+)
+
+type MagicLinkHandlerTestSuite struct {
+	suite.Suite
+	svc        *handlers.MockMagicLinkService
+	userSvc    *handlers.MockUserService
+	sessionSvc *handlers.MockSessionService
+	mailer     *handlers.MockMailer
+	server     *api.Server
+}
+
+func (s *MagicLinkHandlerTestSuite) SetupTest() {
+	svc := handlers.NewMockMagicLinkService(s.T())
+	userSvc := handlers.NewMockUserService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	sessionSvc := handlers.NewMockSessionService(s.T())
+	m := handlers.NewMockMailer(s.T())
+	h := handlers.NewMagicLinkHandler(openapi.NewHandler(), svc, userSvc, sessionSvc, m)
+	s.svc = svc
+	s.userSvc = userSvc
+	s.sessionSvc = sessionSvc
+	s.mailer = m
+	s.server = getServer(userSvc, patSvc, h)
+}
+
+func TestMagicLinkHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(MagicLinkHandlerTestSuite))
+}
+
+func (s *MagicLinkHandlerTestSuite) request(body *handlers.MagicLinkRequest) *http.Request {
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, "/auth/magic-link", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func magicLinkCookie(resp *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range resp.Result().Cookies() {
+		if c.Name == "magic_link" {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Request_204() {
+	user := getUser()
+	req := s.request(&handlers.MagicLinkRequest{Email: user.Email})
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, user.Email, "").
+		Return(user, nil)
+
+	s.svc.EXPECT().
+		CountSince(mock.Anything, user.Id, mock.Anything).
+		Return(0, nil)
+
+	var created *models.MagicLink
+	s.svc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.MagicLink) { created = model }).
+		Return(nil, nil)
+
+	var sent *mailer.Message
+	s.mailer.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, msg *mailer.Message) { sent = msg }).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	cookie := magicLinkCookie(resp)
+	if s.Assert().NotNil(cookie) {
+		s.Assert().Equal("/auth/magic-link/callback", cookie.Path)
+		s.Assert().True(cookie.HttpOnly)
+		s.Assert().Equal(http.SameSiteLaxMode, cookie.SameSite)
+	}
+	if s.Assert().NotNil(created) && s.Assert().NotNil(sent) {
+		s.Assert().Equal(user.Id, created.UserId)
+		s.Assert().Equal(user.Email, sent.To)
+		s.Assert().Contains(sent.Body, viper.GetString(config.BaseURL)+"/auth/magic-link/callback?token="+created.Id+".")
+		s.Assert().False(strings.Contains(sent.Body, created.Token), "hash must not be sent")
+		s.Assert().NotEqual(cookie.Value, created.Nonce, "nonce must be hashed")
+	}
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Request_204_Not_Found() {
+	req := s.request(&handlers.MagicLinkRequest{Email: "nobody@example.com"})
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, &services.Error{
+			Kind:    services.NotExist,
+			Message: services.ErrUserNotFound.Error(),
+		})
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().NotNil(magicLinkCookie(resp), "responses must not differ")
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Request_204_Rate_Limited() {
+	user := getUser()
+	req := s.request(&handlers.MagicLinkRequest{Email: user.Email})
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.svc.EXPECT().
+		CountSince(mock.Anything, user.Id, mock.MatchedBy(func(t time.Time) bool {
+			return time.Since(t) >= viper.GetDuration(config.MagicLinkWindow)
+		})).
+		Return(int64(viper.GetInt(config.MagicLinkMaxEmails)), nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().NotNil(magicLinkCookie(resp))
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Request_400_Redirect() {
+	req := s.request(&handlers.MagicLinkRequest{Email: "test@example.com", Redirect: "https://evil.com/"})
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Equal(handlers.ErrOAuth2RedirectInvalid.Error(), result.Message)
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Request_422() {
+	req := httptest.NewRequest(http.MethodPost, "/auth/magic-link", bytes.NewBuffer([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+}
+
+// newMagicLink returns a stored magic link of user
+// with its token and the nonce of its cookie.
+func newMagicLink(user *models.User, redirect string) (*models.MagicLink, string, string) {
+	ml, _ := models.NewMagicLink(user.Id, "nonce", redirect)
+	token := ml.Token
+	_ = ml.Encrypt()
+	return ml, token, "nonce"
+}
+
+func (s *MagicLinkHandlerTestSuite) callback(token string, nonce string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/auth/magic-link/callback?"+url.Values{"token": {token}}.Encode(), nil)
+	if nonce != "" {
+		req.AddCookie(&http.Cookie{Name: "magic_link", Value: nonce})
+	}
+	return req
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Callback_200() {
+	user := getUser()
+	user.VerifyEmail()
+	ml, token, nonce := newMagicLink(user, "")
+	req := s.callback(token, nonce)
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ml.Id).
+		Return(ml, nil)
+
+	s.svc.EXPECT().
+		Use(mock.Anything, ml).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, mock.Anything, user).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.LoginResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotEqual("", result.AccessToken)
+	s.Assert().NotEqual("", result.RefreshToken)
+	if cookie := magicLinkCookie(resp); s.Assert().NotNil(cookie) {
+		s.Assert().Equal(-1, cookie.MaxAge)
+	}
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Callback_200_Verifies_Email() {
+	user := getUser()
+	ml, token, nonce := newMagicLink(user, "")
+	req := s.callback(token, nonce)
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ml.Id).
+		Return(ml, nil)
+
+	s.svc.EXPECT().
+		Use(mock.Anything, ml).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, user.Id, mock.MatchedBy(func(u *models.User) bool { return u.IsEmailVerified() })).
+		Return(user, nil).Once()
+
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, "", user).
+		Return(user, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().True(user.IsEmailVerified())
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Callback_200_MFA_Required() {
+	user, _ := getMFAUser("abcdefghijkl")
+	user.VerifyEmail()
+	ml, token, nonce := newMagicLink(user, "")
+	req := s.callback(token, nonce)
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ml.Id).
+		Return(ml, nil)
+
+	s.svc.EXPECT().
+		Use(mock.Anything, ml).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.MFAChallengeResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().True(result.MFARequired)
+	s.Assert().NotEqual("", result.MFAToken)
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Callback_303_Redirect() {
+	viper.Set(config.OAuth2RedirectAllowList, []string{"https://example.com/app/"})
+	defer viper.Set(config.OAuth2RedirectAllowList, []string{})
+
+	user := getUser()
+	user.VerifyEmail()
+	ml, token, nonce := newMagicLink(user, "https://example.com/app/home")
+	req := s.callback(token, nonce)
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ml.Id).
+		Return(ml, nil)
+
+	s.svc.EXPECT().
+		Use(mock.Anything, ml).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, mock.Anything, user).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	cookies := map[string]string{}
+	for _, c := range resp.Result().Cookies() {
+		cookies[c.Name] = c.Value
+	}
+
+	s.Assert().Equal(http.StatusSeeOther, resp.Code)
+	s.Assert().Equal("https://example.com/app/home", resp.Header().Get("Location"))
+	s.Assert().NotEqual("", cookies[viper.GetString(config.JWTAccessTokenCookieName)])
+	s.Assert().NotEqual("", cookies[viper.GetString(config.JWTRefreshTokenCookieName)])
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Callback_401_Used_Concurrently() {
+	user := getUser()
+	user.VerifyEmail()
+	ml, token, nonce := newMagicLink(user, "")
+	req := s.callback(token, nonce)
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, ml.Id).
+		Return(ml, nil)
+
+	// another request used it after it was read
+	s.svc.EXPECT().
+		Use(mock.Anything, ml).
+		Return(&services.Error{Kind: services.Conflict})
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Equal(handlers.ErrMagicLinkInvalid.Error(), result.Message)
+}
+
+// prefetching by mail scanners, which don't have the cookie, must not use up the link
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Callback_401_Other_Browser() {
+	user := getUser()
+	ml, token, _ := newMagicLink(user, "")
+
+	testCases := []struct {
+		name  string
+		nonce string
+	}{
+		{"without cookie", ""},
+		{"with other cookie", "other"},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			req := s.callback(token, tc.nonce)
+			resp := httptest.NewRecorder()
+
+			if tc.nonce != "" {
+				s.svc.EXPECT().
+					Read(mock.Anything, ml.Id).
+					Return(ml, nil).Once()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+			s.Assert().Equal(handlers.ErrMagicLinkOtherBrowser.Error(), result.Message)
+			s.Assert().Nil(ml.UsedAt)
+		})
+	}
+}
+
+func (s *MagicLinkHandlerTestSuite) TestMagicLinkHandler_Callback_401_Invalid() {
+	user := getUser()
+	used, usedToken, nonce := newMagicLink(user, "")
+	used.Use()
+	expired, expiredToken, _ := newMagicLink(user, "")
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+
+	testCases := []struct {
+		name  string
+		token string
+		ml    *models.MagicLink
+		err   error
+	}{
+		{"malformed", "invalid", nil, nil},
+		{"not found", "abc.def", nil, &services.Error{Kind: services.NotExist, Message: services.ErrMagicLinkNotFound.Error()}},
+		{"used", usedToken, used, nil},
+		{"expired", expiredToken, expired, nil},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			req := s.callback(tc.token, nonce)
+			resp := httptest.NewRecorder()
+
+			if tc.ml != nil || tc.err != nil {
+				s.svc.EXPECT().
+					Read(mock.Anything, mock.Anything).
+					Return(tc.ml, tc.err).Once()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+			s.Assert().Equal(handlers.ErrMagicLinkInvalid.Error(), result.Message)
+		})
+	}
+}
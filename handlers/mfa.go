@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+type MFAHandler struct {
+	*openapi.Handler
+	svc UserService
+}
+
+func NewMFAHandler(openapi *openapi.Handler, svc UserService) *MFAHandler {
+	return &MFAHandler{
+		Handler: openapi,
+		svc:     svc,
+	}
+}
+
+func (h *MFAHandler) Register(s *server.Server) {
+	s.Add(http.MethodPost, "/me/mfa/totp", h.enrollTOTP)
+	s.Add(http.MethodPost, "/me/mfa/totp/confirm", h.confirmTOTP)
+}
+
+type EnrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+func (h *MFAHandler) enrollTOTP(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.svc.Read(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	secret, uri, err := user.EnrollTOTP()
+	if err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	_, err = h.svc.Update(ctx, currentUser.Id, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating user")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, &EnrollTOTPResponse{Secret: secret, URI: uri})
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code"`
+}
+
+type ConfirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (h *MFAHandler) confirmTOTP(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	body := &ConfirmTOTPRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.svc.Read(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	codes, err := user.ConfirmTOTP(body.Code)
+	if err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	_, err = h.svc.Update(ctx, currentUser.Id, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating user")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, &ConfirmTOTPResponse{RecoveryCodes: codes})
+}
+
+func (h *MFAHandler) checkModelErr(c echo.Context, err error) error {
+	var me *models.Error
+	if errors.As(err, &me) {
+		switch me.Kind {
+		case models.Invalid:
+			return h.Validate(c, http.StatusBadRequest, echo.Map{"message": me.Message})
+		case models.Conflict:
+			return h.Validate(c, http.StatusConflict, echo.Map{"message": me.Message})
+		}
+	}
+	log.Error().Err(err).Msg("failed enabling totp")
+	return err
+}
+
+type MFAChallengeResponse struct {
+	ExpiresIn   int64  `json:"expires_in"`
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+// newMFAChallenge is returned instead of tokens to users with MFA enabled,
+// the MFA token has to be sent back along with a code to /auth/login/mfa.
+func newMFAChallenge(user *models.User, deviceName string) (*MFAChallengeResponse, error) {
+	claims := map[string]any{}
+	if deviceName != "" {
+		claims["device_name"] = deviceName
+	}
+
+	token, err := jwt.GenerateMFAToken(user.Id, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MFAChallengeResponse{
+		ExpiresIn:   int64(viper.GetDuration(config.MFAChallengeExpiry).Seconds()),
+		MFARequired: true,
+		MFAToken:    string(token),
+	}, nil
+}
@@ -0,0 +1,166 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/util/totp"
+)
+
+type MFAHandlerTestSuite struct {
+	suite.Suite
+	svc         *handlers.MockUserService
+	server      *api.Server
+	user        *models.User
+	accessToken []byte
+}
+
+func (s *MFAHandlerTestSuite) SetupTest() {
+	svc := handlers.NewMockUserService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	h := handlers.NewMFAHandler(openapi.NewHandler(), svc)
+	user := getUser()
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	s.svc = svc
+	s.server = getServer(svc, patSvc, h)
+	s.user = user
+	s.accessToken = access
+}
+
+func TestMFAHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(MFAHandlerTestSuite))
+}
+
+func (s *MFAHandlerTestSuite) TestMFAHandler_EnrollTOTP_200() {
+	req := httptest.NewRequest(http.MethodPost, "/me/mfa/totp", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, s.user.Id, s.user).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.EnrollTOTPResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotEmpty(result.Secret)
+	// only the encrypted secret is stored
+	s.Assert().NotEqual(s.user.TOTPSecret, result.Secret)
+	s.Assert().True(strings.HasPrefix(result.URI, "otpauth://totp/"))
+	s.Assert().False(s.user.IsMFAEnabled())
+}
+
+func (s *MFAHandlerTestSuite) TestMFAHandler_EnrollTOTP_409() {
+	secret, _, _ := s.user.EnrollTOTP()
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+	_, _ = s.user.ConfirmTOTP(code)
+
+	req := httptest.NewRequest(http.MethodPost, "/me/mfa/totp", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(models.ErrTOTPExist.Error(), result.Message)
+}
+
+func (s *MFAHandlerTestSuite) TestMFAHandler_ConfirmTOTP_200() {
+	secret, _, _ := s.user.EnrollTOTP()
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+	b, _ := json.Marshal(&handlers.ConfirmTOTPRequest{Code: code})
+
+	req := httptest.NewRequest(http.MethodPost, "/me/mfa/totp/confirm", strings.NewReader(string(b)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, s.user.Id, s.user).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.ConfirmTOTPResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Len(result.RecoveryCodes, models.RecoveryCodesCount)
+	s.Assert().True(s.user.IsMFAEnabled())
+}
+
+func (s *MFAHandlerTestSuite) TestMFAHandler_ConfirmTOTP_400() {
+	_, _, _ = s.user.EnrollTOTP()
+	b, _ := json.Marshal(&handlers.ConfirmTOTPRequest{Code: "000000"})
+
+	req := httptest.NewRequest(http.MethodPost, "/me/mfa/totp/confirm", strings.NewReader(string(b)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Equal(models.ErrMFACodeInvalid.Error(), result.Message)
+	s.Assert().False(s.user.IsMFAEnabled())
+}
+
+func (s *MFAHandlerTestSuite) TestMFAHandler_ConfirmTOTP_409_Not_Enrolled() {
+	b, _ := json.Marshal(&handlers.ConfirmTOTPRequest{Code: "123456"})
+
+	req := httptest.NewRequest(http.MethodPost, "/me/mfa/totp/confirm", strings.NewReader(string(b)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(models.ErrTOTPNotEnrolled.Error(), result.Message)
+}
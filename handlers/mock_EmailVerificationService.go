@@ -0,0 +1,202 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package handlers
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockEmailVerificationService is an autogenerated mock type for the EmailVerificationService type
+type MockEmailVerificationService struct {
+	mock.Mock
+}
+
+type MockEmailVerificationService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockEmailVerificationService) EXPECT() *MockEmailVerificationService_Expecter {
+	return &MockEmailVerificationService_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockEmailVerificationService) Create(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.EmailVerification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.EmailVerification) (*models.EmailVerification, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.EmailVerification) *models.EmailVerification); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.EmailVerification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.EmailVerification) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockEmailVerificationService_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockEmailVerificationService_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.EmailVerification
+func (_e *MockEmailVerificationService_Expecter) Create(ctx interface{}, model interface{}) *MockEmailVerificationService_Create_Call {
+	return &MockEmailVerificationService_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockEmailVerificationService_Create_Call) Run(run func(ctx context.Context, model *models.EmailVerification)) *MockEmailVerificationService_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.EmailVerification))
+	})
+	return _c
+}
+
+func (_c *MockEmailVerificationService_Create_Call) Return(_a0 *models.EmailVerification, _a1 error) *MockEmailVerificationService_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockEmailVerificationService_Create_Call) RunAndReturn(run func(context.Context, *models.EmailVerification) (*models.EmailVerification, error)) *MockEmailVerificationService_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Read provides a mock function with given fields: ctx, id
+func (_m *MockEmailVerificationService) Read(ctx context.Context, id string) (*models.EmailVerification, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Read")
+	}
+
+	var r0 *models.EmailVerification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.EmailVerification, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.EmailVerification); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.EmailVerification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockEmailVerificationService_Read_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Read'
+type MockEmailVerificationService_Read_Call struct {
+	*mock.Call
+}
+
+// Read is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockEmailVerificationService_Expecter) Read(ctx interface{}, id interface{}) *MockEmailVerificationService_Read_Call {
+	return &MockEmailVerificationService_Read_Call{Call: _e.mock.On("Read", ctx, id)}
+}
+
+func (_c *MockEmailVerificationService_Read_Call) Run(run func(ctx context.Context, id string)) *MockEmailVerificationService_Read_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockEmailVerificationService_Read_Call) Return(_a0 *models.EmailVerification, _a1 error) *MockEmailVerificationService_Read_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockEmailVerificationService_Read_Call) RunAndReturn(run func(context.Context, string) (*models.EmailVerification, error)) *MockEmailVerificationService_Read_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Use provides a mock function with given fields: ctx, model
+func (_m *MockEmailVerificationService) Use(ctx context.Context, model *models.EmailVerification) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Use")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.EmailVerification) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockEmailVerificationService_Use_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Use'
+type MockEmailVerificationService_Use_Call struct {
+	*mock.Call
+}
+
+// Use is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.EmailVerification
+func (_e *MockEmailVerificationService_Expecter) Use(ctx interface{}, model interface{}) *MockEmailVerificationService_Use_Call {
+	return &MockEmailVerificationService_Use_Call{Call: _e.mock.On("Use", ctx, model)}
+}
+
+func (_c *MockEmailVerificationService_Use_Call) Run(run func(ctx context.Context, model *models.EmailVerification)) *MockEmailVerificationService_Use_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.EmailVerification))
+	})
+	return _c
+}
+
+func (_c *MockEmailVerificationService_Use_Call) Return(_a0 error) *MockEmailVerificationService_Use_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockEmailVerificationService_Use_Call) RunAndReturn(run func(context.Context, *models.EmailVerification) error) *MockEmailVerificationService_Use_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockEmailVerificationService creates a new instance of MockEmailVerificationService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockEmailVerificationService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockEmailVerificationService {
+	mock := &MockEmailVerificationService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,203 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package handlers
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockLoginAttemptService is an autogenerated mock type for the LoginAttemptService type
+type MockLoginAttemptService struct {
+	mock.Mock
+}
+
+type MockLoginAttemptService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockLoginAttemptService) EXPECT() *MockLoginAttemptService_Expecter {
+	return &MockLoginAttemptService_Expecter{mock: &_m.Mock}
+}
+
+// Fail provides a mock function with given fields: ctx, model
+func (_m *MockLoginAttemptService) Fail(ctx context.Context, model *models.LoginAttempt) (*models.LoginAttempt, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Fail")
+	}
+
+	var r0 *models.LoginAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.LoginAttempt) (*models.LoginAttempt, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.LoginAttempt) *models.LoginAttempt); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginAttempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.LoginAttempt) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLoginAttemptService_Fail_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Fail'
+type MockLoginAttemptService_Fail_Call struct {
+	*mock.Call
+}
+
+// Fail is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.LoginAttempt
+func (_e *MockLoginAttemptService_Expecter) Fail(ctx interface{}, model interface{}) *MockLoginAttemptService_Fail_Call {
+	return &MockLoginAttemptService_Fail_Call{Call: _e.mock.On("Fail", ctx, model)}
+}
+
+func (_c *MockLoginAttemptService_Fail_Call) Run(run func(ctx context.Context, model *models.LoginAttempt)) *MockLoginAttemptService_Fail_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.LoginAttempt))
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptService_Fail_Call) Return(_a0 *models.LoginAttempt, _a1 error) *MockLoginAttemptService_Fail_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLoginAttemptService_Fail_Call) RunAndReturn(run func(context.Context, *models.LoginAttempt) (*models.LoginAttempt, error)) *MockLoginAttemptService_Fail_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Read provides a mock function with given fields: ctx, kind, value
+func (_m *MockLoginAttemptService) Read(ctx context.Context, kind string, value string) (*models.LoginAttempt, error) {
+	ret := _m.Called(ctx, kind, value)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Read")
+	}
+
+	var r0 *models.LoginAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.LoginAttempt, error)); ok {
+		return rf(ctx, kind, value)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.LoginAttempt); ok {
+		r0 = rf(ctx, kind, value)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginAttempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, kind, value)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLoginAttemptService_Read_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Read'
+type MockLoginAttemptService_Read_Call struct {
+	*mock.Call
+}
+
+// Read is a helper method to define mock.On call
+//   - ctx context.Context
+//   - kind string
+//   - value string
+func (_e *MockLoginAttemptService_Expecter) Read(ctx interface{}, kind interface{}, value interface{}) *MockLoginAttemptService_Read_Call {
+	return &MockLoginAttemptService_Read_Call{Call: _e.mock.On("Read", ctx, kind, value)}
+}
+
+func (_c *MockLoginAttemptService_Read_Call) Run(run func(ctx context.Context, kind string, value string)) *MockLoginAttemptService_Read_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptService_Read_Call) Return(_a0 *models.LoginAttempt, _a1 error) *MockLoginAttemptService_Read_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLoginAttemptService_Read_Call) RunAndReturn(run func(context.Context, string, string) (*models.LoginAttempt, error)) *MockLoginAttemptService_Read_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Reset provides a mock function with given fields: ctx, model
+func (_m *MockLoginAttemptService) Reset(ctx context.Context, model *models.LoginAttempt) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Reset")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.LoginAttempt) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLoginAttemptService_Reset_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Reset'
+type MockLoginAttemptService_Reset_Call struct {
+	*mock.Call
+}
+
+// Reset is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.LoginAttempt
+func (_e *MockLoginAttemptService_Expecter) Reset(ctx interface{}, model interface{}) *MockLoginAttemptService_Reset_Call {
+	return &MockLoginAttemptService_Reset_Call{Call: _e.mock.On("Reset", ctx, model)}
+}
+
+func (_c *MockLoginAttemptService_Reset_Call) Run(run func(ctx context.Context, model *models.LoginAttempt)) *MockLoginAttemptService_Reset_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.LoginAttempt))
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptService_Reset_Call) Return(_a0 error) *MockLoginAttemptService_Reset_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLoginAttemptService_Reset_Call) RunAndReturn(run func(context.Context, *models.LoginAttempt) error) *MockLoginAttemptService_Reset_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockLoginAttemptService creates a new instance of MockLoginAttemptService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockLoginAttemptService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockLoginAttemptService {
+	mock := &MockLoginAttemptService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,262 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package handlers
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// MockMagicLinkService is an autogenerated mock type for the MagicLinkService type
+type MockMagicLinkService struct {
+	mock.Mock
+}
+
+type MockMagicLinkService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockMagicLinkService) EXPECT() *MockMagicLinkService_Expecter {
+	return &MockMagicLinkService_Expecter{mock: &_m.Mock}
+}
+
+// CountSince provides a mock function with given fields: ctx, userId, t
+func (_m *MockMagicLinkService) CountSince(ctx context.Context, userId string, t time.Time) (int64, error) {
+	ret := _m.Called(ctx, userId, t)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountSince")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) (int64, error)); ok {
+		return rf(ctx, userId, t)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) int64); ok {
+		r0 = rf(ctx, userId, t)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time) error); ok {
+		r1 = rf(ctx, userId, t)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockMagicLinkService_CountSince_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CountSince'
+type MockMagicLinkService_CountSince_Call struct {
+	*mock.Call
+}
+
+// CountSince is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId string
+//   - t time.Time
+func (_e *MockMagicLinkService_Expecter) CountSince(ctx interface{}, userId interface{}, t interface{}) *MockMagicLinkService_CountSince_Call {
+	return &MockMagicLinkService_CountSince_Call{Call: _e.mock.On("CountSince", ctx, userId, t)}
+}
+
+func (_c *MockMagicLinkService_CountSince_Call) Run(run func(ctx context.Context, userId string, t time.Time)) *MockMagicLinkService_CountSince_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(time.Time))
+	})
+	return _c
+}
+
+func (_c *MockMagicLinkService_CountSince_Call) Return(_a0 int64, _a1 error) *MockMagicLinkService_CountSince_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockMagicLinkService_CountSince_Call) RunAndReturn(run func(context.Context, string, time.Time) (int64, error)) *MockMagicLinkService_CountSince_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockMagicLinkService) Create(ctx context.Context, model *models.MagicLink) (*models.MagicLink, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.MagicLink
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.MagicLink) (*models.MagicLink, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.MagicLink) *models.MagicLink); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.MagicLink)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.MagicLink) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockMagicLinkService_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockMagicLinkService_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.MagicLink
+func (_e *MockMagicLinkService_Expecter) Create(ctx interface{}, model interface{}) *MockMagicLinkService_Create_Call {
+	return &MockMagicLinkService_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockMagicLinkService_Create_Call) Run(run func(ctx context.Context, model *models.MagicLink)) *MockMagicLinkService_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.MagicLink))
+	})
+	return _c
+}
+
+func (_c *MockMagicLinkService_Create_Call) Return(_a0 *models.MagicLink, _a1 error) *MockMagicLinkService_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockMagicLinkService_Create_Call) RunAndReturn(run func(context.Context, *models.MagicLink) (*models.MagicLink, error)) *MockMagicLinkService_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Read provides a mock function with given fields: ctx, id
+func (_m *MockMagicLinkService) Read(ctx context.Context, id string) (*models.MagicLink, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Read")
+	}
+
+	var r0 *models.MagicLink
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.MagicLink, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.MagicLink); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.MagicLink)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockMagicLinkService_Read_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Read'
+type MockMagicLinkService_Read_Call struct {
+	*mock.Call
+}
+
+// Read is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockMagicLinkService_Expecter) Read(ctx interface{}, id interface{}) *MockMagicLinkService_Read_Call {
+	return &MockMagicLinkService_Read_Call{Call: _e.mock.On("Read", ctx, id)}
+}
+
+func (_c *MockMagicLinkService_Read_Call) Run(run func(ctx context.Context, id string)) *MockMagicLinkService_Read_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockMagicLinkService_Read_Call) Return(_a0 *models.MagicLink, _a1 error) *MockMagicLinkService_Read_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockMagicLinkService_Read_Call) RunAndReturn(run func(context.Context, string) (*models.MagicLink, error)) *MockMagicLinkService_Read_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Use provides a mock function with given fields: ctx, model
+func (_m *MockMagicLinkService) Use(ctx context.Context, model *models.MagicLink) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Use")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.MagicLink) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockMagicLinkService_Use_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Use'
+type MockMagicLinkService_Use_Call struct {
+	*mock.Call
+}
+
+// Use is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.MagicLink
+func (_e *MockMagicLinkService_Expecter) Use(ctx interface{}, model interface{}) *MockMagicLinkService_Use_Call {
+	return &MockMagicLinkService_Use_Call{Call: _e.mock.On("Use", ctx, model)}
+}
+
+func (_c *MockMagicLinkService_Use_Call) Run(run func(ctx context.Context, model *models.MagicLink)) *MockMagicLinkService_Use_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.MagicLink))
+	})
+	return _c
+}
+
+func (_c *MockMagicLinkService_Use_Call) Return(_a0 error) *MockMagicLinkService_Use_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockMagicLinkService_Use_Call) RunAndReturn(run func(context.Context, *models.MagicLink) error) *MockMagicLinkService_Use_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockMagicLinkService creates a new instance of MockMagicLinkService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockMagicLinkService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockMagicLinkService {
+	mock := &MockMagicLinkService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -81,34 +81,41 @@ func (_c *MockPersonalAccessTokenService_Create_Call) RunAndReturn(run func(cont
 	return _c
 }
 
-// Find provides a mock function with given fields: ctx, userId
-func (_m *MockPersonalAccessTokenService) Find(ctx context.Context, userId string) (models.PersonalAccessTokens, error) {
-	ret := _m.Called(ctx, userId)
+// Find provides a mock function with given fields: ctx, params
+func (_m *MockPersonalAccessTokenService) Find(ctx context.Context, params *models.PersonalAccessTokenSearchParams) (int64, models.PersonalAccessTokens, error) {
+	ret := _m.Called(ctx, params)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Find")
 	}
 
-	var r0 models.PersonalAccessTokens
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (models.PersonalAccessTokens, error)); ok {
-		return rf(ctx, userId)
+	var r0 int64
+	var r1 models.PersonalAccessTokens
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PersonalAccessTokenSearchParams) (int64, models.PersonalAccessTokens, error)); ok {
+		return rf(ctx, params)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) models.PersonalAccessTokens); ok {
-		r0 = rf(ctx, userId)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PersonalAccessTokenSearchParams) int64); ok {
+		r0 = rf(ctx, params)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(models.PersonalAccessTokens)
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.PersonalAccessTokenSearchParams) models.PersonalAccessTokens); ok {
+		r1 = rf(ctx, params)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(models.PersonalAccessTokens)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, userId)
+	if rf, ok := ret.Get(2).(func(context.Context, *models.PersonalAccessTokenSearchParams) error); ok {
+		r2 = rf(ctx, params)
 	} else {
-		r1 = ret.Error(1)
+		r2 = ret.Error(2)
 	}
 
-	return r0, r1
+	return r0, r1, r2
 }
 
 // MockPersonalAccessTokenService_Find_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Find'
@@ -118,24 +125,24 @@ type MockPersonalAccessTokenService_Find_Call struct {
 
 // Find is a helper method to define mock.On call
 //   - ctx context.Context
-//   - userId string
-func (_e *MockPersonalAccessTokenService_Expecter) Find(ctx interface{}, userId interface{}) *MockPersonalAccessTokenService_Find_Call {
-	return &MockPersonalAccessTokenService_Find_Call{Call: _e.mock.On("Find", ctx, userId)}
+//   - params *models.PersonalAccessTokenSearchParams
+func (_e *MockPersonalAccessTokenService_Expecter) Find(ctx interface{}, params interface{}) *MockPersonalAccessTokenService_Find_Call {
+	return &MockPersonalAccessTokenService_Find_Call{Call: _e.mock.On("Find", ctx, params)}
 }
 
-func (_c *MockPersonalAccessTokenService_Find_Call) Run(run func(ctx context.Context, userId string)) *MockPersonalAccessTokenService_Find_Call {
+func (_c *MockPersonalAccessTokenService_Find_Call) Run(run func(ctx context.Context, params *models.PersonalAccessTokenSearchParams)) *MockPersonalAccessTokenService_Find_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(string))
+		run(args[0].(context.Context), args[1].(*models.PersonalAccessTokenSearchParams))
 	})
 	return _c
 }
 
-func (_c *MockPersonalAccessTokenService_Find_Call) Return(_a0 models.PersonalAccessTokens, _a1 error) *MockPersonalAccessTokenService_Find_Call {
-	_c.Call.Return(_a0, _a1)
+func (_c *MockPersonalAccessTokenService_Find_Call) Return(_a0 int64, _a1 models.PersonalAccessTokens, _a2 error) *MockPersonalAccessTokenService_Find_Call {
+	_c.Call.Return(_a0, _a1, _a2)
 	return _c
 }
 
-func (_c *MockPersonalAccessTokenService_Find_Call) RunAndReturn(run func(context.Context, string) (models.PersonalAccessTokens, error)) *MockPersonalAccessTokenService_Find_Call {
+func (_c *MockPersonalAccessTokenService_Find_Call) RunAndReturn(run func(context.Context, *models.PersonalAccessTokenSearchParams) (int64, models.PersonalAccessTokens, error)) *MockPersonalAccessTokenService_Find_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -200,6 +207,65 @@ func (_c *MockPersonalAccessTokenService_FindOne_Call) RunAndReturn(run func(con
 	return _c
 }
 
+// FindUnscoped provides a mock function with given fields: ctx, userId
+func (_m *MockPersonalAccessTokenService) FindUnscoped(ctx context.Context, userId string) (models.PersonalAccessTokens, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindUnscoped")
+	}
+
+	var r0 models.PersonalAccessTokens
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.PersonalAccessTokens, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.PersonalAccessTokens); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(models.PersonalAccessTokens)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPersonalAccessTokenService_FindUnscoped_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindUnscoped'
+type MockPersonalAccessTokenService_FindUnscoped_Call struct {
+	*mock.Call
+}
+
+// FindUnscoped is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId string
+func (_e *MockPersonalAccessTokenService_Expecter) FindUnscoped(ctx interface{}, userId interface{}) *MockPersonalAccessTokenService_FindUnscoped_Call {
+	return &MockPersonalAccessTokenService_FindUnscoped_Call{Call: _e.mock.On("FindUnscoped", ctx, userId)}
+}
+
+func (_c *MockPersonalAccessTokenService_FindUnscoped_Call) Run(run func(ctx context.Context, userId string)) *MockPersonalAccessTokenService_FindUnscoped_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenService_FindUnscoped_Call) Return(_a0 models.PersonalAccessTokens, _a1 error) *MockPersonalAccessTokenService_FindUnscoped_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenService_FindUnscoped_Call) RunAndReturn(run func(context.Context, string) (models.PersonalAccessTokens, error)) *MockPersonalAccessTokenService_FindUnscoped_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Read provides a mock function with given fields: ctx, userId, id
 func (_m *MockPersonalAccessTokenService) Read(ctx context.Context, userId string, id string) (*models.PersonalAccessToken, error) {
 	ret := _m.Called(ctx, userId, id)
@@ -260,12 +326,12 @@ func (_c *MockPersonalAccessTokenService_Read_Call) RunAndReturn(run func(contex
 	return _c
 }
 
-// Revoke provides a mock function with given fields: ctx, model
-func (_m *MockPersonalAccessTokenService) Revoke(ctx context.Context, model *models.PersonalAccessToken) error {
+// Rehash provides a mock function with given fields: ctx, model
+func (_m *MockPersonalAccessTokenService) Rehash(ctx context.Context, model *models.PersonalAccessToken) error {
 	ret := _m.Called(ctx, model)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Revoke")
+		panic("no return value specified for Rehash")
 	}
 
 	var r0 error
@@ -278,6 +344,53 @@ func (_m *MockPersonalAccessTokenService) Revoke(ctx context.Context, model *mod
 	return r0
 }
 
+// MockPersonalAccessTokenService_Rehash_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Rehash'
+type MockPersonalAccessTokenService_Rehash_Call struct {
+	*mock.Call
+}
+
+// Rehash is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.PersonalAccessToken
+func (_e *MockPersonalAccessTokenService_Expecter) Rehash(ctx interface{}, model interface{}) *MockPersonalAccessTokenService_Rehash_Call {
+	return &MockPersonalAccessTokenService_Rehash_Call{Call: _e.mock.On("Rehash", ctx, model)}
+}
+
+func (_c *MockPersonalAccessTokenService_Rehash_Call) Run(run func(ctx context.Context, model *models.PersonalAccessToken)) *MockPersonalAccessTokenService_Rehash_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.PersonalAccessToken))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenService_Rehash_Call) Return(_a0 error) *MockPersonalAccessTokenService_Rehash_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenService_Rehash_Call) RunAndReturn(run func(context.Context, *models.PersonalAccessToken) error) *MockPersonalAccessTokenService_Rehash_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Revoke provides a mock function with given fields: ctx, id, model
+func (_m *MockPersonalAccessTokenService) Revoke(ctx context.Context, id string, model *models.PersonalAccessToken) error {
+	ret := _m.Called(ctx, id, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.PersonalAccessToken) error); ok {
+		r0 = rf(ctx, id, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // MockPersonalAccessTokenService_Revoke_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Revoke'
 type MockPersonalAccessTokenService_Revoke_Call struct {
 	*mock.Call
@@ -285,14 +398,15 @@ type MockPersonalAccessTokenService_Revoke_Call struct {
 
 // Revoke is a helper method to define mock.On call
 //   - ctx context.Context
+//   - id string
 //   - model *models.PersonalAccessToken
-func (_e *MockPersonalAccessTokenService_Expecter) Revoke(ctx interface{}, model interface{}) *MockPersonalAccessTokenService_Revoke_Call {
-	return &MockPersonalAccessTokenService_Revoke_Call{Call: _e.mock.On("Revoke", ctx, model)}
+func (_e *MockPersonalAccessTokenService_Expecter) Revoke(ctx interface{}, id interface{}, model interface{}) *MockPersonalAccessTokenService_Revoke_Call {
+	return &MockPersonalAccessTokenService_Revoke_Call{Call: _e.mock.On("Revoke", ctx, id, model)}
 }
 
-func (_c *MockPersonalAccessTokenService_Revoke_Call) Run(run func(ctx context.Context, model *models.PersonalAccessToken)) *MockPersonalAccessTokenService_Revoke_Call {
+func (_c *MockPersonalAccessTokenService_Revoke_Call) Run(run func(ctx context.Context, id string, model *models.PersonalAccessToken)) *MockPersonalAccessTokenService_Revoke_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(*models.PersonalAccessToken))
+		run(args[0].(context.Context), args[1].(string), args[2].(*models.PersonalAccessToken))
 	})
 	return _c
 }
@@ -302,7 +416,102 @@ func (_c *MockPersonalAccessTokenService_Revoke_Call) Return(_a0 error) *MockPer
 	return _c
 }
 
-func (_c *MockPersonalAccessTokenService_Revoke_Call) RunAndReturn(run func(context.Context, *models.PersonalAccessToken) error) *MockPersonalAccessTokenService_Revoke_Call {
+func (_c *MockPersonalAccessTokenService_Revoke_Call) RunAndReturn(run func(context.Context, string, *models.PersonalAccessToken) error) *MockPersonalAccessTokenService_Revoke_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeAll provides a mock function with given fields: ctx, id, userId
+func (_m *MockPersonalAccessTokenService) RevokeAll(ctx context.Context, id string, userId string) error {
+	ret := _m.Called(ctx, id, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAll")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, id, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPersonalAccessTokenService_RevokeAll_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeAll'
+type MockPersonalAccessTokenService_RevokeAll_Call struct {
+	*mock.Call
+}
+
+// RevokeAll is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - userId string
+func (_e *MockPersonalAccessTokenService_Expecter) RevokeAll(ctx interface{}, id interface{}, userId interface{}) *MockPersonalAccessTokenService_RevokeAll_Call {
+	return &MockPersonalAccessTokenService_RevokeAll_Call{Call: _e.mock.On("RevokeAll", ctx, id, userId)}
+}
+
+func (_c *MockPersonalAccessTokenService_RevokeAll_Call) Run(run func(ctx context.Context, id string, userId string)) *MockPersonalAccessTokenService_RevokeAll_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenService_RevokeAll_Call) Return(_a0 error) *MockPersonalAccessTokenService_RevokeAll_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenService_RevokeAll_Call) RunAndReturn(run func(context.Context, string, string) error) *MockPersonalAccessTokenService_RevokeAll_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Use provides a mock function with given fields: ctx, model
+func (_m *MockPersonalAccessTokenService) Use(ctx context.Context, model *models.PersonalAccessToken) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Use")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PersonalAccessToken) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPersonalAccessTokenService_Use_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Use'
+type MockPersonalAccessTokenService_Use_Call struct {
+	*mock.Call
+}
+
+// Use is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.PersonalAccessToken
+func (_e *MockPersonalAccessTokenService_Expecter) Use(ctx interface{}, model interface{}) *MockPersonalAccessTokenService_Use_Call {
+	return &MockPersonalAccessTokenService_Use_Call{Call: _e.mock.On("Use", ctx, model)}
+}
+
+func (_c *MockPersonalAccessTokenService_Use_Call) Run(run func(ctx context.Context, model *models.PersonalAccessToken)) *MockPersonalAccessTokenService_Use_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.PersonalAccessToken))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenService_Use_Call) Return(_a0 error) *MockPersonalAccessTokenService_Use_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenService_Use_Call) RunAndReturn(run func(context.Context, *models.PersonalAccessToken) error) *MockPersonalAccessTokenService_Use_Call {
 	_c.Call.Return(run)
 	return _c
 }
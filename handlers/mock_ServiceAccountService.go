@@ -0,0 +1,437 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package handlers
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockServiceAccountService is an autogenerated mock type for the ServiceAccountService type
+type MockServiceAccountService struct {
+	mock.Mock
+}
+
+type MockServiceAccountService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockServiceAccountService) EXPECT() *MockServiceAccountService_Expecter {
+	return &MockServiceAccountService_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, id, model
+func (_m *MockServiceAccountService) Create(ctx context.Context, id string, model *models.ServiceAccount) (*models.ServiceAccount, error) {
+	ret := _m.Called(ctx, id, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.ServiceAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.ServiceAccount) (*models.ServiceAccount, error)); ok {
+		return rf(ctx, id, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.ServiceAccount) *models.ServiceAccount); ok {
+		r0 = rf(ctx, id, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ServiceAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *models.ServiceAccount) error); ok {
+		r1 = rf(ctx, id, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceAccountService_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockServiceAccountService_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - model *models.ServiceAccount
+func (_e *MockServiceAccountService_Expecter) Create(ctx interface{}, id interface{}, model interface{}) *MockServiceAccountService_Create_Call {
+	return &MockServiceAccountService_Create_Call{Call: _e.mock.On("Create", ctx, id, model)}
+}
+
+func (_c *MockServiceAccountService_Create_Call) Run(run func(ctx context.Context, id string, model *models.ServiceAccount)) *MockServiceAccountService_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(*models.ServiceAccount))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountService_Create_Call) Return(_a0 *models.ServiceAccount, _a1 error) *MockServiceAccountService_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceAccountService_Create_Call) RunAndReturn(run func(context.Context, string, *models.ServiceAccount) (*models.ServiceAccount, error)) *MockServiceAccountService_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Delete provides a mock function with given fields: ctx, id, model
+func (_m *MockServiceAccountService) Delete(ctx context.Context, id string, model *models.ServiceAccount) error {
+	ret := _m.Called(ctx, id, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.ServiceAccount) error); ok {
+		r0 = rf(ctx, id, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockServiceAccountService_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type MockServiceAccountService_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - model *models.ServiceAccount
+func (_e *MockServiceAccountService_Expecter) Delete(ctx interface{}, id interface{}, model interface{}) *MockServiceAccountService_Delete_Call {
+	return &MockServiceAccountService_Delete_Call{Call: _e.mock.On("Delete", ctx, id, model)}
+}
+
+func (_c *MockServiceAccountService_Delete_Call) Run(run func(ctx context.Context, id string, model *models.ServiceAccount)) *MockServiceAccountService_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(*models.ServiceAccount))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountService_Delete_Call) Return(_a0 error) *MockServiceAccountService_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockServiceAccountService_Delete_Call) RunAndReturn(run func(context.Context, string, *models.ServiceAccount) error) *MockServiceAccountService_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Find provides a mock function with given fields: ctx, params
+func (_m *MockServiceAccountService) Find(ctx context.Context, params *models.ServiceAccountSearchParams) (int64, models.ServiceAccounts, error) {
+	ret := _m.Called(ctx, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Find")
+	}
+
+	var r0 int64
+	var r1 models.ServiceAccounts
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ServiceAccountSearchParams) (int64, models.ServiceAccounts, error)); ok {
+		return rf(ctx, params)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ServiceAccountSearchParams) int64); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ServiceAccountSearchParams) models.ServiceAccounts); ok {
+		r1 = rf(ctx, params)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(models.ServiceAccounts)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *models.ServiceAccountSearchParams) error); ok {
+		r2 = rf(ctx, params)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockServiceAccountService_Find_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Find'
+type MockServiceAccountService_Find_Call struct {
+	*mock.Call
+}
+
+// Find is a helper method to define mock.On call
+//   - ctx context.Context
+//   - params *models.ServiceAccountSearchParams
+func (_e *MockServiceAccountService_Expecter) Find(ctx interface{}, params interface{}) *MockServiceAccountService_Find_Call {
+	return &MockServiceAccountService_Find_Call{Call: _e.mock.On("Find", ctx, params)}
+}
+
+func (_c *MockServiceAccountService_Find_Call) Run(run func(ctx context.Context, params *models.ServiceAccountSearchParams)) *MockServiceAccountService_Find_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.ServiceAccountSearchParams))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountService_Find_Call) Return(_a0 int64, _a1 models.ServiceAccounts, _a2 error) *MockServiceAccountService_Find_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *MockServiceAccountService_Find_Call) RunAndReturn(run func(context.Context, *models.ServiceAccountSearchParams) (int64, models.ServiceAccounts, error)) *MockServiceAccountService_Find_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Read provides a mock function with given fields: ctx, id
+func (_m *MockServiceAccountService) Read(ctx context.Context, id string) (*models.ServiceAccount, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Read")
+	}
+
+	var r0 *models.ServiceAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.ServiceAccount, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.ServiceAccount); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ServiceAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceAccountService_Read_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Read'
+type MockServiceAccountService_Read_Call struct {
+	*mock.Call
+}
+
+// Read is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockServiceAccountService_Expecter) Read(ctx interface{}, id interface{}) *MockServiceAccountService_Read_Call {
+	return &MockServiceAccountService_Read_Call{Call: _e.mock.On("Read", ctx, id)}
+}
+
+func (_c *MockServiceAccountService_Read_Call) Run(run func(ctx context.Context, id string)) *MockServiceAccountService_Read_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountService_Read_Call) Return(_a0 *models.ServiceAccount, _a1 error) *MockServiceAccountService_Read_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceAccountService_Read_Call) RunAndReturn(run func(context.Context, string) (*models.ServiceAccount, error)) *MockServiceAccountService_Read_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ReadByClientId provides a mock function with given fields: ctx, clientId
+func (_m *MockServiceAccountService) ReadByClientId(ctx context.Context, clientId string) (*models.ServiceAccount, error) {
+	ret := _m.Called(ctx, clientId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReadByClientId")
+	}
+
+	var r0 *models.ServiceAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.ServiceAccount, error)); ok {
+		return rf(ctx, clientId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.ServiceAccount); ok {
+		r0 = rf(ctx, clientId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ServiceAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, clientId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceAccountService_ReadByClientId_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReadByClientId'
+type MockServiceAccountService_ReadByClientId_Call struct {
+	*mock.Call
+}
+
+// ReadByClientId is a helper method to define mock.On call
+//   - ctx context.Context
+//   - clientId string
+func (_e *MockServiceAccountService_Expecter) ReadByClientId(ctx interface{}, clientId interface{}) *MockServiceAccountService_ReadByClientId_Call {
+	return &MockServiceAccountService_ReadByClientId_Call{Call: _e.mock.On("ReadByClientId", ctx, clientId)}
+}
+
+func (_c *MockServiceAccountService_ReadByClientId_Call) Run(run func(ctx context.Context, clientId string)) *MockServiceAccountService_ReadByClientId_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountService_ReadByClientId_Call) Return(_a0 *models.ServiceAccount, _a1 error) *MockServiceAccountService_ReadByClientId_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceAccountService_ReadByClientId_Call) RunAndReturn(run func(context.Context, string) (*models.ServiceAccount, error)) *MockServiceAccountService_ReadByClientId_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, id, model
+func (_m *MockServiceAccountService) Update(ctx context.Context, id string, model *models.ServiceAccount) (*models.ServiceAccount, error) {
+	ret := _m.Called(ctx, id, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.ServiceAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.ServiceAccount) (*models.ServiceAccount, error)); ok {
+		return rf(ctx, id, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.ServiceAccount) *models.ServiceAccount); ok {
+		r0 = rf(ctx, id, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ServiceAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *models.ServiceAccount) error); ok {
+		r1 = rf(ctx, id, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceAccountService_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockServiceAccountService_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - model *models.ServiceAccount
+func (_e *MockServiceAccountService_Expecter) Update(ctx interface{}, id interface{}, model interface{}) *MockServiceAccountService_Update_Call {
+	return &MockServiceAccountService_Update_Call{Call: _e.mock.On("Update", ctx, id, model)}
+}
+
+func (_c *MockServiceAccountService_Update_Call) Run(run func(ctx context.Context, id string, model *models.ServiceAccount)) *MockServiceAccountService_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(*models.ServiceAccount))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountService_Update_Call) Return(_a0 *models.ServiceAccount, _a1 error) *MockServiceAccountService_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceAccountService_Update_Call) RunAndReturn(run func(context.Context, string, *models.ServiceAccount) (*models.ServiceAccount, error)) *MockServiceAccountService_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Use provides a mock function with given fields: ctx, model, clientId
+func (_m *MockServiceAccountService) Use(ctx context.Context, model *models.ServiceAccount, clientId string) error {
+	ret := _m.Called(ctx, model, clientId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Use")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ServiceAccount, string) error); ok {
+		r0 = rf(ctx, model, clientId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockServiceAccountService_Use_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Use'
+type MockServiceAccountService_Use_Call struct {
+	*mock.Call
+}
+
+// Use is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.ServiceAccount
+//   - clientId string
+func (_e *MockServiceAccountService_Expecter) Use(ctx interface{}, model interface{}, clientId interface{}) *MockServiceAccountService_Use_Call {
+	return &MockServiceAccountService_Use_Call{Call: _e.mock.On("Use", ctx, model, clientId)}
+}
+
+func (_c *MockServiceAccountService_Use_Call) Run(run func(ctx context.Context, model *models.ServiceAccount, clientId string)) *MockServiceAccountService_Use_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.ServiceAccount), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountService_Use_Call) Return(_a0 error) *MockServiceAccountService_Use_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockServiceAccountService_Use_Call) RunAndReturn(run func(context.Context, *models.ServiceAccount, string) error) *MockServiceAccountService_Use_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockServiceAccountService creates a new instance of MockServiceAccountService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockServiceAccountService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockServiceAccountService {
+	mock := &MockServiceAccountService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
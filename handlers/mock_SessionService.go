@@ -0,0 +1,421 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package handlers
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockSessionService is an autogenerated mock type for the SessionService type
+type MockSessionService struct {
+	mock.Mock
+}
+
+type MockSessionService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockSessionService) EXPECT() *MockSessionService_Expecter {
+	return &MockSessionService_Expecter{mock: &_m.Mock}
+}
+
+// CompareAndSwap provides a mock function with given fields: ctx, model, previous
+func (_m *MockSessionService) CompareAndSwap(ctx context.Context, model *models.Session, previous string) error {
+	ret := _m.Called(ctx, model, previous)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CompareAndSwap")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session, string) error); ok {
+		r0 = rf(ctx, model, previous)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockSessionService_CompareAndSwap_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CompareAndSwap'
+type MockSessionService_CompareAndSwap_Call struct {
+	*mock.Call
+}
+
+// CompareAndSwap is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.Session
+//   - previous string
+func (_e *MockSessionService_Expecter) CompareAndSwap(ctx interface{}, model interface{}, previous interface{}) *MockSessionService_CompareAndSwap_Call {
+	return &MockSessionService_CompareAndSwap_Call{Call: _e.mock.On("CompareAndSwap", ctx, model, previous)}
+}
+
+func (_c *MockSessionService_CompareAndSwap_Call) Run(run func(ctx context.Context, model *models.Session, previous string)) *MockSessionService_CompareAndSwap_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.Session), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockSessionService_CompareAndSwap_Call) Return(_a0 error) *MockSessionService_CompareAndSwap_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockSessionService_CompareAndSwap_Call) RunAndReturn(run func(context.Context, *models.Session, string) error) *MockSessionService_CompareAndSwap_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockSessionService) Create(ctx context.Context, model *models.Session) (*models.Session, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) (*models.Session, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) *models.Session); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Session) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionService_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockSessionService_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.Session
+func (_e *MockSessionService_Expecter) Create(ctx interface{}, model interface{}) *MockSessionService_Create_Call {
+	return &MockSessionService_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockSessionService_Create_Call) Run(run func(ctx context.Context, model *models.Session)) *MockSessionService_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.Session))
+	})
+	return _c
+}
+
+func (_c *MockSessionService_Create_Call) Return(_a0 *models.Session, _a1 error) *MockSessionService_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionService_Create_Call) RunAndReturn(run func(context.Context, *models.Session) (*models.Session, error)) *MockSessionService_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Find provides a mock function with given fields: ctx, userId
+func (_m *MockSessionService) Find(ctx context.Context, userId string) (models.Sessions, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Find")
+	}
+
+	var r0 models.Sessions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.Sessions, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.Sessions); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(models.Sessions)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionService_Find_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Find'
+type MockSessionService_Find_Call struct {
+	*mock.Call
+}
+
+// Find is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId string
+func (_e *MockSessionService_Expecter) Find(ctx interface{}, userId interface{}) *MockSessionService_Find_Call {
+	return &MockSessionService_Find_Call{Call: _e.mock.On("Find", ctx, userId)}
+}
+
+func (_c *MockSessionService_Find_Call) Run(run func(ctx context.Context, userId string)) *MockSessionService_Find_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockSessionService_Find_Call) Return(_a0 models.Sessions, _a1 error) *MockSessionService_Find_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionService_Find_Call) RunAndReturn(run func(context.Context, string) (models.Sessions, error)) *MockSessionService_Find_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Read provides a mock function with given fields: ctx, userId, id
+func (_m *MockSessionService) Read(ctx context.Context, userId string, id string) (*models.Session, error) {
+	ret := _m.Called(ctx, userId, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Read")
+	}
+
+	var r0 *models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.Session, error)); ok {
+		return rf(ctx, userId, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.Session); ok {
+		r0 = rf(ctx, userId, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userId, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionService_Read_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Read'
+type MockSessionService_Read_Call struct {
+	*mock.Call
+}
+
+// Read is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId string
+//   - id string
+func (_e *MockSessionService_Expecter) Read(ctx interface{}, userId interface{}, id interface{}) *MockSessionService_Read_Call {
+	return &MockSessionService_Read_Call{Call: _e.mock.On("Read", ctx, userId, id)}
+}
+
+func (_c *MockSessionService_Read_Call) Run(run func(ctx context.Context, userId string, id string)) *MockSessionService_Read_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockSessionService_Read_Call) Return(_a0 *models.Session, _a1 error) *MockSessionService_Read_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionService_Read_Call) RunAndReturn(run func(context.Context, string, string) (*models.Session, error)) *MockSessionService_Read_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Revoke provides a mock function with given fields: ctx, id, model, reason
+func (_m *MockSessionService) Revoke(ctx context.Context, id string, model *models.Session, reason string) error {
+	ret := _m.Called(ctx, id, model, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Revoke")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.Session, string) error); ok {
+		r0 = rf(ctx, id, model, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockSessionService_Revoke_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Revoke'
+type MockSessionService_Revoke_Call struct {
+	*mock.Call
+}
+
+// Revoke is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - model *models.Session
+//   - reason string
+func (_e *MockSessionService_Expecter) Revoke(ctx interface{}, id interface{}, model interface{}, reason interface{}) *MockSessionService_Revoke_Call {
+	return &MockSessionService_Revoke_Call{Call: _e.mock.On("Revoke", ctx, id, model, reason)}
+}
+
+func (_c *MockSessionService_Revoke_Call) Run(run func(ctx context.Context, id string, model *models.Session, reason string)) *MockSessionService_Revoke_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(*models.Session), args[3].(string))
+	})
+	return _c
+}
+
+func (_c *MockSessionService_Revoke_Call) Return(_a0 error) *MockSessionService_Revoke_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockSessionService_Revoke_Call) RunAndReturn(run func(context.Context, string, *models.Session, string) error) *MockSessionService_Revoke_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RevokeAll provides a mock function with given fields: ctx, id, userId, exceptId, reason
+func (_m *MockSessionService) RevokeAll(ctx context.Context, id string, userId string, exceptId string, reason string) error {
+	ret := _m.Called(ctx, id, userId, exceptId, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RevokeAll")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, string) error); ok {
+		r0 = rf(ctx, id, userId, exceptId, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockSessionService_RevokeAll_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RevokeAll'
+type MockSessionService_RevokeAll_Call struct {
+	*mock.Call
+}
+
+// RevokeAll is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+//   - userId string
+//   - exceptId string
+//   - reason string
+func (_e *MockSessionService_Expecter) RevokeAll(ctx interface{}, id interface{}, userId interface{}, exceptId interface{}, reason interface{}) *MockSessionService_RevokeAll_Call {
+	return &MockSessionService_RevokeAll_Call{Call: _e.mock.On("RevokeAll", ctx, id, userId, exceptId, reason)}
+}
+
+func (_c *MockSessionService_RevokeAll_Call) Run(run func(ctx context.Context, id string, userId string, exceptId string, reason string)) *MockSessionService_RevokeAll_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].(string), args[4].(string))
+	})
+	return _c
+}
+
+func (_c *MockSessionService_RevokeAll_Call) Return(_a0 error) *MockSessionService_RevokeAll_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockSessionService_RevokeAll_Call) RunAndReturn(run func(context.Context, string, string, string, string) error) *MockSessionService_RevokeAll_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockSessionService) Update(ctx context.Context, model *models.Session) (*models.Session, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) (*models.Session, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) *models.Session); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Session) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionService_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockSessionService_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.Session
+func (_e *MockSessionService_Expecter) Update(ctx interface{}, model interface{}) *MockSessionService_Update_Call {
+	return &MockSessionService_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockSessionService_Update_Call) Run(run func(ctx context.Context, model *models.Session)) *MockSessionService_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.Session))
+	})
+	return _c
+}
+
+func (_c *MockSessionService_Update_Call) Return(_a0 *models.Session, _a1 error) *MockSessionService_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionService_Update_Call) RunAndReturn(run func(context.Context, *models.Session) (*models.Session, error)) *MockSessionService_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockSessionService creates a new instance of MockSessionService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSessionService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSessionService {
+	mock := &MockSessionService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
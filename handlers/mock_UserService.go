@@ -195,6 +195,65 @@ func (_c *MockUserService_Find_Call) RunAndReturn(run func(context.Context, *mod
 	return _c
 }
 
+// FindIdsByRoles provides a mock function with given fields: ctx, roles
+func (_m *MockUserService) FindIdsByRoles(ctx context.Context, roles []string) ([]string, error) {
+	ret := _m.Called(ctx, roles)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindIdsByRoles")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]string, error)); ok {
+		return rf(ctx, roles)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []string); ok {
+		r0 = rf(ctx, roles)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, roles)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockUserService_FindIdsByRoles_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindIdsByRoles'
+type MockUserService_FindIdsByRoles_Call struct {
+	*mock.Call
+}
+
+// FindIdsByRoles is a helper method to define mock.On call
+//   - ctx context.Context
+//   - roles []string
+func (_e *MockUserService_Expecter) FindIdsByRoles(ctx interface{}, roles interface{}) *MockUserService_FindIdsByRoles_Call {
+	return &MockUserService_FindIdsByRoles_Call{Call: _e.mock.On("FindIdsByRoles", ctx, roles)}
+}
+
+func (_c *MockUserService_FindIdsByRoles_Call) Run(run func(ctx context.Context, roles []string)) *MockUserService_FindIdsByRoles_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].([]string))
+	})
+	return _c
+}
+
+func (_c *MockUserService_FindIdsByRoles_Call) Return(_a0 []string, _a1 error) *MockUserService_FindIdsByRoles_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockUserService_FindIdsByRoles_Call) RunAndReturn(run func(context.Context, []string) ([]string, error)) *MockUserService_FindIdsByRoles_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // FindOneByEmailOrUsername provides a mock function with given fields: ctx, email, username
 func (_m *MockUserService) FindOneByEmailOrUsername(ctx context.Context, email string, username string) (*models.User, error) {
 	ret := _m.Called(ctx, email, username)
@@ -255,6 +314,66 @@ func (_c *MockUserService_FindOneByEmailOrUsername_Call) RunAndReturn(run func(c
 	return _c
 }
 
+// FindOneByIdentity provides a mock function with given fields: ctx, provider, subject
+func (_m *MockUserService) FindOneByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
+	ret := _m.Called(ctx, provider, subject)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOneByIdentity")
+	}
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.User, error)); ok {
+		return rf(ctx, provider, subject)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.User); ok {
+		r0 = rf(ctx, provider, subject)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, provider, subject)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockUserService_FindOneByIdentity_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOneByIdentity'
+type MockUserService_FindOneByIdentity_Call struct {
+	*mock.Call
+}
+
+// FindOneByIdentity is a helper method to define mock.On call
+//   - ctx context.Context
+//   - provider string
+//   - subject string
+func (_e *MockUserService_Expecter) FindOneByIdentity(ctx interface{}, provider interface{}, subject interface{}) *MockUserService_FindOneByIdentity_Call {
+	return &MockUserService_FindOneByIdentity_Call{Call: _e.mock.On("FindOneByIdentity", ctx, provider, subject)}
+}
+
+func (_c *MockUserService_FindOneByIdentity_Call) Run(run func(ctx context.Context, provider string, subject string)) *MockUserService_FindOneByIdentity_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockUserService_FindOneByIdentity_Call) Return(_a0 *models.User, _a1 error) *MockUserService_FindOneByIdentity_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockUserService_FindOneByIdentity_Call) RunAndReturn(run func(context.Context, string, string) (*models.User, error)) *MockUserService_FindOneByIdentity_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Read provides a mock function with given fields: ctx, id
 func (_m *MockUserService) Read(ctx context.Context, id string) (*models.User, error) {
 	ret := _m.Called(ctx, id)
@@ -374,6 +493,53 @@ func (_c *MockUserService_Update_Call) RunAndReturn(run func(context.Context, st
 	return _c
 }
 
+// WithTransaction provides a mock function with given fields: ctx, fn
+func (_m *MockUserService) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockUserService_WithTransaction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WithTransaction'
+type MockUserService_WithTransaction_Call struct {
+	*mock.Call
+}
+
+// WithTransaction is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(context.Context) error
+func (_e *MockUserService_Expecter) WithTransaction(ctx interface{}, fn interface{}) *MockUserService_WithTransaction_Call {
+	return &MockUserService_WithTransaction_Call{Call: _e.mock.On("WithTransaction", ctx, fn)}
+}
+
+func (_c *MockUserService_WithTransaction_Call) Run(run func(ctx context.Context, fn func(context.Context) error)) *MockUserService_WithTransaction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(func(context.Context) error))
+	})
+	return _c
+}
+
+func (_c *MockUserService_WithTransaction_Call) Return(_a0 error) *MockUserService_WithTransaction_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockUserService_WithTransaction_Call) RunAndReturn(run func(context.Context, func(context.Context) error) error) *MockUserService_WithTransaction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockUserService creates a new instance of MockUserService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockUserService(t interface {
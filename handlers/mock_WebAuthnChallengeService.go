@@ -0,0 +1,214 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package handlers
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockWebAuthnChallengeService is an autogenerated mock type for the WebAuthnChallengeService type
+type MockWebAuthnChallengeService struct {
+	mock.Mock
+}
+
+type MockWebAuthnChallengeService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockWebAuthnChallengeService) EXPECT() *MockWebAuthnChallengeService_Expecter {
+	return &MockWebAuthnChallengeService_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnChallengeService) Create(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.WebAuthnChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnChallenge) *models.WebAuthnChallenge); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnChallenge)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebAuthnChallenge) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnChallengeService_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockWebAuthnChallengeService_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnChallenge
+func (_e *MockWebAuthnChallengeService_Expecter) Create(ctx interface{}, model interface{}) *MockWebAuthnChallengeService_Create_Call {
+	return &MockWebAuthnChallengeService_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockWebAuthnChallengeService_Create_Call) Run(run func(ctx context.Context, model *models.WebAuthnChallenge)) *MockWebAuthnChallengeService_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnChallenge))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeService_Create_Call) Return(_a0 *models.WebAuthnChallenge, _a1 error) *MockWebAuthnChallengeService_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeService_Create_Call) RunAndReturn(run func(context.Context, *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)) *MockWebAuthnChallengeService_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Read provides a mock function with given fields: ctx, id
+func (_m *MockWebAuthnChallengeService) Read(ctx context.Context, id string) (*models.WebAuthnChallenge, error) {
+	ret := _m.Called(ctx, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Read")
+	}
+
+	var r0 *models.WebAuthnChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.WebAuthnChallenge, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.WebAuthnChallenge); ok {
+		r0 = rf(ctx, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnChallenge)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnChallengeService_Read_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Read'
+type MockWebAuthnChallengeService_Read_Call struct {
+	*mock.Call
+}
+
+// Read is a helper method to define mock.On call
+//   - ctx context.Context
+//   - id string
+func (_e *MockWebAuthnChallengeService_Expecter) Read(ctx interface{}, id interface{}) *MockWebAuthnChallengeService_Read_Call {
+	return &MockWebAuthnChallengeService_Read_Call{Call: _e.mock.On("Read", ctx, id)}
+}
+
+func (_c *MockWebAuthnChallengeService_Read_Call) Run(run func(ctx context.Context, id string)) *MockWebAuthnChallengeService_Read_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeService_Read_Call) Return(_a0 *models.WebAuthnChallenge, _a1 error) *MockWebAuthnChallengeService_Read_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeService_Read_Call) RunAndReturn(run func(context.Context, string) (*models.WebAuthnChallenge, error)) *MockWebAuthnChallengeService_Read_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnChallengeService) Update(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.WebAuthnChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnChallenge) *models.WebAuthnChallenge); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnChallenge)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebAuthnChallenge) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnChallengeService_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockWebAuthnChallengeService_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnChallenge
+func (_e *MockWebAuthnChallengeService_Expecter) Update(ctx interface{}, model interface{}) *MockWebAuthnChallengeService_Update_Call {
+	return &MockWebAuthnChallengeService_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockWebAuthnChallengeService_Update_Call) Run(run func(ctx context.Context, model *models.WebAuthnChallenge)) *MockWebAuthnChallengeService_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnChallenge))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeService_Update_Call) Return(_a0 *models.WebAuthnChallenge, _a1 error) *MockWebAuthnChallengeService_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeService_Update_Call) RunAndReturn(run func(context.Context, *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)) *MockWebAuthnChallengeService_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockWebAuthnChallengeService creates a new instance of MockWebAuthnChallengeService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockWebAuthnChallengeService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockWebAuthnChallengeService {
+	mock := &MockWebAuthnChallengeService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,321 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package handlers
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockWebAuthnCredentialService is an autogenerated mock type for the WebAuthnCredentialService type
+type MockWebAuthnCredentialService struct {
+	mock.Mock
+}
+
+type MockWebAuthnCredentialService_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockWebAuthnCredentialService) EXPECT() *MockWebAuthnCredentialService_Expecter {
+	return &MockWebAuthnCredentialService_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnCredentialService) Create(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.WebAuthnCredential
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) (*models.WebAuthnCredential, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) *models.WebAuthnCredential); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnCredential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebAuthnCredential) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnCredentialService_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockWebAuthnCredentialService_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnCredential
+func (_e *MockWebAuthnCredentialService_Expecter) Create(ctx interface{}, model interface{}) *MockWebAuthnCredentialService_Create_Call {
+	return &MockWebAuthnCredentialService_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockWebAuthnCredentialService_Create_Call) Run(run func(ctx context.Context, model *models.WebAuthnCredential)) *MockWebAuthnCredentialService_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnCredential))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Create_Call) Return(_a0 *models.WebAuthnCredential, _a1 error) *MockWebAuthnCredentialService_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Create_Call) RunAndReturn(run func(context.Context, *models.WebAuthnCredential) (*models.WebAuthnCredential, error)) *MockWebAuthnCredentialService_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Delete provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnCredentialService) Delete(ctx context.Context, model *models.WebAuthnCredential) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockWebAuthnCredentialService_Delete_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Delete'
+type MockWebAuthnCredentialService_Delete_Call struct {
+	*mock.Call
+}
+
+// Delete is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnCredential
+func (_e *MockWebAuthnCredentialService_Expecter) Delete(ctx interface{}, model interface{}) *MockWebAuthnCredentialService_Delete_Call {
+	return &MockWebAuthnCredentialService_Delete_Call{Call: _e.mock.On("Delete", ctx, model)}
+}
+
+func (_c *MockWebAuthnCredentialService_Delete_Call) Run(run func(ctx context.Context, model *models.WebAuthnCredential)) *MockWebAuthnCredentialService_Delete_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnCredential))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Delete_Call) Return(_a0 error) *MockWebAuthnCredentialService_Delete_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Delete_Call) RunAndReturn(run func(context.Context, *models.WebAuthnCredential) error) *MockWebAuthnCredentialService_Delete_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Find provides a mock function with given fields: ctx, userId
+func (_m *MockWebAuthnCredentialService) Find(ctx context.Context, userId string) (models.WebAuthnCredentials, error) {
+	ret := _m.Called(ctx, userId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Find")
+	}
+
+	var r0 models.WebAuthnCredentials
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.WebAuthnCredentials, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.WebAuthnCredentials); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(models.WebAuthnCredentials)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnCredentialService_Find_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Find'
+type MockWebAuthnCredentialService_Find_Call struct {
+	*mock.Call
+}
+
+// Find is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId string
+func (_e *MockWebAuthnCredentialService_Expecter) Find(ctx interface{}, userId interface{}) *MockWebAuthnCredentialService_Find_Call {
+	return &MockWebAuthnCredentialService_Find_Call{Call: _e.mock.On("Find", ctx, userId)}
+}
+
+func (_c *MockWebAuthnCredentialService_Find_Call) Run(run func(ctx context.Context, userId string)) *MockWebAuthnCredentialService_Find_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Find_Call) Return(_a0 models.WebAuthnCredentials, _a1 error) *MockWebAuthnCredentialService_Find_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Find_Call) RunAndReturn(run func(context.Context, string) (models.WebAuthnCredentials, error)) *MockWebAuthnCredentialService_Find_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Read provides a mock function with given fields: ctx, userId, id
+func (_m *MockWebAuthnCredentialService) Read(ctx context.Context, userId string, id string) (*models.WebAuthnCredential, error) {
+	ret := _m.Called(ctx, userId, id)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Read")
+	}
+
+	var r0 *models.WebAuthnCredential
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*models.WebAuthnCredential, error)); ok {
+		return rf(ctx, userId, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *models.WebAuthnCredential); ok {
+		r0 = rf(ctx, userId, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnCredential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, userId, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnCredentialService_Read_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Read'
+type MockWebAuthnCredentialService_Read_Call struct {
+	*mock.Call
+}
+
+// Read is a helper method to define mock.On call
+//   - ctx context.Context
+//   - userId string
+//   - id string
+func (_e *MockWebAuthnCredentialService_Expecter) Read(ctx interface{}, userId interface{}, id interface{}) *MockWebAuthnCredentialService_Read_Call {
+	return &MockWebAuthnCredentialService_Read_Call{Call: _e.mock.On("Read", ctx, userId, id)}
+}
+
+func (_c *MockWebAuthnCredentialService_Read_Call) Run(run func(ctx context.Context, userId string, id string)) *MockWebAuthnCredentialService_Read_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Read_Call) Return(_a0 *models.WebAuthnCredential, _a1 error) *MockWebAuthnCredentialService_Read_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Read_Call) RunAndReturn(run func(context.Context, string, string) (*models.WebAuthnCredential, error)) *MockWebAuthnCredentialService_Read_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnCredentialService) Update(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.WebAuthnCredential
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) (*models.WebAuthnCredential, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) *models.WebAuthnCredential); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnCredential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebAuthnCredential) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnCredentialService_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockWebAuthnCredentialService_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnCredential
+func (_e *MockWebAuthnCredentialService_Expecter) Update(ctx interface{}, model interface{}) *MockWebAuthnCredentialService_Update_Call {
+	return &MockWebAuthnCredentialService_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockWebAuthnCredentialService_Update_Call) Run(run func(ctx context.Context, model *models.WebAuthnCredential)) *MockWebAuthnCredentialService_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnCredential))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Update_Call) Return(_a0 *models.WebAuthnCredential, _a1 error) *MockWebAuthnCredentialService_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialService_Update_Call) RunAndReturn(run func(context.Context, *models.WebAuthnCredential) (*models.WebAuthnCredential, error)) *MockWebAuthnCredentialService_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockWebAuthnCredentialService creates a new instance of MockWebAuthnCredentialService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockWebAuthnCredentialService(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockWebAuthnCredentialService {
+	mock := &MockWebAuthnCredentialService{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,473 @@
+package handlers
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/cookie"
+	"github.com/alexferl/echo-boilerplate/util/idp"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+	"github.com/alexferl/echo-boilerplate/util/rand"
+)
+
+var (
+	ErrOAuth2EmailExist       = errors.New("email already in-use by another account")
+	ErrOAuth2IdentityExist    = errors.New("account already linked to another user")
+	ErrOAuth2LoginFailed      = errors.New("failed to log in")
+	ErrOAuth2ProviderNotFound = errors.New("oauth2 provider not found")
+	ErrOAuth2RedirectInvalid  = errors.New("redirect not allowed")
+	ErrOAuth2StateInvalid     = errors.New("oauth2 state invalid")
+)
+
+type OAuth2Handler struct {
+	*openapi.Handler
+	providers            *idp.Registry
+	svc                  UserService
+	sessionSvc           SessionService
+	emailVerificationSvc EmailVerificationService
+	mailer               Mailer
+}
+
+func NewOAuth2Handler(
+	openapi *openapi.Handler,
+	providers *idp.Registry,
+	svc UserService,
+	sessionSvc SessionService,
+	emailVerificationSvc EmailVerificationService,
+	mailer Mailer,
+) *OAuth2Handler {
+	return &OAuth2Handler{
+		Handler:              openapi,
+		providers:            providers,
+		svc:                  svc,
+		sessionSvc:           sessionSvc,
+		emailVerificationSvc: emailVerificationSvc,
+		mailer:               mailer,
+	}
+}
+
+func (h *OAuth2Handler) Register(s *server.Server) {
+	s.Add(http.MethodGet, "/oauth2/:provider/callback", h.callback)
+	s.Add(http.MethodGet, "/oauth2/:provider/login", h.login)
+}
+
+func (h *OAuth2Handler) login(c echo.Context) error {
+	provider, ok := h.providers.Get(c.Param("provider"))
+	if !ok {
+		return h.Validate(c, http.StatusNotFound, echo.Map{"message": ErrOAuth2ProviderNotFound.Error()})
+	}
+
+	redirect := c.QueryParam("redirect")
+	// the tokens can only be handed over with cookies when redirecting
+	if redirect != "" && (!viper.GetBool(config.CookiesEnabled) || !redirectAllowed(redirect)) {
+		return h.Validate(c, http.StatusBadRequest, echo.Map{"message": ErrOAuth2RedirectInvalid.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	url, err := authorize(ctx, c, provider, &oauth2State{Redirect: redirect})
+	if err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusTemporaryRedirect, url)
+}
+
+func (h *OAuth2Handler) callback(c echo.Context) error {
+	provider, ok := h.providers.Get(c.Param("provider"))
+	if !ok {
+		return h.Validate(c, http.StatusNotFound, echo.Map{"message": ErrOAuth2ProviderNotFound.Error()})
+	}
+
+	failed := echo.Map{"message": ErrOAuth2LoginFailed.Error()}
+
+	state, err := readOAuth2State(c, provider.Name)
+	if err != nil {
+		log.Warn().Err(err).Str("provider", provider.Name).Msg("failed reading oauth2 state")
+		return h.Validate(c, http.StatusUnauthorized, failed)
+	}
+	c.SetCookie(stateCookie(provider.Name, "", -1))
+
+	// the user denied access or the provider failed
+	if e := c.QueryParam("error"); e != "" {
+		log.Warn().Str("provider", provider.Name).Str("error", e).Msg("oauth2 provider returned an error")
+		return h.Validate(c, http.StatusUnauthorized, failed)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	identity, err := provider.Exchange(ctx, c.QueryParam("code"), state.Verifier, state.Nonce)
+	if err != nil {
+		log.Error().Err(err).Str("provider", provider.Name).Msg("failed getting identity")
+		return h.Validate(c, http.StatusUnauthorized, failed)
+	}
+
+	user, err := h.svc.FindOneByIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		var se *services.Error
+		// deleted users keep their identities, they can't be linked to another
+		if errors.As(err, &se) && se.Kind == services.Deleted {
+			if state.Link != "" {
+				return h.Validate(c, http.StatusConflict, echo.Map{"message": ErrOAuth2IdentityExist.Error()})
+			}
+			return h.Validate(c, http.StatusUnauthorized, failed)
+		}
+		if !errors.As(err, &se) || se.Kind != services.NotExist {
+			log.Error().Err(err).Msg("failed getting user")
+			return err
+		}
+	}
+
+	if state.Link != "" {
+		return h.link(ctx, c, identity, user, state)
+	}
+
+	if user == nil {
+		// the email doesn't prove ownership of the account, users have
+		// to log in to it first to link their account at the provider.
+		// Deleted users keep their email so it can't be signed up with.
+		user, err = h.svc.FindOneByEmailOrUsername(ctx, identity.Email, "")
+		var se *services.Error
+		if err == nil || errors.As(err, &se) && se.Kind == services.Deleted {
+			return h.Validate(c, http.StatusConflict, echo.Map{"message": ErrOAuth2EmailExist.Error()})
+		}
+
+		if !errors.As(err, &se) || se.Kind != services.NotExist {
+			log.Error().Err(err).Msg("failed getting user")
+			return err
+		}
+
+		return h.signup(ctx, c, identity, state.Redirect)
+	}
+
+	// the provider vouching for the email verifies it if it's still the one of the user
+	if !user.IsEmailVerified() && identity.EmailVerified && strings.EqualFold(identity.Email, user.Email) {
+		user.VerifyEmail()
+		_, err = h.svc.Update(ctx, user.Id, user)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating user")
+			return err
+		}
+	}
+
+	if emailVerificationRequired(user) {
+		return h.Validate(c, http.StatusForbidden, echo.Map{"message": ErrEmailNotVerified.Error()})
+	}
+
+	return completeLogin(ctx, c, h.Handler, h.svc, h.sessionSvc, user, state.Redirect)
+}
+
+// link links identity to the user who started linking it, linked is
+// the user identity is already linked to if there's one.
+func (h *OAuth2Handler) link(ctx context.Context, c echo.Context, identity *idp.Identity, linked *models.User, state *oauth2State) error {
+	if linked != nil && linked.Id != state.Link {
+		return h.Validate(c, http.StatusConflict, echo.Map{"message": ErrOAuth2IdentityExist.Error()})
+	}
+
+	user := linked
+	if user == nil {
+		var err error
+		user, err = h.svc.Read(ctx, state.Link)
+		if err != nil {
+			log.Error().Err(err).Msg("failed getting user")
+			return err
+		}
+
+		other, err := h.svc.FindOneByEmailOrUsername(ctx, identity.Email, "")
+		if err != nil {
+			var se *services.Error
+			if !errors.As(err, &se) || (se.Kind != services.NotExist && se.Kind != services.Deleted) {
+				log.Error().Err(err).Msg("failed getting user")
+				return err
+			}
+		} else if other.Id != user.Id {
+			return h.Validate(c, http.StatusConflict, echo.Map{"message": ErrOAuth2EmailExist.Error()})
+		}
+
+		err = user.LinkIdentity(identity.Provider, identity.Subject, identity.Email)
+		if err != nil {
+			var me *models.Error
+			if errors.As(err, &me) && me.Kind == models.Conflict {
+				return h.Validate(c, http.StatusConflict, echo.Map{"message": me.Message})
+			}
+			return err
+		}
+
+		_, err = h.svc.Update(ctx, user.Id, user)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating user")
+			return err
+		}
+	}
+
+	if state.Redirect != "" {
+		return c.Redirect(http.StatusSeeOther, state.Redirect)
+	}
+
+	return h.Validate(c, http.StatusOK, user.IdentitiesResponse())
+}
+
+// signup creates the user of identity and logs them in.
+func (h *OAuth2Handler) signup(ctx context.Context, c echo.Context, identity *idp.Identity, redirect string) error {
+	username, err := newUsername(identity.Email)
+	if err != nil {
+		return fmt.Errorf("failed generating username: %v", err)
+	}
+
+	user := models.NewUser(identity.Email, username)
+	user.Name = identity.Name
+	if identity.EmailVerified {
+		user.VerifyEmail()
+	}
+
+	err = user.LinkIdentity(identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.svc.Create(ctx, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed inserting user")
+		return err
+	}
+
+	if !user.IsEmailVerified() {
+		err = sendEmailVerification(ctx, h.emailVerificationSvc, h.mailer, user)
+		if err != nil {
+			log.Error().Err(err).Msg("failed sending email verification")
+		}
+	}
+
+	if emailVerificationRequired(user) {
+		return h.Validate(c, http.StatusForbidden, echo.Map{"message": ErrEmailNotVerified.Error()})
+	}
+
+	return completeLogin(ctx, c, h.Handler, h.svc, h.sessionSvc, user, redirect)
+}
+
+// completeLogin logs in user, or starts their MFA challenge if they have MFA enabled, at
+// the end of a login the browser was redirected through. It redirects to redirect with
+// the tokens in cookies or the MFA token in the fragment if it's set, or returns them.
+func completeLogin(
+	ctx context.Context,
+	c echo.Context,
+	h *openapi.Handler,
+	svc UserService,
+	sessionSvc SessionService,
+	user *models.User,
+	redirect string,
+) error {
+	if user.IsMFAEnabled() {
+		resp, err := newMFAChallenge(user, "")
+		if err != nil {
+			log.Error().Err(err).Msg("failed generating mfa token")
+			return err
+		}
+
+		if redirect != "" {
+			// in the fragment so it isn't sent to the server of the target
+			u, _ := url.Parse(redirect)
+			u.Fragment = url.Values{
+				"expires_in": {strconv.FormatInt(resp.ExpiresIn, 10)},
+				"mfa_token":  {resp.MFAToken},
+			}.Encode()
+			return c.Redirect(http.StatusSeeOther, u.String())
+		}
+
+		return h.Validate(c, http.StatusOK, resp)
+	}
+
+	if redirect == "" {
+		return createSession(ctx, c, h, svc, sessionSvc, user, "")
+	}
+
+	_, err := startSession(ctx, c, svc, sessionSvc, user, "")
+	if err != nil {
+		return err
+	}
+
+	return c.Redirect(http.StatusSeeOther, redirect)
+}
+
+// newUsername generates a username for users signing up with a
+// provider from the local part of their email and a random suffix.
+func newUsername(email string) (string, error) {
+	local, _, _ := strings.Cut(email, "@")
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, local)
+	if len(name) > 20 {
+		name = name[:20]
+	}
+	if name == "" {
+		name = "user"
+	}
+
+	b, err := rand.GenerateRandomBytes(4)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s-%s", name, hex.EncodeToString(b)), nil
+}
+
+// authorize starts a login with provider, keeping s in the state
+// cookie, and returns the URL of the provider's consent page.
+func authorize(ctx context.Context, c echo.Context, provider *idp.Provider, s *oauth2State) (string, error) {
+	state, err := rand.GenerateRandomString(80)
+	if err != nil {
+		return "", fmt.Errorf("failed generating state: %v", err)
+	}
+
+	nonce, err := rand.GenerateRandomString(32)
+	if err != nil {
+		return "", fmt.Errorf("failed generating nonce: %v", err)
+	}
+
+	s.Nonce = nonce
+	s.State = state
+	s.Verifier = oauth2.GenerateVerifier()
+
+	url, err := provider.AuthCodeURL(ctx, s.State, s.Verifier, s.Nonce)
+	if err != nil {
+		log.Error().Err(err).Str("provider", provider.Name).Msg("failed getting auth code url")
+		return "", err
+	}
+
+	value, err := s.encode(provider.Name)
+	if err != nil {
+		log.Error().Err(err).Msg("failed generating oauth2 state token")
+		return "", err
+	}
+
+	c.SetCookie(stateCookie(provider.Name, value, int(viper.GetDuration(config.OAuth2StateExpiry).Seconds())))
+
+	return url, nil
+}
+
+// redirectAllowed reports whether target is in the redirect allow-list, its origin has
+// to be the same as one of the entries and its path has to be under the entry's path.
+func redirectAllowed(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return false
+	}
+
+	// browsers resolve dot segments before requesting the target
+	p := path.Clean("/" + u.Path)
+	for _, entry := range viper.GetStringSlice(config.OAuth2RedirectAllowList) {
+		allowed, err := url.Parse(entry)
+		if err != nil || allowed.Host == "" {
+			continue
+		}
+
+		if u.Scheme != allowed.Scheme || !strings.EqualFold(u.Host, allowed.Host) {
+			continue
+		}
+
+		prefix := strings.TrimSuffix(allowed.Path, "/")
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// oauth2State is kept in the state cookie, signed so it can't
+// be tampered with, until the provider redirects back. Link is
+// the id of the user linking the provider to their account.
+type oauth2State struct {
+	Link     string
+	Nonce    string
+	Redirect string
+	State    string
+	Verifier string
+}
+
+func (s *oauth2State) encode(provider string) (string, error) {
+	claims := map[string]any{
+		"nonce":    s.Nonce,
+		"state":    s.State,
+		"verifier": s.Verifier,
+	}
+	if s.Link != "" {
+		claims["link"] = s.Link
+	}
+	if s.Redirect != "" {
+		claims["redirect"] = s.Redirect
+	}
+
+	token, err := jwt.GenerateOAuth2StateToken(provider, claims)
+	if err != nil {
+		return "", err
+	}
+
+	return string(token), nil
+}
+
+// readOAuth2State returns the state of the login with provider
+// the callback was called for, if it matches the state parameter.
+func readOAuth2State(c echo.Context, provider string) (*oauth2State, error) {
+	cookie, err := c.Cookie("state")
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.ParseEncoded([]byte(cookie.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	claims := token.PrivateClaims()
+	if claims["type"] != jwt.OAuth2StateToken.String() || token.Subject() != provider {
+		return nil, ErrOAuth2StateInvalid
+	}
+
+	s := &oauth2State{}
+	s.Link, _ = claims["link"].(string)
+	s.Nonce, _ = claims["nonce"].(string)
+	s.Redirect, _ = claims["redirect"].(string)
+	s.State, _ = claims["state"].(string)
+	s.Verifier, _ = claims["verifier"].(string)
+
+	if s.State == "" || s.State != c.QueryParam("state") {
+		return nil, ErrOAuth2StateInvalid
+	}
+
+	return s, nil
+}
+
+func stateCookie(provider string, value string, maxAge int) *http.Cookie {
+	return cookie.New(&cookie.Options{
+		Name:     "state",
+		Value:    value,
+		Path:     fmt.Sprintf("/oauth2/%s/callback", provider),
+		SameSite: http.SameSiteLaxMode, // needs to be Lax since it's across domains
+		HttpOnly: true,
+		MaxAge:   maxAge,
+	})
+}
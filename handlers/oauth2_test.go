@@ -0,0 +1,541 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/idp"
+	"github.com/alexferl/echo-boilerplate/util/idp/idptest"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+type OAuth2HandlerTestSuite struct {
+	suite.Suite
+	svc                  *handlers.MockUserService
+	sessionSvc           *handlers.MockSessionService
+	emailVerificationSvc *handlers.MockEmailVerificationService
+	mailer               *handlers.MockMailer
+	server               *api.Server
+	idp                  *idptest.Server
+}
+
+func (s *OAuth2HandlerTestSuite) SetupTest() {
+	s.idp = idptest.NewServer()
+
+	viper.Set(config.OAuth2Providers, []string{"fake"})
+	viper.Set(config.OAuth2ProviderKey("fake", config.OAuth2ProviderClientId), idptest.ClientId)
+	viper.Set(config.OAuth2ProviderKey("fake", config.OAuth2ProviderClientSecret), idptest.ClientSecret)
+	viper.Set(config.OAuth2ProviderKey("fake", config.OAuth2ProviderDiscoveryURL), s.idp.DiscoveryURL())
+
+	providers, err := idp.NewRegistry()
+	s.Require().NoError(err)
+
+	svc := handlers.NewMockUserService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	sessionSvc := handlers.NewMockSessionService(s.T())
+	emailVerificationSvc := handlers.NewMockEmailVerificationService(s.T())
+	m := handlers.NewMockMailer(s.T())
+	h := handlers.NewOAuth2Handler(openapi.NewHandler(), providers, svc, sessionSvc, emailVerificationSvc, m)
+	s.svc = svc
+	s.sessionSvc = sessionSvc
+	s.emailVerificationSvc = emailVerificationSvc
+	s.mailer = m
+	s.server = getServer(svc, patSvc, h)
+}
+
+func (s *OAuth2HandlerTestSuite) TearDownTest() {
+	s.idp.Close()
+	viper.Set(config.OAuth2Providers, []string{""})
+}
+
+func TestOAuth2HandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(OAuth2HandlerTestSuite))
+}
+
+// login starts a login and returns the request the provider redirects the user back with.
+func (s *OAuth2HandlerTestSuite) login(redirect string) *http.Request {
+	target := "/oauth2/fake/login"
+	if redirect != "" {
+		target += "?" + url.Values{"redirect": {redirect}}.Encode()
+	}
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusTemporaryRedirect, resp.Code)
+
+	callback, err := s.idp.Authorize(resp.Header().Get("Location"))
+	s.Require().NoError(err)
+	s.Require().Equal("/oauth2/fake/callback", callback.Path)
+
+	req = httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, c := range resp.Result().Cookies() {
+		if c.Name == "state" {
+			s.Require().Equal(callback.Path, c.Path)
+			req.AddCookie(c)
+		}
+	}
+
+	return req
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Login_307() {
+	req := httptest.NewRequest(http.MethodGet, "/oauth2/fake/login", nil)
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	location, err := url.Parse(resp.Header().Get("Location"))
+	s.Require().NoError(err)
+
+	s.Assert().Equal(http.StatusTemporaryRedirect, resp.Code)
+	s.Assert().True(strings.HasPrefix(location.String(), s.idp.URL+"/authorize"))
+	s.Assert().Equal(idptest.ClientId, location.Query().Get("client_id"))
+	s.Assert().Equal("http://localhost:1323/oauth2/fake/callback", location.Query().Get("redirect_uri"))
+	s.Assert().NotEqual("", location.Query().Get("state"))
+	s.Assert().NotEqual("", location.Query().Get("nonce"))
+	s.Assert().NotEqual("", location.Query().Get("code_challenge"))
+	s.Assert().Equal("S256", location.Query().Get("code_challenge_method"))
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Login_400_Redirect() {
+	viper.Set(config.OAuth2RedirectAllowList, []string{"https://example.com/app/", "http://localhost:3000"})
+	defer viper.Set(config.OAuth2RedirectAllowList, []string{})
+
+	for _, redirect := range []string{
+		"/app",
+		"https://evil.com/app/",
+		"http://example.com/app/",
+		"https://example.com/application",
+		"https://example.com/app/../admin",
+		"https://user@example.com/app/",
+		"javascript:alert(1)",
+	} {
+		s.T().Run(redirect, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/oauth2/fake/login?"+url.Values{"redirect": {redirect}}.Encode(), nil)
+			resp := httptest.NewRecorder()
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusBadRequest, resp.Code)
+			s.Assert().Equal(handlers.ErrOAuth2RedirectInvalid.Error(), result.Message)
+		})
+	}
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Login_400_Redirect_Cookies_Disabled() {
+	viper.Set(config.OAuth2RedirectAllowList, []string{"https://example.com/app/"})
+	viper.Set(config.CookiesEnabled, false)
+	defer func() {
+		viper.Set(config.OAuth2RedirectAllowList, []string{})
+		viper.Set(config.CookiesEnabled, true)
+	}()
+
+	req := httptest.NewRequest(http.MethodGet, "/oauth2/fake/login?redirect=https://example.com/app/", nil)
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_404() {
+	for _, path := range []string{"/oauth2/unknown/login", "/oauth2/unknown/callback"} {
+		s.T().Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			resp := httptest.NewRecorder()
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusNotFound, resp.Code)
+			s.Assert().Equal(handlers.ErrOAuth2ProviderNotFound.Error(), result.Message)
+		})
+	}
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_200_New_User() {
+	req := s.login("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "test@example.com", "").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	var user *models.User
+	s.svc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.User) { user = model }).
+		RunAndReturn(func(ctx context.Context, model *models.User) (*models.User, error) { return model, nil })
+
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.LoginResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotEqual("", result.AccessToken)
+	s.Assert().NotEqual("", result.RefreshToken)
+	if s.Assert().NotNil(user) {
+		s.Assert().Equal("test@example.com", user.Email)
+		s.Assert().Equal("Test User", user.Name)
+		s.Assert().True(strings.HasPrefix(user.Username, "test-"))
+		s.Assert().True(user.IsEmailVerified())
+		if identity := user.Identity("fake"); s.Assert().NotNil(identity) {
+			s.Assert().Equal("1234567890", identity.Subject)
+		}
+	}
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_200_Existing_User() {
+	user := getUser()
+	_ = user.LinkIdentity("fake", "1234567890", "test@example.com")
+	req := s.login("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, mock.Anything, user).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.LoginResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotEqual("", result.AccessToken)
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_303_Redirect() {
+	viper.Set(config.OAuth2RedirectAllowList, []string{"https://example.com/app/"})
+	defer viper.Set(config.OAuth2RedirectAllowList, []string{})
+
+	user := getUser()
+	req := s.login("https://example.com/app/home?tab=1")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, mock.Anything, user).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	cookies := map[string]string{}
+	for _, c := range resp.Result().Cookies() {
+		cookies[c.Name] = c.Value
+	}
+
+	s.Assert().Equal(http.StatusSeeOther, resp.Code)
+	s.Assert().Equal("https://example.com/app/home?tab=1", resp.Header().Get("Location"))
+	s.Assert().NotEqual("", cookies[viper.GetString(config.JWTAccessTokenCookieName)])
+	s.Assert().NotEqual("", cookies[viper.GetString(config.JWTRefreshTokenCookieName)])
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_303_Redirect_MFA_Required() {
+	viper.Set(config.OAuth2RedirectAllowList, []string{"https://example.com/app/"})
+	defer viper.Set(config.OAuth2RedirectAllowList, []string{})
+
+	user, _ := getMFAUser("abcdefghijkl")
+	user.VerifyEmail()
+	req := s.login("https://example.com/app/")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	location, err := url.Parse(resp.Header().Get("Location"))
+	s.Require().NoError(err)
+	fragment, err := url.ParseQuery(location.Fragment)
+	s.Require().NoError(err)
+
+	s.Assert().Equal(http.StatusSeeOther, resp.Code)
+	s.Assert().Equal("https://example.com/app/", location.Scheme+"://"+location.Host+location.Path)
+	s.Assert().NotEqual("", fragment.Get("mfa_token"))
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_200_MFA_Required() {
+	user, _ := getMFAUser("abcdefghijkl")
+	user.VerifyEmail()
+	req := s.login("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.MFAChallengeResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().True(result.MFARequired)
+	s.Assert().NotEqual("", result.MFAToken)
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_409_Email_Exist() {
+	user := getUser()
+	req := s.login("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "test@example.com", "").
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(handlers.ErrOAuth2EmailExist.Error(), result.Message)
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_409_Email_Exist_Deleted() {
+	req := s.login("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "test@example.com", "").
+		Return(nil, &services.Error{Kind: services.Deleted})
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+	s.Assert().Equal(handlers.ErrOAuth2EmailExist.Error(), result.Message)
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_401_User_Deleted() {
+	req := s.login("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.Deleted})
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Equal(handlers.ErrOAuth2LoginFailed.Error(), result.Message)
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_403_Email_Not_Verified() {
+	viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyLogin)
+	defer viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyNone)
+
+	s.idp.Claims["email_verified"] = false
+	req := s.login("")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		FindOneByIdentity(mock.Anything, "fake", "1234567890").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, "test@example.com", "").
+		Return(nil, &services.Error{Kind: services.NotExist})
+
+	s.svc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.emailVerificationSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.mailer.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Equal(handlers.ErrEmailNotVerified.Error(), result.Message)
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_Existing_User_Email_Verification() {
+	viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyLogin)
+	defer viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyNone)
+
+	testCases := []struct {
+		name          string
+		emailVerified bool
+		email         string
+		code          int
+	}{
+		{"verified by provider", true, "test@example.com", http.StatusOK},
+		{"not verified by provider", false, "test@example.com", http.StatusForbidden},
+		{"email changed since linking", true, "other@example.com", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			user := models.NewUser(tc.email, "test")
+			_ = user.LinkIdentity("fake", "1234567890", "test@example.com")
+			s.idp.Claims["email_verified"] = tc.emailVerified
+			req := s.login("")
+			resp := httptest.NewRecorder()
+
+			s.svc.EXPECT().
+				FindOneByIdentity(mock.Anything, "fake", "1234567890").
+				Return(user, nil).Once()
+
+			if tc.code == http.StatusOK {
+				s.svc.EXPECT().
+					Update(mock.Anything, mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+						return u.IsEmailVerified()
+					})).
+					Return(user, nil).Twice()
+
+				s.sessionSvc.EXPECT().
+					Create(mock.Anything, mock.Anything).
+					Return(nil, nil).Once()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(tc.code, resp.Code)
+		})
+	}
+}
+
+func (s *OAuth2HandlerTestSuite) TestOAuth2Handler_Callback_401() {
+	testCases := []struct {
+		name   string
+		modify func(req *http.Request) *http.Request
+	}{
+		{"state cookie missing", func(req *http.Request) *http.Request {
+			return httptest.NewRequest(http.MethodGet, req.URL.RequestURI(), nil)
+		}},
+		{"state mismatch", func(req *http.Request) *http.Request {
+			q := req.URL.Query()
+			q.Set("state", "wrong")
+			r := httptest.NewRequest(http.MethodGet, req.URL.Path+"?"+q.Encode(), nil)
+			c, _ := req.Cookie("state")
+			r.AddCookie(c)
+			return r
+		}},
+		{"state cookie tampered", func(req *http.Request) *http.Request {
+			r := httptest.NewRequest(http.MethodGet, req.URL.RequestURI(), nil)
+			c, _ := req.Cookie("state")
+			c.Value += "a"
+			r.AddCookie(c)
+			return r
+		}},
+		{"state cookie of another provider", func(req *http.Request) *http.Request {
+			token, _ := jwt.GenerateOAuth2StateToken("other", map[string]any{"state": req.URL.Query().Get("state")})
+			r := httptest.NewRequest(http.MethodGet, req.URL.RequestURI(), nil)
+			r.AddCookie(&http.Cookie{Name: "state", Value: string(token)})
+			return r
+		}},
+		{"state cookie of another type", func(req *http.Request) *http.Request {
+			token, _ := jwt.GenerateAccessToken("fake", map[string]any{"state": req.URL.Query().Get("state")})
+			r := httptest.NewRequest(http.MethodGet, req.URL.RequestURI(), nil)
+			r.AddCookie(&http.Cookie{Name: "state", Value: string(token)})
+			return r
+		}},
+		{"invalid code", func(req *http.Request) *http.Request {
+			q := req.URL.Query()
+			q.Set("code", "wrong")
+			r := httptest.NewRequest(http.MethodGet, req.URL.Path+"?"+q.Encode(), nil)
+			c, _ := req.Cookie("state")
+			r.AddCookie(c)
+			return r
+		}},
+		{"access denied", func(req *http.Request) *http.Request {
+			q := req.URL.Query()
+			q.Del("code")
+			q.Set("error", "access_denied")
+			r := httptest.NewRequest(http.MethodGet, req.URL.Path+"?"+q.Encode(), nil)
+			c, _ := req.Cookie("state")
+			r.AddCookie(c)
+			return r
+		}},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			req := tc.modify(s.login(""))
+			resp := httptest.NewRecorder()
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+			s.Assert().Equal(handlers.ErrOAuth2LoginFailed.Error(), result.Message)
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	jwx "github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+var (
+	ErrOAuth2ClientInvalid        = errors.New("invalid client")
+	ErrOAuth2GrantTypeUnsupported = errors.New("unsupported grant type")
+	ErrOAuth2TokenTypeUnsupported = errors.New("service account tokens are revoked by deleting their credential")
+)
+
+// Error codes of RFC 6749 returned by the token endpoint.
+const (
+	OAuth2ErrorInvalidClient        = "invalid_client"
+	OAuth2ErrorUnsupportedGrantType = "unsupported_grant_type"
+	OAuth2ErrorUnsupportedTokenType = "unsupported_token_type"
+)
+
+const GrantTypeClientCredentials = "client_credentials"
+
+// Token type hints of RFC 7009, personal access
+// and service account tokens have their own.
+const (
+	TokenTypeAccess   = "access_token"
+	TokenTypePersonal = "personal_access_token"
+	TokenTypeRefresh  = "refresh_token"
+	TokenTypeService  = "service_access_token"
+)
+
+// OAuth2TokenHandler lets the clients of --oauth2-clients check and revoke
+// tokens without having to verify them, see RFC 7662 and RFC 7009, and
+// service accounts get access tokens with the client credentials grant.
+type OAuth2TokenHandler struct {
+	*openapi.Handler
+	svc        UserService
+	sessionSvc SessionService
+	patSvc     PersonalAccessTokenService
+	saSvc      ServiceAccountService
+}
+
+func NewOAuth2TokenHandler(
+	openapi *openapi.Handler,
+	svc UserService,
+	sessionSvc SessionService,
+	patSvc PersonalAccessTokenService,
+	saSvc ServiceAccountService,
+) *OAuth2TokenHandler {
+	return &OAuth2TokenHandler{
+		Handler:    openapi,
+		svc:        svc,
+		sessionSvc: sessionSvc,
+		patSvc:     patSvc,
+		saSvc:      saSvc,
+	}
+}
+
+func (h *OAuth2TokenHandler) Register(s *server.Server) {
+	s.Add(http.MethodPost, "/oauth2/token", h.token)
+	s.Add(http.MethodPost, "/oauth2/introspect", h.introspect)
+	s.Add(http.MethodPost, "/oauth2/revoke", h.revoke)
+}
+
+type TokenRequest struct {
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+	GrantType     string `form:"grant_type"`
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+type IntrospectResponse struct {
+	Active    bool             `json:"active"`
+	Actor     *IntrospectActor `json:"act,omitempty"`
+	ExpiresAt int64            `json:"exp,omitempty"`
+	IssuedAt  int64            `json:"iat,omitempty"`
+	Issuer    string           `json:"iss,omitempty"`
+	JWTId     string           `json:"jti,omitempty"`
+	Scope     string           `json:"scope,omitempty"`
+	Subject   string           `json:"sub,omitempty"`
+	TokenType string           `json:"token_type,omitempty"`
+	Username  string           `json:"username,omitempty"`
+}
+
+// IntrospectActor is the user impersonating the subject of a token.
+type IntrospectActor struct {
+	Subject string `json:"sub"`
+}
+
+type ClientCredentialsResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// token exchanges the client id and secret of a service account credential
+// for an access token, there's no refresh token, clients ask for a new one.
+func (h *OAuth2TokenHandler) token(c echo.Context) error {
+	body := &TokenRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	if body.GrantType != GrantTypeClientCredentials {
+		return h.tokenError(c, http.StatusBadRequest, OAuth2ErrorUnsupportedGrantType, ErrOAuth2GrantTypeUnsupported)
+	}
+
+	id, secret := clientCredentials(c, body)
+	if id == "" || secret == "" {
+		return h.tokenError(c, http.StatusUnauthorized, OAuth2ErrorInvalidClient, ErrOAuth2ClientInvalid)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	sa, err := h.saSvc.ReadByClientId(ctx, id)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.tokenError(c, http.StatusUnauthorized, OAuth2ErrorInvalidClient, ErrOAuth2ClientInvalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting service account")
+		return err
+	}
+
+	token, err := sa.Token(id, secret)
+	if err != nil {
+		var me *models.Error
+		if errors.As(err, &me) {
+			return h.tokenError(c, http.StatusUnauthorized, OAuth2ErrorInvalidClient, ErrOAuth2ClientInvalid)
+		}
+		log.Error().Err(err).Msg("failed generating service token")
+		return err
+	}
+
+	// failing to record the usage shouldn't fail the request
+	if err = h.saSvc.Use(ctx, sa, id); err != nil {
+		log.Error().Err(err).Msg("failed updating service account credential usage")
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	resp := &ClientCredentialsResponse{
+		AccessToken: string(token),
+		ExpiresIn:   int64(viper.GetDuration(config.JWTAccessTokenExpiry).Seconds()),
+		TokenType:   "Bearer",
+	}
+
+	return h.Validate(c, http.StatusOK, resp)
+}
+
+func (h *OAuth2TokenHandler) introspect(c echo.Context) error {
+	body := &TokenRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	if !clientAuthenticated(c, body) {
+		return h.clientInvalid(c)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	active, err := h.activeToken(ctx, body.Token)
+	if err != nil {
+		return err
+	}
+
+	if active == nil {
+		return h.Validate(c, http.StatusOK, &IntrospectResponse{Active: false})
+	}
+
+	token := active.token
+	scope, _ := token.PrivateClaims()["scope"].(string)
+	resp := &IntrospectResponse{
+		Active:    true,
+		ExpiresAt: token.Expiration().Unix(),
+		IssuedAt:  token.IssuedAt().Unix(),
+		Issuer:    token.Issuer(),
+		JWTId:     token.JwtID(),
+		Scope:     scope,
+		Subject:   token.Subject(),
+		TokenType: tokenType(token),
+		Username:  active.user.Username,
+	}
+	if act, ok := token.PrivateClaims()["act"].(map[string]any); ok {
+		sub, _ := act["sub"].(string)
+		resp.Actor = &IntrospectActor{Subject: sub}
+	}
+
+	return h.Validate(c, http.StatusOK, resp)
+}
+
+func (h *OAuth2TokenHandler) revoke(c echo.Context) error {
+	body := &TokenRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	if !clientAuthenticated(c, body) {
+		return h.clientInvalid(c)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	active, err := h.activeToken(ctx, body.Token)
+	if err != nil {
+		return err
+	}
+
+	// invalid tokens are ignored since there's nothing left to revoke,
+	// access tokens end with the session they were issued for and
+	// service account tokens with the credential they were issued for
+	switch {
+	case active == nil:
+	case active.sa != nil:
+		return h.tokenError(c, http.StatusBadRequest, OAuth2ErrorUnsupportedTokenType, ErrOAuth2TokenTypeUnsupported)
+	case active.session != nil:
+		if err = h.sessionSvc.Revoke(ctx, "", active.session, models.SessionRevokedClient); err != nil {
+			log.Error().Err(err).Msg("failed revoking session")
+			return err
+		}
+	case active.pat != nil:
+		if err = h.patSvc.Revoke(ctx, "", active.pat); err != nil {
+			log.Error().Err(err).Msg("failed revoking personal access token")
+			return err
+		}
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// validToken holds a valid token with its user and its session or personal
+// access token, or with its service account for service account tokens.
+type validToken struct {
+	token   jwx.Token
+	user    *models.User
+	session *models.Session
+	pat     *models.PersonalAccessToken
+	sa      *models.ServiceAccount
+}
+
+// activeToken parses encodedToken and returns it if it and its user are
+// still valid, a nil validToken means it isn't, which isn't an error.
+func (h *OAuth2TokenHandler) activeToken(ctx context.Context, encodedToken string) (*validToken, error) {
+	token, err := jwt.ParseEncoded([]byte(encodedToken))
+	if err != nil || tokenType(token) == "" {
+		return nil, nil
+	}
+
+	if tokenType(token) == TokenTypeService {
+		return h.activeServiceToken(ctx, token)
+	}
+
+	user, err := h.svc.Read(ctx, token.Subject())
+	if err != nil {
+		return nil, ignoreNotExist(err, "failed getting user")
+	}
+
+	if user.IsBanned || user.IsLocked {
+		return nil, nil
+	}
+
+	active := &validToken{token: token, user: user}
+	claims := token.PrivateClaims()
+	switch tokenType(token) {
+	case TokenTypeAccess, TokenTypeRefresh:
+		// like when authenticating, tokens issued before sessions are inactive
+		sid, ok := claims["sid"].(string)
+		if !ok {
+			return nil, nil
+		}
+
+		active.session, err = h.sessionSvc.Read(ctx, user.Id, sid)
+		if err != nil {
+			return nil, ignoreNotExist(err, "failed getting session")
+		}
+
+		if active.session.IsRevoked {
+			return nil, nil
+		}
+
+		// only the latest refresh token of a session is valid
+		if tokenType(token) == TokenTypeRefresh && active.session.ValidateRefreshToken(encodedToken) != nil {
+			return nil, nil
+		}
+	case TokenTypePersonal:
+		if patId, ok := claims["pat_id"].(string); ok {
+			active.pat, err = h.patSvc.Read(ctx, user.Id, patId)
+			if err != nil {
+				return nil, ignoreNotExist(err, "failed getting personal access token")
+			}
+
+			if active.pat.Validate(encodedToken) != nil {
+				return nil, nil
+			}
+		} else {
+			// tokens created before scopes have no pat_id, they're matched by hash
+			pats, err := h.patSvc.FindUnscoped(ctx, user.Id)
+			if err != nil {
+				return nil, ignoreNotExist(err, "failed getting personal access tokens")
+			}
+
+			active.pat = pats.Match(encodedToken)
+			if active.pat == nil {
+				return nil, nil
+			}
+		}
+
+		if active.pat.IsRevoked || time.Now().After(*active.pat.ExpiresAt) {
+			return nil, nil
+		}
+	}
+
+	return active, nil
+}
+
+// activeServiceToken returns token if its service account
+// and the credential it was issued for still exist.
+func (h *OAuth2TokenHandler) activeServiceToken(ctx context.Context, token jwx.Token) (*validToken, error) {
+	sa, err := h.saSvc.Read(ctx, token.Subject())
+	if err != nil {
+		return nil, ignoreNotExist(err, "failed getting service account")
+	}
+
+	clientId, _ := token.PrivateClaims()["client_id"].(string)
+	if sa.Credential(clientId) == nil {
+		return nil, nil
+	}
+
+	return &validToken{token: token, user: sa.User(), sa: sa}, nil
+}
+
+// ignoreNotExist ignores the errors of missing or deleted documents,
+// which make tokens inactive, and logs the others.
+func ignoreNotExist(err error, msg string) error {
+	var se *services.Error
+	if errors.As(err, &se) {
+		if se.Kind == services.NotExist || se.Kind == services.Deleted {
+			return nil
+		}
+	}
+	log.Error().Err(err).Msg(msg)
+	return err
+}
+
+// tokenError responds with the error format of RFC 6749.
+func (h *OAuth2TokenHandler) tokenError(c echo.Context, status int, code string, err error) error {
+	if status == http.StatusUnauthorized {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="oauth2"`)
+	}
+	return h.Validate(c, status, echo.Map{"error": code, "error_description": err.Error()})
+}
+
+func (h *OAuth2TokenHandler) clientInvalid(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="oauth2"`)
+	return h.Validate(c, http.StatusUnauthorized, echo.Map{"message": ErrOAuth2ClientInvalid.Error()})
+}
+
+// clientAuthenticated checks the client credentials of the Authorization
+// header, or of the body when there's none, against --oauth2-clients.
+func clientAuthenticated(c echo.Context, body *TokenRequest) bool {
+	id, secret := clientCredentials(c, body)
+	if id == "" || secret == "" {
+		return false
+	}
+
+	expected, ok := viper.GetStringMapString(config.OAuth2Clients)[id]
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) == 1
+}
+
+// clientCredentials returns the client id and secret of the
+// Authorization header, or of the body when there's none.
+func clientCredentials(c echo.Context, body *TokenRequest) (string, string) {
+	id, secret, ok := c.Request().BasicAuth()
+	if !ok {
+		return body.ClientId, body.ClientSecret
+	}
+	return id, secret
+}
+
+// tokenType returns the token type hint of the tokens clients can
+// introspect and revoke, and an empty string for any other token.
+func tokenType(token jwx.Token) string {
+	switch token.PrivateClaims()["type"] {
+	case jwt.AccessToken.String():
+		return TokenTypeAccess
+	case jwt.RefreshToken.String():
+		return TokenTypeRefresh
+	case jwt.PersonalToken.String():
+		return TokenTypePersonal
+	case jwt.ServiceToken.String():
+		return TokenTypeService
+	}
+
+	return ""
+}
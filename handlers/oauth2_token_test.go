@@ -0,0 +1,549 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+type OAuth2TokenHandlerTestSuite struct {
+	suite.Suite
+	svc          *handlers.MockUserService
+	sessionSvc   *handlers.MockSessionService
+	patSvc       *handlers.MockPersonalAccessTokenService
+	saSvc        *handlers.MockServiceAccountService
+	server       *api.Server
+	user         *models.User
+	session      *models.Session
+	accessToken  []byte
+	refreshToken []byte
+}
+
+func (s *OAuth2TokenHandlerTestSuite) SetupTest() {
+	svc := handlers.NewMockUserService(s.T())
+	sessionSvc := handlers.NewMockSessionService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	saSvc := handlers.NewMockServiceAccountService(s.T())
+	h := handlers.NewOAuth2TokenHandler(openapi.NewHandler(), svc, sessionSvc, patSvc, saSvc)
+
+	viper.Set(config.OAuth2Clients, map[string]string{"gateway": "secret"})
+
+	user := getUser()
+	session := models.NewSession(user.Id)
+	access, refresh, _ := user.Login(session)
+
+	s.svc = svc
+	s.sessionSvc = sessionSvc
+	s.patSvc = patSvc
+	s.saSvc = saSvc
+	s.server = getServer(svc, patSvc, h)
+	s.user = user
+	s.session = session
+	s.accessToken = access
+	s.refreshToken = refresh
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TearDownTest() {
+	viper.Set(config.OAuth2Clients, map[string]string{})
+}
+
+func TestOAuth2TokenHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(OAuth2TokenHandlerTestSuite))
+}
+
+func (s *OAuth2TokenHandlerTestSuite) request(path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", echo.MIMEApplicationForm)
+	req.SetBasicAuth("gateway", "secret")
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func (s *OAuth2TokenHandlerTestSuite) introspect(token []byte) *handlers.IntrospectResponse {
+	resp := s.request("/oauth2/introspect", url.Values{"token": {string(token)}})
+	s.Require().Equal(http.StatusOK, resp.Code)
+
+	var result handlers.IntrospectResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	return &result
+}
+
+func (s *OAuth2TokenHandlerTestSuite) newPAT() (*models.PersonalAccessToken, string) {
+	pat, err := models.NewPersonalAccessToken(
+		s.user.Id,
+		"ci",
+		time.Now().Add(7*24*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeTasksRead},
+	)
+	s.Require().NoError(err)
+	token := pat.Token
+	s.Require().NoError(pat.Encrypt())
+	return pat, token
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Access() {
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, s.session.Id).
+		Return(s.session, nil)
+
+	result := s.introspect(s.accessToken)
+
+	s.Assert().True(result.Active)
+	s.Assert().Equal(s.user.Id, result.Subject)
+	s.Assert().Equal(s.user.Username, result.Username)
+	s.Assert().Equal(handlers.TokenTypeAccess, result.TokenType)
+	s.Assert().NotZero(result.ExpiresAt)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Access_No_Session() {
+	access, _ := jwt.GenerateAccessToken(s.user.Id, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.Assert().False(s.introspect(access).Active)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Impersonation() {
+	super := models.NewUserWithRole("super@example.com", "super", models.SuperRole)
+	session := models.NewSession(s.user.Id)
+	token, err := s.user.Impersonate(super, session)
+	s.Require().NoError(err)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, session.Id).
+		Return(session, nil)
+
+	result := s.introspect(token)
+
+	s.Assert().True(result.Active)
+	s.Assert().Equal(s.user.Id, result.Subject)
+	if s.Assert().NotNil(result.Actor) {
+		s.Assert().Equal(super.Id, result.Actor.Subject)
+	}
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Client_Body() {
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, s.session.Id).
+		Return(s.session, nil)
+
+	form := url.Values{
+		"client_id":     {"gateway"},
+		"client_secret": {"secret"},
+		"token":         {string(s.refreshToken)},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", echo.MIMEApplicationForm)
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.IntrospectResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().True(result.Active)
+	s.Assert().Equal(handlers.TokenTypeRefresh, result.TokenType)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Refresh_Rotated() {
+	_ = s.session.Rotate([]byte("newer"))
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, s.session.Id).
+		Return(s.session, nil)
+
+	s.Assert().False(s.introspect(s.refreshToken).Active)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Session_Revoked() {
+	s.session.Revoke(s.user.Id, models.SessionRevokedLogout)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, s.session.Id).
+		Return(s.session, nil)
+
+	s.Assert().False(s.introspect(s.accessToken).Active)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Banned() {
+	s.user.IsBanned = true
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.Assert().False(s.introspect(s.accessToken).Active)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_User_Deleted() {
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(nil, services.NewError(nil, services.Deleted, services.ErrUserDeleted.Error()))
+
+	s.Assert().False(s.introspect(s.accessToken).Active)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_PAT() {
+	pat, token := s.newPAT()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.patSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, pat.Id).
+		Return(pat, nil)
+
+	result := s.introspect([]byte(token))
+
+	s.Assert().True(result.Active)
+	s.Assert().Equal(handlers.TokenTypePersonal, result.TokenType)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_PAT_Unscoped() {
+	token, _ := jwt.GeneratePersonalToken(s.user.Id, 7*24*time.Hour, nil)
+	expiresAt := time.Now().Add(7 * 24 * time.Hour)
+	pat := models.PersonalAccessToken{Id: "legacy", ExpiresAt: &expiresAt, Token: string(token), UserId: s.user.Id}
+	s.Require().NoError(pat.Encrypt())
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.patSvc.EXPECT().
+		FindUnscoped(mock.Anything, s.user.Id).
+		Return(models.PersonalAccessTokens{pat}, nil)
+
+	result := s.introspect(token)
+
+	s.Assert().True(result.Active)
+	s.Assert().Equal(handlers.TokenTypePersonal, result.TokenType)
+	s.Assert().Empty(result.Scope)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_PAT_Revoked() {
+	pat, token := s.newPAT()
+	pat.IsRevoked = true
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.patSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, pat.Id).
+		Return(pat, nil)
+
+	s.Assert().False(s.introspect([]byte(token)).Active)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Invalid() {
+	result := s.introspect([]byte("invalid"))
+
+	s.Assert().False(result.Active)
+	s.Assert().Empty(result.Subject)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_401() {
+	form := url.Values{"token": {string(s.accessToken)}}
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", echo.MIMEApplicationForm)
+	req.SetBasicAuth("gateway", "wrong")
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Contains(resp.Body.String(), handlers.ErrOAuth2ClientInvalid.Error())
+	s.Assert().NotEmpty(resp.Header().Get(echo.HeaderWWWAuthenticate))
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Revoke_200_Refresh() {
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, s.session.Id).
+		Return(s.session, nil)
+
+	s.sessionSvc.EXPECT().
+		Revoke(mock.Anything, "", s.session, models.SessionRevokedClient).
+		Return(nil)
+
+	resp := s.request("/oauth2/revoke", url.Values{
+		"token":           {string(s.refreshToken)},
+		"token_type_hint": {handlers.TokenTypeRefresh},
+	})
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Revoke_200_Impersonation() {
+	super := models.NewUserWithRole("super@example.com", "super", models.SuperRole)
+	session := models.NewSession(s.user.Id)
+	token, err := s.user.Impersonate(super, session)
+	s.Require().NoError(err)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.sessionSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, session.Id).
+		Return(session, nil)
+
+	s.sessionSvc.EXPECT().
+		Revoke(mock.Anything, "", session, models.SessionRevokedClient).
+		Return(nil)
+
+	resp := s.request("/oauth2/revoke", url.Values{"token": {string(token)}})
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Revoke_200_PAT() {
+	pat, token := s.newPAT()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.patSvc.EXPECT().
+		Read(mock.Anything, s.user.Id, pat.Id).
+		Return(pat, nil)
+
+	s.patSvc.EXPECT().
+		Revoke(mock.Anything, "", pat).
+		Return(nil)
+
+	resp := s.request("/oauth2/revoke", url.Values{"token": {token}})
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Revoke_200_Invalid() {
+	resp := s.request("/oauth2/revoke", url.Values{"token": {"invalid"}})
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Revoke_401() {
+	form := url.Values{"token": {string(s.refreshToken)}}
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/revoke", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", echo.MIMEApplicationForm)
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) newServiceAccount() (*models.ServiceAccount, *models.ServiceAccountCredentialCreateResponse) {
+	sa := models.NewServiceAccount("ci", "")
+	sa.Roles = []string{models.UserRole.String()}
+	cred, err := sa.AddCredential()
+	s.Require().NoError(err)
+	return sa, cred
+}
+
+func (s *OAuth2TokenHandlerTestSuite) serviceToken(sa *models.ServiceAccount, cred *models.ServiceAccountCredentialCreateResponse) []byte {
+	token, err := sa.Token(cred.ClientId, cred.ClientSecret)
+	s.Require().NoError(err)
+	return token
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Service() {
+	sa, cred := s.newServiceAccount()
+	token := s.serviceToken(sa, cred)
+
+	s.saSvc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	result := s.introspect(token)
+
+	s.Assert().True(result.Active)
+	s.Assert().Equal(sa.Id, result.Subject)
+	s.Assert().Equal(handlers.TokenTypeService, result.TokenType)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Introspect_200_Service_Inactive() {
+	sa, cred := s.newServiceAccount()
+	token := s.serviceToken(sa, cred)
+
+	testCases := []struct {
+		name string
+		sa   *models.ServiceAccount
+		err  error
+	}{
+		{"credential deleted", models.NewServiceAccount("ci", ""), nil},
+		{"deleted", nil, services.NewError(nil, services.Deleted, services.ErrServiceAccountDeleted.Error())},
+		{"not found", nil, services.NewError(nil, services.NotExist, services.ErrServiceAccountNotFound.Error())},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			s.saSvc.EXPECT().
+				Read(mock.Anything, sa.Id).
+				Return(tc.sa, tc.err).Once()
+
+			s.Assert().False(s.introspect(token).Active)
+		})
+	}
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Revoke_400_Service() {
+	sa, cred := s.newServiceAccount()
+
+	s.saSvc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	resp := s.request("/oauth2/revoke", url.Values{"token": {string(s.serviceToken(sa, cred))}})
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Contains(resp.Body.String(), handlers.OAuth2ErrorUnsupportedTokenType)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) clientCredentials(form url.Values, id string, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", echo.MIMEApplicationForm)
+	req.SetBasicAuth(id, secret)
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Token_200() {
+	sa, cred := s.newServiceAccount()
+
+	s.saSvc.EXPECT().
+		ReadByClientId(mock.Anything, cred.ClientId).
+		Return(sa, nil)
+
+	s.saSvc.EXPECT().
+		Use(mock.Anything, sa, cred.ClientId).
+		Return(nil)
+
+	form := url.Values{"grant_type": {handlers.GrantTypeClientCredentials}}
+	resp := s.clientCredentials(form, cred.ClientId, cred.ClientSecret)
+
+	var result handlers.ClientCredentialsResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Equal("no-store", resp.Header().Get(echo.HeaderCacheControl))
+	s.Assert().Equal("Bearer", result.TokenType)
+	s.Assert().Equal(int64(viper.GetDuration(config.JWTAccessTokenExpiry).Seconds()), result.ExpiresIn)
+
+	token, err := jwt.ParseEncoded([]byte(result.AccessToken))
+	s.Require().NoError(err)
+	s.Assert().Equal(sa.Id, token.Subject())
+	s.Assert().Equal(jwt.ServiceToken.String(), token.PrivateClaims()["type"])
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Token_200_Client_Body() {
+	sa, cred := s.newServiceAccount()
+
+	s.saSvc.EXPECT().
+		ReadByClientId(mock.Anything, cred.ClientId).
+		Return(sa, nil)
+
+	s.saSvc.EXPECT().
+		Use(mock.Anything, sa, cred.ClientId).
+		Return(nil)
+
+	form := url.Values{
+		"grant_type":    {handlers.GrantTypeClientCredentials},
+		"client_id":     {cred.ClientId},
+		"client_secret": {cred.ClientSecret},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", echo.MIMEApplicationForm)
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Token_400_Grant_Type() {
+	form := url.Values{"grant_type": {"password"}}
+	resp := s.clientCredentials(form, "sa_1", "secret")
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Contains(resp.Body.String(), handlers.OAuth2ErrorUnsupportedGrantType)
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Token_401_Secret() {
+	sa, cred := s.newServiceAccount()
+
+	s.saSvc.EXPECT().
+		ReadByClientId(mock.Anything, cred.ClientId).
+		Return(sa, nil)
+
+	form := url.Values{"grant_type": {handlers.GrantTypeClientCredentials}}
+	resp := s.clientCredentials(form, cred.ClientId, "wrong")
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Contains(resp.Body.String(), handlers.OAuth2ErrorInvalidClient)
+	s.Assert().NotEmpty(resp.Header().Get(echo.HeaderWWWAuthenticate))
+}
+
+func (s *OAuth2TokenHandlerTestSuite) TestOAuth2TokenHandler_Token_401_Deleted() {
+	s.saSvc.EXPECT().
+		ReadByClientId(mock.Anything, "sa_1").
+		Return(nil, services.NewError(nil, services.Deleted, services.ErrServiceAccountDeleted.Error()))
+
+	form := url.Values{"grant_type": {handlers.GrantTypeClientCredentials}}
+	resp := s.clientCredentials(form, "sa_1", "secret")
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Contains(resp.Body.String(), handlers.OAuth2ErrorInvalidClient)
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/mailer"
+)
+
+type PasswordResetService interface {
+	Create(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error)
+	CountSince(ctx context.Context, userId string, t time.Time) (int64, error)
+	Read(ctx context.Context, id string) (*models.PasswordReset, error)
+	Use(ctx context.Context, model *models.PasswordReset) error
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg *mailer.Message) error
+}
+
+var (
+	ErrPasswordResetThrottled    = errors.New("too many password resets requested, try again later")
+	ErrPasswordResetTokenInvalid = errors.New("invalid or expired token")
+)
+
+type PasswordResetHandler struct {
+	*openapi.Handler
+	svc             PasswordResetService
+	userSvc         UserService
+	sessionSvc      SessionService
+	loginAttemptSvc LoginAttemptService
+	mailer          Mailer
+}
+
+func NewPasswordResetHandler(
+	openapi *openapi.Handler,
+	svc PasswordResetService,
+	userSvc UserService,
+	sessionSvc SessionService,
+	loginAttemptSvc LoginAttemptService,
+	mailer Mailer,
+) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		Handler:         openapi,
+		svc:             svc,
+		userSvc:         userSvc,
+		sessionSvc:      sessionSvc,
+		loginAttemptSvc: loginAttemptSvc,
+		mailer:          mailer,
+	}
+}
+
+func (h *PasswordResetHandler) Register(s *server.Server) {
+	s.Add(http.MethodPost, "/auth/password/forgot", h.forgot)
+	s.Add(http.MethodPost, "/auth/password/reset", h.reset)
+	s.Add(http.MethodPut, "/me/password", h.change)
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// forgot always responds the same way, and takes as long, to avoid disclosing
+// which emails have an account or that too many resets were sent to one.
+func (h *PasswordResetHandler) forgot(c echo.Context) error {
+	body := &ForgotPasswordRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	// every request counts, whether the email has an account or not
+	ip, err := h.loginAttemptSvc.Read(ctx, models.LoginAttemptPasswordReset, c.RealIP())
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting login attempt")
+		return err
+	}
+
+	if ip.Locked() {
+		return h.throttled(c, time.Until(*ip.LockedUntil))
+	}
+
+	_, err = h.loginAttemptSvc.Fail(ctx, ip)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating login attempt")
+		return err
+	}
+
+	// the token is hashed before looking up the user so it takes as long
+	// for emails without an account, and the email is sent in the background
+	pr, err := models.NewPasswordReset("")
+	if err != nil {
+		log.Error().Err(err).Msg("failed generating password reset")
+		return err
+	}
+
+	token := pr.Token
+	err = pr.Encrypt()
+	if err != nil {
+		log.Error().Err(err).Msg("failed encrypting password reset")
+		return err
+	}
+
+	user, err := h.userSvc.FindOneByEmailOrUsername(ctx, body.Email, "")
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.Validate(c, http.StatusNoContent, nil)
+			}
+		}
+		log.Error().Err(err).Msg("failed finding user")
+		return err
+	}
+
+	if user.IsBanned {
+		return h.Validate(c, http.StatusNoContent, nil)
+	}
+
+	since := time.Now().Add(-viper.GetDuration(config.PasswordResetWindow))
+	count, err := h.svc.CountSince(ctx, user.Id, since)
+	if err != nil {
+		log.Error().Err(err).Msg("failed counting password resets")
+		return err
+	}
+
+	if count >= int64(viper.GetInt(config.PasswordResetMaxEmails)) {
+		log.Warn().Str("user_id", user.Id).Msg("too many password resets requested")
+		return h.Validate(c, http.StatusNoContent, nil)
+	}
+
+	pr.UserId = user.Id
+	_, err = h.svc.Create(ctx, pr)
+	if err != nil {
+		log.Error().Err(err).Msg("failed inserting password reset")
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"A password reset was requested for your account. "+
+				"Use the following token to choose a new password, it expires in %s:\n\n%s\n\n"+
+				"If you didn't request this, you can ignore this email.\n",
+			user.Username, viper.GetDuration(config.PasswordResetTokenExpiry), token,
+		),
+	}
+	go h.send(msg)
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+// send sends msg outside the request, which doesn't wait for it.
+func (h *PasswordResetHandler) send(msg *mailer.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	if err := h.mailer.Send(ctx, msg); err != nil {
+		log.Error().Err(err).Msg("failed sending password reset email")
+	}
+}
+
+func (h *PasswordResetHandler) throttled(c echo.Context, retryAfter time.Duration) error {
+	c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	return h.Validate(c, http.StatusTooManyRequests, echo.Map{"message": ErrPasswordResetThrottled.Error()})
+}
+
+type ResetPasswordRequest struct {
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+func (h *PasswordResetHandler) reset(c echo.Context) error {
+	body := &ResetPasswordRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	invalid := echo.Map{"message": ErrPasswordResetTokenInvalid.Error()}
+
+	id, err := models.ParsePasswordResetToken(body.Token)
+	if err != nil {
+		return h.Validate(c, http.StatusBadRequest, invalid)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	pr, err := h.svc.Read(ctx, id)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist {
+				return h.Validate(c, http.StatusBadRequest, invalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting password reset")
+		return err
+	}
+
+	if err = pr.Validate(body.Token); err != nil {
+		return h.Validate(c, http.StatusBadRequest, invalid)
+	}
+
+	user, err := h.userSvc.Read(ctx, pr.UserId)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.Validate(c, http.StatusBadRequest, invalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	// the token is kept when the password doesn't meet the policy
+	err = user.SetPassword(body.Password)
+	if err != nil {
+		if m, ok := passwordPolicyErrors(err); ok {
+			return h.Validate(c, http.StatusUnprocessableEntity, m)
+		}
+		log.Error().Err(err).Msg("failed setting password")
+		return err
+	}
+
+	// consume the token before anything else so it can't be used twice
+	err = h.svc.Use(ctx, pr)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.Conflict {
+				return h.Validate(c, http.StatusBadRequest, invalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed updating password reset")
+		return err
+	}
+
+	_, err = h.userSvc.Update(ctx, user.Id, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating user")
+		return err
+	}
+
+	err = h.sessionSvc.RevokeAll(ctx, user.Id, user.Id, "", models.SessionRevokedPasswordReset)
+	if err != nil {
+		log.Error().Err(err).Msg("failed revoking sessions")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// change replaces the password of the current user and revokes
+// their other sessions, the current one stays logged in.
+func (h *PasswordResetHandler) change(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	body := &ChangePasswordRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.Read(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	// the current password is throttled like a login, otherwise
+	// a stolen session could be used to guess it without limit
+	account, err := h.loginAttemptSvc.Read(ctx, models.LoginAttemptAccount, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting login attempt")
+		return err
+	}
+
+	if d := account.RetryAfter(); d > 0 {
+		return loginThrottled(c, h.Handler, d)
+	}
+
+	err = user.ChangePassword(body.CurrentPassword, body.NewPassword)
+	if err != nil {
+		if m, ok := passwordPolicyErrors(err); ok {
+			return h.Validate(c, http.StatusUnprocessableEntity, m)
+		}
+		if reauthFailed(err) {
+			if _, err := h.loginAttemptSvc.Fail(ctx, account); err != nil {
+				log.Error().Err(err).Msg("failed updating login attempt")
+				return err
+			}
+		}
+		var me *models.Error
+		if errors.As(err, &me) {
+			return h.Validate(c, http.StatusBadRequest, echo.Map{"message": me.Message})
+		}
+		log.Error().Err(err).Msg("failed setting password")
+		return err
+	}
+
+	if account.Failures > 0 {
+		err = h.loginAttemptSvc.Reset(ctx, account)
+		if err != nil {
+			log.Error().Err(err).Msg("failed updating login attempt")
+			return err
+		}
+	}
+
+	_, err = h.userSvc.Update(ctx, user.Id, user)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating user")
+		return err
+	}
+
+	err = h.sessionSvc.RevokeAll(ctx, user.Id, user.Id, currentSessionId(c), models.SessionRevokedPasswordChange)
+	if err != nil {
+		log.Error().Err(err).Msg("failed revoking sessions")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+// passwordPolicyErrors returns the validation errors of err
+// when it's a password not meeting the password policy.
+func passwordPolicyErrors(err error) (echo.Map, bool) {
+	var me *models.Error
+	if errors.As(err, &me) && len(me.Errors) > 0 {
+		return echo.Map{"message": "validation error", "errors": me.Errors}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,593 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/mailer"
+)
+
+type PasswordResetHandlerTestSuite struct {
+	suite.Suite
+	svc             *handlers.MockPasswordResetService
+	userSvc         *handlers.MockUserService
+	sessionSvc      *handlers.MockSessionService
+	loginAttemptSvc *handlers.MockLoginAttemptService
+	mailer          *handlers.MockMailer
+	server          *api.Server
+}
+
+func (s *PasswordResetHandlerTestSuite) SetupTest() {
+	svc := handlers.NewMockPasswordResetService(s.T())
+	userSvc := handlers.NewMockUserService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	sessionSvc := handlers.NewMockSessionService(s.T())
+	loginAttemptSvc := handlers.NewMockLoginAttemptService(s.T())
+	m := handlers.NewMockMailer(s.T())
+	h := handlers.NewPasswordResetHandler(openapi.NewHandler(), svc, userSvc, sessionSvc, loginAttemptSvc, m)
+	s.svc = svc
+	s.userSvc = userSvc
+	s.sessionSvc = sessionSvc
+	s.loginAttemptSvc = loginAttemptSvc
+	s.mailer = m
+	s.server = getServer(userSvc, patSvc, h)
+}
+
+func TestPasswordResetHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(PasswordResetHandlerTestSuite))
+}
+
+func (s *PasswordResetHandlerTestSuite) expectRequests() {
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptPasswordReset, mock.Anything).
+		RunAndReturn(func(ctx context.Context, kind string, value string) (*models.LoginAttempt, error) {
+			return models.NewLoginAttempt(kind, value), nil
+		})
+
+	s.loginAttemptSvc.EXPECT().
+		Fail(mock.Anything, mock.Anything).
+		Return(nil, nil)
+}
+
+// waitSent waits for the email sent after responding.
+func (s *PasswordResetHandlerTestSuite) waitSent(sent chan *mailer.Message) *mailer.Message {
+	select {
+	case msg := <-sent:
+		return msg
+	case <-time.After(5 * time.Second):
+		s.Fail("email not sent")
+		return nil
+	}
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Forgot_204() {
+	user := getUser()
+	b, _ := json.Marshal(&handlers.ForgotPasswordRequest{Email: user.Email})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.expectRequests()
+
+	var created *models.PasswordReset
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, user.Email, "").
+		Return(user, nil)
+
+	s.svc.EXPECT().
+		CountSince(mock.Anything, user.Id, mock.Anything).
+		Return(0, nil)
+
+	s.svc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.PasswordReset) { created = model }).
+		Return(nil, nil)
+
+	ch := make(chan *mailer.Message, 1)
+	s.mailer.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, msg *mailer.Message) { ch <- msg }).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	sent := s.waitSent(ch)
+	if s.Assert().NotNil(created) && s.Assert().NotNil(sent) {
+		s.Assert().Equal(user.Id, created.UserId)
+		s.Assert().Equal(user.Email, sent.To)
+		s.Assert().Contains(sent.Body, created.Id+".")
+		s.Assert().False(strings.Contains(sent.Body, created.Token), "hash must not be sent")
+	}
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Forgot_204_Mailer_Err() {
+	user := getUser()
+	b, _ := json.Marshal(&handlers.ForgotPasswordRequest{Email: user.Email})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.expectRequests()
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.svc.EXPECT().
+		CountSince(mock.Anything, user.Id, mock.Anything).
+		Return(0, nil)
+
+	s.svc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	ch := make(chan *mailer.Message, 1)
+	s.mailer.EXPECT().
+		Send(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, msg *mailer.Message) { ch <- msg }).
+		Return(errors.New("error"))
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.waitSent(ch)
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Forgot_204_Too_Many() {
+	user := getUser()
+	b, _ := json.Marshal(&handlers.ForgotPasswordRequest{Email: user.Email})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.expectRequests()
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(user, nil)
+
+	s.svc.EXPECT().
+		CountSince(mock.Anything, user.Id, mock.Anything).
+		Return(int64(viper.GetInt(config.PasswordResetMaxEmails)), nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Forgot_429() {
+	b, _ := json.Marshal(&handlers.ForgotPasswordRequest{Email: "test@example.com"})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptPasswordReset, "192.0.2.1").
+		Return(&models.LoginAttempt{LockedUntil: &lockedUntil}, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusTooManyRequests, resp.Code)
+	s.Assert().Equal(handlers.ErrPasswordResetThrottled.Error(), result.Message)
+	s.Assert().Equal("600", resp.Header().Get("Retry-After"))
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Forgot_204_Not_Found() {
+	b, _ := json.Marshal(&handlers.ForgotPasswordRequest{Email: "nobody@example.com"})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.expectRequests()
+
+	s.userSvc.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, &services.Error{
+			Kind:    services.NotExist,
+			Message: services.ErrUserNotFound.Error(),
+		})
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().Empty(resp.Body.Bytes())
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Forgot_422() {
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", bytes.NewBuffer([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Reset_204() {
+	user := getUser()
+	pr, _ := models.NewPasswordReset(user.Id)
+	token := pr.Token
+	_ = pr.Encrypt()
+
+	b, _ := json.Marshal(&handlers.ResetPasswordRequest{Token: token, Password: "correct-horse-staple-battery"})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, pr.Id).
+		Return(pr, nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.svc.EXPECT().
+		Use(mock.Anything, pr).
+		Run(func(ctx context.Context, model *models.PasswordReset) { model.Use() }).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, user.Id, user).
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		RevokeAll(mock.Anything, user.Id, user.Id, "", models.SessionRevokedPasswordReset).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().NotNil(pr.UsedAt)
+	s.Assert().NoError(user.ValidatePassword("correct-horse-staple-battery"))
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Reset_400() {
+	user := getUser()
+
+	used, _ := models.NewPasswordReset(user.Id)
+	usedToken := used.Token
+	_ = used.Encrypt()
+	used.Use()
+
+	expired, _ := models.NewPasswordReset(user.Id)
+	expiredToken := expired.Token
+	_ = expired.Encrypt()
+	past := time.Now().Add(-time.Minute)
+	expired.ExpiresAt = &past
+
+	mismatch, _ := models.NewPasswordReset(user.Id)
+	_ = mismatch.Encrypt()
+
+	testCases := []struct {
+		name  string
+		token string
+		pr    *models.PasswordReset
+		err   error
+	}{
+		{"malformed", "invalid", nil, nil},
+		{"not found", "abc.def", nil, &services.Error{Kind: services.NotExist}},
+		{"used", usedToken, used, nil},
+		{"expired", expiredToken, expired, nil},
+		{"mismatch", mismatch.Id + ".wrong", mismatch, nil},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			b, _ := json.Marshal(&handlers.ResetPasswordRequest{Token: tc.token, Password: "correct-horse-staple-battery"})
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewBuffer(b))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			if tc.pr != nil || tc.err != nil {
+				s.svc.EXPECT().
+					Read(mock.Anything, mock.Anything).
+					Return(tc.pr, tc.err).Once()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			var result echo.HTTPError
+			_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+			s.Assert().Equal(http.StatusBadRequest, resp.Code)
+			s.Assert().Equal(handlers.ErrPasswordResetTokenInvalid.Error(), result.Message)
+		})
+	}
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Reset_400_Used_Concurrently() {
+	user := getUser()
+	pr, _ := models.NewPasswordReset(user.Id)
+	token := pr.Token
+	_ = pr.Encrypt()
+
+	b, _ := json.Marshal(&handlers.ResetPasswordRequest{Token: token, Password: "correct-horse-staple-battery"})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, pr.Id).
+		Return(pr, nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	// another request used it after it was read
+	s.svc.EXPECT().
+		Use(mock.Anything, pr).
+		Return(&services.Error{Kind: services.Conflict})
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Equal(handlers.ErrPasswordResetTokenInvalid.Error(), result.Message)
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Reset_422() {
+	b, _ := json.Marshal(&handlers.ResetPasswordRequest{Token: "abc.def", Password: "short"})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Change_204() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	session := models.NewSession(user.Id)
+	access, _, _ := user.Login(session)
+
+	b, _ := json.Marshal(&handlers.ChangePasswordRequest{
+		CurrentPassword: "current-password",
+		NewPassword:     "correct-horse-staple-battery",
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(models.NewLoginAttempt(models.LoginAttemptAccount, user.Id), nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, user.Id, user).
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		RevokeAll(mock.Anything, user.Id, user.Id, session.Id, models.SessionRevokedPasswordChange).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().NoError(user.ValidatePassword("correct-horse-staple-battery"))
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Change_400() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	b, _ := json.Marshal(&handlers.ChangePasswordRequest{
+		CurrentPassword: "wrong-password",
+		NewPassword:     "correct-horse-staple-battery",
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(models.NewLoginAttempt(models.LoginAttemptAccount, user.Id), nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Fail(mock.Anything, mock.Anything).
+		Return(nil, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Equal(models.ErrPasswordInvalid.Error(), result.Message)
+	s.Assert().NoError(user.ValidatePassword("current-password"))
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Reset_422_Password_Policy() {
+	user := getUser()
+	pr, _ := models.NewPasswordReset(user.Id)
+	token := pr.Token
+	_ = pr.Encrypt()
+
+	b, _ := json.Marshal(&handlers.ResetPasswordRequest{Token: token, Password: user.Username + "-password"})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, pr.Id).
+		Return(pr, nil)
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+	s.Assert().Contains(resp.Body.String(), "password can't contain the username or email")
+	// the token can be used again with another password
+	s.Assert().Nil(pr.UsedAt)
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Change_422_Password_Policy() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	b, _ := json.Marshal(&handlers.ChangePasswordRequest{
+		CurrentPassword: "current-password",
+		NewPassword:     user.Username + "-password",
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(models.NewLoginAttempt(models.LoginAttemptAccount, user.Id), nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+	s.Assert().Contains(resp.Body.String(), "password can't contain the username or email")
+	s.Assert().NoError(user.ValidatePassword("current-password"))
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Change_204_Reset_Failures() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	session := models.NewSession(user.Id)
+	access, _, _ := user.Login(session)
+
+	b, _ := json.Marshal(&handlers.ChangePasswordRequest{
+		CurrentPassword: "current-password",
+		NewPassword:     "correct-horse-staple-battery",
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	lastFailureAt := time.Now().Add(-time.Minute)
+	account := models.NewLoginAttempt(models.LoginAttemptAccount, user.Id)
+	account.Failures = 1
+	account.LastFailureAt = &lastFailureAt
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(account, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Reset(mock.Anything, account).
+		Return(nil)
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, user.Id, user).
+		Return(user, nil)
+
+	s.sessionSvc.EXPECT().
+		RevokeAll(mock.Anything, user.Id, user.Id, session.Id, models.SessionRevokedPasswordChange).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+}
+
+func (s *PasswordResetHandlerTestSuite) TestPasswordResetHandler_Change_429() {
+	user := getUser()
+	_ = user.SetPassword("current-password")
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	b, _ := json.Marshal(&handlers.ChangePasswordRequest{
+		CurrentPassword: "current-password",
+		NewPassword:     "correct-horse-staple-battery",
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+string(access))
+	resp := httptest.NewRecorder()
+
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	lastFailureAt := time.Now()
+	locked := models.NewLoginAttempt(models.LoginAttemptAccount, user.Id)
+	locked.Failures = 5
+	locked.LastFailureAt = &lastFailureAt
+	locked.LockedUntil = &lockedUntil
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, user.Id).
+		Return(user, nil)
+
+	s.loginAttemptSvc.EXPECT().
+		Read(mock.Anything, models.LoginAttemptAccount, user.Id).
+		Return(locked, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusTooManyRequests, resp.Code)
+	s.Assert().Equal(handlers.ErrLoginThrottled.Error(), result.Message)
+	s.Assert().Equal("600", resp.Header().Get("Retry-After"))
+	s.Assert().NoError(user.ValidatePassword("current-password"))
+}
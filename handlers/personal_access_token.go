@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/alexferl/echo-openapi"
@@ -13,19 +15,25 @@ import (
 
 	"github.com/alexferl/echo-boilerplate/models"
 	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/pagination"
 )
 
 type PersonalAccessTokenService interface {
 	Create(ctx context.Context, model *models.PersonalAccessToken) (*models.PersonalAccessToken, error)
 	Read(ctx context.Context, userId string, id string) (*models.PersonalAccessToken, error)
-	Find(ctx context.Context, userId string) (models.PersonalAccessTokens, error)
+	Find(ctx context.Context, params *models.PersonalAccessTokenSearchParams) (int64, models.PersonalAccessTokens, error)
 	FindOne(ctx context.Context, userId string, name string) (*models.PersonalAccessToken, error)
-	Revoke(ctx context.Context, model *models.PersonalAccessToken) error
+	FindUnscoped(ctx context.Context, userId string) (models.PersonalAccessTokens, error)
+	Rehash(ctx context.Context, model *models.PersonalAccessToken) error
+	Revoke(ctx context.Context, id string, model *models.PersonalAccessToken) error
+	RevokeAll(ctx context.Context, id string, userId string) error
+	Use(ctx context.Context, model *models.PersonalAccessToken) error
 }
 
 type PersonalAccessTokenHandler struct {
 	*openapi.Handler
-	svc PersonalAccessTokenService
+	svc     PersonalAccessTokenService
+	userSvc UserService
 }
 
 func (h *PersonalAccessTokenHandler) Register(s *server.Server) {
@@ -33,18 +41,29 @@ func (h *PersonalAccessTokenHandler) Register(s *server.Server) {
 	s.Add(http.MethodGet, "/me/personal_access_tokens", h.list)
 	s.Add(http.MethodGet, "/me/personal_access_tokens/:id", h.get)
 	s.Add(http.MethodDelete, "/me/personal_access_tokens/:id", h.revoke)
+	s.Add(http.MethodGet, "/users/:username/personal_access_tokens", h.listUser)
+	s.Add(http.MethodDelete, "/users/:username/personal_access_tokens", h.revokeUserAll)
+	s.Add(http.MethodGet, "/users/:username/personal_access_tokens/:id", h.getUser)
+	s.Add(http.MethodDelete, "/users/:username/personal_access_tokens/:id", h.revokeUser)
+	s.Add(http.MethodGet, "/personal_access_tokens", h.search)
 }
 
-func NewPersonalAccessTokenHandler(openapi *openapi.Handler, svc PersonalAccessTokenService) *PersonalAccessTokenHandler {
+func NewPersonalAccessTokenHandler(
+	openapi *openapi.Handler,
+	svc PersonalAccessTokenService,
+	userSvc UserService,
+) *PersonalAccessTokenHandler {
 	return &PersonalAccessTokenHandler{
 		Handler: openapi,
 		svc:     svc,
+		userSvc: userSvc,
 	}
 }
 
 type CreatePersonalAccessTokenRequest struct {
-	Name      string `json:"name"`
-	ExpiresAt string `json:"expires_at"`
+	Name      string   `json:"name"`
+	ExpiresAt string   `json:"expires_at"`
+	Scopes    []string `json:"scopes"`
 }
 
 func (h *PersonalAccessTokenHandler) create(c echo.Context) error {
@@ -72,12 +91,14 @@ func (h *PersonalAccessTokenHandler) create(c echo.Context) error {
 		return h.Validate(c, http.StatusConflict, echo.Map{"message": "token name already in-use"})
 	}
 
-	newPAT, err := models.NewPersonalAccessToken(currentUser.Id, body.Name, body.ExpiresAt)
+	newPAT, err := models.NewPersonalAccessToken(currentUser.Id, body.Name, body.ExpiresAt, body.Scopes)
 	if err != nil {
-		if errors.Is(err, models.ErrExpiresAtPast) {
+		if errors.Is(err, models.ErrExpiresAtPast) ||
+			errors.Is(err, models.ErrScopeInvalid) ||
+			errors.Is(err, models.ErrScopesMissing) {
 			m := echo.Map{
 				"message": "validation error",
-				"errors":  []string{models.ErrExpiresAtPast.Error()},
+				"errors":  []string{err.Error()},
 			}
 			return h.Validate(c, http.StatusUnprocessableEntity, m)
 		}
@@ -108,9 +129,14 @@ func (h *PersonalAccessTokenHandler) list(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
-	pats, err := h.svc.Find(ctx, currentUser.Id)
+	params := &models.PersonalAccessTokenSearchParams{
+		UserId: currentUser.Id,
+		Sort:   c.QueryParam("sort"),
+	}
+
+	_, pats, err := h.svc.Find(ctx, params)
 	if err != nil {
-		log.Error().Err(err).Msg("failed getting personal access token")
+		log.Error().Err(err).Msg("failed getting personal access tokens")
 		return err
 	}
 
@@ -124,45 +150,198 @@ func (h *PersonalAccessTokenHandler) get(c echo.Context) error {
 	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
-	pat, err := h.svc.Read(ctx, currentUser.Id, id)
+	return h.getToken(ctx, c, currentUser.Id, id)
+}
+
+func (h *PersonalAccessTokenHandler) revoke(c echo.Context) error {
+	id := c.Param("id")
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	return h.revokeToken(ctx, c, currentUser.Id, id)
+}
+
+func (h *PersonalAccessTokenHandler) listUser(c echo.Context) error {
+	id := c.Param("username")
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.Read(ctx, id)
 	if err != nil {
-		var se *services.Error
-		if errors.As(err, &se) {
-			if se.Kind == services.NotExist {
-				return h.Validate(c, http.StatusNotFound, echo.Map{"message": se.Message})
-			}
-		}
-		log.Error().Err(err).Msg("failed getting personal access token")
+		return h.readUser(c, err)
+	}
+
+	err = user.ReadPersonalAccessTokens(currentUser)
+	if err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	params := &models.PersonalAccessTokenSearchParams{
+		UserId: user.Id,
+		Sort:   c.QueryParam("sort"),
+	}
+
+	_, pats, err := h.svc.Find(ctx, params)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting personal access tokens")
 		return err
 	}
 
-	return h.Validate(c, http.StatusOK, pat.Response())
+	return h.Validate(c, http.StatusOK, pats.Response())
 }
 
-func (h *PersonalAccessTokenHandler) revoke(c echo.Context) error {
-	id := c.Param("id")
+func (h *PersonalAccessTokenHandler) revokeUserAll(c echo.Context) error {
+	id := c.Param("username")
 	currentUser := c.Get("user").(*models.User)
 
 	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
-	pat, err := h.svc.Read(ctx, currentUser.Id, id)
+	user, err := h.userSvc.Read(ctx, id)
 	if err != nil {
-		var se *services.Error
-		if errors.As(err, &se) {
-			if se.Kind == services.NotExist {
-				return h.Validate(c, http.StatusNotFound, echo.Map{"message": se.Message})
-			}
+		return h.readUser(c, err)
+	}
+
+	err = user.RevokePersonalAccessTokens(currentUser)
+	if err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	err = h.svc.RevokeAll(ctx, currentUser.Id, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed revoking personal access tokens")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+func (h *PersonalAccessTokenHandler) getUser(c echo.Context) error {
+	id := c.Param("username")
+	patId := c.Param("id")
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.Read(ctx, id)
+	if err != nil {
+		return h.readUser(c, err)
+	}
+
+	err = user.ReadPersonalAccessTokens(currentUser)
+	if err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	return h.getToken(ctx, c, user.Id, patId)
+}
+
+func (h *PersonalAccessTokenHandler) revokeUser(c echo.Context) error {
+	id := c.Param("username")
+	patId := c.Param("id")
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.Read(ctx, id)
+	if err != nil {
+		return h.readUser(c, err)
+	}
+
+	err = user.RevokePersonalAccessTokens(currentUser)
+	if err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	return h.revokeToken(ctx, c, user.Id, patId)
+}
+
+func (h *PersonalAccessTokenHandler) search(c echo.Context) error {
+	page, perPage, limit, skip := pagination.ParseParams(c)
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	params := &models.PersonalAccessTokenSearchParams{
+		Sort:  c.QueryParam("sort"),
+		Limit: limit,
+		Skip:  skip,
+	}
+
+	if owner := c.QueryParam("owner"); owner != "" {
+		user, err := h.userSvc.Read(ctx, owner)
+		if err != nil {
+			return h.readUser(c, err)
+		}
+
+		err = user.ReadPersonalAccessTokens(currentUser)
+		if err != nil {
+			return h.checkModelErr(c, err)
+		}
+		params.UserId = user.Id
+	} else if roles := currentUser.NotOutrankedRoles(); len(roles) > 0 {
+		// only the tokens of the users the current user outranks, and its own, are listed
+		ids, err := h.userSvc.FindIdsByRoles(ctx, roles)
+		if err != nil {
+			log.Error().Err(err).Msg("failed getting users")
+			return err
 		}
-		log.Error().Err(err).Msg("failed getting personal access token")
+		params.ExcludeUserIds = slices.DeleteFunc(ids, func(id string) bool { return id == currentUser.Id })
+	}
+
+	// the formats are already validated against the OpenAPI spec
+	if v := c.QueryParam("expires_after"); v != "" {
+		t, _ := time.Parse(time.RFC3339, v)
+		params.ExpiresAfter = &t
+	}
+	if v := c.QueryParam("expires_before"); v != "" {
+		t, _ := time.Parse(time.RFC3339, v)
+		params.ExpiresBefore = &t
+	}
+	if v := c.QueryParam("is_revoked"); v != "" {
+		revoked, _ := strconv.ParseBool(v)
+		params.IsRevoked = &revoked
+	}
+
+	count, pats, err := h.svc.Find(ctx, params)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting personal access tokens")
 		return err
 	}
 
+	pagination.SetHeaders(c.Request(), c.Response().Header(), int(count), page, perPage)
+
+	return h.Validate(c, http.StatusOK, pats.Response())
+}
+
+func (h *PersonalAccessTokenHandler) getToken(ctx context.Context, c echo.Context, userId string, id string) error {
+	pat, err := h.svc.Read(ctx, userId, id)
+	if err != nil {
+		return h.readToken(c, err)
+	}
+
+	return h.Validate(c, http.StatusOK, pat.Response())
+}
+
+func (h *PersonalAccessTokenHandler) revokeToken(ctx context.Context, c echo.Context, userId string, id string) error {
+	pat, err := h.svc.Read(ctx, userId, id)
+	if err != nil {
+		return h.readToken(c, err)
+	}
+
 	if pat.IsRevoked == true {
 		return h.Validate(c, http.StatusConflict, echo.Map{"message": "personal access token already revoked"})
 	}
 
-	err = h.svc.Revoke(ctx, pat)
+	currentUser := c.Get("user").(*models.User)
+	err = h.svc.Revoke(ctx, currentUser.Id, pat)
 	if err != nil {
 		log.Error().Err(err).Msg("failed deleting personal access token")
 		return err
@@ -170,3 +349,39 @@ func (h *PersonalAccessTokenHandler) revoke(c echo.Context) error {
 
 	return h.Validate(c, http.StatusNoContent, nil)
 }
+
+func (h *PersonalAccessTokenHandler) readToken(c echo.Context, err error) error {
+	var se *services.Error
+	if errors.As(err, &se) {
+		if se.Kind == services.NotExist {
+			return h.Validate(c, http.StatusNotFound, echo.Map{"message": se.Message})
+		}
+	}
+	log.Error().Err(err).Msg("failed getting personal access token")
+	return err
+}
+
+func (h *PersonalAccessTokenHandler) readUser(c echo.Context, err error) error {
+	var se *services.Error
+	if errors.As(err, &se) {
+		msg := echo.Map{"message": se.Message}
+		if se.Kind == services.NotExist {
+			return h.Validate(c, http.StatusNotFound, msg)
+		} else if se.Kind == services.Deleted {
+			return h.Validate(c, http.StatusGone, msg)
+		}
+	}
+	log.Error().Err(err).Msg("failed getting user")
+	return err
+}
+
+func (h *PersonalAccessTokenHandler) checkModelErr(c echo.Context, err error) error {
+	var me *models.Error
+	if errors.As(err, &me) {
+		if me.Kind == models.Permission {
+			return h.Validate(c, http.StatusForbidden, echo.Map{"message": me.Message})
+		}
+	}
+	log.Error().Err(err).Msg("failed revoking personal access tokens")
+	return err
+}
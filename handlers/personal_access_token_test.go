@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
 	"testing"
 	"time"
 
@@ -29,20 +31,27 @@ type PersonalAccessTokenHandlerTestSuite struct {
 	accessToken      []byte
 	admin            *models.User
 	adminAccessToken []byte
+	super            *models.User
 }
 
 func (s *PersonalAccessTokenHandlerTestSuite) SetupTest() {
 	userSvc := handlers.NewMockUserService(s.T())
 	svc := handlers.NewMockPersonalAccessTokenService(s.T())
-	h := handlers.NewPersonalAccessTokenHandler(openapi.NewHandler(), svc)
+	h := handlers.NewPersonalAccessTokenHandler(openapi.NewHandler(), svc, userSvc)
 	user := getUser()
-	access, _, _ := user.Login()
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	admin := getAdmin()
+	adminAccess, _, _ := admin.Login(models.NewSession(admin.Id))
 
 	s.svc = svc
 	s.userSvc = userSvc
 	s.server = getServer(userSvc, svc, h)
 	s.user = user
 	s.accessToken = access
+	s.admin = admin
+	s.adminAccessToken = adminAccess
+	s.super = getSuper()
 }
 
 func TestPersonalAccessTokenHandlerTestSuite(t *testing.T) {
@@ -57,6 +66,7 @@ func createTokens(userId string, num int) models.PersonalAccessTokens {
 			userId,
 			fmt.Sprintf("my_token%d", i),
 			time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+			[]string{models.ScopeTasksRead},
 		)
 		result = append(result, *pat)
 	}
@@ -68,10 +78,11 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Cre
 	payload := &handlers.CreatePersonalAccessTokenRequest{
 		Name:      "My Token",
 		ExpiresAt: time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02"),
+		Scopes:    []string{models.ScopeTasksRead},
 	}
 	b, _ := json.Marshal(payload)
 
-	newPAT, _ := models.NewPersonalAccessToken(s.user.Id, payload.Name, payload.ExpiresAt)
+	newPAT, _ := models.NewPersonalAccessToken(s.user.Id, payload.Name, payload.ExpiresAt, payload.Scopes)
 
 	req := httptest.NewRequest(http.MethodPost, "/me/personal_access_tokens", bytes.NewBuffer(b))
 	req.Header.Set("Content-Type", "application/json")
@@ -113,10 +124,11 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Cre
 	payload := &handlers.CreatePersonalAccessTokenRequest{
 		Name:      "My Token",
 		ExpiresAt: time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02"),
+		Scopes:    []string{models.ScopeTasksRead},
 	}
 	b, _ := json.Marshal(payload)
 
-	newPAT, _ := models.NewPersonalAccessToken(s.user.Id, payload.Name, payload.ExpiresAt)
+	newPAT, _ := models.NewPersonalAccessToken(s.user.Id, payload.Name, payload.ExpiresAt, payload.Scopes)
 
 	req := httptest.NewRequest(http.MethodPost, "/me/personal_access_tokens", bytes.NewBuffer(b))
 	req.Header.Set("Content-Type", "application/json")
@@ -163,10 +175,11 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Cre
 	payload := &handlers.CreatePersonalAccessTokenRequest{
 		Name:      "My Token",
 		ExpiresAt: time.Now().Add(-(7 * 24) * time.Hour).Format("2006-01-02"),
+		Scopes:    []string{models.ScopeTasksRead},
 	}
 	b, _ := json.Marshal(payload)
 
-	newPAT, _ := models.NewPersonalAccessToken(s.user.Id, payload.Name, payload.ExpiresAt)
+	newPAT, _ := models.NewPersonalAccessToken(s.user.Id, payload.Name, payload.ExpiresAt, payload.Scopes)
 
 	req := httptest.NewRequest(http.MethodPost, "/me/personal_access_tokens", bytes.NewBuffer(b))
 	req.Header.Set("Content-Type", "application/json")
@@ -203,7 +216,7 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Lis
 
 	s.svc.EXPECT().
 		Find(mock.Anything, mock.Anything).
-		Return(pats, nil)
+		Return(int64(len(pats)), pats, nil)
 
 	s.server.ServeHTTP(resp, req)
 
@@ -214,6 +227,52 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Lis
 	s.Assert().Equal(num, len(result.Tokens))
 }
 
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_List_200_Sort() {
+	pats := createTokens(s.user.Id, 2)
+	pats[0].Use("192.0.2.1", "curl/8.0")
+
+	req := httptest.NewRequest(http.MethodGet, "/me/personal_access_tokens?sort=-last_used_at", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	params := &models.PersonalAccessTokenSearchParams{UserId: s.user.Id, Sort: "-last_used_at"}
+	s.svc.EXPECT().
+		Find(mock.Anything, params).
+		Return(int64(len(pats)), pats, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.PersonalAccessTokensResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Equal("192.0.2.1", result.Tokens[0].LastUsedIP)
+	s.Assert().NotNil(result.Tokens[0].LastUsedAt)
+	s.Assert().Nil(result.Tokens[1].LastUsedAt)
+}
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_List_422_Sort() {
+	req := httptest.NewRequest(http.MethodGet, "/me/personal_access_tokens?sort=token", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+}
+
 func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_List_401() {
 	req := httptest.NewRequest(http.MethodGet, "/me/personal_access_tokens", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -229,6 +288,7 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Get
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
 	)
 
 	req := httptest.NewRequest(http.MethodGet, "/me/personal_access_tokens/id", nil)
@@ -285,6 +345,7 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Rev
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
 	)
 
 	req := httptest.NewRequest(http.MethodDelete, "/me/personal_access_tokens/id", nil)
@@ -302,7 +363,7 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Rev
 		Return(newPAT, nil)
 
 	s.svc.EXPECT().
-		Revoke(mock.Anything, mock.Anything).
+		Revoke(mock.Anything, s.user.Id, mock.Anything).
 		Return(nil)
 
 	s.server.ServeHTTP(resp, req)
@@ -352,6 +413,7 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Rev
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
 	)
 	newPAT.IsRevoked = true
 
@@ -373,3 +435,272 @@ func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Rev
 
 	s.Assert().Equal(http.StatusConflict, resp.Code)
 }
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_ListUser_200() {
+	pats := createTokens(s.user.Id, 2)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/test/personal_access_tokens", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil).Once()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, "test").
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		Find(mock.Anything, &models.PersonalAccessTokenSearchParams{UserId: s.user.Id}).
+		Return(int64(len(pats)), pats, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.PersonalAccessTokensResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Len(result.Tokens, 2)
+}
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_GetUser_200() {
+	pat := createTokens(s.user.Id, 1)[0]
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users/test/personal_access_tokens/%s", pat.Id), nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil).Once()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, "test").
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id, pat.Id).
+		Return(&pat, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.PersonalAccessTokenResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Equal(pat.Id, result.Id)
+}
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_User_204() {
+	pat := createTokens(s.user.Id, 1)[0]
+
+	testCases := []struct {
+		endpoint string
+	}{
+		{"/users/test/personal_access_tokens"},
+		{fmt.Sprintf("/users/test/personal_access_tokens/%s", pat.Id)},
+	}
+	for _, tc := range testCases {
+		s.T().Run(tc.endpoint, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, tc.endpoint, nil)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+			resp := httptest.NewRecorder()
+
+			// middleware
+			s.userSvc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(s.admin, nil).Once()
+
+			s.userSvc.EXPECT().
+				Read(mock.Anything, "test").
+				Return(s.user, nil).Once()
+
+			s.svc.EXPECT().
+				RevokeAll(mock.Anything, s.admin.Id, s.user.Id).
+				Return(nil).Maybe()
+
+			s.svc.EXPECT().
+				Read(mock.Anything, s.user.Id, pat.Id).
+				Return(&pat, nil).Maybe()
+
+			s.svc.EXPECT().
+				Revoke(mock.Anything, s.admin.Id, &pat).
+				Return(nil).Maybe()
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusNoContent, resp.Code)
+		})
+	}
+}
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_User_404() {
+	req := httptest.NewRequest(http.MethodGet, "/users/test/personal_access_tokens", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil).Once()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, "test").
+		Return(nil, &services.Error{
+			Kind:    services.NotExist,
+			Message: services.ErrUserNotFound.Error(),
+		}).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNotFound, resp.Code)
+}
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Search_200() {
+	pats := createTokens(s.user.Id, 3)
+
+	after := time.Now().Truncate(time.Second).UTC()
+	before := after.Add(30 * 24 * time.Hour)
+	revoked := false
+
+	q := url.Values{
+		"owner":          {"test"},
+		"expires_after":  {after.Format(time.RFC3339)},
+		"expires_before": {before.Format(time.RFC3339)},
+		"is_revoked":     {"false"},
+		"sort":           {"expires_at"},
+		"per_page":       {"2"},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/personal_access_tokens?"+q.Encode(), nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil).Once()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, "test").
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		Find(mock.Anything, mock.MatchedBy(func(p *models.PersonalAccessTokenSearchParams) bool {
+			return p.UserId == s.user.Id &&
+				p.ExpiresAfter.Equal(after) &&
+				p.ExpiresBefore.Equal(before) &&
+				*p.IsRevoked == revoked &&
+				p.Sort == "expires_at" &&
+				p.Limit == 2 &&
+				p.Skip == 0
+		})).
+		Return(int64(len(pats)), pats[:2], nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.PersonalAccessTokensResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Len(result.Tokens, 2)
+	s.Assert().Equal("3", resp.Header().Get("X-Total"))
+}
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Search_200_Outranked_Owners() {
+	pats := createTokens(s.user.Id, 2)
+
+	req := httptest.NewRequest(http.MethodGet, "/personal_access_tokens", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil).Once()
+
+	s.userSvc.EXPECT().
+		FindIdsByRoles(mock.Anything, []string{models.AdminRole.String(), models.SuperRole.String()}).
+		Return([]string{s.admin.Id, s.super.Id}, nil).Once()
+
+	s.svc.EXPECT().
+		Find(mock.Anything, mock.MatchedBy(func(p *models.PersonalAccessTokenSearchParams) bool {
+			return p.UserId == "" && slices.Equal(p.ExcludeUserIds, []string{s.super.Id})
+		})).
+		Return(int64(len(pats)), pats, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.PersonalAccessTokensResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Len(result.Tokens, 2)
+}
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Search_422() {
+	req := httptest.NewRequest(http.MethodGet, "/personal_access_tokens?expires_after=tomorrow", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+}
+
+func (s *PersonalAccessTokenHandlerTestSuite) TestPersonalAccessTokenHandler_Admin_403() {
+	testCases := []struct {
+		method   string
+		endpoint string
+		token    []byte
+		current  *models.User
+		target   *models.User
+	}{
+		{http.MethodGet, "/personal_access_tokens", s.accessToken, s.user, nil},
+		{http.MethodGet, "/users/1/personal_access_tokens", s.accessToken, s.user, nil},
+		{http.MethodDelete, "/users/1/personal_access_tokens", s.accessToken, s.user, nil},
+		{http.MethodGet, "/users/1/personal_access_tokens/1", s.accessToken, s.user, nil},
+		{http.MethodDelete, "/users/1/personal_access_tokens", s.adminAccessToken, s.admin, s.super},
+		{http.MethodDelete, "/users/1/personal_access_tokens/1", s.adminAccessToken, s.admin, s.super},
+		{http.MethodGet, "/users/1/personal_access_tokens", s.adminAccessToken, s.admin, s.super},
+		{http.MethodGet, "/users/1/personal_access_tokens/1", s.adminAccessToken, s.admin, s.super},
+		{http.MethodGet, "/personal_access_tokens?owner=1", s.adminAccessToken, s.admin, s.super},
+	}
+	for _, tc := range testCases {
+		s.T().Run(fmt.Sprintf("%s_%s", tc.method, tc.endpoint), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.endpoint, nil)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tc.token))
+			resp := httptest.NewRecorder()
+
+			// middleware
+			s.userSvc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(tc.current, nil).Once()
+
+			if tc.target != nil {
+				s.userSvc.EXPECT().
+					Read(mock.Anything, mock.Anything).
+					Return(tc.target, nil).Once()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusForbidden, resp.Code)
+		})
+	}
+}
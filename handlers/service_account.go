@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/pagination"
+)
+
+type ServiceAccountService interface {
+	Create(ctx context.Context, id string, model *models.ServiceAccount) (*models.ServiceAccount, error)
+	Read(ctx context.Context, id string) (*models.ServiceAccount, error)
+	ReadByClientId(ctx context.Context, clientId string) (*models.ServiceAccount, error)
+	Update(ctx context.Context, id string, model *models.ServiceAccount) (*models.ServiceAccount, error)
+	Delete(ctx context.Context, id string, model *models.ServiceAccount) error
+	Use(ctx context.Context, model *models.ServiceAccount, clientId string) error
+	Find(ctx context.Context, params *models.ServiceAccountSearchParams) (int64, models.ServiceAccounts, error)
+}
+
+// ServiceAccountHandler lets admins manage the service accounts
+// and the credentials they get access tokens with.
+type ServiceAccountHandler struct {
+	*openapi.Handler
+	svc ServiceAccountService
+}
+
+func NewServiceAccountHandler(openapi *openapi.Handler, svc ServiceAccountService) *ServiceAccountHandler {
+	return &ServiceAccountHandler{
+		Handler: openapi,
+		svc:     svc,
+	}
+}
+
+func (h *ServiceAccountHandler) Register(s *server.Server) {
+	s.Add(http.MethodPost, "/service_accounts", h.create)
+	s.Add(http.MethodGet, "/service_accounts", h.list)
+	s.Add(http.MethodGet, "/service_accounts/:id", h.get)
+	s.Add(http.MethodPatch, "/service_accounts/:id", h.update)
+	s.Add(http.MethodDelete, "/service_accounts/:id", h.delete)
+	s.Add(http.MethodPost, "/service_accounts/:id/credentials", h.createCredential)
+	s.Add(http.MethodDelete, "/service_accounts/:id/credentials/:client_id", h.deleteCredential)
+}
+
+type CreateServiceAccountRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Roles       []string `json:"roles"`
+}
+
+func (h *ServiceAccountHandler) create(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	body := &CreateServiceAccountRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	model := models.NewServiceAccount(body.Name, body.Description)
+	if err := model.SetRoles(currentUser, body.Roles); err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	sa, err := h.svc.Create(ctx, currentUser.Id, model)
+	if err != nil {
+		return h.checkExist(c, err, "creating")
+	}
+
+	return h.Validate(c, http.StatusOK, sa.Response())
+}
+
+func (h *ServiceAccountHandler) list(c echo.Context) error {
+	page, perPage, limit, skip := pagination.ParseParams(c)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	params := &models.ServiceAccountSearchParams{
+		Limit: limit,
+		Skip:  skip,
+	}
+
+	count, sas, err := h.svc.Find(ctx, params)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting service accounts")
+		return err
+	}
+
+	pagination.SetHeaders(c.Request(), c.Response().Header(), int(count), page, perPage)
+
+	return h.Validate(c, http.StatusOK, sas.Response())
+}
+
+func (h *ServiceAccountHandler) get(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	sa, err := h.svc.Read(ctx, c.Param("id"))
+	if err != nil {
+		return h.readServiceAccount(c, err)
+	}
+
+	return h.Validate(c, http.StatusOK, sa.Response())
+}
+
+type UpdateServiceAccountRequest struct {
+	Name        *string  `json:"name,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+}
+
+func (h *ServiceAccountHandler) update(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	body := &UpdateServiceAccountRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	sa, err := h.svc.Read(ctx, c.Param("id"))
+	if err != nil {
+		return h.readServiceAccount(c, err)
+	}
+
+	if err = sa.Manage(currentUser); err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	if body.Roles != nil {
+		if err = sa.SetRoles(currentUser, body.Roles); err != nil {
+			return h.checkModelErr(c, err)
+		}
+	}
+
+	if body.Name != nil {
+		sa.Name = *body.Name
+	}
+
+	if body.Description != nil {
+		sa.Description = *body.Description
+	}
+
+	res, err := h.svc.Update(ctx, currentUser.Id, sa)
+	if err != nil {
+		return h.checkExist(c, err, "updating")
+	}
+
+	return h.Validate(c, http.StatusOK, res.Response())
+}
+
+func (h *ServiceAccountHandler) delete(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	sa, err := h.svc.Read(ctx, c.Param("id"))
+	if err != nil {
+		return h.readServiceAccount(c, err)
+	}
+
+	if err = sa.Manage(currentUser); err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	if err = h.svc.Delete(ctx, currentUser.Id, sa); err != nil {
+		log.Error().Err(err).Msg("failed deleting service account")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+func (h *ServiceAccountHandler) createCredential(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	sa, err := h.svc.Read(ctx, c.Param("id"))
+	if err != nil {
+		return h.readServiceAccount(c, err)
+	}
+
+	if err = sa.Manage(currentUser); err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	cred, err := sa.AddCredential()
+	if err != nil {
+		log.Error().Err(err).Msg("failed generating service account credential")
+		return err
+	}
+
+	if _, err = h.svc.Update(ctx, currentUser.Id, sa); err != nil {
+		log.Error().Err(err).Msg("failed updating service account")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, cred)
+}
+
+func (h *ServiceAccountHandler) deleteCredential(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+	clientId := c.Param("client_id")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
+	defer cancel()
+
+	sa, err := h.svc.Read(ctx, c.Param("id"))
+	if err != nil {
+		return h.readServiceAccount(c, err)
+	}
+
+	if err = sa.Manage(currentUser); err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	if sa.Credential(clientId) == nil {
+		msg := echo.Map{"message": models.ErrServiceAccountCredentialNotFound.Error()}
+		return h.Validate(c, http.StatusNotFound, msg)
+	}
+
+	if err = sa.RemoveCredential(clientId); err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	if _, err = h.svc.Update(ctx, currentUser.Id, sa); err != nil {
+		log.Error().Err(err).Msg("failed updating service account")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+func (h *ServiceAccountHandler) readServiceAccount(c echo.Context, err error) error {
+	var se *services.Error
+	if errors.As(err, &se) {
+		msg := echo.Map{"message": se.Message}
+		if se.Kind == services.NotExist {
+			return h.Validate(c, http.StatusNotFound, msg)
+		} else if se.Kind == services.Deleted {
+			return h.Validate(c, http.StatusGone, msg)
+		}
+	}
+	log.Error().Err(err).Msg("failed getting service account")
+	return err
+}
+
+func (h *ServiceAccountHandler) checkExist(c echo.Context, err error, action string) error {
+	var se *services.Error
+	if errors.As(err, &se) {
+		if se.Kind == services.Exist {
+			return h.Validate(c, http.StatusConflict, echo.Map{"message": se.Message})
+		}
+	}
+	log.Error().Err(err).Msgf("failed %s service account", action)
+	return err
+}
+
+func (h *ServiceAccountHandler) checkModelErr(c echo.Context, err error) error {
+	var me *models.Error
+	if errors.As(err, &me) {
+		switch me.Kind {
+		case models.Invalid:
+			m := echo.Map{
+				"message": "validation error",
+				"errors":  []string{me.Message},
+			}
+			return h.Validate(c, http.StatusUnprocessableEntity, m)
+		case models.Conflict:
+			return h.Validate(c, http.StatusConflict, echo.Map{"message": me.Message})
+		case models.Permission:
+			return h.Validate(c, http.StatusForbidden, echo.Map{"message": me.Message})
+		}
+	}
+	log.Error().Err(err).Msg("failed managing service account")
+	return err
+}
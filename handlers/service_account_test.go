@@ -0,0 +1,278 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type ServiceAccountHandlerTestSuite struct {
+	suite.Suite
+	svc              *handlers.MockServiceAccountService
+	userSvc          *handlers.MockUserService
+	server           *api.Server
+	user             *models.User
+	accessToken      []byte
+	admin            *models.User
+	adminAccessToken []byte
+}
+
+func (s *ServiceAccountHandlerTestSuite) SetupTest() {
+	userSvc := handlers.NewMockUserService(s.T())
+	svc := handlers.NewMockServiceAccountService(s.T())
+	h := handlers.NewServiceAccountHandler(openapi.NewHandler(), svc)
+	user := getUser()
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	admin := getAdmin()
+	adminAccess, _, _ := admin.Login(models.NewSession(admin.Id))
+
+	s.svc = svc
+	s.userSvc = userSvc
+	s.server = getServer(userSvc, nil, h)
+	s.user = user
+	s.accessToken = access
+	s.admin = admin
+	s.adminAccessToken = adminAccess
+}
+
+func TestServiceAccountHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(ServiceAccountHandlerTestSuite))
+}
+
+func newServiceAccount(roles ...string) *models.ServiceAccount {
+	sa := models.NewServiceAccount("ci", "Deploys from CI")
+	sa.Roles = roles
+	sa.Create("2000")
+	return sa
+}
+
+func (s *ServiceAccountHandlerTestSuite) request(method string, path string, payload any) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		_ = json.NewEncoder(&body).Encode(payload)
+	}
+
+	req := httptest.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_Create_200() {
+	payload := &handlers.CreateServiceAccountRequest{
+		Name:  "ci",
+		Roles: []string{models.UserRole.String()},
+	}
+
+	s.svc.EXPECT().
+		Create(mock.Anything, s.admin.Id, mock.Anything).
+		RunAndReturn(func(_ context.Context, id string, sa *models.ServiceAccount) (*models.ServiceAccount, error) {
+			sa.Create(id)
+			return sa, nil
+		})
+
+	resp := s.request(http.MethodPost, "/service_accounts", payload)
+
+	var result models.ServiceAccountResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Equal(payload.Name, result.Name)
+	s.Assert().Equal(payload.Roles, result.Roles)
+	s.Assert().Empty(result.Credentials)
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_Create_403_Role() {
+	payload := &handlers.CreateServiceAccountRequest{
+		Name:  "ci",
+		Roles: []string{models.AdminRole.String()},
+	}
+
+	resp := s.request(http.MethodPost, "/service_accounts", payload)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Contains(resp.Body.String(), models.ErrRoleAddMorePrivileged.Error())
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_Create_409() {
+	payload := &handlers.CreateServiceAccountRequest{
+		Name:  "ci",
+		Roles: []string{models.UserRole.String()},
+	}
+
+	s.svc.EXPECT().
+		Create(mock.Anything, s.admin.Id, mock.Anything).
+		Return(nil, services.NewError(nil, services.Exist, services.ErrServiceAccountExist.Error()))
+
+	resp := s.request(http.MethodPost, "/service_accounts", payload)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_Create_403_User() {
+	req := httptest.NewRequest(http.MethodPost, "/service_accounts", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_List_200() {
+	sas := models.ServiceAccounts{*newServiceAccount("user"), *newServiceAccount("admin")}
+
+	s.svc.EXPECT().
+		Find(mock.Anything, &models.ServiceAccountSearchParams{Limit: 10}).
+		Return(int64(len(sas)), sas, nil)
+
+	resp := s.request(http.MethodGet, "/service_accounts", nil)
+
+	var result models.ServiceAccountsResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Len(result.ServiceAccounts, 2)
+	s.Assert().Equal("2", resp.Header().Get("X-Total"))
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_Get_404() {
+	s.svc.EXPECT().
+		Read(mock.Anything, "1").
+		Return(nil, services.NewError(nil, services.NotExist, services.ErrServiceAccountNotFound.Error()))
+
+	resp := s.request(http.MethodGet, "/service_accounts/1", nil)
+
+	s.Assert().Equal(http.StatusNotFound, resp.Code)
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_Update_200() {
+	sa := newServiceAccount("user")
+	name := "deploy"
+
+	s.svc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, s.admin.Id, sa).
+		Return(sa, nil)
+
+	resp := s.request(http.MethodPatch, fmt.Sprintf("/service_accounts/%s", sa.Id), &handlers.UpdateServiceAccountRequest{Name: &name})
+
+	var result models.ServiceAccountResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Equal(name, result.Name)
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_Update_403_More_Privileged() {
+	sa := newServiceAccount("admin")
+
+	s.svc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	resp := s.request(http.MethodPatch, fmt.Sprintf("/service_accounts/%s", sa.Id), &handlers.UpdateServiceAccountRequest{
+		Roles: []string{models.UserRole.String()},
+	})
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Contains(resp.Body.String(), models.ErrServiceAccountMorePrivileged.Error())
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_Delete_204() {
+	sa := newServiceAccount("user")
+
+	s.svc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	s.svc.EXPECT().
+		Delete(mock.Anything, s.admin.Id, sa).
+		Return(nil)
+
+	resp := s.request(http.MethodDelete, fmt.Sprintf("/service_accounts/%s", sa.Id), nil)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_CreateCredential_200() {
+	sa := newServiceAccount("user")
+
+	s.svc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, s.admin.Id, sa).
+		Return(sa, nil)
+
+	resp := s.request(http.MethodPost, fmt.Sprintf("/service_accounts/%s/credentials", sa.Id), nil)
+
+	var result models.ServiceAccountCredentialCreateResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotEmpty(result.ClientSecret)
+	s.Assert().NotNil(sa.Credential(result.ClientId))
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_DeleteCredential_204() {
+	sa := newServiceAccount("user")
+	cred, _ := sa.AddCredential()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	s.svc.EXPECT().
+		Update(mock.Anything, s.admin.Id, sa).
+		Return(sa, nil)
+
+	resp := s.request(http.MethodDelete, fmt.Sprintf("/service_accounts/%s/credentials/%s", sa.Id, cred.ClientId), nil)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+	s.Assert().Empty(sa.Credentials)
+}
+
+func (s *ServiceAccountHandlerTestSuite) TestServiceAccountHandler_DeleteCredential_404() {
+	sa := newServiceAccount("user")
+
+	s.svc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	resp := s.request(http.MethodDelete, fmt.Sprintf("/service_accounts/%s/credentials/sa_1", sa.Id), nil)
+
+	s.Assert().Equal(http.StatusNotFound, resp.Code)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	jwx "github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+type SessionService interface {
+	Create(ctx context.Context, model *models.Session) (*models.Session, error)
+	Read(ctx context.Context, userId string, id string) (*models.Session, error)
+	Update(ctx context.Context, model *models.Session) (*models.Session, error)
+	CompareAndSwap(ctx context.Context, model *models.Session, previous string) error
+	Revoke(ctx context.Context, id string, model *models.Session, reason string) error
+	RevokeAll(ctx context.Context, id string, userId string, exceptId string, reason string) error
+	Find(ctx context.Context, userId string) (models.Sessions, error)
+}
+
+type SessionHandler struct {
+	*openapi.Handler
+	svc     SessionService
+	userSvc UserService
+}
+
+func NewSessionHandler(openapi *openapi.Handler, svc SessionService, userSvc UserService) *SessionHandler {
+	return &SessionHandler{
+		Handler: openapi,
+		svc:     svc,
+		userSvc: userSvc,
+	}
+}
+
+func (h *SessionHandler) Register(s *server.Server) {
+	s.Add(http.MethodGet, "/me/sessions", h.list)
+	s.Add(http.MethodDelete, "/me/sessions", h.revokeOthers)
+	s.Add(http.MethodDelete, "/me/sessions/:id", h.revoke)
+	s.Add(http.MethodGet, "/users/:username/sessions", h.listUser)
+	s.Add(http.MethodDelete, "/users/:username/sessions", h.revokeUserAll)
+	s.Add(http.MethodDelete, "/users/:username/sessions/:id", h.revokeUser)
+}
+
+func (h *SessionHandler) list(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	sessions, err := h.svc.Find(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting sessions")
+		return err
+	}
+
+	sid := currentSessionId(c)
+	resp := sessions.Response()
+	for i := range resp.Sessions {
+		resp.Sessions[i].Current = resp.Sessions[i].Id == sid
+	}
+
+	return h.Validate(c, http.StatusOK, resp)
+}
+
+func (h *SessionHandler) revokeOthers(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	err := h.svc.RevokeAll(ctx, currentUser.Id, currentUser.Id, currentSessionId(c), models.SessionRevokedUser)
+	if err != nil {
+		log.Error().Err(err).Msg("failed revoking sessions")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+func (h *SessionHandler) revoke(c echo.Context) error {
+	id := c.Param("id")
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	return h.revokeSession(ctx, c, currentUser.Id, id, models.SessionRevokedUser)
+}
+
+func (h *SessionHandler) listUser(c echo.Context) error {
+	id := c.Param("username")
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.Read(ctx, id)
+	if err != nil {
+		return h.readUser(c, err)
+	}
+
+	sessions, err := h.svc.Find(ctx, user.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting sessions")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, sessions.Response())
+}
+
+func (h *SessionHandler) revokeUserAll(c echo.Context) error {
+	id := c.Param("username")
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.Read(ctx, id)
+	if err != nil {
+		return h.readUser(c, err)
+	}
+
+	err = user.RevokeSessions(currentUser)
+	if err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	err = h.svc.RevokeAll(ctx, currentUser.Id, user.Id, "", models.SessionRevokedAdmin)
+	if err != nil {
+		log.Error().Err(err).Msg("failed revoking sessions")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+func (h *SessionHandler) revokeUser(c echo.Context) error {
+	id := c.Param("username")
+	sessionId := c.Param("id")
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.userSvc.Read(ctx, id)
+	if err != nil {
+		return h.readUser(c, err)
+	}
+
+	err = user.RevokeSessions(currentUser)
+	if err != nil {
+		return h.checkModelErr(c, err)
+	}
+
+	return h.revokeSession(ctx, c, user.Id, sessionId, models.SessionRevokedAdmin)
+}
+
+func (h *SessionHandler) revokeSession(ctx context.Context, c echo.Context, userId string, id string, reason string) error {
+	session, err := h.svc.Read(ctx, userId, id)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist {
+				return h.Validate(c, http.StatusNotFound, echo.Map{"message": se.Message})
+			}
+		}
+		log.Error().Err(err).Msg("failed getting session")
+		return err
+	}
+
+	if session.IsRevoked {
+		return h.Validate(c, http.StatusConflict, echo.Map{"message": "session already revoked"})
+	}
+
+	currentUser := c.Get("user").(*models.User)
+	err = h.svc.Revoke(ctx, currentUser.Id, session, reason)
+	if err != nil {
+		log.Error().Err(err).Msg("failed revoking session")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+func (h *SessionHandler) readUser(c echo.Context, err error) error {
+	var se *services.Error
+	if errors.As(err, &se) {
+		msg := echo.Map{"message": se.Message}
+		if se.Kind == services.NotExist {
+			return h.Validate(c, http.StatusNotFound, msg)
+		} else if se.Kind == services.Deleted {
+			return h.Validate(c, http.StatusGone, msg)
+		}
+	}
+	log.Error().Err(err).Msg("failed getting user")
+	return err
+}
+
+func (h *SessionHandler) checkModelErr(c echo.Context, err error) error {
+	var me *models.Error
+	if errors.As(err, &me) {
+		if me.Kind == models.Permission {
+			return h.Validate(c, http.StatusForbidden, echo.Map{"message": me.Message})
+		}
+	}
+	log.Error().Err(err).Msg("failed revoking sessions")
+	return err
+}
+
+// currentSessionId returns the session the access token was issued for.
+// Personal access tokens aren't tied to a session.
+func currentSessionId(c echo.Context) string {
+	token, ok := c.Get("token").(jwx.Token)
+	if !ok {
+		return ""
+	}
+
+	sid, _ := token.PrivateClaims()["sid"].(string)
+	return sid
+}
+
+// isServiceAccount reports whether the current user is a service account.
+func isServiceAccount(c echo.Context) bool {
+	token, ok := c.Get("token").(jwx.Token)
+	return ok && token.PrivateClaims()["type"] == jwt.ServiceToken.String()
+}
@@ -0,0 +1,319 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type SessionHandlerTestSuite struct {
+	suite.Suite
+	svc              *handlers.MockSessionService
+	userSvc          *handlers.MockUserService
+	server           *api.Server
+	user             *models.User
+	session          *models.Session
+	accessToken      []byte
+	admin            *models.User
+	adminAccessToken []byte
+	super            *models.User
+}
+
+func (s *SessionHandlerTestSuite) SetupTest() {
+	userSvc := handlers.NewMockUserService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	svc := handlers.NewMockSessionService(s.T())
+	h := handlers.NewSessionHandler(openapi.NewHandler(), svc, userSvc)
+
+	user := getUser()
+	session := models.NewSession(user.Id)
+	access, _, _ := user.Login(session)
+
+	admin := getAdmin()
+	adminAccess, _, _ := admin.Login(models.NewSession(admin.Id))
+
+	s.svc = svc
+	s.userSvc = userSvc
+	s.server = getServer(userSvc, patSvc, h)
+	s.user = user
+	s.session = session
+	s.accessToken = access
+	s.admin = admin
+	s.adminAccessToken = adminAccess
+	s.super = getSuper()
+}
+
+func TestSessionHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionHandlerTestSuite))
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_List_200() {
+	other := models.NewSession(s.user.Id)
+	_ = other.Rotate([]byte("token"))
+
+	req := httptest.NewRequest(http.MethodGet, "/me/sessions", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.Sessions{*s.session, *other}, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.SessionsResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	if s.Assert().Len(result.Sessions, 2) {
+		s.Assert().True(result.Sessions[0].Current)
+		s.Assert().False(result.Sessions[1].Current)
+	}
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_RevokeOthers_204() {
+	req := httptest.NewRequest(http.MethodDelete, "/me/sessions", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		RevokeAll(mock.Anything, s.user.Id, s.user.Id, s.session.Id, models.SessionRevokedUser).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_Revoke_204() {
+	other := models.NewSession(s.user.Id)
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/me/sessions/%s", other.Id), nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id, other.Id).
+		Return(other, nil)
+
+	s.svc.EXPECT().
+		Revoke(mock.Anything, s.user.Id, other, models.SessionRevokedUser).
+		Return(nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_Revoke_404() {
+	req := httptest.NewRequest(http.MethodDelete, "/me/sessions/1", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, &services.Error{
+			Kind:    services.NotExist,
+			Message: services.ErrSessionNotFound.Error(),
+		})
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusNotFound, resp.Code)
+	s.Assert().Equal(services.ErrSessionNotFound.Error(), result.Message)
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_Revoke_409() {
+	other := models.NewSession(s.user.Id)
+	other.Revoke(s.user.Id, models.SessionRevokedLogout)
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/me/sessions/%s", other.Id), nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		Return(other, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusConflict, resp.Code)
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_ListUser_200() {
+	req := httptest.NewRequest(http.MethodGet, "/users/1/sessions", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil).Once()
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.svc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.Sessions{*s.session}, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.SessionsResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Len(result.Sessions, 1)
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_User_204() {
+	testCases := []struct {
+		endpoint string
+	}{
+		{"/users/1/sessions"},
+		{fmt.Sprintf("/users/1/sessions/%s", s.session.Id)},
+	}
+	for _, tc := range testCases {
+		s.T().Run(tc.endpoint, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, tc.endpoint, nil)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+			resp := httptest.NewRecorder()
+
+			// middleware
+			s.userSvc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(s.admin, nil).Once()
+
+			s.userSvc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(s.user, nil).Once()
+
+			s.svc.EXPECT().
+				RevokeAll(mock.Anything, s.admin.Id, s.user.Id, "", models.SessionRevokedAdmin).
+				Return(nil).Maybe()
+
+			s.svc.EXPECT().
+				Read(mock.Anything, s.user.Id, s.session.Id).
+				Return(s.session, nil).Maybe()
+
+			s.svc.EXPECT().
+				Revoke(mock.Anything, s.admin.Id, s.session, models.SessionRevokedAdmin).
+				Return(nil).Maybe()
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusNoContent, resp.Code)
+		})
+	}
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_401() {
+	testCases := []struct {
+		method   string
+		endpoint string
+	}{
+		{http.MethodGet, "/me/sessions"},
+		{http.MethodDelete, "/me/sessions"},
+		{http.MethodDelete, "/me/sessions/1"},
+		{http.MethodGet, "/users/1/sessions"},
+		{http.MethodDelete, "/users/1/sessions"},
+		{http.MethodDelete, "/users/1/sessions/1"},
+	}
+	for _, tc := range testCases {
+		s.T().Run(fmt.Sprintf("%s_%s", tc.method, tc.endpoint), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.endpoint, nil)
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+		})
+	}
+}
+
+func (s *SessionHandlerTestSuite) TestSessionHandler_403() {
+	testCases := []struct {
+		method   string
+		endpoint string
+		token    []byte
+		current  *models.User
+		target   *models.User
+	}{
+		{http.MethodGet, "/users/1/sessions", s.accessToken, s.user, nil},
+		{http.MethodDelete, "/users/1/sessions", s.accessToken, s.user, nil},
+		{http.MethodDelete, "/users/1/sessions", s.adminAccessToken, s.admin, s.super},
+		{http.MethodDelete, "/users/1/sessions/1", s.adminAccessToken, s.admin, s.super},
+	}
+	for _, tc := range testCases {
+		s.T().Run(fmt.Sprintf("%s_%s", tc.method, tc.endpoint), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.endpoint, nil)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", tc.token))
+			resp := httptest.NewRecorder()
+
+			// middleware
+			s.userSvc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(tc.current, nil).Once()
+
+			if tc.target != nil {
+				s.userSvc.EXPECT().
+					Read(mock.Anything, mock.Anything).
+					Return(tc.target, nil).Once()
+			}
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusForbidden, resp.Code)
+		})
+	}
+}
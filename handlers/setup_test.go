@@ -1,7 +1,10 @@
 package handlers_test
 
 import (
+	"context"
+
 	api "github.com/alexferl/golib/http/api/server"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/alexferl/echo-boilerplate/handlers"
 	"github.com/alexferl/echo-boilerplate/models"
@@ -31,5 +34,16 @@ func getSuper() *models.User {
 }
 
 func getServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessTokenService, handler ...handlers.Handler) *api.Server {
-	return server.NewTestServer(userSvc, patSvc, handler...)
+	// tests using the tokens of service accounts make their own server,
+	// and the sessions of access tokens are only checked by the server tests
+	sessionSvc := &handlers.MockSessionService{}
+	sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, userId string, id string) (*models.Session, error) {
+			session := models.NewSession(userId)
+			session.Id = id
+			return session, nil
+		}).Maybe()
+
+	return server.NewTestServer(userSvc, patSvc, nil, sessionSvc, handler...)
 }
@@ -106,7 +106,7 @@ func (h *TaskHandler) get(c echo.Context) error {
 
 	task, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readTask(c, err)()
+		return h.readTask(c, err)
 	}
 
 	return h.Validate(c, http.StatusOK, task.Response())
@@ -131,7 +131,7 @@ func (h *TaskHandler) update(c echo.Context) error {
 
 	task, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readTask(c, err)()
+		return h.readTask(c, err)
 	}
 
 	if currentUser.Id != task.CreatedBy.(*models.User).Id && !currentUser.HasRoleOrHigher(models.AdminRole) {
@@ -170,7 +170,7 @@ func (h *TaskHandler) transition(c echo.Context) error {
 
 	task, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readTask(c, err)()
+		return h.readTask(c, err)
 	}
 
 	if *body.Completed != task.Completed {
@@ -199,7 +199,7 @@ func (h *TaskHandler) delete(c echo.Context) error {
 
 	task, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readTask(c, err)()
+		return h.readTask(c, err)
 	}
 
 	if currentUser.Id != task.CreatedBy.(*models.User).Id && !currentUser.HasRoleOrHigher(models.AdminRole) {
@@ -215,16 +215,16 @@ func (h *TaskHandler) delete(c echo.Context) error {
 	return h.Validate(c, http.StatusNoContent, nil)
 }
 
-func (h *TaskHandler) readTask(c echo.Context, err error) func() error {
+func (h *TaskHandler) readTask(c echo.Context, err error) error {
 	var se *services.Error
 	if errors.As(err, &se) {
 		msg := echo.Map{"message": se.Message}
 		if se.Kind == services.NotExist {
-			return func() error { return h.Validate(c, http.StatusNotFound, msg) }
+			return h.Validate(c, http.StatusNotFound, msg)
 		} else if se.Kind == services.Deleted {
-			return func() error { return h.Validate(c, http.StatusGone, msg) }
+			return h.Validate(c, http.StatusGone, msg)
 		}
 	}
 	log.Error().Err(err).Msg("failed getting task")
-	return func() error { return err }
+	return err
 }
@@ -34,7 +34,7 @@ func (s *TaskHandlerTestSuite) SetupTest() {
 	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
 	h := handlers.NewTaskHandler(openapi.NewHandler(), svc)
 	user := getUser()
-	access, _, _ := user.Login()
+	access, _, _ := user.Login(models.NewSession(user.Id))
 
 	s.svc = svc
 	s.userSvc = userSvc
@@ -10,7 +10,9 @@ import (
 	"github.com/alexferl/golib/http/api/server"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
 
+	"github.com/alexferl/echo-boilerplate/config"
 	"github.com/alexferl/echo-boilerplate/models"
 	"github.com/alexferl/echo-boilerplate/services"
 	"github.com/alexferl/echo-boilerplate/util/pagination"
@@ -22,18 +24,32 @@ type UserService interface {
 	Update(ctx context.Context, id string, model *models.User) (*models.User, error)
 	Delete(ctx context.Context, id string, model *models.User) error
 	Find(ctx context.Context, params *models.UserSearchParams) (int64, models.Users, error)
+	FindIdsByRoles(ctx context.Context, roles []string) ([]string, error)
 	FindOneByEmailOrUsername(ctx context.Context, email string, username string) (*models.User, error)
+	FindOneByIdentity(ctx context.Context, provider string, subject string) (*models.User, error)
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
+var ErrImpersonateServiceAccount = errors.New("service accounts can't impersonate users")
+
 type UserHandler struct {
 	*openapi.Handler
-	svc UserService
+	svc        UserService
+	patSvc     PersonalAccessTokenService
+	sessionSvc SessionService
 }
 
-func NewUserHandler(openapi *openapi.Handler, svc UserService) *UserHandler {
+func NewUserHandler(
+	openapi *openapi.Handler,
+	svc UserService,
+	patSvc PersonalAccessTokenService,
+	sessionSvc SessionService,
+) *UserHandler {
 	return &UserHandler{
-		Handler: openapi,
-		svc:     svc,
+		Handler:    openapi,
+		svc:        svc,
+		patSvc:     patSvc,
+		sessionSvc: sessionSvc,
 	}
 }
 
@@ -48,6 +64,7 @@ func (h *UserHandler) Register(s *server.Server) {
 	s.Add(http.MethodDelete, "/users/:username/lock", h.unlock)
 	s.Add(http.MethodPut, "/users/:username/roles/:role", h.addRole)
 	s.Add(http.MethodDelete, "/users/:username/roles/:role", h.removeRole)
+	s.Add(http.MethodPost, "/users/:username/impersonate", h.impersonate)
 	s.Add(http.MethodGet, "/users", h.list)
 }
 
@@ -115,7 +132,7 @@ func (h *UserHandler) get(c echo.Context) error {
 
 	user, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readUser(c, err)()
+		return h.readUser(c, err)
 	}
 
 	if currentUser.HasRoleOrHigher(models.AdminRole) {
@@ -145,7 +162,7 @@ func (h *UserHandler) update(c echo.Context) error {
 
 	user, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readUser(c, err)()
+		return h.readUser(c, err)
 	}
 
 	if body.Name != nil {
@@ -165,32 +182,94 @@ func (h *UserHandler) update(c echo.Context) error {
 	return h.Validate(c, http.StatusOK, res.AdminResponse())
 }
 
+// RestrictUserRequest is the optional body to ban or lock a user,
+// without Until it lasts until it's lifted.
+type RestrictUserRequest struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
 func (h *UserHandler) ban(c echo.Context) error {
 	id := c.Param("username")
 	currentUser := c.Get("user").(*models.User)
 
+	body := &RestrictUserRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	user, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readUser(c, err)()
+		return h.readUser(c, err)
 	}
 
-	err = user.Ban(currentUser)
+	err = user.Ban(currentUser, body.Reason, body.Until)
 	if err != nil {
-		return h.checkModelErr(c, err, "banning")()
+		return h.checkModelErr(c, err, "banning")
 	}
+	models.Act(ctx, user.BannedBy)
 
-	_, err = h.svc.Update(ctx, currentUser.Id, user)
+	err = h.restrict(ctx, currentUser, user, user.CurrentBan(), models.SessionRevokedBan)
 	if err != nil {
-		log.Error().Err(err).Msg("failed updating user")
+		log.Error().Err(err).Msg("failed banning user")
 		return err
 	}
 
 	return h.Validate(c, http.StatusNoContent, nil)
 }
 
+// restrict saves user once banned or locked, along with revoking its sessions and
+// personal access tokens so they don't outlive it, all in a single transaction.
+// What was revoked is recorded in restriction.
+func (h *UserHandler) restrict(
+	ctx context.Context,
+	currentUser *models.User,
+	user *models.User,
+	restriction *models.Restriction,
+	reason string,
+) error {
+	return h.svc.WithTransaction(ctx, func(ctx context.Context) error {
+		// the transaction can be retried
+		restriction.RevokedPersonalAccessTokens = nil
+		restriction.RevokedSessions = nil
+
+		isRevoked := false
+		_, pats, err := h.patSvc.Find(ctx, &models.PersonalAccessTokenSearchParams{
+			UserId:    user.Id,
+			IsRevoked: &isRevoked,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, pat := range pats {
+			if err = h.patSvc.Revoke(ctx, currentUser.Id, &pat); err != nil {
+				return err
+			}
+			restriction.RevokedPersonalAccessTokens = append(restriction.RevokedPersonalAccessTokens, pat.Id)
+		}
+
+		sessions, err := h.sessionSvc.Find(ctx, user.Id)
+		if err != nil {
+			return err
+		}
+
+		for _, session := range sessions {
+			if err = h.sessionSvc.Revoke(ctx, currentUser.Id, &session, reason); err != nil {
+				return err
+			}
+			restriction.RevokedSessions = append(restriction.RevokedSessions, session.Id)
+		}
+
+		_, err = h.svc.Update(ctx, currentUser.Id, user)
+		return err
+	})
+}
+
 func (h *UserHandler) unban(c echo.Context) error {
 	id := c.Param("username")
 	currentUser := c.Get("user").(*models.User)
@@ -200,13 +279,14 @@ func (h *UserHandler) unban(c echo.Context) error {
 
 	user, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readUser(c, err)()
+		return h.readUser(c, err)
 	}
 
 	err = user.Unban(currentUser)
 	if err != nil {
-		return h.checkModelErr(c, err, "unbanning")()
+		return h.checkModelErr(c, err, "unbanning")
 	}
+	models.Act(ctx, user.UnbannedBy)
 
 	_, err = h.svc.Update(ctx, currentUser.Id, user)
 	if err != nil {
@@ -221,22 +301,29 @@ func (h *UserHandler) lock(c echo.Context) error {
 	id := c.Param("username")
 	currentUser := c.Get("user").(*models.User)
 
+	body := &RestrictUserRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
 	defer cancel()
 
 	user, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readUser(c, err)()
+		return h.readUser(c, err)
 	}
 
-	err = user.Lock(currentUser)
+	err = user.Lock(currentUser, body.Reason, body.Until)
 	if err != nil {
-		return h.checkModelErr(c, err, "locking")()
+		return h.checkModelErr(c, err, "locking")
 	}
+	models.Act(ctx, user.LockedBy)
 
-	_, err = h.svc.Update(ctx, currentUser.Id, user)
+	err = h.restrict(ctx, currentUser, user, user.CurrentLock(), models.SessionRevokedLock)
 	if err != nil {
-		log.Error().Err(err).Msg("failed updating user")
+		log.Error().Err(err).Msg("failed locking user")
 		return err
 	}
 
@@ -252,13 +339,14 @@ func (h *UserHandler) unlock(c echo.Context) error {
 
 	user, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readUser(c, err)()
+		return h.readUser(c, err)
 	}
 
 	err = user.Unlock(currentUser)
 	if err != nil {
-		return h.checkModelErr(c, err, "locking")()
+		return h.checkModelErr(c, err, "locking")
 	}
+	models.Act(ctx, user.UnlockedBy)
 
 	_, err = h.svc.Update(ctx, currentUser.Id, user)
 	if err != nil {
@@ -279,12 +367,12 @@ func (h *UserHandler) addRole(c echo.Context) error {
 
 	user, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readUser(c, err)()
+		return h.readUser(c, err)
 	}
 
 	err = user.AddRole(currentUser, models.RolesMap[role])
 	if err != nil {
-		return h.checkModelErr(c, err, "locking")()
+		return h.checkModelErr(c, err, "locking")
 	}
 
 	_, err = h.svc.Update(ctx, currentUser.Id, user)
@@ -306,12 +394,12 @@ func (h *UserHandler) removeRole(c echo.Context) error {
 
 	user, err := h.svc.Read(ctx, id)
 	if err != nil {
-		return h.readUser(c, err)()
+		return h.readUser(c, err)
 	}
 
 	err = user.RemoveRole(currentUser, models.RolesMap[role])
 	if err != nil {
-		return h.checkModelErr(c, err, "locking")()
+		return h.checkModelErr(c, err, "locking")
 	}
 
 	_, err = h.svc.Update(ctx, currentUser.Id, user)
@@ -323,6 +411,56 @@ func (h *UserHandler) removeRole(c echo.Context) error {
 	return h.Validate(c, http.StatusNoContent, nil)
 }
 
+type ImpersonateResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+func (h *UserHandler) impersonate(c echo.Context) error {
+	id := c.Param("username")
+	currentUser := c.Get("user").(*models.User)
+
+	// the actor of an impersonation has to be a user, the server
+	// couldn't authenticate a service account as one
+	if isServiceAccount(c) {
+		return h.Validate(c, http.StatusForbidden, echo.Map{"message": ErrImpersonateServiceAccount.Error()})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.svc.Read(ctx, id)
+	if err != nil {
+		return h.readUser(c, err)
+	}
+
+	session := newSession(c, user.Id, "")
+	token, err := user.Impersonate(currentUser, session)
+	if err != nil {
+		return h.checkModelErr(c, err, "impersonating")
+	}
+
+	_, err = h.sessionSvc.Create(ctx, session)
+	if err != nil {
+		log.Error().Err(err).Msg("failed inserting session")
+		return err
+	}
+
+	log.Info().
+		Str("actor_id", currentUser.Id).
+		Str("user_id", user.Id).
+		Msg("started impersonation")
+
+	resp := &ImpersonateResponse{
+		AccessToken: string(token),
+		ExpiresIn:   int64(viper.GetDuration(config.ImpersonationExpiry).Seconds()),
+		TokenType:   "Bearer",
+	}
+
+	return h.Validate(c, http.StatusOK, resp)
+}
+
 func (h *UserHandler) list(c echo.Context) error {
 	page, perPage, limit, skip := pagination.ParseParams(c)
 
@@ -344,30 +482,36 @@ func (h *UserHandler) list(c echo.Context) error {
 	return h.Validate(c, http.StatusOK, users.AdminResponse())
 }
 
-func (h *UserHandler) readUser(c echo.Context, err error) func() error {
+func (h *UserHandler) readUser(c echo.Context, err error) error {
 	var se *services.Error
 	if errors.As(err, &se) {
 		msg := echo.Map{"message": se.Message}
 		if se.Kind == services.NotExist {
-			return func() error { return h.Validate(c, http.StatusNotFound, msg) }
+			return h.Validate(c, http.StatusNotFound, msg)
 		} else if se.Kind == services.Deleted {
-			return func() error { return h.Validate(c, http.StatusGone, msg) }
+			return h.Validate(c, http.StatusGone, msg)
 		}
 	}
 	log.Error().Err(err).Msg("failed getting user")
-	return func() error { return err }
+	return err
 }
 
-func (h *UserHandler) checkModelErr(c echo.Context, err error, action string) func() error {
+func (h *UserHandler) checkModelErr(c echo.Context, err error, action string) error {
 	var me *models.Error
 	if errors.As(err, &me) {
 		msg := echo.Map{"message": me.Message}
 		if me.Kind == models.Conflict {
-			return func() error { return h.Validate(c, http.StatusConflict, msg) }
+			return h.Validate(c, http.StatusConflict, msg)
 		} else if me.Kind == models.Permission {
-			return func() error { return h.Validate(c, http.StatusForbidden, msg) }
+			return h.Validate(c, http.StatusForbidden, msg)
+		} else if me.Kind == models.Invalid {
+			m := echo.Map{
+				"message": "validation error",
+				"errors":  []string{me.Message},
+			}
+			return h.Validate(c, http.StatusUnprocessableEntity, m)
 		}
 	}
 	log.Error().Err(err).Msgf("failed %s user", action)
-	return func() error { return err }
+	return err
 }
@@ -2,11 +2,15 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/alexferl/echo-openapi"
 	api "github.com/alexferl/golib/http/api/server"
@@ -16,12 +20,15 @@ import (
 
 	"github.com/alexferl/echo-boilerplate/handlers"
 	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/server"
 	"github.com/alexferl/echo-boilerplate/services"
 )
 
 type UserHandlerTestSuite struct {
 	suite.Suite
 	svc              *handlers.MockUserService
+	patSvc           *handlers.MockPersonalAccessTokenService
+	sessionSvc       *handlers.MockSessionService
 	server           *api.Server
 	user             *models.User
 	accessToken      []byte
@@ -33,17 +40,20 @@ type UserHandlerTestSuite struct {
 func (s *UserHandlerTestSuite) SetupTest() {
 	svc := handlers.NewMockUserService(s.T())
 	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
-	h := handlers.NewUserHandler(openapi.NewHandler(), svc)
+	sessionSvc := handlers.NewMockSessionService(s.T())
+	h := handlers.NewUserHandler(openapi.NewHandler(), svc, patSvc, sessionSvc)
 
 	user := getUser()
-	access, _, _ := user.Login()
+	access, _, _ := user.Login(models.NewSession(user.Id))
 
 	admin := getAdmin()
-	adminAccess, _, _ := admin.Login()
+	adminAccess, _, _ := admin.Login(models.NewSession(admin.Id))
 
 	super := getSuper()
 
 	s.svc = svc
+	s.patSvc = patSvc
+	s.sessionSvc = sessionSvc
 	s.server = getServer(svc, patSvc, h)
 	s.user = user
 	s.accessToken = access
@@ -285,19 +295,17 @@ func (s *UserHandlerTestSuite) TestUserHandler_Update_410() {
 
 func (s *UserHandlerTestSuite) TestUserHandler_204() {
 	bannedUser := models.NewUser("banned@example.com", "banned")
-	_ = bannedUser.Ban(s.admin)
+	_ = bannedUser.Ban(s.admin, "", nil)
 
 	lockedUser := models.NewUser("locked@example.com", "locked")
-	_ = lockedUser.Lock(s.admin)
+	_ = lockedUser.Lock(s.admin, "", nil)
 
 	testCases := []struct {
 		method   string
 		endpoint string
 		target   *models.User
 	}{
-		{http.MethodPut, "/users/1/ban", s.user},
 		{http.MethodDelete, "/users/1/ban", bannedUser},
-		{http.MethodPut, "/users/1/lock", s.user},
 		{http.MethodDelete, "/users/1/lock", lockedUser},
 		{http.MethodPut, "/users/1/roles/admin", s.user},
 		{http.MethodDelete, "/users/1/roles/user", s.user},
@@ -329,6 +337,153 @@ func (s *UserHandlerTestSuite) TestUserHandler_204() {
 	}
 }
 
+func (s *UserHandlerTestSuite) TestUserHandler_Restrict_204() {
+	until := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	testCases := []struct {
+		endpoint string
+		reason   string
+		matcher  func(u *models.User) bool
+	}{
+		{"/users/1/ban", models.SessionRevokedBan, func(u *models.User) bool {
+			return u.IsBanned && u.BanReason == "spam" && u.BannedUntil.Equal(until) && len(u.BanHistory) == 1
+		}},
+		{"/users/1/lock", models.SessionRevokedLock, func(u *models.User) bool {
+			return u.IsLocked && u.LockReason == "spam" && u.LockedUntil.Equal(until) && len(u.LockHistory) == 1
+		}},
+	}
+	for _, tc := range testCases {
+		s.T().Run(tc.endpoint, func(t *testing.T) {
+			payload := &handlers.RestrictUserRequest{
+				Reason: "spam",
+				Until:  &until,
+			}
+			b, err := json.Marshal(payload)
+			s.Assert().NoError(err)
+
+			req := httptest.NewRequest(http.MethodPut, tc.endpoint, bytes.NewBuffer(b))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+			resp := httptest.NewRecorder()
+
+			// middleware
+			s.svc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(s.admin, nil).Once()
+
+			s.svc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(getUser(), nil).Once()
+
+			s.svc.EXPECT().
+				WithTransaction(mock.Anything, mock.Anything).
+				RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+					return fn(ctx)
+				}).Once()
+
+			pat := &models.PersonalAccessToken{Id: "1", UserId: "1000"}
+			s.patSvc.EXPECT().
+				Find(mock.Anything, mock.MatchedBy(func(p *models.PersonalAccessTokenSearchParams) bool {
+					return p.UserId == "1000" && !*p.IsRevoked
+				})).
+				Return(1, models.PersonalAccessTokens{*pat}, nil).Once()
+
+			s.patSvc.EXPECT().
+				Revoke(mock.Anything, s.admin.Id, mock.Anything).
+				Return(nil).Once()
+
+			session := models.NewSession("1000")
+			s.sessionSvc.EXPECT().
+				Find(mock.Anything, "1000").
+				Return(models.Sessions{*session}, nil).Once()
+
+			s.sessionSvc.EXPECT().
+				Revoke(mock.Anything, s.admin.Id, mock.Anything, tc.reason).
+				Return(nil).Once()
+
+			s.svc.EXPECT().
+				Update(mock.Anything, mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+					r := u.CurrentBan()
+					if tc.reason == models.SessionRevokedLock {
+						r = u.CurrentLock()
+					}
+					return tc.matcher(u) &&
+						slices.Equal([]string{pat.Id}, r.RevokedPersonalAccessTokens) &&
+						slices.Equal([]string{session.Id}, r.RevokedSessions)
+				})).
+				Return(nil, nil).Once()
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusNoContent, resp.Code)
+		})
+	}
+}
+
+func (s *UserHandlerTestSuite) TestUserHandler_Restrict_500() {
+	req := httptest.NewRequest(http.MethodPut, "/users/1/ban", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.admin, nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(getUser(), nil).Once()
+
+	// the ban isn't saved when revoking fails
+	s.svc.EXPECT().
+		WithTransaction(mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, fn func(context.Context) error) error {
+			return fn(ctx)
+		}).Once()
+
+	s.patSvc.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(0, models.PersonalAccessTokens{}, nil).Once()
+
+	s.sessionSvc.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(nil, errors.New("")).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusInternalServerError, resp.Code)
+}
+
+func (s *UserHandlerTestSuite) TestUserHandler_Restrict_422() {
+	for _, endpoint := range []string{"/users/1/ban", "/users/1/lock"} {
+		s.T().Run(endpoint, func(t *testing.T) {
+			until := time.Now().Add(-time.Hour)
+			payload := &handlers.RestrictUserRequest{Until: &until}
+			b, err := json.Marshal(payload)
+			s.Assert().NoError(err)
+
+			req := httptest.NewRequest(http.MethodPut, endpoint, bytes.NewBuffer(b))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+			resp := httptest.NewRecorder()
+
+			// middleware
+			s.svc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(s.admin, nil).Once()
+
+			s.svc.EXPECT().
+				Read(mock.Anything, mock.Anything).
+				Return(getUser(), nil).Once()
+
+			s.server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(http.StatusUnprocessableEntity, resp.Code)
+		})
+	}
+}
+
 func (s *UserHandlerTestSuite) TestUserHandler_401() {
 	testCases := []struct {
 		method   string
@@ -621,3 +776,110 @@ func (s *UserHandlerTestSuite) TestUserHandler_List_403() {
 
 	s.Assert().Equal(http.StatusForbidden, resp.Code)
 }
+
+func (s *UserHandlerTestSuite) TestUserHandler_Impersonate_200() {
+	superAccess, _, _ := s.super.Login(models.NewSession(s.super.Id))
+
+	req := httptest.NewRequest(http.MethodPost, "/users/1000/impersonate", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", superAccess))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.svc.EXPECT().
+		Read(mock.Anything, s.super.Id).
+		Return(s.super, nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, "1000").
+		Return(s.user, nil).Once()
+
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.MatchedBy(func(session *models.Session) bool {
+			return session.UserId == s.user.Id && session.Actor.Id == s.super.Id
+		})).
+		Return(nil, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	var result handlers.ImpersonateResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotEmpty(result.AccessToken)
+	s.Assert().Equal("Bearer", result.TokenType)
+}
+
+func (s *UserHandlerTestSuite) TestUserHandler_Impersonate_403_Service_Account() {
+	sa := models.NewServiceAccount("ci", "")
+	sa.Roles = []string{models.SuperRole.String()}
+	cred, err := sa.AddCredential()
+	s.Require().NoError(err)
+	token, err := sa.Token(cred.ClientId, cred.ClientSecret)
+	s.Require().NoError(err)
+
+	saSvc := handlers.NewMockServiceAccountService(s.T())
+	h := handlers.NewUserHandler(openapi.NewHandler(), s.svc, s.patSvc, s.sessionSvc)
+	srv := server.NewTestServer(s.svc, s.patSvc, saSvc, s.sessionSvc, h)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/1000/impersonate", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	saSvc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil).Once()
+
+	srv.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Equal(handlers.ErrImpersonateServiceAccount.Error(), result.Message)
+}
+
+func (s *UserHandlerTestSuite) TestUserHandler_Impersonate_403_Admin() {
+	req := httptest.NewRequest(http.MethodPost, "/users/1000/impersonate", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.adminAccessToken))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.svc.EXPECT().
+		Read(mock.Anything, s.admin.Id).
+		Return(s.admin, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+}
+
+func (s *UserHandlerTestSuite) TestUserHandler_Impersonate_403_Super() {
+	superAccess, _, _ := s.super.Login(models.NewSession(s.super.Id))
+	super1 := models.NewUserWithRole("super1@example.com", "super1", models.SuperRole)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/super1/impersonate", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", superAccess))
+	resp := httptest.NewRecorder()
+
+	// middleware
+	s.svc.EXPECT().
+		Read(mock.Anything, s.super.Id).
+		Return(s.super, nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, "super1").
+		Return(super1, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Equal(models.ErrImpersonateSuper.Error(), result.Message)
+}
@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/alexferl/golib/http/api/server"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type WebAuthnCredentialService interface {
+	Create(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error)
+	Read(ctx context.Context, userId string, id string) (*models.WebAuthnCredential, error)
+	Update(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error)
+	Delete(ctx context.Context, model *models.WebAuthnCredential) error
+	Find(ctx context.Context, userId string) (models.WebAuthnCredentials, error)
+}
+
+type WebAuthnChallengeService interface {
+	Create(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)
+	Read(ctx context.Context, id string) (*models.WebAuthnChallenge, error)
+	Update(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)
+}
+
+var (
+	ErrWebAuthnCredentialInvalid = errors.New("invalid webauthn credential")
+	ErrWebAuthnLoginFailed       = errors.New("failed to log in")
+)
+
+type WebAuthnHandler struct {
+	*openapi.Handler
+	svc          WebAuthnCredentialService
+	challengeSvc WebAuthnChallengeService
+	userSvc      UserService
+	sessionSvc   SessionService
+	webauthn     *webauthn.WebAuthn
+}
+
+func NewWebAuthnHandler(
+	openapi *openapi.Handler,
+	svc WebAuthnCredentialService,
+	challengeSvc WebAuthnChallengeService,
+	userSvc UserService,
+	sessionSvc SessionService,
+) *WebAuthnHandler {
+	displayName := viper.GetString(config.WebAuthnRPDisplayName)
+	if displayName == "" {
+		displayName = viper.GetString(config.AppName)
+	}
+
+	timeout := webauthn.TimeoutConfig{
+		Enforce:    true,
+		Timeout:    viper.GetDuration(config.WebAuthnTimeout),
+		TimeoutUVD: viper.GetDuration(config.WebAuthnTimeout),
+	}
+
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          viper.GetString(config.WebAuthnRPID),
+		RPDisplayName: displayName,
+		RPOrigins:     viper.GetStringSlice(config.WebAuthnRPOrigins),
+		Timeouts: webauthn.TimeoutsConfig{
+			Login:        timeout,
+			Registration: timeout,
+		},
+	})
+	if err != nil {
+		log.Panic().Err(err).Msg("failed creating webauthn")
+	}
+
+	return &WebAuthnHandler{
+		Handler:      openapi,
+		svc:          svc,
+		challengeSvc: challengeSvc,
+		userSvc:      userSvc,
+		sessionSvc:   sessionSvc,
+		webauthn:     w,
+	}
+}
+
+func (h *WebAuthnHandler) Register(s *server.Server) {
+	s.Add(http.MethodPost, "/auth/webauthn/login/begin", h.beginLogin)
+	s.Add(http.MethodPost, "/auth/webauthn/login/finish", h.finishLogin)
+	s.Add(http.MethodGet, "/me/webauthn/credentials", h.list)
+	s.Add(http.MethodPost, "/me/webauthn/credentials/begin", h.beginRegistration)
+	s.Add(http.MethodPost, "/me/webauthn/credentials/finish", h.finishRegistration)
+	s.Add(http.MethodDelete, "/me/webauthn/credentials/:id", h.delete)
+}
+
+type WebAuthnBeginResponse struct {
+	ChallengeId string `json:"challenge_id"`
+	Options     any    `json:"options"`
+}
+
+func (h *WebAuthnHandler) list(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	credentials, err := h.svc.Find(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting webauthn credentials")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, credentials.Response())
+}
+
+func (h *WebAuthnHandler) beginRegistration(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	user, err := h.webAuthnUser(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.Credentials))
+	for _, cred := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, cred.Descriptor())
+	}
+
+	creation, session, err := h.webauthn.BeginRegistration(
+		user,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		log.Error().Err(err).Msg("failed beginning webauthn registration")
+		return err
+	}
+
+	challenge := models.NewWebAuthnChallenge(models.WebAuthnChallengeRegistration, user.Id, session)
+	_, err = h.challengeSvc.Create(ctx, challenge)
+	if err != nil {
+		log.Error().Err(err).Msg("failed inserting webauthn challenge")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, &WebAuthnBeginResponse{ChallengeId: challenge.Id, Options: creation})
+}
+
+type FinishWebAuthnRegistrationRequest struct {
+	ChallengeId string          `json:"challenge_id"`
+	Credential  json.RawMessage `json:"credential"`
+	Name        string          `json:"name"`
+}
+
+func (h *WebAuthnHandler) finishRegistration(c echo.Context) error {
+	currentUser := c.Get("user").(*models.User)
+
+	body := &FinishWebAuthnRegistrationRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	invalid := echo.Map{"message": ErrWebAuthnCredentialInvalid.Error()}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	challenge, err := h.useChallenge(ctx, body.ChallengeId, models.WebAuthnChallengeRegistration)
+	if err != nil {
+		return h.readChallenge(c, err, http.StatusBadRequest, invalid)
+	}
+
+	if challenge.UserId != currentUser.Id {
+		return h.Validate(c, http.StatusBadRequest, invalid)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(body.Credential))
+	if err != nil {
+		return h.Validate(c, http.StatusBadRequest, invalid)
+	}
+
+	user, err := h.webAuthnUser(ctx, currentUser.Id)
+	if err != nil {
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	credential, err := h.webauthn.CreateCredential(user, challenge.Session, parsed)
+	if err != nil {
+		return h.Validate(c, http.StatusBadRequest, invalid)
+	}
+
+	res, err := h.svc.Create(ctx, models.NewWebAuthnCredential(user.Id, body.Name, credential))
+	if err != nil {
+		log.Error().Err(err).Msg("failed inserting webauthn credential")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, res.Response())
+}
+
+func (h *WebAuthnHandler) delete(c echo.Context) error {
+	id := c.Param("id")
+	currentUser := c.Get("user").(*models.User)
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	credential, err := h.svc.Read(ctx, currentUser.Id, id)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist {
+				return h.Validate(c, http.StatusNotFound, echo.Map{"message": se.Message})
+			}
+		}
+		log.Error().Err(err).Msg("failed getting webauthn credential")
+		return err
+	}
+
+	err = h.svc.Delete(ctx, credential)
+	if err != nil {
+		log.Error().Err(err).Msg("failed deleting webauthn credential")
+		return err
+	}
+
+	return h.Validate(c, http.StatusNoContent, nil)
+}
+
+type BeginWebAuthnLoginRequest struct {
+	Email    string `json:"email,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// beginLogin lets the authenticator pick the credential when no user is given,
+// which is what passkeys are meant for. Unknown users get the same response
+// to avoid disclosing which accounts exist.
+func (h *WebAuthnHandler) beginLogin(c echo.Context) error {
+	body := &BeginWebAuthnLoginRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	var user *models.WebAuthnUser
+	if body.Email != "" || body.Username != "" {
+		res, err := h.userSvc.FindOneByEmailOrUsername(ctx, body.Email, body.Username)
+		if err != nil {
+			var se *services.Error
+			if !errors.As(err, &se) || (se.Kind != services.NotExist && se.Kind != services.Deleted) {
+				log.Error().Err(err).Msg("failed finding user")
+				return err
+			}
+		}
+
+		if res != nil {
+			credentials, err := h.svc.Find(ctx, res.Id)
+			if err != nil {
+				log.Error().Err(err).Msg("failed getting webauthn credentials")
+				return err
+			}
+
+			if len(credentials) > 0 {
+				user = &models.WebAuthnUser{User: res, Credentials: credentials}
+			}
+		}
+	}
+
+	var assertion *protocol.CredentialAssertion
+	var session *webauthn.SessionData
+	var err error
+	opt := webauthn.WithUserVerification(protocol.VerificationRequired)
+	userId := ""
+	if user != nil {
+		userId = user.Id
+		assertion, session, err = h.webauthn.BeginLogin(user, opt)
+	} else {
+		assertion, session, err = h.webauthn.BeginDiscoverableLogin(opt)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed beginning webauthn login")
+		return err
+	}
+
+	challenge := models.NewWebAuthnChallenge(models.WebAuthnChallengeLogin, userId, session)
+	_, err = h.challengeSvc.Create(ctx, challenge)
+	if err != nil {
+		log.Error().Err(err).Msg("failed inserting webauthn challenge")
+		return err
+	}
+
+	return h.Validate(c, http.StatusOK, &WebAuthnBeginResponse{ChallengeId: challenge.Id, Options: assertion})
+}
+
+type FinishWebAuthnLoginRequest struct {
+	ChallengeId string          `json:"challenge_id"`
+	Credential  json.RawMessage `json:"credential"`
+	DeviceName  string          `json:"device_name,omitempty"`
+}
+
+func (h *WebAuthnHandler) finishLogin(c echo.Context) error {
+	body := &FinishWebAuthnLoginRequest{}
+	if err := c.Bind(body); err != nil {
+		log.Error().Err(err).Msg("failed binding body")
+		return err
+	}
+
+	failed := echo.Map{"message": ErrWebAuthnLoginFailed.Error()}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), time.Second*10)
+	defer cancel()
+
+	challenge, err := h.useChallenge(ctx, body.ChallengeId, models.WebAuthnChallengeLogin)
+	if err != nil {
+		return h.readChallenge(c, err, http.StatusUnauthorized, failed)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(body.Credential))
+	if err != nil {
+		return h.Validate(c, http.StatusUnauthorized, failed)
+	}
+
+	userId := challenge.UserId
+	if userId == "" {
+		userId = string(parsed.Response.UserHandle)
+	}
+
+	if userId == "" {
+		return h.Validate(c, http.StatusUnauthorized, failed)
+	}
+
+	user, err := h.webAuthnUser(ctx, userId)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return h.Validate(c, http.StatusUnauthorized, failed)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting user")
+		return err
+	}
+
+	var validated *webauthn.Credential
+	if challenge.UserId != "" {
+		validated, err = h.webauthn.ValidateLogin(user, challenge.Session, parsed)
+	} else {
+		handler := func(rawID, userHandle []byte) (webauthn.User, error) { return user, nil }
+		validated, err = h.webauthn.ValidateDiscoverableLogin(handler, challenge.Session, parsed)
+	}
+	if err != nil {
+		return h.Validate(c, http.StatusUnauthorized, failed)
+	}
+
+	var credential *models.WebAuthnCredential
+	for i := range user.Credentials {
+		if bytes.Equal(user.Credentials[i].CredentialId, validated.ID) {
+			credential = &user.Credentials[i]
+			break
+		}
+	}
+
+	if credential == nil {
+		return h.Validate(c, http.StatusUnauthorized, failed)
+	}
+
+	if err = credential.Use(validated); err != nil {
+		log.Warn().Str("credential_id", credential.Id).Msg("webauthn sign count didn't increase, possible cloned authenticator")
+		return h.Validate(c, http.StatusUnauthorized, failed)
+	}
+
+	_, err = h.svc.Update(ctx, credential)
+	if err != nil {
+		log.Error().Err(err).Msg("failed updating webauthn credential")
+		return err
+	}
+
+	if emailVerificationRequired(user.User) {
+		return h.Validate(c, http.StatusForbidden, echo.Map{"message": ErrEmailNotVerified.Error()})
+	}
+
+	return createSession(ctx, c, h.Handler, h.userSvc, h.sessionSvc, user.User, body.DeviceName)
+}
+
+// useChallenge consumes the challenge before anything else so it can't be used twice.
+func (h *WebAuthnHandler) useChallenge(ctx context.Context, id string, kind string) (*models.WebAuthnChallenge, error) {
+	challenge, err := h.challengeSvc.Read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = challenge.Validate(kind); err != nil {
+		return nil, err
+	}
+
+	challenge.Use()
+	_, err = h.challengeSvc.Update(ctx, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+func (h *WebAuthnHandler) readChallenge(c echo.Context, err error, status int, body echo.Map) error {
+	var se *services.Error
+	if errors.As(err, &se) {
+		if se.Kind != services.NotExist {
+			log.Error().Err(err).Msg("failed getting webauthn challenge")
+			return err
+		}
+	}
+	return h.Validate(c, status, body)
+}
+
+func (h *WebAuthnHandler) webAuthnUser(ctx context.Context, id string) (*models.WebAuthnUser, error) {
+	user, err := h.userSvc.Read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := h.svc.Find(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WebAuthnUser{User: user, Credentials: credentials}, nil
+}
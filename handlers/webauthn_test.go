@@ -0,0 +1,528 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexferl/echo-openapi"
+	api "github.com/alexferl/golib/http/api/server"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+// softAuthenticator is a software authenticator signing with
+// an ES256 key, it's used in place of a browser and a security key.
+type softAuthenticator struct {
+	key        *ecdsa.PrivateKey
+	id         []byte
+	counter    uint32
+	userHandle []byte
+}
+
+func newSoftAuthenticator(userHandle string) *softAuthenticator {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return &softAuthenticator{key: key, id: id, userHandle: []byte(userHandle)}
+}
+
+func (a *softAuthenticator) authData(attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte(viper.GetString(config.WebAuthnRPID)))
+
+	flags := byte(0x01 | 0x04) // user present, user verified
+	if attested {
+		flags |= 0x40
+	}
+
+	b := append([]byte{}, rpIdHash[:]...)
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, a.counter)
+
+	if attested {
+		pub, _ := webauthncbor.Marshal(map[int]any{
+			1:  2,  // kty: EC2
+			3:  -7, // alg: ES256
+			-1: 1,  // crv: P-256
+			-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+			-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+		})
+		b = append(b, make([]byte, 16)...) // aaguid
+		b = binary.BigEndian.AppendUint16(b, uint16(len(a.id)))
+		b = append(b, a.id...)
+		b = append(b, pub...)
+	}
+
+	return b
+}
+
+func (a *softAuthenticator) clientData(typ string, challenge string) []byte {
+	b, _ := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": challenge,
+		"origin":    viper.GetStringSlice(config.WebAuthnRPOrigins)[0],
+	})
+	return b
+}
+
+func (a *softAuthenticator) create(challenge string) json.RawMessage {
+	attestation, _ := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(true),
+	})
+
+	enc := base64.RawURLEncoding
+	b, _ := json.Marshal(map[string]any{
+		"id":    enc.EncodeToString(a.id),
+		"rawId": enc.EncodeToString(a.id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    enc.EncodeToString(a.clientData("webauthn.create", challenge)),
+			"attestationObject": enc.EncodeToString(attestation),
+		},
+	})
+	return b
+}
+
+func (a *softAuthenticator) get(challenge string) json.RawMessage {
+	a.counter++
+	authData := a.authData(false)
+	clientData := a.clientData("webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	sig, _ := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+
+	enc := base64.RawURLEncoding
+	b, _ := json.Marshal(map[string]any{
+		"id":    enc.EncodeToString(a.id),
+		"rawId": enc.EncodeToString(a.id),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    enc.EncodeToString(clientData),
+			"authenticatorData": enc.EncodeToString(authData),
+			"signature":         enc.EncodeToString(sig),
+			"userHandle":        enc.EncodeToString(a.userHandle),
+		},
+	})
+	return b
+}
+
+type WebAuthnHandlerTestSuite struct {
+	suite.Suite
+	svc          *handlers.MockWebAuthnCredentialService
+	challengeSvc *handlers.MockWebAuthnChallengeService
+	userSvc      *handlers.MockUserService
+	sessionSvc   *handlers.MockSessionService
+	server       *api.Server
+	user         *models.User
+	accessToken  []byte
+}
+
+func (s *WebAuthnHandlerTestSuite) SetupTest() {
+	svc := handlers.NewMockWebAuthnCredentialService(s.T())
+	challengeSvc := handlers.NewMockWebAuthnChallengeService(s.T())
+	userSvc := handlers.NewMockUserService(s.T())
+	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
+	sessionSvc := handlers.NewMockSessionService(s.T())
+	h := handlers.NewWebAuthnHandler(openapi.NewHandler(), svc, challengeSvc, userSvc, sessionSvc)
+	user := getUser()
+	access, _, _ := user.Login(models.NewSession(user.Id))
+
+	s.svc = svc
+	s.challengeSvc = challengeSvc
+	s.userSvc = userSvc
+	s.sessionSvc = sessionSvc
+	s.server = getServer(userSvc, patSvc, h)
+	s.user = user
+	s.accessToken = access
+}
+
+func TestWebAuthnHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(WebAuthnHandlerTestSuite))
+}
+
+// register runs a registration ceremony for a and returns the stored credential.
+func (s *WebAuthnHandlerTestSuite) register(a *softAuthenticator) *models.WebAuthnCredential {
+	s.userSvc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.WebAuthnCredentials{}, nil).Twice()
+
+	var challenge *models.WebAuthnChallenge
+	s.challengeSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.WebAuthnChallenge) { challenge = model }).
+		Return(nil, nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/me/webauthn/credentials/begin", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var begin handlers.WebAuthnBeginResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &begin)
+
+	s.Require().Equal(http.StatusOK, resp.Code)
+	s.Require().NotNil(challenge)
+	s.Require().Equal(challenge.Id, begin.ChallengeId)
+	s.Require().Equal(models.WebAuthnChallengeRegistration, challenge.Kind)
+
+	s.challengeSvc.EXPECT().
+		Read(mock.Anything, challenge.Id).
+		Return(challenge, nil).Once()
+
+	s.challengeSvc.EXPECT().
+		Update(mock.Anything, challenge).
+		Return(challenge, nil).Once()
+
+	var credential *models.WebAuthnCredential
+	s.svc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.WebAuthnCredential) { credential = model }).
+		RunAndReturn(func(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+			return model, nil
+		}).Once()
+
+	b, _ := json.Marshal(&handlers.FinishWebAuthnRegistrationRequest{
+		ChallengeId: challenge.Id,
+		Credential:  a.create(challenge.Session.Challenge),
+		Name:        "My Phone",
+	})
+
+	req = httptest.NewRequest(http.MethodPost, "/me/webauthn/credentials/finish", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp = httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.WebAuthnCredentialResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Require().Equal(http.StatusOK, resp.Code)
+	s.Require().NotNil(credential)
+	s.Require().NotNil(challenge.UsedAt)
+	s.Require().Equal(credential.Id, result.Id)
+	s.Require().Equal("My Phone", result.Name)
+	s.Require().Equal(a.id, credential.CredentialId)
+
+	return credential
+}
+
+// beginLogin runs the first step of a discoverable login and returns its challenge.
+func (s *WebAuthnHandlerTestSuite) beginLogin() *models.WebAuthnChallenge {
+	var challenge *models.WebAuthnChallenge
+	s.challengeSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.WebAuthnChallenge) { challenge = model }).
+		Return(nil, nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/webauthn/login/begin", bytes.NewBuffer([]byte(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Require().Equal(http.StatusOK, resp.Code)
+	s.Require().NotNil(challenge)
+	s.Require().Equal(models.WebAuthnChallengeLogin, challenge.Kind)
+	s.Require().Equal("", challenge.UserId)
+
+	return challenge
+}
+
+func (s *WebAuthnHandlerTestSuite) finishLogin(challenge *models.WebAuthnChallenge, credential json.RawMessage) *httptest.ResponseRecorder {
+	b, _ := json.Marshal(&handlers.FinishWebAuthnLoginRequest{
+		ChallengeId: challenge.Id,
+		Credential:  credential,
+		DeviceName:  "My Laptop",
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/webauthn/login/finish", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	return resp
+}
+
+func (s *WebAuthnHandlerTestSuite) TestWebAuthnHandler_Register_And_Login_200() {
+	a := newSoftAuthenticator(s.user.Id)
+	credential := s.register(a)
+
+	challenge := s.beginLogin()
+
+	s.challengeSvc.EXPECT().
+		Read(mock.Anything, challenge.Id).
+		Return(challenge, nil).Once()
+
+	s.challengeSvc.EXPECT().
+		Update(mock.Anything, challenge).
+		Return(challenge, nil).Once()
+
+	s.svc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.WebAuthnCredentials{*credential}, nil).Once()
+
+	var used *models.WebAuthnCredential
+	s.svc.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.WebAuthnCredential) { used = model }).
+		Return(nil, nil).Once()
+
+	var session *models.Session
+	s.sessionSvc.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, model *models.Session) { session = model }).
+		Return(nil, nil).Once()
+
+	s.userSvc.EXPECT().
+		Update(mock.Anything, "", s.user).
+		Return(s.user, nil).Once()
+
+	resp := s.finishLogin(challenge, a.get(challenge.Session.Challenge))
+
+	var result handlers.LoginResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotEqual("", result.AccessToken)
+	s.Assert().NotEqual("", result.RefreshToken)
+	s.Assert().NotEmpty(resp.Result().Cookies())
+	if s.Assert().NotNil(used) && s.Assert().NotNil(session) {
+		s.Assert().Equal(uint32(1), used.SignCount)
+		s.Assert().NotNil(used.LastUsedAt)
+		s.Assert().Equal("My Laptop", session.DeviceName)
+		s.Assert().Equal(s.user.Id, session.UserId)
+	}
+}
+
+func (s *WebAuthnHandlerTestSuite) TestWebAuthnHandler_Login_401_Cloned() {
+	a := newSoftAuthenticator(s.user.Id)
+	credential := s.register(a)
+	// another authenticator with the same key already went further
+	credential.SignCount = 5
+
+	challenge := s.beginLogin()
+
+	s.challengeSvc.EXPECT().
+		Read(mock.Anything, challenge.Id).
+		Return(challenge, nil).Once()
+
+	s.challengeSvc.EXPECT().
+		Update(mock.Anything, challenge).
+		Return(challenge, nil).Once()
+
+	s.svc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.WebAuthnCredentials{*credential}, nil).Once()
+
+	resp := s.finishLogin(challenge, a.get(challenge.Session.Challenge))
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Equal(handlers.ErrWebAuthnLoginFailed.Error(), result.Message)
+}
+
+func (s *WebAuthnHandlerTestSuite) TestWebAuthnHandler_Login_401_Wrong_Key() {
+	a := newSoftAuthenticator(s.user.Id)
+	credential := s.register(a)
+
+	// same credential id, different private key
+	impostor := newSoftAuthenticator(s.user.Id)
+	impostor.id = a.id
+
+	challenge := s.beginLogin()
+
+	s.challengeSvc.EXPECT().
+		Read(mock.Anything, challenge.Id).
+		Return(challenge, nil).Once()
+
+	s.challengeSvc.EXPECT().
+		Update(mock.Anything, challenge).
+		Return(challenge, nil).Once()
+
+	s.svc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.WebAuthnCredentials{*credential}, nil).Once()
+
+	resp := s.finishLogin(challenge, impostor.get(challenge.Session.Challenge))
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+}
+
+func (s *WebAuthnHandlerTestSuite) TestWebAuthnHandler_Login_401_Challenge() {
+	a := newSoftAuthenticator(s.user.Id)
+
+	used := s.beginLogin()
+	used.Use()
+
+	registration := s.beginLogin()
+	registration.Kind = models.WebAuthnChallengeRegistration
+
+	testCases := []struct {
+		name      string
+		challenge *models.WebAuthnChallenge
+		err       error
+	}{
+		{"not found", used, &services.Error{Kind: services.NotExist}},
+		{"used", used, nil},
+		{"wrong kind", registration, nil},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			if tc.err != nil {
+				s.challengeSvc.EXPECT().
+					Read(mock.Anything, mock.Anything).
+					Return(nil, tc.err).Once()
+			} else {
+				s.challengeSvc.EXPECT().
+					Read(mock.Anything, mock.Anything).
+					Return(tc.challenge, nil).Once()
+			}
+
+			resp := s.finishLogin(tc.challenge, a.get(tc.challenge.Session.Challenge))
+
+			s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+		})
+	}
+}
+
+func (s *WebAuthnHandlerTestSuite) TestWebAuthnHandler_Register_400_Other_User() {
+	a := newSoftAuthenticator(s.user.Id)
+	challenge := s.beginLogin()
+	challenge.Kind = models.WebAuthnChallengeRegistration
+	challenge.UserId = "2000"
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.challengeSvc.EXPECT().
+		Read(mock.Anything, challenge.Id).
+		Return(challenge, nil).Once()
+
+	s.challengeSvc.EXPECT().
+		Update(mock.Anything, challenge).
+		Return(challenge, nil).Once()
+
+	b, _ := json.Marshal(&handlers.FinishWebAuthnRegistrationRequest{
+		ChallengeId: challenge.Id,
+		Credential:  a.create(challenge.Session.Challenge),
+		Name:        "My Phone",
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/me/webauthn/credentials/finish", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusBadRequest, resp.Code)
+	s.Assert().Equal(handlers.ErrWebAuthnCredentialInvalid.Error(), result.Message)
+}
+
+func (s *WebAuthnHandlerTestSuite) TestWebAuthnHandler_List_200() {
+	a := newSoftAuthenticator(s.user.Id)
+	credential := s.register(a)
+
+	s.svc.EXPECT().
+		Find(mock.Anything, s.user.Id).
+		Return(models.WebAuthnCredentials{*credential}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/me/webauthn/credentials", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result models.WebAuthnCredentialsResponse
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	if s.Assert().Len(result.Credentials, 1) {
+		s.Assert().Equal(credential.Id, result.Credentials[0].Id)
+	}
+}
+
+func (s *WebAuthnHandlerTestSuite) TestWebAuthnHandler_Delete_204() {
+	credential := models.NewWebAuthnCredential(s.user.Id, "My Phone", &webauthn.Credential{ID: []byte("123")})
+
+	s.userSvc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id, credential.Id).
+		Return(credential, nil)
+
+	s.svc.EXPECT().
+		Delete(mock.Anything, credential).
+		Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/me/webauthn/credentials/"+credential.Id, nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusNoContent, resp.Code)
+}
+
+func (s *WebAuthnHandlerTestSuite) TestWebAuthnHandler_Delete_404() {
+	s.userSvc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id, "123").
+		Return(nil, &services.Error{
+			Kind:    services.NotExist,
+			Message: services.ErrWebAuthnCredentialNotFound.Error(),
+		})
+
+	req := httptest.NewRequest(http.MethodDelete, "/me/webauthn/credentials/123", nil)
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusNotFound, resp.Code)
+	s.Assert().Equal(services.ErrWebAuthnCredentialNotFound.Error(), result.Message)
+}
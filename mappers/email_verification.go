@@ -0,0 +1,63 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// EmailVerification represents the mapper used for interacting with EmailVerification documents.
+type EmailVerification struct {
+	mapper data.Mapper
+}
+
+func NewEmailVerification(client *mongo.Client) *EmailVerification {
+	return &EmailVerification{data.NewMapper(client, viper.GetString(config.AppName), "email_verifications")}
+}
+
+func (e *EmailVerification) Create(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error) {
+	filter := bson.D{{"id", model.Id}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := e.mapper.FindOneAndUpdate(ctx, filter, model, &models.EmailVerification{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.EmailVerification), nil
+}
+
+func (e *EmailVerification) FindOne(ctx context.Context, filter any) (*models.EmailVerification, error) {
+	res, err := e.mapper.FindOne(ctx, filter, &models.EmailVerification{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.EmailVerification), nil
+}
+
+func (e *EmailVerification) Update(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error) {
+	filter := bson.D{{"id", model.Id}}
+	res, err := e.mapper.FindOneAndUpdate(ctx, filter, model, &models.EmailVerification{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.EmailVerification), nil
+}
+
+// UpdateOne applies update to the document matching filter and returns how many matched.
+func (e *EmailVerification) UpdateOne(ctx context.Context, filter any, update any) (int64, error) {
+	res, err := e.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.MatchedCount, nil
+}
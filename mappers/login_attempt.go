@@ -0,0 +1,48 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// LoginAttempt represents the mapper used for interacting with LoginAttempt documents.
+type LoginAttempt struct {
+	mapper data.Mapper
+}
+
+func NewLoginAttempt(client *mongo.Client) *LoginAttempt {
+	return &LoginAttempt{data.NewMapper(client, viper.GetString(config.AppName), "login_attempts")}
+}
+
+func (l *LoginAttempt) FindOne(ctx context.Context, filter any) (*models.LoginAttempt, error) {
+	res, err := l.mapper.FindOne(ctx, filter, &models.LoginAttempt{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.LoginAttempt), nil
+}
+
+// Upsert applies update to the document matching filter, creating it if there's none,
+// and returns the document as updated.
+func (l *LoginAttempt) Upsert(ctx context.Context, filter any, update any) (*models.LoginAttempt, error) {
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := l.mapper.FindOneAndModify(ctx, filter, update, &models.LoginAttempt{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.LoginAttempt), nil
+}
+
+func (l *LoginAttempt) UpdateOne(ctx context.Context, filter any, update any) error {
+	_, err := l.mapper.UpdateOne(ctx, filter, update)
+	return err
+}
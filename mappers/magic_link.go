@@ -0,0 +1,67 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// MagicLink represents the mapper used for interacting with MagicLink documents.
+type MagicLink struct {
+	mapper data.Mapper
+}
+
+func NewMagicLink(client *mongo.Client) *MagicLink {
+	return &MagicLink{data.NewMapper(client, viper.GetString(config.AppName), "magic_links")}
+}
+
+func (m *MagicLink) Create(ctx context.Context, model *models.MagicLink) (*models.MagicLink, error) {
+	filter := bson.D{{"id", model.Id}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := m.mapper.FindOneAndUpdate(ctx, filter, model, &models.MagicLink{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.MagicLink), nil
+}
+
+func (m *MagicLink) Count(ctx context.Context, filter any) (int64, error) {
+	return m.mapper.Count(ctx, filter)
+}
+
+func (m *MagicLink) FindOne(ctx context.Context, filter any) (*models.MagicLink, error) {
+	res, err := m.mapper.FindOne(ctx, filter, &models.MagicLink{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.MagicLink), nil
+}
+
+func (m *MagicLink) Update(ctx context.Context, model *models.MagicLink) (*models.MagicLink, error) {
+	filter := bson.D{{"id", model.Id}}
+	res, err := m.mapper.FindOneAndUpdate(ctx, filter, model, &models.MagicLink{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.MagicLink), nil
+}
+
+// UpdateOne applies update to the document matching filter and returns how many matched.
+func (m *MagicLink) UpdateOne(ctx context.Context, filter any, update any) (int64, error) {
+	res, err := m.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.MatchedCount, nil
+}
@@ -0,0 +1,67 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// PasswordReset represents the mapper used for interacting with PasswordReset documents.
+type PasswordReset struct {
+	mapper data.Mapper
+}
+
+func NewPasswordReset(client *mongo.Client) *PasswordReset {
+	return &PasswordReset{data.NewMapper(client, viper.GetString(config.AppName), "password_resets")}
+}
+
+func (p *PasswordReset) Create(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error) {
+	filter := bson.D{{"id", model.Id}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := p.mapper.FindOneAndUpdate(ctx, filter, model, &models.PasswordReset{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.PasswordReset), nil
+}
+
+func (p *PasswordReset) Count(ctx context.Context, filter any) (int64, error) {
+	return p.mapper.Count(ctx, filter)
+}
+
+func (p *PasswordReset) FindOne(ctx context.Context, filter any) (*models.PasswordReset, error) {
+	res, err := p.mapper.FindOne(ctx, filter, &models.PasswordReset{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.PasswordReset), nil
+}
+
+func (p *PasswordReset) Update(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error) {
+	filter := bson.D{{"id", model.Id}}
+	res, err := p.mapper.FindOneAndUpdate(ctx, filter, model, &models.PasswordReset{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.PasswordReset), nil
+}
+
+// UpdateOne applies update to the document matching filter and returns how many matched.
+func (p *PasswordReset) UpdateOne(ctx context.Context, filter any, update any) (int64, error) {
+	res, err := p.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.MatchedCount, nil
+}
@@ -33,8 +33,21 @@ func (p PersonalAccessToken) Create(ctx context.Context, model *models.PersonalA
 	return res.(*models.PersonalAccessToken), nil
 }
 
-func (p PersonalAccessToken) Find(ctx context.Context, filter any) (models.PersonalAccessTokens, error) {
-	res, err := p.mapper.Find(ctx, filter, models.PersonalAccessTokens{})
+func (p PersonalAccessToken) Count(ctx context.Context, filter any) (int64, error) {
+	return p.mapper.Count(ctx, filter)
+}
+
+func (p PersonalAccessToken) DeleteMany(ctx context.Context, filter any) (int64, error) {
+	res, err := p.mapper.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+func (p PersonalAccessToken) Find(ctx context.Context, filter any, opts ...*options.FindOptions) (models.PersonalAccessTokens, error) {
+	res, err := p.mapper.Find(ctx, filter, models.PersonalAccessTokens{}, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -60,3 +73,42 @@ func (p PersonalAccessToken) Update(ctx context.Context, model *models.PersonalA
 
 	return res.(*models.PersonalAccessToken), nil
 }
+
+func (p PersonalAccessToken) UpdateMany(ctx context.Context, filter any, update any) (int64, error) {
+	res, err := p.mapper.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.ModifiedCount, nil
+}
+
+// UpdateUsage only sets the last use fields of model so it can't undo
+// a revocation that happened since model was read.
+func (p PersonalAccessToken) UpdateUsage(ctx context.Context, model *models.PersonalAccessToken) error {
+	filter := bson.D{{"id", model.Id}}
+	update := bson.D{{"$set", bson.D{
+		{"last_used_at", model.LastUsedAt},
+		{"last_used_ip", model.LastUsedIP},
+		{"last_used_user_agent", model.LastUsedUserAgent},
+	}}}
+	_, err := p.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateToken only sets the token hash of model so it can't undo
+// a revocation that happened since model was read.
+func (p PersonalAccessToken) UpdateToken(ctx context.Context, model *models.PersonalAccessToken) error {
+	filter := bson.D{{"id", model.Id}}
+	update := bson.D{{"$set", bson.D{{"token", model.Token}}}}
+	_, err := p.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
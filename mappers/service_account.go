@@ -0,0 +1,81 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// ServiceAccount represents the mapper used for interacting with ServiceAccount documents.
+type ServiceAccount struct {
+	mapper data.Mapper
+}
+
+func NewServiceAccount(client *mongo.Client) *ServiceAccount {
+	return &ServiceAccount{data.NewMapper(client, viper.GetString(config.AppName), "service_accounts")}
+}
+
+func (s *ServiceAccount) Create(ctx context.Context, model *models.ServiceAccount) (*models.ServiceAccount, error) {
+	filter := bson.D{{"id", model.Id}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := s.mapper.FindOneAndUpdate(ctx, filter, model, &models.ServiceAccount{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.ServiceAccount), nil
+}
+
+func (s *ServiceAccount) Find(ctx context.Context, filter any, limit int, skip int) (int64, models.ServiceAccounts, error) {
+	count, err := s.mapper.Count(ctx, filter)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	opts := options.Find().SetLimit(int64(limit)).SetSkip(int64(skip))
+	res, err := s.mapper.Find(ctx, filter, models.ServiceAccounts{}, opts)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return count, res.(models.ServiceAccounts), nil
+}
+
+func (s *ServiceAccount) FindOne(ctx context.Context, filter any) (*models.ServiceAccount, error) {
+	res, err := s.mapper.FindOne(ctx, filter, &models.ServiceAccount{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.ServiceAccount), nil
+}
+
+func (s *ServiceAccount) Update(ctx context.Context, model *models.ServiceAccount) (*models.ServiceAccount, error) {
+	filter := bson.D{{"id", model.Id}}
+	res, err := s.mapper.FindOneAndUpdate(ctx, filter, model, &models.ServiceAccount{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.ServiceAccount), nil
+}
+
+// UpdateUsage only sets the last use of cred so it can't bring back
+// credentials removed since model was read.
+func (s *ServiceAccount) UpdateUsage(ctx context.Context, model *models.ServiceAccount, cred *models.ServiceAccountCredential) error {
+	filter := bson.D{{"id", model.Id}, {"credentials.client_id", cred.ClientId}}
+	update := bson.D{{"$set", bson.D{{"credentials.$.last_used_at", cred.LastUsedAt}}}}
+	_, err := s.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
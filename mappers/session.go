@@ -0,0 +1,72 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// Session represents the mapper used for interacting with Session documents.
+type Session struct {
+	mapper data.Mapper
+}
+
+func NewSession(client *mongo.Client) *Session {
+	return &Session{data.NewMapper(client, viper.GetString(config.AppName), "sessions")}
+}
+
+func (s *Session) Create(ctx context.Context, model *models.Session) (*models.Session, error) {
+	filter := bson.D{{"id", model.Id}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := s.mapper.FindOneAndUpdate(ctx, filter, model, &models.Session{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.Session), nil
+}
+
+func (s *Session) Find(ctx context.Context, filter any) (models.Sessions, error) {
+	res, err := s.mapper.Find(ctx, filter, models.Sessions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(models.Sessions), nil
+}
+
+func (s *Session) FindOne(ctx context.Context, filter any) (*models.Session, error) {
+	res, err := s.mapper.FindOne(ctx, filter, &models.Session{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.Session), nil
+}
+
+func (s *Session) Update(ctx context.Context, model *models.Session) (*models.Session, error) {
+	filter := bson.D{{"id", model.Id}}
+	res, err := s.mapper.FindOneAndUpdate(ctx, filter, model, &models.Session{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.Session), nil
+}
+
+// UpdateIf updates model only if its document also matches filter.
+func (s *Session) UpdateIf(ctx context.Context, filter any, model *models.Session) (*models.Session, error) {
+	res, err := s.mapper.FindOneAndUpdate(ctx, filter, model, &models.Session{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.Session), nil
+}
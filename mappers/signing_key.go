@@ -0,0 +1,53 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// SigningKey represents the mapper used for interacting with SigningKey documents.
+type SigningKey struct {
+	mapper data.Mapper
+}
+
+func NewSigningKey(client *mongo.Client) *SigningKey {
+	return &SigningKey{data.NewMapper(client, viper.GetString(config.AppName), "signing_keys")}
+}
+
+func (s *SigningKey) Create(ctx context.Context, model *models.SigningKey) (*models.SigningKey, error) {
+	filter := bson.D{{"id", model.Id}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := s.mapper.FindOneAndUpdate(ctx, filter, model, &models.SigningKey{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.SigningKey), nil
+}
+
+func (s *SigningKey) Find(ctx context.Context, filter any) (models.SigningKeys, error) {
+	res, err := s.mapper.Find(ctx, filter, models.SigningKeys{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(models.SigningKeys), nil
+}
+
+func (s *SigningKey) Update(ctx context.Context, model *models.SigningKey) (*models.SigningKey, error) {
+	filter := bson.D{{"id", model.Id}}
+	res, err := s.mapper.FindOneAndUpdate(ctx, filter, model, &models.SigningKey{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.SigningKey), nil
+}
@@ -104,41 +104,43 @@ func (t *Task) getPipeline(filter any, limit int, skip int) mongo.Pipeline {
 		filter = bson.D{}
 	}
 
+	pipeline := mongo.Pipeline{{{"$match", filter}}}
+	pipeline = append(pipeline, lookupActor("created_by", false)...)
+	pipeline = append(pipeline, lookupActor("updated_by", true)...)
+	pipeline = append(pipeline, lookupActor("completed_by", true)...)
+
+	return append(pipeline, mongo.Pipeline{
+		{{"$sort", bson.D{{"_id", -1}}}},
+		{{"$limit", skip + limit}},
+		{{"$skip", skip}},
+	}...)
+}
+
+// lookupActor replaces the reference in field with the user or the
+// service account it refers to, optional references may be missing.
+func lookupActor(field string, optional bool) mongo.Pipeline {
+	sa := field + "_service_account"
+
 	return mongo.Pipeline{
-		{{"$match", filter}},
 		{{"$lookup", bson.M{
-			"from":         "users",
-			"localField":   "created_by.id",
+			"from":         "service_accounts",
+			"localField":   field + ".id",
 			"foreignField": "id",
-			"as":           "created_by",
+			"as":           sa,
 		}}},
-		{{"$unwind", "$created_by"}},
 		{{"$lookup", bson.M{
 			"from":         "users",
-			"localField":   "updated_by.id",
+			"localField":   field + ".id",
 			"foreignField": "id",
-			"as":           "updated_by",
+			"as":           field,
 		}}},
+		{{"$set", bson.M{field: bson.M{"$concatArrays": bson.A{"$" + field, "$" + sa}}}}},
+		{{"$unset", sa}},
 		{{
 			"$unwind", bson.D{
-				{"path", "$updated_by"},
-				{"preserveNullAndEmptyArrays", true},
+				{"path", "$" + field},
+				{"preserveNullAndEmptyArrays", optional},
 			},
 		}},
-		{{"$lookup", bson.M{
-			"from":         "users",
-			"localField":   "completed_by.id",
-			"foreignField": "id",
-			"as":           "completed_by",
-		}}},
-		{{
-			"$unwind", bson.D{
-				{"path", "$completed_by"},
-				{"preserveNullAndEmptyArrays", true},
-			},
-		}},
-		{{"$sort", bson.D{{"_id", -1}}}},
-		{{"$limit", skip + limit}},
-		{{"$skip", skip}},
 	}
 }
@@ -57,6 +57,18 @@ func (u *User) FindOne(ctx context.Context, filter any) (*models.User, error) {
 	return res.(*models.User), nil
 }
 
+// FindOneByEmailOrUsername is FindOne with the collation of the unique indexes on
+// email and username, filter should only match on those for the indexes to be used.
+func (u *User) FindOneByEmailOrUsername(ctx context.Context, filter any) (*models.User, error) {
+	opts := options.FindOne().SetCollation(&options.Collation{Locale: "en", Strength: 2})
+	res, err := u.mapper.FindOne(ctx, filter, &models.User{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.User), nil
+}
+
 func (u *User) Update(ctx context.Context, model *models.User) (*models.User, error) {
 	filter := bson.D{{"id", model.Id}}
 	res, err := u.mapper.FindOneAndUpdate(ctx, filter, model, &models.User{})
@@ -66,3 +78,18 @@ func (u *User) Update(ctx context.Context, model *models.User) (*models.User, er
 
 	return res.(*models.User), nil
 }
+
+// UpdateOne applies update to the document matching filter and returns how many matched.
+func (u *User) UpdateOne(ctx context.Context, filter any, update any) (int64, error) {
+	res, err := u.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.MatchedCount, nil
+}
+
+// WithTransaction runs fn in a transaction, see data.Mapper.
+func (u *User) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return u.mapper.WithTransaction(ctx, fn)
+}
@@ -0,0 +1,53 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// WebAuthnChallenge represents the mapper used for interacting with WebAuthnChallenge documents.
+type WebAuthnChallenge struct {
+	mapper data.Mapper
+}
+
+func NewWebAuthnChallenge(client *mongo.Client) *WebAuthnChallenge {
+	return &WebAuthnChallenge{data.NewMapper(client, viper.GetString(config.AppName), "webauthn_challenges")}
+}
+
+func (w *WebAuthnChallenge) Create(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error) {
+	filter := bson.D{{"id", model.Id}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := w.mapper.FindOneAndUpdate(ctx, filter, model, &models.WebAuthnChallenge{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.WebAuthnChallenge), nil
+}
+
+func (w *WebAuthnChallenge) FindOne(ctx context.Context, filter any) (*models.WebAuthnChallenge, error) {
+	res, err := w.mapper.FindOne(ctx, filter, &models.WebAuthnChallenge{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.WebAuthnChallenge), nil
+}
+
+func (w *WebAuthnChallenge) Update(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error) {
+	filter := bson.D{{"id", model.Id}}
+	res, err := w.mapper.FindOneAndUpdate(ctx, filter, model, &models.WebAuthnChallenge{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.WebAuthnChallenge), nil
+}
@@ -0,0 +1,62 @@
+package mappers
+
+import (
+	"context"
+
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// WebAuthnCredential represents the mapper used for interacting with WebAuthnCredential documents.
+type WebAuthnCredential struct {
+	mapper data.Mapper
+}
+
+func NewWebAuthnCredential(client *mongo.Client) *WebAuthnCredential {
+	return &WebAuthnCredential{data.NewMapper(client, viper.GetString(config.AppName), "webauthn_credentials")}
+}
+
+func (w *WebAuthnCredential) Create(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+	filter := bson.D{{"id", model.Id}}
+	opts := options.FindOneAndUpdate().SetUpsert(true)
+	res, err := w.mapper.FindOneAndUpdate(ctx, filter, model, &models.WebAuthnCredential{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.WebAuthnCredential), nil
+}
+
+func (w *WebAuthnCredential) Find(ctx context.Context, filter any) (models.WebAuthnCredentials, error) {
+	res, err := w.mapper.Find(ctx, filter, models.WebAuthnCredentials{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(models.WebAuthnCredentials), nil
+}
+
+func (w *WebAuthnCredential) FindOne(ctx context.Context, filter any) (*models.WebAuthnCredential, error) {
+	res, err := w.mapper.FindOne(ctx, filter, &models.WebAuthnCredential{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.WebAuthnCredential), nil
+}
+
+func (w *WebAuthnCredential) Update(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+	filter := bson.D{{"id", model.Id}}
+	res, err := w.mapper.FindOneAndUpdate(ctx, filter, model, &models.WebAuthnCredential{})
+	if err != nil {
+		return nil, err
+	}
+
+	return res.(*models.WebAuthnCredential), nil
+}
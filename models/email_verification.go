@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/password"
+	"github.com/alexferl/echo-boilerplate/util/rand"
+)
+
+var (
+	ErrEmailVerificationExpired  = errors.New("email verification token expired")
+	ErrEmailVerificationInvalid  = errors.New("email verification token invalid")
+	ErrEmailVerificationMismatch = errors.New("email verification token is for another email")
+	ErrEmailVerificationUsed     = errors.New("email verification token already used")
+)
+
+// EmailVerification is a single-use token proving the ownership of Email.
+// The token is prefixed by the id so it can be looked up, only its hash is stored.
+type EmailVerification struct {
+	Id        string     `bson:"id"`
+	CreatedAt *time.Time `bson:"created_at"`
+	Email     string     `bson:"email"`
+	ExpiresAt *time.Time `bson:"expires_at"`
+	Token     string     `bson:"token"`
+	UsedAt    *time.Time `bson:"used_at"`
+	UserId    string     `bson:"user_id"`
+}
+
+func NewEmailVerification(userId string, email string) (*EmailVerification, error) {
+	s, err := rand.GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	id := xid.New().String()
+	now := time.Now()
+	expiresAt := now.Add(viper.GetDuration(config.EmailVerificationTokenExpiry))
+
+	return &EmailVerification{
+		Id:        id,
+		CreatedAt: &now,
+		Email:     email,
+		ExpiresAt: &expiresAt,
+		Token:     fmt.Sprintf("%s.%s", id, s),
+		UserId:    userId,
+	}, nil
+}
+
+// ParseEmailVerificationToken returns the id of the EmailVerification the token belongs to.
+func ParseEmailVerificationToken(token string) (string, error) {
+	id, _, found := strings.Cut(token, ".")
+	if !found || id == "" {
+		return "", ErrEmailVerificationInvalid
+	}
+
+	return id, nil
+}
+
+func (ev *EmailVerification) Encrypt() error {
+	b, err := password.Hash([]byte(ev.Token))
+	if err != nil {
+		return err
+	}
+
+	ev.Token = b
+
+	return nil
+}
+
+func (ev *EmailVerification) Validate(token string) error {
+	if ev.UsedAt != nil {
+		return ErrEmailVerificationUsed
+	}
+
+	if time.Now().After(*ev.ExpiresAt) {
+		return ErrEmailVerificationExpired
+	}
+
+	if err := password.Verify([]byte(ev.Token), []byte(token)); err != nil {
+		return ErrEmailVerificationInvalid
+	}
+
+	return nil
+}
+
+// Verify marks the token as used and the email of user as verified, the
+// pending email of user replaces the current one when it's the one verified.
+// It fails if user changed their email since the token was sent.
+func (ev *EmailVerification) Verify(user *User) error {
+	switch {
+	case user.Email == ev.Email:
+	case user.PendingEmail != "" && user.PendingEmail == ev.Email:
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+	default:
+		return ErrEmailVerificationMismatch
+	}
+
+	ev.Use()
+	user.VerifyEmail()
+
+	return nil
+}
+
+func (ev *EmailVerification) Use() {
+	t := time.Now()
+	ev.UsedAt = &t
+}
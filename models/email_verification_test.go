@@ -0,0 +1,73 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerification(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+
+	ev, err := NewEmailVerification(user.Id, user.Email)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, ev.UserId)
+	assert.Equal(t, user.Email, ev.Email)
+	assert.True(t, ev.ExpiresAt.After(time.Now()))
+
+	token := ev.Token
+	id, err := ParseEmailVerificationToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, ev.Id, id)
+
+	err = ev.Encrypt()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, ev.Token)
+
+	assert.NoError(t, ev.Validate(token))
+	assert.ErrorIs(t, ev.Validate("wrong"), ErrEmailVerificationInvalid)
+
+	assert.False(t, user.IsEmailVerified())
+	assert.NoError(t, ev.Verify(user))
+	assert.True(t, user.IsEmailVerified())
+	assert.ErrorIs(t, ev.Validate(token), ErrEmailVerificationUsed)
+}
+
+func TestEmailVerification_Expired(t *testing.T) {
+	ev, err := NewEmailVerification("1", "test@email.com")
+	assert.NoError(t, err)
+
+	token := ev.Token
+	assert.NoError(t, ev.Encrypt())
+
+	past := time.Now().Add(-time.Minute)
+	ev.ExpiresAt = &past
+	assert.ErrorIs(t, ev.Validate(token), ErrEmailVerificationExpired)
+}
+
+func TestEmailVerification_Mismatch(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+
+	ev, err := NewEmailVerification(user.Id, user.Email)
+	assert.NoError(t, err)
+
+	user.SetEmail("new@email.com")
+	assert.ErrorIs(t, ev.Verify(user), ErrEmailVerificationMismatch)
+	assert.Nil(t, ev.UsedAt)
+	assert.False(t, user.IsEmailVerified())
+}
+
+func TestEmailVerification_PendingEmail(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+	user.VerifyEmail()
+	user.PendingEmail = "new@email.com"
+
+	ev, err := NewEmailVerification(user.Id, user.PendingEmail)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ev.Verify(user))
+	assert.Equal(t, "new@email.com", user.Email)
+	assert.Empty(t, user.PendingEmail)
+	assert.True(t, user.IsEmailVerified())
+}
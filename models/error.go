@@ -6,6 +6,8 @@ import "fmt"
 type Error struct {
 	Kind    Kind
 	Message string
+	// Errors lists everything wrong with an Invalid value when there's more than the Message.
+	Errors []string
 }
 
 // Kind defines supported error types.
@@ -15,10 +17,11 @@ const (
 	Other Kind = iota + 1 // Unclassified error.
 	Conflict
 	Permission
+	Invalid
 )
 
 func (k Kind) String() string {
-	return [...]string{"other", "conflict", "permission"}[k-1]
+	return [...]string{"other", "conflict", "permission", "invalid"}[k-1]
 }
 
 // NewError instantiates a new error.
@@ -30,6 +33,16 @@ func NewError(err error, kind Kind) error {
 	return e
 }
 
+// NewValidationError instantiates a new Invalid error listing errs.
+func NewValidationError(err error, errs []string) error {
+	e := &Error{
+		Kind:    Invalid,
+		Message: err.Error(),
+		Errors:  errs,
+	}
+	return e
+}
+
 // Error returns the message.
 func (e *Error) Error() string {
 	return fmt.Sprintf("kind=%s, message=%v", e.Kind, e.Message)
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrIdentityExist     = errors.New("another account of this provider is already linked")
+	ErrIdentityLastLogin = errors.New("cannot unlink the last way to log in")
+)
+
+// Identity is an account of the user at an identity provider, users
+// logging in with the provider are matched on its subject.
+type Identity struct {
+	Email    string     `bson:"email"`
+	LinkedAt *time.Time `bson:"linked_at"`
+	Provider string     `bson:"provider"`
+	Subject  string     `bson:"subject"`
+}
+
+type IdentityResponse struct {
+	Email    string     `json:"email"`
+	LinkedAt *time.Time `json:"linked_at"`
+	Provider string     `json:"provider"`
+}
+
+type IdentitiesResponse struct {
+	Identities []IdentityResponse `json:"identities"`
+}
+
+func (i *Identity) Response() *IdentityResponse {
+	return &IdentityResponse{
+		Email:    i.Email,
+		LinkedAt: i.LinkedAt,
+		Provider: i.Provider,
+	}
+}
+
+// Identity returns the identity of u at provider or nil if it isn't linked.
+func (u *User) Identity(provider string) *Identity {
+	for i := range u.Identities {
+		if u.Identities[i].Provider == provider {
+			return &u.Identities[i]
+		}
+	}
+
+	return nil
+}
+
+// LinkIdentity links the account with subject at provider to u,
+// only one account of each provider can be linked.
+func (u *User) LinkIdentity(provider string, subject string, email string) error {
+	if identity := u.Identity(provider); identity != nil {
+		if identity.Subject != subject {
+			return NewError(ErrIdentityExist, Conflict)
+		}
+		return nil
+	}
+
+	t := time.Now()
+	u.Identities = append(u.Identities, Identity{
+		Email:    email,
+		LinkedAt: &t,
+		Provider: provider,
+		Subject:  subject,
+	})
+
+	return nil
+}
+
+// UnlinkIdentity removes the identity of u at provider, unless the user has no password,
+// passkey or other identity left to log in with. hasPasskey is whether u has a passkey.
+func (u *User) UnlinkIdentity(provider string, hasPasskey bool) error {
+	if u.Password == "" && !hasPasskey && len(u.Identities) < 2 {
+		return NewError(ErrIdentityLastLogin, Conflict)
+	}
+
+	identities := make([]Identity, 0, len(u.Identities))
+	for _, identity := range u.Identities {
+		if identity.Provider != provider {
+			identities = append(identities, identity)
+		}
+	}
+	u.Identities = identities
+
+	return nil
+}
+
+func (u *User) IdentitiesResponse() *IdentitiesResponse {
+	res := make([]IdentityResponse, 0)
+	for _, identity := range u.Identities {
+		res = append(res, *identity.Response())
+	}
+	return &IdentitiesResponse{Identities: res}
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_LinkIdentity(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+
+	assert.NoError(t, user.LinkIdentity("github", "123", "test@example.com"))
+	assert.NoError(t, user.LinkIdentity("github", "123", "test@example.com"))
+	assert.NoError(t, user.LinkIdentity("google", "456", "other@example.com"))
+	assert.Len(t, user.Identities, 2)
+
+	identity := user.Identity("github")
+	if assert.NotNil(t, identity) {
+		assert.Equal(t, "123", identity.Subject)
+		assert.NotNil(t, identity.LinkedAt)
+	}
+	assert.Nil(t, user.Identity("gitlab"))
+
+	err := user.LinkIdentity("github", "789", "test@example.com")
+	var e *Error
+	if assert.ErrorAs(t, err, &e) {
+		assert.Equal(t, ErrIdentityExist.Error(), e.Message)
+		assert.Equal(t, Conflict, e.Kind)
+	}
+
+	res := user.IdentitiesResponse()
+	assert.Len(t, res.Identities, 2)
+	assert.Equal(t, "github", res.Identities[0].Provider)
+}
+
+func TestUser_UnlinkIdentity(t *testing.T) {
+	withPassword := NewUser("test@example.com", "test")
+	withPassword.Password = "hash"
+	_ = withPassword.LinkIdentity("github", "123", "")
+
+	withIdentities := NewUser("test@example.com", "test")
+	_ = withIdentities.LinkIdentity("github", "123", "")
+	_ = withIdentities.LinkIdentity("google", "456", "")
+
+	withPasskey := NewUser("test@example.com", "test")
+	_ = withPasskey.LinkIdentity("github", "123", "")
+
+	last := NewUser("test@example.com", "test")
+	_ = last.LinkIdentity("github", "123", "")
+
+	testCases := []struct {
+		name       string
+		user       *User
+		hasPasskey bool
+		err        error
+	}{
+		{"password left", withPassword, false, nil},
+		{"identity left", withIdentities, false, nil},
+		{"passkey left", withPasskey, true, nil},
+		{"last login method", last, false, ErrIdentityLastLogin},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			count := len(tc.user.Identities)
+			err := tc.user.UnlinkIdentity("github", tc.hasPasskey)
+			if tc.err != nil {
+				var e *Error
+				if assert.True(t, errors.As(err, &e)) {
+					assert.Equal(t, tc.err.Error(), e.Message)
+					assert.Equal(t, Conflict, e.Kind)
+				}
+				assert.Len(t, tc.user.Identities, count)
+			} else {
+				assert.NoError(t, err)
+				assert.Nil(t, tc.user.Identity("github"))
+				assert.Len(t, tc.user.Identities, count-1)
+			}
+		})
+	}
+}
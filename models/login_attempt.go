@@ -0,0 +1,150 @@
+package models
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+)
+
+const (
+	LoginAttemptAccount       = "account"
+	LoginAttemptIP            = "ip"
+	LoginAttemptMFA           = "mfa"
+	LoginAttemptPasswordReset = "password_reset"
+)
+
+// LoginAttempt counts the failed logins of an account, a source IP address or an
+// MFA token, and the password resets requested from an IP address. Each failure
+// requires a longer wait before the next attempt, and reaching the maximum number
+// of failures locks it temporarily. This is separate from the lock set by admins
+// on users, which doesn't expire.
+type LoginAttempt struct {
+	Id            string     `bson:"id"`
+	ExpiresAt     *time.Time `bson:"expires_at"`
+	Failures      int        `bson:"failures"`
+	Kind          string     `bson:"kind"`
+	LastFailureAt *time.Time `bson:"last_failure_at"`
+	LockedUntil   *time.Time `bson:"locked_until"`
+}
+
+// NewLoginAttempt creates a LoginAttempt without failures for value,
+// which is a user id, an IP address or an MFA token id depending on kind.
+func NewLoginAttempt(kind string, value string) *LoginAttempt {
+	return &LoginAttempt{
+		Id:   LoginAttemptId(kind, value),
+		Kind: kind,
+	}
+}
+
+func LoginAttemptId(kind string, value string) string {
+	return kind + ":" + value
+}
+
+// RetryAfter returns how long to wait before the next attempt is allowed, zero if it is.
+func (a *LoginAttempt) RetryAfter() time.Duration {
+	now := time.Now()
+	if a.LockedUntil != nil && now.Before(*a.LockedUntil) {
+		return a.LockedUntil.Sub(now)
+	}
+
+	if a.LastFailureAt == nil || a.forgotten(now) {
+		return 0
+	}
+
+	next := a.LastFailureAt.Add(a.backoff())
+	if now.Before(next) {
+		return next.Sub(now)
+	}
+
+	return 0
+}
+
+// Locked reports whether a reached the maximum number of failures and is still locked.
+func (a *LoginAttempt) Locked() bool {
+	return a.LockedUntil != nil && time.Now().Before(*a.LockedUntil)
+}
+
+// Lock locks a once its failures reach the maximum and returns true if it did.
+// a must be as returned by the datastore after counting the failure,
+// so that concurrent failures are all counted.
+func (a *LoginAttempt) Lock() bool {
+	if a.LockedUntil != nil || a.LastFailureAt == nil {
+		return false
+	}
+
+	if max := a.maxAttempts(); max <= 0 || a.Failures < max {
+		return false
+	}
+
+	lockedUntil := a.LastFailureAt.Add(a.lockDuration())
+	a.LockedUntil = &lockedUntil
+	if a.ExpiresAt == nil || a.ExpiresAt.Before(lockedUntil) {
+		a.ExpiresAt = &lockedUntil
+	}
+
+	return true
+}
+
+// Window returns how long failures are remembered after the last one.
+func (a *LoginAttempt) Window() time.Duration {
+	if a.Kind == LoginAttemptPasswordReset {
+		return viper.GetDuration(config.PasswordResetWindow)
+	}
+	return viper.GetDuration(config.LoginThrottleWindow)
+}
+
+func (a *LoginAttempt) Reset() {
+	a.Failures = 0
+	a.LastFailureAt = nil
+	a.LockedUntil = nil
+}
+
+// forgotten reports whether the failures are old enough to be ignored.
+func (a *LoginAttempt) forgotten(now time.Time) bool {
+	if a.LastFailureAt == nil {
+		return false
+	}
+	return now.Sub(*a.LastFailureAt) > a.Window()
+}
+
+// backoff doubles the base delay for every failure after the first, up to the maximum.
+func (a *LoginAttempt) backoff() time.Duration {
+	d := viper.GetDuration(config.LoginThrottleBackoffBase)
+	max := viper.GetDuration(config.LoginThrottleBackoffMax)
+	if d <= 0 || a.Failures == 0 {
+		return 0
+	}
+
+	for i := 1; i < a.Failures && d < max; i++ {
+		d *= 2
+	}
+
+	return min(d, max)
+}
+
+// lockDuration locks MFA tokens for as long as they're valid, so they can't be used again.
+func (a *LoginAttempt) lockDuration() time.Duration {
+	switch a.Kind {
+	case LoginAttemptMFA:
+		return viper.GetDuration(config.MFAChallengeExpiry)
+	case LoginAttemptPasswordReset:
+		return viper.GetDuration(config.PasswordResetWindow)
+	default:
+		return viper.GetDuration(config.LoginThrottleLockDuration)
+	}
+}
+
+func (a *LoginAttempt) maxAttempts() int {
+	switch a.Kind {
+	case LoginAttemptIP:
+		return viper.GetInt(config.LoginThrottleIPMaxAttempts)
+	case LoginAttemptMFA:
+		return viper.GetInt(config.MFAMaxAttempts)
+	case LoginAttemptPasswordReset:
+		return viper.GetInt(config.PasswordResetIPMaxRequests)
+	default:
+		return viper.GetInt(config.LoginThrottleAccountMaxAttempts)
+	}
+}
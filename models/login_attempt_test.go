@@ -0,0 +1,107 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/config"
+)
+
+// fail simulates the datastore counting a failure of a.
+func fail(a *LoginAttempt) {
+	now := time.Now()
+	a.Failures++
+	a.LastFailureAt = &now
+	expiresAt := now.Add(a.Window())
+	a.ExpiresAt = &expiresAt
+	a.Lock()
+}
+
+func TestLoginAttempt(t *testing.T) {
+	a := NewLoginAttempt(LoginAttemptAccount, "1")
+	assert.Equal(t, "account:1", a.Id)
+	assert.Equal(t, time.Duration(0), a.RetryAfter())
+	assert.False(t, a.Lock())
+
+	fail(a)
+	assert.InDelta(t, time.Second, a.RetryAfter(), float64(100*time.Millisecond))
+
+	fail(a)
+	assert.InDelta(t, 2*time.Second, a.RetryAfter(), float64(100*time.Millisecond))
+
+	for a.Failures < viper.GetInt(config.LoginThrottleAccountMaxAttempts)-1 {
+		fail(a)
+		assert.Nil(t, a.LockedUntil)
+	}
+	assert.LessOrEqual(t, a.RetryAfter(), viper.GetDuration(config.LoginThrottleBackoffMax))
+
+	fail(a)
+	assert.NotNil(t, a.LockedUntil)
+	assert.Greater(t, a.RetryAfter(), viper.GetDuration(config.LoginThrottleBackoffMax))
+	assert.False(t, a.ExpiresAt.Before(*a.LockedUntil))
+
+	// already locked
+	assert.False(t, a.Lock())
+
+	a.Reset()
+	assert.Equal(t, 0, a.Failures)
+	assert.Equal(t, time.Duration(0), a.RetryAfter())
+}
+
+func TestLoginAttempt_Lock_Concurrent(t *testing.T) {
+	// failures counted concurrently can go past the maximum
+	now := time.Now()
+	a := NewLoginAttempt(LoginAttemptIP, "192.0.2.1")
+	a.Failures = viper.GetInt(config.LoginThrottleIPMaxAttempts) + 3
+	a.LastFailureAt = &now
+
+	assert.True(t, a.Lock())
+	assert.Greater(t, a.RetryAfter(), viper.GetDuration(config.LoginThrottleBackoffMax))
+}
+
+func TestLoginAttempt_Expired(t *testing.T) {
+	a := NewLoginAttempt(LoginAttemptIP, "192.0.2.1")
+	past := time.Now().Add(-a.Window() - time.Minute)
+	a.Failures = 3
+	a.LastFailureAt = &past
+	assert.Equal(t, time.Duration(0), a.RetryAfter())
+
+	// the lock ended
+	lastFailureAt := time.Now().Add(-10 * time.Minute)
+	lockedUntil := time.Now().Add(-time.Second)
+	a.Failures = viper.GetInt(config.LoginThrottleIPMaxAttempts)
+	a.LastFailureAt = &lastFailureAt
+	a.LockedUntil = &lockedUntil
+	assert.Equal(t, time.Duration(0), a.RetryAfter())
+}
+
+func TestLoginAttempt_MFA(t *testing.T) {
+	a := NewLoginAttempt(LoginAttemptMFA, "token")
+
+	for a.Failures < viper.GetInt(config.MFAMaxAttempts)-1 {
+		fail(a)
+		assert.False(t, a.Locked())
+	}
+
+	fail(a)
+	assert.True(t, a.Locked())
+	// locked for as long as the token is valid
+	assert.InDelta(t, viper.GetDuration(config.MFAChallengeExpiry), a.LockedUntil.Sub(*a.LastFailureAt), 0)
+}
+
+func TestLoginAttempt_PasswordReset(t *testing.T) {
+	a := NewLoginAttempt(LoginAttemptPasswordReset, "192.0.2.1")
+	assert.Equal(t, viper.GetDuration(config.PasswordResetWindow), a.Window())
+
+	for a.Failures < viper.GetInt(config.PasswordResetIPMaxRequests)-1 {
+		fail(a)
+		assert.False(t, a.Locked())
+	}
+
+	fail(a)
+	assert.True(t, a.Locked())
+	assert.InDelta(t, viper.GetDuration(config.PasswordResetWindow), a.LockedUntil.Sub(*a.LastFailureAt), 0)
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/password"
+	"github.com/alexferl/echo-boilerplate/util/rand"
+)
+
+var (
+	ErrMagicLinkExpired      = errors.New("magic link expired")
+	ErrMagicLinkInvalid      = errors.New("magic link invalid")
+	ErrMagicLinkNonceInvalid = errors.New("magic link nonce invalid")
+	ErrMagicLinkUsed         = errors.New("magic link already used")
+)
+
+// MagicLink is a single-use token emailed to a user to log in without a password.
+// The token is prefixed by the id so it can be looked up. The nonce is kept in a
+// cookie of the browser that asked for the link, so mail scanners following it
+// can't use it up. Only the hashes of both are stored. Links are deleted at DeleteAt,
+// which is after the rate limit window so they're still counted.
+type MagicLink struct {
+	Id        string     `bson:"id"`
+	CreatedAt *time.Time `bson:"created_at"`
+	DeleteAt  *time.Time `bson:"delete_at"`
+	ExpiresAt *time.Time `bson:"expires_at"`
+	Nonce     string     `bson:"nonce"`
+	Redirect  string     `bson:"redirect"`
+	Token     string     `bson:"token"`
+	UsedAt    *time.Time `bson:"used_at"`
+	UserId    string     `bson:"user_id"`
+}
+
+// NewMagicLink creates a MagicLink for userId that can only be used along with nonce,
+// redirect is where the user is sent after logging in.
+func NewMagicLink(userId string, nonce string, redirect string) (*MagicLink, error) {
+	s, err := rand.GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	id := xid.New().String()
+	now := time.Now()
+	expiresAt := now.Add(viper.GetDuration(config.MagicLinkTokenExpiry))
+	deleteAt := now.Add(viper.GetDuration(config.MagicLinkWindow))
+	if deleteAt.Before(expiresAt) {
+		deleteAt = expiresAt
+	}
+
+	return &MagicLink{
+		Id:        id,
+		CreatedAt: &now,
+		DeleteAt:  &deleteAt,
+		ExpiresAt: &expiresAt,
+		Nonce:     nonce,
+		Redirect:  redirect,
+		Token:     fmt.Sprintf("%s.%s", id, s),
+		UserId:    userId,
+	}, nil
+}
+
+// ParseMagicLinkToken returns the id of the MagicLink the token belongs to.
+func ParseMagicLinkToken(token string) (string, error) {
+	id, _, found := strings.Cut(token, ".")
+	if !found || id == "" {
+		return "", ErrMagicLinkInvalid
+	}
+
+	return id, nil
+}
+
+func (ml *MagicLink) Encrypt() error {
+	token, err := password.Hash([]byte(ml.Token))
+	if err != nil {
+		return err
+	}
+
+	nonce, err := password.Hash([]byte(ml.Nonce))
+	if err != nil {
+		return err
+	}
+
+	ml.Token = token
+	ml.Nonce = nonce
+
+	return nil
+}
+
+func (ml *MagicLink) Validate(token string, nonce string) error {
+	if ml.UsedAt != nil {
+		return ErrMagicLinkUsed
+	}
+
+	if time.Now().After(*ml.ExpiresAt) {
+		return ErrMagicLinkExpired
+	}
+
+	if err := password.Verify([]byte(ml.Token), []byte(token)); err != nil {
+		return ErrMagicLinkInvalid
+	}
+
+	if err := password.Verify([]byte(ml.Nonce), []byte(nonce)); err != nil {
+		return ErrMagicLinkNonceInvalid
+	}
+
+	return nil
+}
+
+func (ml *MagicLink) Use() {
+	t := time.Now()
+	ml.UsedAt = &t
+}
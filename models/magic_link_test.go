@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMagicLink(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+
+	ml, err := NewMagicLink(user.Id, "nonce", "https://app.example.com/home")
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, ml.UserId)
+	assert.Equal(t, "https://app.example.com/home", ml.Redirect)
+	assert.True(t, ml.ExpiresAt.After(time.Now()))
+	assert.Equal(t, ml.CreatedAt.Add(time.Hour), *ml.DeleteAt)
+
+	token := ml.Token
+	id, err := ParseMagicLinkToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, ml.Id, id)
+
+	err = ml.Encrypt()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, ml.Token)
+	assert.NotEqual(t, "nonce", ml.Nonce)
+
+	assert.NoError(t, ml.Validate(token, "nonce"))
+	assert.ErrorIs(t, ml.Validate("wrong", "nonce"), ErrMagicLinkInvalid)
+	assert.ErrorIs(t, ml.Validate(token, "wrong"), ErrMagicLinkNonceInvalid)
+
+	ml.Use()
+	assert.ErrorIs(t, ml.Validate(token, "nonce"), ErrMagicLinkUsed)
+}
+
+func TestMagicLink_Expired(t *testing.T) {
+	ml, err := NewMagicLink("1", "nonce", "")
+	assert.NoError(t, err)
+
+	token := ml.Token
+	assert.NoError(t, ml.Encrypt())
+
+	past := time.Now().Add(-time.Minute)
+	ml.ExpiresAt = &past
+	assert.ErrorIs(t, ml.Validate(token, "nonce"), ErrMagicLinkExpired)
+}
+
+func TestParseMagicLinkToken(t *testing.T) {
+	testCases := []struct {
+		token string
+		id    string
+		err   error
+	}{
+		{"abc.def", "abc", nil},
+		{"abc", "", ErrMagicLinkInvalid},
+		{".def", "", ErrMagicLinkInvalid},
+		{"", "", ErrMagicLinkInvalid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.token, func(t *testing.T) {
+			id, err := ParseMagicLinkToken(tc.token)
+			assert.Equal(t, tc.id, id)
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
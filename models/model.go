@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"time"
 
 	"github.com/rs/xid"
@@ -21,6 +22,36 @@ type Model struct {
 // Ref is a reference to another document.
 type Ref struct {
 	Id string `json:"id" bson:"id"`
+	// Actor is the user who really made the write while impersonating Id.
+	Actor *Ref `json:"actor,omitempty" bson:"actor,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx where the writes are made
+// by actorId while impersonating the current user.
+func WithActor(ctx context.Context, actorId string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actorId)
+}
+
+// ActorFromContext returns the user impersonating the
+// current user of ctx, an empty string if there's none.
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// Act marks ref with the actor of ctx, so writes made while
+// impersonating keep both users. Other writes are left as is.
+func Act(ctx context.Context, ref any) {
+	r, ok := ref.(*Ref)
+	if !ok || r == nil {
+		return
+	}
+
+	if actor := ActorFromContext(ctx); actor != "" {
+		r.Actor = &Ref{Id: actor}
+	}
 }
 
 func NewModel() *Model {
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,3 +24,23 @@ func TestModel(t *testing.T) {
 	assert.Equal(t, id, m.DeletedBy.(*Ref).Id)
 	assert.NotNil(t, m.DeletedAt)
 }
+
+func TestModel_Act(t *testing.T) {
+	m := NewModel()
+	m.Create("1")
+	Act(context.Background(), m.CreatedBy)
+	assert.Nil(t, m.CreatedBy.(*Ref).Actor)
+
+	ctx := WithActor(context.Background(), "2")
+	assert.Equal(t, "2", ActorFromContext(ctx))
+
+	m.Update("1")
+	Act(ctx, m.UpdatedBy)
+	assert.Equal(t, "1", m.UpdatedBy.(*Ref).Id)
+	assert.Equal(t, "2", m.UpdatedBy.(*Ref).Actor.Id)
+
+	// revocations without a user have no ref to mark
+	var ref *Ref
+	Act(ctx, ref)
+	assert.Nil(t, ref)
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/password"
+	"github.com/alexferl/echo-boilerplate/util/rand"
+)
+
+var (
+	ErrPasswordResetExpired = errors.New("password reset token expired")
+	ErrPasswordResetInvalid = errors.New("password reset token invalid")
+	ErrPasswordResetUsed    = errors.New("password reset token already used")
+)
+
+// PasswordReset is a single-use token allowing a user to set a new password.
+// The token is prefixed by the id so it can be looked up, only its hash is stored.
+type PasswordReset struct {
+	Id        string     `bson:"id"`
+	CreatedAt *time.Time `bson:"created_at"`
+	ExpiresAt *time.Time `bson:"expires_at"`
+	Token     string     `bson:"token"`
+	UsedAt    *time.Time `bson:"used_at"`
+	UserId    string     `bson:"user_id"`
+}
+
+func NewPasswordReset(userId string) (*PasswordReset, error) {
+	s, err := rand.GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	id := xid.New().String()
+	now := time.Now()
+	expiresAt := now.Add(viper.GetDuration(config.PasswordResetTokenExpiry))
+
+	return &PasswordReset{
+		Id:        id,
+		CreatedAt: &now,
+		ExpiresAt: &expiresAt,
+		Token:     fmt.Sprintf("%s.%s", id, s),
+		UserId:    userId,
+	}, nil
+}
+
+// ParsePasswordResetToken returns the id of the PasswordReset the token belongs to.
+func ParsePasswordResetToken(token string) (string, error) {
+	id, _, found := strings.Cut(token, ".")
+	if !found || id == "" {
+		return "", ErrPasswordResetInvalid
+	}
+
+	return id, nil
+}
+
+func (pr *PasswordReset) Encrypt() error {
+	b, err := password.Hash([]byte(pr.Token))
+	if err != nil {
+		return err
+	}
+
+	pr.Token = b
+
+	return nil
+}
+
+func (pr *PasswordReset) Validate(token string) error {
+	if pr.UsedAt != nil {
+		return ErrPasswordResetUsed
+	}
+
+	if time.Now().After(*pr.ExpiresAt) {
+		return ErrPasswordResetExpired
+	}
+
+	if err := password.Verify([]byte(pr.Token), []byte(token)); err != nil {
+		return ErrPasswordResetInvalid
+	}
+
+	return nil
+}
+
+func (pr *PasswordReset) Use() {
+	t := time.Now()
+	pr.UsedAt = &t
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordReset(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+
+	pr, err := NewPasswordReset(user.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, pr.UserId)
+	assert.True(t, pr.ExpiresAt.After(time.Now()))
+
+	token := pr.Token
+	id, err := ParsePasswordResetToken(token)
+	assert.NoError(t, err)
+	assert.Equal(t, pr.Id, id)
+
+	err = pr.Encrypt()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, pr.Token)
+
+	assert.NoError(t, pr.Validate(token))
+	assert.ErrorIs(t, pr.Validate("wrong"), ErrPasswordResetInvalid)
+
+	pr.Use()
+	assert.ErrorIs(t, pr.Validate(token), ErrPasswordResetUsed)
+}
+
+func TestPasswordReset_Expired(t *testing.T) {
+	pr, err := NewPasswordReset("1")
+	assert.NoError(t, err)
+
+	token := pr.Token
+	assert.NoError(t, pr.Encrypt())
+
+	past := time.Now().Add(-time.Minute)
+	pr.ExpiresAt = &past
+	assert.ErrorIs(t, pr.Validate(token), ErrPasswordResetExpired)
+}
+
+func TestParsePasswordResetToken(t *testing.T) {
+	testCases := []struct {
+		token string
+		id    string
+		err   error
+	}{
+		{"abc.def", "abc", nil},
+		{"abc", "", ErrPasswordResetInvalid},
+		{".def", "", ErrPasswordResetInvalid},
+		{"", "", ErrPasswordResetInvalid},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.token, func(t *testing.T) {
+			id, err := ParsePasswordResetToken(tc.token)
+			assert.Equal(t, tc.id, id)
+			assert.Equal(t, tc.err, err)
+		})
+	}
+}
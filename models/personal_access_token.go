@@ -2,6 +2,9 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/rs/xid"
@@ -10,25 +13,57 @@ import (
 	"github.com/alexferl/echo-boilerplate/util/password"
 )
 
-var ErrExpiresAtPast = errors.New("expires_at cannot be in the past")
+var (
+	ErrExpiresAtPast = errors.New("expires_at cannot be in the past")
+	ErrScopeInvalid  = errors.New("invalid scope")
+	ErrScopesMissing = errors.New("at least one scope is required")
+)
+
+// Scopes limit what personal access tokens can do, on top of the roles of their
+// owner, see casbin/scopes.csv. They're sent in the space separated scope claim.
+const (
+	ScopeAdmin      = "admin"
+	ScopeTasksRead  = "tasks:read"
+	ScopeTasksWrite = "tasks:write"
+	ScopeUsersRead  = "users:read"
+)
+
+var Scopes = []string{ScopeAdmin, ScopeTasksRead, ScopeTasksWrite, ScopeUsersRead}
+
+// PersonalAccessTokenUsageInterval is how often the last use of a token is
+// recorded, so busy tokens don't write on every request.
+const PersonalAccessTokenUsageInterval = 5 * time.Minute
 
 type PersonalAccessToken struct {
-	Id        string     `bson:"id"`
-	CreatedAt *time.Time `bson:"created_at"`
-	ExpiresAt *time.Time `bson:"expires_at"`
-	IsRevoked bool       `bson:"is_revoked"`
-	Name      string     `bson:"name"`
-	Token     string     `bson:"token"`
-	UserId    string     `bson:"user_id"`
+	Id                string     `bson:"id"`
+	CreatedAt         *time.Time `bson:"created_at"`
+	ExpiresAt         *time.Time `bson:"expires_at"`
+	IsExpired         bool       `bson:"is_expired"`
+	IsRevoked         bool       `bson:"is_revoked"`
+	LastUsedAt        *time.Time `bson:"last_used_at"`
+	LastUsedIP        string     `bson:"last_used_ip"`
+	LastUsedUserAgent string     `bson:"last_used_user_agent"`
+	Name              string     `bson:"name"`
+	RemindedAt        *time.Time `bson:"reminded_at"`
+	RevokedAt         *time.Time `bson:"revoked_at"`
+	RevokedBy         *Ref       `bson:"revoked_by"`
+	Scopes            []string   `bson:"scopes"`
+	Token             string     `bson:"token"`
+	UserId            string     `bson:"user_id"`
 }
 
 type PersonalAccessTokenResponse struct {
-	Id        string     `json:"id" bson:"id"`
-	CreatedAt *time.Time `json:"created_at"`
-	ExpiresAt *time.Time `json:"expires_at"`
-	IsRevoked bool       `json:"is_revoked"`
-	Name      string     `json:"name"`
-	UserId    string     `json:"user_id"`
+	Id                string     `json:"id" bson:"id"`
+	CreatedAt         *time.Time `json:"created_at"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	IsExpired         bool       `json:"is_expired"`
+	IsRevoked         bool       `json:"is_revoked"`
+	LastUsedAt        *time.Time `json:"last_used_at"`
+	LastUsedIP        string     `json:"last_used_ip"`
+	LastUsedUserAgent string     `json:"last_used_user_agent"`
+	Name              string     `json:"name"`
+	Scopes            []string   `json:"scopes"`
+	UserId            string     `json:"user_id"`
 }
 
 type PersonalAccessTokenCreateResponse struct {
@@ -36,27 +71,40 @@ type PersonalAccessTokenCreateResponse struct {
 	Token string `json:"token"`
 }
 
-func NewPersonalAccessToken(userId string, name string, expiresAt string) (*PersonalAccessToken, error) {
+func NewPersonalAccessToken(userId string, name string, expiresAt string, scopes []string) (*PersonalAccessToken, error) {
 	t, err := time.Parse("2006-01-02", expiresAt)
 	if err != nil {
 		return nil, err
 	}
 
+	if len(scopes) == 0 {
+		return nil, ErrScopesMissing
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrScopeInvalid, scope)
+		}
+	}
+
 	now := time.Now()
 	if t.Before(now) {
 		return nil, ErrExpiresAtPast
 	}
 
-	pat, err := jwt.GeneratePersonalToken(userId, t.Sub(now), nil)
+	id := xid.New().String()
+	claims := map[string]any{"pat_id": id, "scope": strings.Join(scopes, " ")}
+	pat, err := jwt.GeneratePersonalToken(userId, t.Sub(now), claims)
 	if err != nil {
 		return nil, err
 	}
 
 	return &PersonalAccessToken{
-		Id:        xid.New().String(),
+		Id:        id,
 		CreatedAt: &now,
 		ExpiresAt: &t,
 		Name:      name,
+		Scopes:    scopes,
 		Token:     string(pat),
 		UserId:    userId,
 	}, nil
@@ -64,27 +112,61 @@ func NewPersonalAccessToken(userId string, name string, expiresAt string) (*Pers
 
 func (pat *PersonalAccessToken) Response() *PersonalAccessTokenResponse {
 	return &PersonalAccessTokenResponse{
-		Id:        pat.Id,
-		CreatedAt: pat.CreatedAt,
-		ExpiresAt: pat.ExpiresAt,
-		IsRevoked: pat.IsRevoked,
-		Name:      pat.Name,
-		UserId:    pat.UserId,
+		Id:                pat.Id,
+		CreatedAt:         pat.CreatedAt,
+		ExpiresAt:         pat.ExpiresAt,
+		IsExpired:         pat.IsExpired,
+		IsRevoked:         pat.IsRevoked,
+		LastUsedAt:        pat.LastUsedAt,
+		LastUsedIP:        pat.LastUsedIP,
+		LastUsedUserAgent: pat.LastUsedUserAgent,
+		Name:              pat.Name,
+		Scopes:            pat.scopes(),
+		UserId:            pat.UserId,
 	}
 }
 
 func (pat *PersonalAccessToken) CreateResponse() *PersonalAccessTokenCreateResponse {
 	return &PersonalAccessTokenCreateResponse{
-		PersonalAccessTokenResponse: PersonalAccessTokenResponse{
-			Id:        pat.Id,
-			CreatedAt: pat.CreatedAt,
-			ExpiresAt: pat.ExpiresAt,
-			IsRevoked: pat.IsRevoked,
-			Name:      pat.Name,
-			UserId:    pat.UserId,
-		},
-		Token: pat.Token,
+		PersonalAccessTokenResponse: *pat.Response(),
+		Token:                       pat.Token,
+	}
+}
+
+// scopes returns the scopes of pat, which are empty for
+// the tokens created before they had any.
+func (pat *PersonalAccessToken) scopes() []string {
+	if pat.Scopes == nil {
+		return []string{}
+	}
+	return pat.Scopes
+}
+
+// Revoke revokes pat, id is the user revoking it,
+// it's empty when it's revoked without one.
+func (pat *PersonalAccessToken) Revoke(id string) {
+	t := time.Now()
+	pat.IsRevoked = true
+	pat.RevokedAt = &t
+	pat.RevokedBy = nil
+	if id != "" {
+		pat.RevokedBy = &Ref{Id: id}
+	}
+}
+
+// Use records that pat was used from ip with userAgent, it returns false
+// when the last use is too recent to be worth saving again.
+func (pat *PersonalAccessToken) Use(ip string, userAgent string) bool {
+	now := time.Now()
+	if pat.LastUsedAt != nil && now.Sub(*pat.LastUsedAt) < PersonalAccessTokenUsageInterval {
+		return false
 	}
+
+	pat.LastUsedAt = &now
+	pat.LastUsedIP = ip
+	pat.LastUsedUserAgent = userAgent
+
+	return true
 }
 
 func (pat *PersonalAccessToken) Encrypt() error {
@@ -102,8 +184,48 @@ func (pat *PersonalAccessToken) Validate(s string) error {
 	return password.Verify([]byte(pat.Token), []byte(s))
 }
 
+// Rehash hashes s again if the token was hashed with older argon2 parameters,
+// s must have been validated. It returns whether the token changed and needs
+// to be saved.
+func (pat *PersonalAccessToken) Rehash(s string) (bool, error) {
+	if !password.NeedsRehash([]byte(pat.Token)) {
+		return false, nil
+	}
+
+	b, err := password.Hash([]byte(s))
+	if err != nil {
+		return false, err
+	}
+	pat.Token = b
+	return true, nil
+}
+
 type PersonalAccessTokens []PersonalAccessToken
 
+// Match returns the token of pats which s validates against, or nil.
+func (pats PersonalAccessTokens) Match(s string) *PersonalAccessToken {
+	for i := range pats {
+		if pats[i].Validate(s) == nil {
+			return &pats[i]
+		}
+	}
+	return nil
+}
+
+// PersonalAccessTokenSearchParams filters and orders lists of tokens, Sort is a
+// field name optionally prefixed with - for descending order. Empty or nil
+// values don't filter, ExcludeUserIds is ignored when UserId is set.
+type PersonalAccessTokenSearchParams struct {
+	UserId         string
+	ExcludeUserIds []string
+	ExpiresAfter   *time.Time
+	ExpiresBefore  *time.Time
+	IsRevoked      *bool
+	Sort           string
+	Limit          int
+	Skip           int
+}
+
 type PersonalAccessTokensResponse struct {
 	Tokens []PersonalAccessTokenResponse `json:"personal_access_tokens"`
 }
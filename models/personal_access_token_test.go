@@ -4,21 +4,30 @@ import (
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
 )
 
 func TestPersonalAccessToken(t *testing.T) {
 	user := NewUser("test@email.com", "test")
 
 	// expires_at not in the past
-	_, err := NewPersonalAccessToken(user.Id, "My Token", time.Now().Format("2006-01-02"))
+	_, err := NewPersonalAccessToken(user.Id, "My Token", time.Now().Format("2006-01-02"), []string{ScopeTasksRead})
 	assert.Error(t, err)
 	assert.Equal(t, ErrExpiresAtPast, err)
 
-	pat, err := NewPersonalAccessToken(user.Id, "My Token", time.Now().Add((7*24)*time.Hour).Format("2006-01-02"))
+	pat, err := NewPersonalAccessToken(user.Id, "My Token", time.Now().Add((7*24)*time.Hour).Format("2006-01-02"), []string{ScopeTasksRead})
 	assert.NoError(t, err)
 
 	token := pat.Token
+	parsed, err := jwt.ParseEncoded([]byte(token))
+	assert.NoError(t, err)
+	assert.Equal(t, pat.Id, parsed.PrivateClaims()["pat_id"])
+	assert.Equal(t, ScopeTasksRead, parsed.PrivateClaims()["scope"])
+
 	create := pat.CreateResponse()
 	assert.Equal(t, token, create.Token)
 
@@ -32,15 +41,94 @@ func TestPersonalAccessToken(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestPersonalAccessToken_Rehash(t *testing.T) {
+	pat, err := NewPersonalAccessToken("1", "My Token", time.Now().Add((7*24)*time.Hour).Format("2006-01-02"), []string{ScopeTasksRead})
+	assert.NoError(t, err)
+
+	token := pat.Token
+	viper.Set(config.PasswordArgon2Time, 1)
+	err = pat.Encrypt()
+	viper.Set(config.PasswordArgon2Time, 3)
+	assert.NoError(t, err)
+
+	rehashed, err := pat.Rehash(token)
+	assert.NoError(t, err)
+	assert.True(t, rehashed)
+	assert.Contains(t, pat.Token, ",t=3,")
+	assert.NoError(t, pat.Validate(token))
+
+	rehashed, err = pat.Rehash(token)
+	assert.NoError(t, err)
+	assert.False(t, rehashed)
+}
+
+func TestPersonalAccessToken_Scopes(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+
+	_, err := NewPersonalAccessToken(user.Id, "My Token", expiresAt, nil)
+	assert.ErrorIs(t, err, ErrScopesMissing)
+
+	_, err = NewPersonalAccessToken(user.Id, "My Token", expiresAt, []string{ScopeTasksRead, "tasks:delete"})
+	assert.ErrorIs(t, err, ErrScopeInvalid)
+
+	pat, err := NewPersonalAccessToken(user.Id, "My Token", expiresAt, []string{ScopeTasksWrite, ScopeUsersRead})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ScopeTasksWrite, ScopeUsersRead}, pat.Response().Scopes)
+
+	parsed, err := jwt.ParseEncoded([]byte(pat.Token))
+	assert.NoError(t, err)
+	assert.Equal(t, "tasks:write users:read", parsed.PrivateClaims()["scope"])
+
+	pat.Scopes = nil
+	assert.Equal(t, []string{}, pat.Response().Scopes)
+}
+
 func TestPersonalAccessTokens(t *testing.T) {
 	user := NewUser("test@email.com", "test")
 
-	pat1, err := NewPersonalAccessToken(user.Id, "My Token1", time.Now().Add((7*24)*time.Hour).Format("2006-01-02"))
+	pat1, err := NewPersonalAccessToken(user.Id, "My Token1", time.Now().Add((7*24)*time.Hour).Format("2006-01-02"), []string{ScopeTasksRead})
 	assert.NoError(t, err)
-	pat2, err := NewPersonalAccessToken(user.Id, "My Token1", time.Now().Add((7*24)*time.Hour).Format("2006-01-02"))
+	pat2, err := NewPersonalAccessToken(user.Id, "My Token1", time.Now().Add((7*24)*time.Hour).Format("2006-01-02"), []string{ScopeTasksRead})
 	assert.NoError(t, err)
 
 	pats := PersonalAccessTokens{*pat1, *pat2}
 	resp := pats.Response()
 	assert.Len(t, resp.Tokens, 2)
 }
+
+func TestPersonalAccessToken_Use(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+
+	pat, err := NewPersonalAccessToken(user.Id, "My Token", expiresAt, []string{ScopeTasksRead})
+	assert.NoError(t, err)
+	assert.Nil(t, pat.Response().LastUsedAt)
+
+	assert.True(t, pat.Use("127.0.0.1", "curl/8.0"))
+	assert.NotNil(t, pat.LastUsedAt)
+	assert.Equal(t, "127.0.0.1", pat.Response().LastUsedIP)
+	assert.Equal(t, "curl/8.0", pat.Response().LastUsedUserAgent)
+
+	// throttled
+	assert.False(t, pat.Use("127.0.0.2", "curl/8.1"))
+	assert.Equal(t, "127.0.0.1", pat.LastUsedIP)
+
+	past := time.Now().Add(-PersonalAccessTokenUsageInterval)
+	pat.LastUsedAt = &past
+	assert.True(t, pat.Use("127.0.0.2", "curl/8.1"))
+	assert.Equal(t, "127.0.0.2", pat.LastUsedIP)
+}
+
+func TestPersonalAccessToken_Revoke(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+
+	pat, err := NewPersonalAccessToken(user.Id, "My Token", expiresAt, []string{ScopeTasksRead})
+	assert.NoError(t, err)
+
+	pat.Revoke(user.Id)
+	assert.True(t, pat.IsRevoked)
+	assert.NotNil(t, pat.RevokedAt)
+	assert.Equal(t, user.Id, pat.RevokedBy.Id)
+}
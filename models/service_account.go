@@ -0,0 +1,246 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+	"github.com/alexferl/echo-boilerplate/util/password"
+	"github.com/alexferl/echo-boilerplate/util/rand"
+)
+
+var (
+	ErrServiceAccountCredentialNotFound = errors.New("credential not found")
+	ErrServiceAccountCredentialInvalid  = errors.New("invalid client credentials")
+	ErrServiceAccountMorePrivileged     = errors.New("cannot manage service account with higher permissions")
+	ErrServiceAccountRoleInvalid        = errors.New("invalid role")
+	ErrServiceAccountRolesMissing       = errors.New("at least one role is required")
+)
+
+// ServiceAccount is a non-human account for other services, it can't log in
+// and only gets access tokens in exchange for the client id and secret of
+// one of its credentials.
+type ServiceAccount struct {
+	*Model      `bson:",inline"`
+	Credentials []ServiceAccountCredential `bson:"credentials"`
+	Description string                     `bson:"description"`
+	Name        string                     `bson:"name"`
+	Roles       []string                   `bson:"roles"`
+}
+
+// ServiceAccountCredential is a client id and secret pair, the
+// secret is hashed and only ever returned when it's created.
+type ServiceAccountCredential struct {
+	ClientId   string     `bson:"client_id"`
+	CreatedAt  *time.Time `bson:"created_at"`
+	LastUsedAt *time.Time `bson:"last_used_at"`
+	Secret     string     `bson:"secret"`
+}
+
+type ServiceAccountResponse struct {
+	Id          string                             `json:"id"`
+	CreatedAt   *time.Time                         `json:"created_at"`
+	Credentials []ServiceAccountCredentialResponse `json:"credentials"`
+	Description string                             `json:"description"`
+	Name        string                             `json:"name"`
+	Roles       []string                           `json:"roles"`
+	UpdatedAt   *time.Time                         `json:"updated_at"`
+}
+
+type ServiceAccountCredentialResponse struct {
+	ClientId   string     `json:"client_id"`
+	CreatedAt  *time.Time `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+type ServiceAccountCredentialCreateResponse struct {
+	ServiceAccountCredentialResponse
+	ClientSecret string `json:"client_secret"`
+}
+
+func NewServiceAccount(name string, description string) *ServiceAccount {
+	return &ServiceAccount{
+		Model:       NewModel(),
+		Credentials: []ServiceAccountCredential{},
+		Description: description,
+		Name:        name,
+		Roles:       []string{},
+	}
+}
+
+func (sa *ServiceAccount) Response() *ServiceAccountResponse {
+	creds := make([]ServiceAccountCredentialResponse, 0)
+	for _, cred := range sa.Credentials {
+		creds = append(creds, *cred.Response())
+	}
+
+	return &ServiceAccountResponse{
+		Id:          sa.Id,
+		CreatedAt:   sa.CreatedAt,
+		Credentials: creds,
+		Description: sa.Description,
+		Name:        sa.Name,
+		Roles:       sa.Roles,
+		UpdatedAt:   sa.UpdatedAt,
+	}
+}
+
+func (cred *ServiceAccountCredential) Response() *ServiceAccountCredentialResponse {
+	return &ServiceAccountCredentialResponse{
+		ClientId:   cred.ClientId,
+		CreatedAt:  cred.CreatedAt,
+		LastUsedAt: cred.LastUsedAt,
+	}
+}
+
+// User returns the user service accounts act as once
+// authenticated, it only has their id, name and roles.
+func (sa *ServiceAccount) User() *User {
+	return &User{
+		Model: &Model{Id: sa.Id},
+		Name:  sa.Name,
+		Roles: sa.Roles,
+	}
+}
+
+// Manage checks if user is allowed to manage sa, the same
+// way as with the users user could ban or lock.
+func (sa *ServiceAccount) Manage(user *User) error {
+	if !hasRoleOrHigher(user, AdminRole) {
+		return NewError(ErrAdminRoleRequired, Permission)
+	}
+
+	if len(sa.Roles) > 0 && sa.User().compare(user) {
+		return NewError(ErrServiceAccountMorePrivileged, Permission)
+	}
+
+	return nil
+}
+
+// SetRoles replaces the roles of sa, user can't give
+// it roles that would stop them from managing it.
+func (sa *ServiceAccount) SetRoles(user *User, roles []string) error {
+	if err := sa.Manage(user); err != nil {
+		return err
+	}
+
+	if len(roles) == 0 {
+		return NewError(ErrServiceAccountRolesMissing, Invalid)
+	}
+
+	var rs []string
+	for _, role := range roles {
+		if _, ok := RolesMap[role]; !ok {
+			return NewError(fmt.Errorf("%w: %s", ErrServiceAccountRoleInvalid, role), Invalid)
+		}
+		if !slices.Contains(rs, role) {
+			rs = append(rs, role)
+		}
+	}
+
+	if (&User{Roles: rs}).compare(user) {
+		return NewError(ErrRoleAddMorePrivileged, Permission)
+	}
+
+	sa.Roles = rs
+
+	return nil
+}
+
+// AddCredential adds a new credential to sa and returns it
+// with its secret, which can't be retrieved afterward.
+func (sa *ServiceAccount) AddCredential() (*ServiceAccountCredentialCreateResponse, error) {
+	secret, err := rand.GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := password.Hash([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cred := ServiceAccountCredential{
+		ClientId:  "sa_" + xid.New().String(),
+		CreatedAt: &now,
+		Secret:    encoded,
+	}
+	sa.Credentials = append(sa.Credentials, cred)
+
+	return &ServiceAccountCredentialCreateResponse{
+		ServiceAccountCredentialResponse: *cred.Response(),
+		ClientSecret:                     secret,
+	}, nil
+}
+
+// RemoveCredential removes the credential of clientId, the
+// access tokens it was exchanged for stop working with it.
+func (sa *ServiceAccount) RemoveCredential(clientId string) error {
+	i := sa.credential(clientId)
+	if i < 0 {
+		return NewError(ErrServiceAccountCredentialNotFound, Conflict)
+	}
+
+	sa.Credentials = slices.Delete(sa.Credentials, i, i+1)
+
+	return nil
+}
+
+// Credential returns the credential of clientId, nil if sa doesn't have it.
+func (sa *ServiceAccount) Credential(clientId string) *ServiceAccountCredential {
+	i := sa.credential(clientId)
+	if i < 0 {
+		return nil
+	}
+	return &sa.Credentials[i]
+}
+
+// Token checks clientId and secret against the credentials
+// of sa and returns an access token when they're valid.
+func (sa *ServiceAccount) Token(clientId string, secret string) ([]byte, error) {
+	i := sa.credential(clientId)
+	if i < 0 || password.Verify([]byte(sa.Credentials[i].Secret), []byte(secret)) != nil {
+		return nil, NewError(ErrServiceAccountCredentialInvalid, Invalid)
+	}
+
+	token, err := jwt.GenerateServiceToken(sa.Id, map[string]any{"client_id": clientId})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sa.Credentials[i].LastUsedAt = &now
+
+	return token, nil
+}
+
+func (sa *ServiceAccount) credential(clientId string) int {
+	return slices.IndexFunc(sa.Credentials, func(cred ServiceAccountCredential) bool {
+		return cred.ClientId == clientId
+	})
+}
+
+type ServiceAccounts []ServiceAccount
+
+type ServiceAccountsResponse struct {
+	ServiceAccounts []ServiceAccountResponse `json:"service_accounts"`
+}
+
+func (sas ServiceAccounts) Response() *ServiceAccountsResponse {
+	res := make([]ServiceAccountResponse, 0)
+	for _, sa := range sas {
+		res = append(res, *sa.Response())
+	}
+
+	return &ServiceAccountsResponse{ServiceAccounts: res}
+}
+
+type ServiceAccountSearchParams struct {
+	Limit int
+	Skip  int
+}
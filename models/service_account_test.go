@@ -0,0 +1,103 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+func TestServiceAccount(t *testing.T) {
+	sa := NewServiceAccount("ci", "Deploys from CI")
+	sa.Roles = []string{UserRole.String()}
+
+	cred, err := sa.AddCredential()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cred.ClientSecret)
+	assert.NotEqual(t, cred.ClientSecret, sa.Credentials[0].Secret)
+	assert.Equal(t, cred.ClientId, sa.Response().Credentials[0].ClientId)
+
+	_, err = sa.Token(cred.ClientId, "wrong")
+	assert.ErrorContains(t, err, ErrServiceAccountCredentialInvalid.Error())
+
+	_, err = sa.Token("wrong", cred.ClientSecret)
+	assert.ErrorContains(t, err, ErrServiceAccountCredentialInvalid.Error())
+
+	token, err := sa.Token(cred.ClientId, cred.ClientSecret)
+	assert.NoError(t, err)
+	assert.NotNil(t, sa.Credential(cred.ClientId).LastUsedAt)
+
+	parsed, err := jwt.ParseEncoded(token)
+	assert.NoError(t, err)
+	assert.Equal(t, sa.Id, parsed.Subject())
+	assert.Equal(t, jwt.ServiceToken.String(), parsed.PrivateClaims()["type"])
+	assert.Equal(t, cred.ClientId, parsed.PrivateClaims()["client_id"])
+
+	user := sa.User()
+	assert.Equal(t, sa.Id, user.Id)
+	assert.Equal(t, sa.Roles, user.Roles)
+
+	assert.NoError(t, sa.RemoveCredential(cred.ClientId))
+	assert.Nil(t, sa.Credential(cred.ClientId))
+	assert.ErrorContains(t, sa.RemoveCredential(cred.ClientId), ErrServiceAccountCredentialNotFound.Error())
+}
+
+func TestServiceAccount_SetRoles(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+	super := NewUserWithRole("super@example.com", "super", SuperRole)
+
+	testCases := []struct {
+		name  string
+		user  *User
+		roles []string
+		err   error
+		kind  Kind
+	}{
+		{"need admin or higher role", user, []string{"user"}, ErrAdminRoleRequired, Permission},
+		{"roles missing", admin, []string{}, ErrServiceAccountRolesMissing, Invalid},
+		{"role invalid", admin, []string{"root"}, ErrServiceAccountRoleInvalid, Invalid},
+		{"admin cannot give admin", admin, []string{"user", "admin"}, ErrRoleAddMorePrivileged, Permission},
+		{"admin can give user", admin, []string{"user", "user"}, nil, 0},
+		{"super can give super", super, []string{"super"}, nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sa := NewServiceAccount("ci", "")
+			err := sa.SetRoles(tc.user, tc.roles)
+			if tc.err != nil {
+				assert.Error(t, err)
+				var e *Error
+				assert.ErrorAs(t, err, &e)
+				if errors.As(err, &e) {
+					assert.Contains(t, e.Message, tc.err.Error())
+					assert.Equal(t, tc.kind, e.Kind)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, sa.Roles, 1)
+			}
+		})
+	}
+}
+
+func TestServiceAccount_Manage(t *testing.T) {
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+	super := NewUserWithRole("super@example.com", "super", SuperRole)
+
+	sa := NewServiceAccount("ci", "")
+	sa.Roles = []string{UserRole.String(), AdminRole.String()}
+
+	err := sa.Manage(admin)
+	var e *Error
+	assert.ErrorAs(t, err, &e)
+	if errors.As(err, &e) {
+		assert.Equal(t, ErrServiceAccountMorePrivileged.Error(), e.Message)
+		assert.Equal(t, Permission, e.Kind)
+	}
+
+	assert.NoError(t, sa.Manage(super))
+}
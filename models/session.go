@@ -0,0 +1,135 @@
+package models
+
+import (
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/password"
+)
+
+const (
+	SessionRevokedAdmin          = "admin"
+	SessionRevokedBan            = "ban"
+	SessionRevokedClient         = "client"
+	SessionRevokedLock           = "lock"
+	SessionRevokedLogout         = "logout"
+	SessionRevokedPasswordChange = "password_change"
+	SessionRevokedPasswordReset  = "password_reset"
+	SessionRevokedReuse          = "token_reuse"
+	SessionRevokedUser           = "user"
+)
+
+// Session is a refresh token family for a single device.
+// Every refresh rotates the token, only the hash of the latest one is kept.
+// The sessions of super users impersonating a user have their Actor and
+// no refresh token.
+type Session struct {
+	Id            string     `bson:"id"`
+	Actor         *Ref       `bson:"actor"`
+	CreatedAt     *time.Time `bson:"created_at"`
+	DeviceName    string     `bson:"device_name"`
+	ExpiresAt     *time.Time `bson:"expires_at"`
+	IP            string     `bson:"ip"`
+	IsRevoked     bool       `bson:"is_revoked"`
+	LastUsedAt    *time.Time `bson:"last_used_at"`
+	RefreshToken  string     `bson:"refresh_token"`
+	RevokedAt     *time.Time `bson:"revoked_at"`
+	RevokedBy     *Ref       `bson:"revoked_by"`
+	RevokedReason string     `bson:"revoked_reason"`
+	UserAgent     string     `bson:"user_agent"`
+	UserId        string     `bson:"user_id"`
+}
+
+type SessionResponse struct {
+	Id         string     `json:"id"`
+	CreatedAt  *time.Time `json:"created_at"`
+	Current    bool       `json:"current"`
+	DeviceName string     `json:"device_name"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	IP         string     `json:"ip"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	UserAgent  string     `json:"user_agent"`
+}
+
+func NewSession(userId string) *Session {
+	now := time.Now()
+	return &Session{
+		Id:        xid.New().String(),
+		CreatedAt: &now,
+		UserId:    userId,
+	}
+}
+
+func (s *Session) Response() *SessionResponse {
+	return &SessionResponse{
+		Id:         s.Id,
+		CreatedAt:  s.CreatedAt,
+		DeviceName: s.DeviceName,
+		ExpiresAt:  s.ExpiresAt,
+		IP:         s.IP,
+		LastUsedAt: s.LastUsedAt,
+		UserAgent:  s.UserAgent,
+	}
+}
+
+// Rotate replaces the current refresh token of the session, the new one is hashed
+// with the current argon2 parameters so older hashes are replaced on refresh.
+func (s *Session) Rotate(token []byte) error {
+	b, err := password.Hash(token)
+	if err != nil {
+		return err
+	}
+
+	t := time.Now()
+	expiresAt := t.Add(viper.GetDuration(config.JWTRefreshTokenExpiry))
+	s.ExpiresAt = &expiresAt
+	s.LastUsedAt = &t
+	s.RefreshToken = b
+
+	return nil
+}
+
+// Impersonate makes s the session of actorId impersonating its user, it
+// lasts --impersonation-expiry and has no refresh token to rotate.
+func (s *Session) Impersonate(actorId string) {
+	t := time.Now()
+	expiresAt := t.Add(viper.GetDuration(config.ImpersonationExpiry))
+	s.Actor = &Ref{Id: actorId}
+	s.ExpiresAt = &expiresAt
+	s.LastUsedAt = &t
+}
+
+// Revoke revokes s for reason, id is the user revoking it,
+// it's empty when it's revoked without one.
+func (s *Session) Revoke(id string, reason string) {
+	t := time.Now()
+	s.IsRevoked = true
+	s.RefreshToken = ""
+	s.RevokedAt = &t
+	s.RevokedBy = nil
+	if id != "" {
+		s.RevokedBy = &Ref{Id: id}
+	}
+	s.RevokedReason = reason
+}
+
+func (s *Session) ValidateRefreshToken(token string) error {
+	return password.Verify([]byte(s.RefreshToken), []byte(token))
+}
+
+type Sessions []Session
+
+type SessionsResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+func (sessions Sessions) Response() *SessionsResponse {
+	res := make([]SessionResponse, 0)
+	for _, session := range sessions {
+		res = append(res, *session.Response())
+	}
+	return &SessionsResponse{Sessions: res}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	session := NewSession(user.Id)
+	assert.Equal(t, user.Id, session.UserId)
+	assert.NotNil(t, session.CreatedAt)
+
+	token := "token"
+	err := session.Rotate([]byte(token))
+	assert.NoError(t, err)
+	assert.NotNil(t, session.ExpiresAt)
+	assert.NotNil(t, session.LastUsedAt)
+
+	err = session.ValidateRefreshToken(token)
+	assert.NoError(t, err)
+
+	err = session.Rotate([]byte("other"))
+	assert.NoError(t, err)
+
+	err = session.ValidateRefreshToken(token)
+	assert.Error(t, err)
+
+	session.Revoke("", SessionRevokedReuse)
+	assert.True(t, session.IsRevoked)
+	assert.NotNil(t, session.RevokedAt)
+	assert.Nil(t, session.RevokedBy)
+	assert.Equal(t, SessionRevokedReuse, session.RevokedReason)
+
+	session.Revoke(user.Id, SessionRevokedUser)
+	assert.Equal(t, user.Id, session.RevokedBy.Id)
+
+	err = session.ValidateRefreshToken("other")
+	assert.Error(t, err)
+
+	resp := session.Response()
+	assert.Equal(t, session.Id, resp.Id)
+	assert.False(t, resp.Current)
+}
+
+func TestSessions(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+
+	sessions := Sessions{*NewSession(user.Id), *NewSession(user.Id)}
+	resp := sessions.Response()
+	assert.Len(t, resp.Sessions, 2)
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/crypt"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+// SigningKey is a key tokens are signed with. Only the active key signs new
+// tokens, retired keys still verify the tokens they signed until they expire
+// and the next key is published before it activates.
+// PrivateKey is encrypted with --jwt-key-encryption-key.
+type SigningKey struct {
+	Id          string     `bson:"id"`
+	ActivatesAt *time.Time `bson:"activates_at"`
+	Algorithm   string     `bson:"algorithm"`
+	CreatedAt   *time.Time `bson:"created_at"`
+	ExpiresAt   *time.Time `bson:"expires_at"`
+	PrivateKey  string     `bson:"private_key"`
+	RetiredAt   *time.Time `bson:"retired_at"`
+}
+
+type SigningKeys []SigningKey
+
+// NewSigningKey generates a new signing key for algorithm.
+func NewSigningKey(algorithm string) (*SigningKey, error) {
+	raw, err := jwt.GenerateKey(jwa.SignatureAlgorithm(algorithm))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSigningKeyFromRaw(raw)
+}
+
+// NewSigningKeyFromRaw creates a SigningKey from an existing private key,
+// its id is the kid of the tokens it signs.
+func NewSigningKeyFromRaw(raw any) (*SigningKey, error) {
+	key, err := jwt.Key(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := jwt.EncodePrivateKey(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptionKey, err := crypt.ParseKey(viper.GetString(config.JWTKeyEncryptionKey))
+	if err != nil {
+		return nil, err
+	}
+
+	// the key id is authenticated so keys can't be swapped
+	privateKey, err := crypt.Encrypt(encryptionKey, b, []byte(key.KeyID()))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &SigningKey{
+		Id:         key.KeyID(),
+		Algorithm:  key.Algorithm().String(),
+		CreatedAt:  &now,
+		PrivateKey: privateKey,
+	}, nil
+}
+
+// Activate makes s start signing tokens at t instead of once it's created.
+func (s *SigningKey) Activate(t time.Time) {
+	s.ActivatesAt = &t
+}
+
+// ActiveSince returns when s started, or starts, signing tokens.
+func (s *SigningKey) ActiveSince() time.Time {
+	if s.ActivatesAt != nil {
+		return *s.ActivatesAt
+	}
+
+	return *s.CreatedAt
+}
+
+// IsPending reports whether s doesn't sign tokens yet at t.
+func (s *SigningKey) IsPending(t time.Time) bool {
+	return t.Before(s.ActiveSince())
+}
+
+// Retire stops s from signing tokens at t, it keeps verifying them for retention.
+func (s *SigningKey) Retire(t time.Time, retention time.Duration) {
+	expiresAt := t.Add(retention)
+	s.RetiredAt = &t
+	s.ExpiresAt = &expiresAt
+}
+
+func (s *SigningKey) IsRetired() bool {
+	return s.RetiredAt != nil
+}
+
+// Key returns the private key of s as a JWK.
+func (s *SigningKey) Key() (jwk.Key, error) {
+	encryptionKey, err := crypt.ParseKey(viper.GetString(config.JWTKeyEncryptionKey))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := crypt.Decrypt(encryptionKey, s.PrivateKey, []byte(s.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := jwt.ParsePrivateKey(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt.Key(raw)
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/crypt"
+)
+
+const testKeyEncryptionKey = "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U="
+
+func TestSigningKey(t *testing.T) {
+	viper.Set(config.JWTKeyEncryptionKey, testKeyEncryptionKey)
+
+	s, err := NewSigningKey("RS256")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, s.Id)
+	assert.False(t, strings.Contains(s.PrivateKey, "PRIVATE KEY"))
+	assert.Equal(t, "RS256", s.Algorithm)
+	assert.NotNil(t, s.CreatedAt)
+	assert.Nil(t, s.ExpiresAt)
+	assert.False(t, s.IsRetired())
+
+	key, err := s.Key()
+	assert.NoError(t, err)
+	assert.Equal(t, s.Id, key.KeyID())
+
+	assert.False(t, s.IsPending(time.Now()))
+	at := time.Now().Add(time.Minute)
+	s.Activate(at)
+	assert.True(t, s.IsPending(time.Now()))
+	assert.False(t, s.IsPending(at))
+	assert.Equal(t, at, s.ActiveSince())
+
+	s.Retire(time.Now(), time.Hour)
+	assert.True(t, s.IsRetired())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *s.ExpiresAt, time.Second)
+}
+
+func TestSigningKey_Encryption(t *testing.T) {
+	viper.Set(config.JWTKeyEncryptionKey, testKeyEncryptionKey)
+	s, err := NewSigningKey("ES256")
+	assert.NoError(t, err)
+
+	// keys can't be swapped
+	other, err := NewSigningKey("ES256")
+	assert.NoError(t, err)
+	other.PrivateKey = s.PrivateKey
+	_, err = other.Key()
+	assert.ErrorIs(t, err, crypt.ErrCiphertextInvalid)
+
+	viper.Set(config.JWTKeyEncryptionKey, "")
+	_, err = s.Key()
+	assert.ErrorIs(t, err, crypt.ErrKeyInvalid)
+	_, err = NewSigningKey("ES256")
+	assert.ErrorIs(t, err, crypt.ErrKeyInvalid)
+}
+
+func TestNewSigningKey_Algorithms(t *testing.T) {
+	viper.Set(config.JWTKeyEncryptionKey, testKeyEncryptionKey)
+
+	for _, alg := range []string{"RS256", "ES256", "ES384", "EdDSA"} {
+		s, err := NewSigningKey(alg)
+		assert.NoError(t, err)
+		assert.Equal(t, alg, s.Algorithm)
+
+		key, err := s.Key()
+		assert.NoError(t, err)
+		assert.Equal(t, s.Id, key.KeyID())
+	}
+
+	_, err := NewSigningKey("HS256")
+	assert.Error(t, err)
+}
@@ -1,12 +1,20 @@
 package models
 
 import (
+	"encoding/base32"
 	"errors"
 	"slices"
+	"strings"
 	"time"
 
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/crypt"
 	"github.com/alexferl/echo-boilerplate/util/jwt"
 	"github.com/alexferl/echo-boilerplate/util/password"
+	"github.com/alexferl/echo-boilerplate/util/rand"
+	"github.com/alexferl/echo-boilerplate/util/totp"
 )
 
 type Role int8
@@ -46,8 +54,27 @@ var (
 	ErrUnlockExist          = errors.New("user already unlocked")
 	ErrUnlockMorePrivileged = errors.New("cannot unlock user with higher permissions")
 
+	ErrUntilPast = errors.New("until must be in the future")
+
 	ErrRoleSelf = errors.New("cannot modify own roles")
 
+	ErrRevokeSessionsMorePrivileged = errors.New("cannot revoke sessions of user with higher permissions")
+
+	ErrRevokePersonalAccessTokensMorePrivileged = errors.New("cannot revoke personal access tokens of user with higher permissions")
+	ErrReadPersonalAccessTokensMorePrivileged   = errors.New("cannot read personal access tokens of user with higher permissions")
+
+	ErrSuperRoleRequired = errors.New("super role required")
+	ErrImpersonateSelf   = errors.New("cannot impersonate self")
+	ErrImpersonateSuper  = errors.New("cannot impersonate super user")
+
+	ErrPasswordInvalid = errors.New("invalid password")
+	ErrPasswordPolicy  = errors.New("password doesn't meet the password policy")
+	ErrEmailUnchanged  = errors.New("email is already the current one")
+
+	ErrMFACodeInvalid  = errors.New("invalid mfa code")
+	ErrTOTPExist       = errors.New("totp already enabled")
+	ErrTOTPNotEnrolled = errors.New("totp enrollment not started")
+
 	ErrRoleAddExist          = errors.New("user already has role")
 	ErrRoleAddMorePrivileged = errors.New("cannot add a more privileged role")
 
@@ -56,47 +83,80 @@ var (
 )
 
 type User struct {
-	*Model        `bson:",inline"`
-	BannedAt      *time.Time `bson:"banned_at"`
-	BannedBy      any        `bson:"banned_by"`
-	Bio           string     `bson:"bio"`
-	Email         string     `bson:"email"`
-	IsBanned      bool       `bson:"is_banned"`
-	IsLocked      bool       `bson:"is_locked"`
-	LastLoginAt   *time.Time `bson:"last_login_at"`
-	LastLogoutAt  *time.Time `bson:"last_logout_at"`
-	LastRefreshAt *time.Time `bson:"last_refresh_at"`
-	LockedAt      *time.Time `bson:"locked_at"`
-	LockedBy      any        `bson:"locked_by"`
-	Name          string     `bson:"name"`
-	Password      string     `bson:"password"`
-	RefreshToken  string     `bson:"refresh_token"`
-	Roles         []string   `bson:"roles"`
-	UnbannedAt    *time.Time `bson:"unbanned_at"`
-	UnbannedBy    any        `bson:"unbanned_by"`
-	UnlockedAt    *time.Time `bson:"unlocked_at"`
-	UnlockedBy    any        `bson:"unlocked_by"`
-	Username      string     `bson:"username"`
+	*Model          `bson:",inline"`
+	BanHistory      []*Restriction `bson:"ban_history"`
+	BanReason       string         `bson:"ban_reason"`
+	BannedAt        *time.Time     `bson:"banned_at"`
+	BannedBy        any            `bson:"banned_by"`
+	BannedUntil     *time.Time     `bson:"banned_until"`
+	Bio             string         `bson:"bio"`
+	Email           string         `bson:"email"`
+	EmailVerifiedAt *time.Time     `bson:"email_verified_at"`
+	Identities      []Identity     `bson:"identities"`
+	IsBanned        bool           `bson:"is_banned"`
+	IsLocked        bool           `bson:"is_locked"`
+	LastLoginAt     *time.Time     `bson:"last_login_at"`
+	LastLogoutAt    *time.Time     `bson:"last_logout_at"`
+	LastRefreshAt   *time.Time     `bson:"last_refresh_at"`
+	LockHistory     []*Restriction `bson:"lock_history"`
+	LockReason      string         `bson:"lock_reason"`
+	LockedAt        *time.Time     `bson:"locked_at"`
+	LockedBy        any            `bson:"locked_by"`
+	LockedUntil     *time.Time     `bson:"locked_until"`
+	Name            string         `bson:"name"`
+	Password        string         `bson:"password"`
+	PendingEmail    string         `bson:"pending_email"`
+	RecoveryCodes   []string       `bson:"recovery_codes"`
+	Roles           []string       `bson:"roles"`
+	TOTPCounter     int64          `bson:"totp_counter"`
+	TOTPEnabledAt   *time.Time     `bson:"totp_enabled_at"`
+	TOTPSecret      string         `bson:"totp_secret"`
+	UnbannedAt      *time.Time     `bson:"unbanned_at"`
+	UnbannedBy      any            `bson:"unbanned_by"`
+	UnlockedAt      *time.Time     `bson:"unlocked_at"`
+	UnlockedBy      any            `bson:"unlocked_by"`
+	Username        string         `bson:"username"`
 }
 
 type UserResponse struct {
-	Id        string     `json:"id"`
-	Bio       string     `json:"bio"`
-	CreatedAt *time.Time `json:"created_at"`
-	Email     string     `json:"email,omitempty"`
-	Name      string     `json:"name"`
-	Roles     []string   `json:"-"`
-	UpdatedAt *time.Time `json:"updated_at"`
-	Username  string     `json:"username"`
+	Id              string     `json:"id"`
+	Bio             string     `json:"bio"`
+	CreatedAt       *time.Time `json:"created_at"`
+	Email           string     `json:"email,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	Name            string     `json:"name"`
+	Roles           []string   `json:"-"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+	Username        string     `json:"username"`
 }
 
 type UserAdminResponse struct {
 	UserResponse
-	IsBanned      bool       `json:"is_banned"`
-	IsLocked      bool       `json:"is_locked"`
-	LastLoginAt   *time.Time `json:"last_login_at"`
-	LastLogoutAt  *time.Time `json:"last_logout_at"`
-	LastRefreshAt *time.Time `json:"last_refresh_at"`
+	BanHistory    []*Restriction `json:"ban_history"`
+	BanReason     string         `json:"ban_reason,omitempty"`
+	BannedUntil   *time.Time     `json:"banned_until"`
+	IsBanned      bool           `json:"is_banned"`
+	IsLocked      bool           `json:"is_locked"`
+	LastLoginAt   *time.Time     `json:"last_login_at"`
+	LastLogoutAt  *time.Time     `json:"last_logout_at"`
+	LastRefreshAt *time.Time     `json:"last_refresh_at"`
+	LockHistory   []*Restriction `json:"lock_history"`
+	LockReason    string         `json:"lock_reason,omitempty"`
+	LockedUntil   *time.Time     `json:"locked_until"`
+}
+
+// Restriction is a ban or lock of a user kept in its history. Until is nil when
+// it lasts until it's lifted, LiftedBy is nil when it was lifted because it expired.
+// The ids of the sessions and personal access tokens revoked along with it are kept.
+type Restriction struct {
+	CreatedAt                   *time.Time `json:"created_at" bson:"created_at"`
+	CreatedBy                   *Ref       `json:"created_by" bson:"created_by"`
+	LiftedAt                    *time.Time `json:"lifted_at" bson:"lifted_at"`
+	LiftedBy                    *Ref       `json:"lifted_by" bson:"lifted_by"`
+	Reason                      string     `json:"reason" bson:"reason"`
+	RevokedPersonalAccessTokens []string   `json:"revoked_personal_access_tokens" bson:"revoked_personal_access_tokens"`
+	RevokedSessions             []string   `json:"revoked_sessions" bson:"revoked_sessions"`
+	Until                       *time.Time `json:"until" bson:"until"`
 }
 
 type UserRef struct {
@@ -122,31 +182,39 @@ func NewUserWithRole(email string, username string, role Role) *User {
 
 func (u *User) Response() *UserResponse {
 	return &UserResponse{
-		Id:        u.Id,
-		Bio:       u.Bio,
-		CreatedAt: u.CreatedAt,
-		Email:     u.Email,
-		Name:      u.Name,
-		UpdatedAt: u.UpdatedAt,
-		Username:  u.Username,
+		Id:              u.Id,
+		Bio:             u.Bio,
+		CreatedAt:       u.CreatedAt,
+		Email:           u.Email,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		Name:            u.Name,
+		UpdatedAt:       u.UpdatedAt,
+		Username:        u.Username,
 	}
 }
 
 func (u *User) AdminResponse() *UserAdminResponse {
 	return &UserAdminResponse{
 		UserResponse: UserResponse{
-			Id:        u.Id,
-			Bio:       u.Bio,
-			CreatedAt: u.CreatedAt,
-			Name:      u.Name,
-			UpdatedAt: u.UpdatedAt,
-			Username:  u.Username,
+			Id:              u.Id,
+			Bio:             u.Bio,
+			CreatedAt:       u.CreatedAt,
+			EmailVerifiedAt: u.EmailVerifiedAt,
+			Name:            u.Name,
+			UpdatedAt:       u.UpdatedAt,
+			Username:        u.Username,
 		},
+		BanHistory:    u.BanHistory,
+		BanReason:     u.BanReason,
+		BannedUntil:   u.BannedUntil,
 		IsBanned:      u.IsBanned,
 		IsLocked:      u.IsLocked,
 		LastLoginAt:   u.LastLoginAt,
 		LastLogoutAt:  u.LastLogoutAt,
 		LastRefreshAt: u.LastRefreshAt,
+		LockHistory:   u.LockHistory,
+		LockReason:    u.LockReason,
+		LockedUntil:   u.LockedUntil,
 	}
 }
 
@@ -160,7 +228,12 @@ func (u *User) Ref() *UserRef {
 	}
 }
 
+// SetPassword hashes s as the password of the user once it meets the password policy.
 func (u *User) SetPassword(s string) error {
+	if errs := password.Check(s, u.Username, u.Email); len(errs) > 0 {
+		return NewValidationError(ErrPasswordPolicy, errs)
+	}
+
 	b, err := password.Hash([]byte(s))
 	if err != nil {
 		return err
@@ -173,6 +246,221 @@ func (u *User) ValidatePassword(s string) error {
 	return password.Verify([]byte(u.Password), []byte(s))
 }
 
+// RehashPassword hashes s again if the password of the user was hashed with
+// older argon2 parameters, s must have been validated. It returns whether the
+// password changed and needs to be saved.
+func (u *User) RehashPassword(s string) (bool, error) {
+	if !password.NeedsRehash([]byte(u.Password)) {
+		return false, nil
+	}
+
+	b, err := password.Hash([]byte(s))
+	if err != nil {
+		return false, err
+	}
+	u.Password = b
+	return true, nil
+}
+
+// SetEmail changes the email of the user, the new one needs to be verified again.
+func (u *User) SetEmail(email string) {
+	if email == u.Email {
+		return
+	}
+
+	u.Email = email
+	u.EmailVerifiedAt = nil
+}
+
+// ChangePassword replaces the password of the user, current must be the one it has now.
+func (u *User) ChangePassword(current string, password string) error {
+	if u.ValidatePassword(current) != nil {
+		return NewError(ErrPasswordInvalid, Invalid)
+	}
+
+	return u.SetPassword(password)
+}
+
+// ChangeEmail makes email the pending email of the user once they
+// re-authenticate with their password, and their MFA code if enabled.
+// It only replaces the current one once verified.
+func (u *User) ChangeEmail(password string, code string, email string) error {
+	if u.ValidatePassword(password) != nil {
+		return NewError(ErrPasswordInvalid, Invalid)
+	}
+
+	if u.IsMFAEnabled() {
+		if err := u.ValidateMFA(code); err != nil {
+			return err
+		}
+	}
+
+	if strings.EqualFold(email, u.Email) {
+		return NewError(ErrEmailUnchanged, Conflict)
+	}
+
+	u.PendingEmail = email
+
+	return nil
+}
+
+func (u *User) VerifyEmail() {
+	t := time.Now()
+	u.EmailVerifiedAt = &t
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// RecoveryCodesCount is the number of recovery codes generated when TOTP is enabled.
+const RecoveryCodesCount = 10
+
+// recoveryCodeSize is the number of random bytes of a recovery code,
+// 80 bits making 16 base32 characters.
+const recoveryCodeSize = 10
+
+// EnrollTOTP generates a new TOTP secret for u and returns it along with
+// its otpauth URI. It needs to be confirmed with ConfirmTOTP before being used.
+// The secret is kept encrypted with --mfa-secret-encryption-key.
+func (u *User) EnrollTOTP() (string, string, error) {
+	if u.IsMFAEnabled() {
+		return "", "", NewError(ErrTOTPExist, Conflict)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	account := u.Email
+	if account == "" {
+		account = u.Username
+	}
+
+	key, err := crypt.ParseKey(viper.GetString(config.MFASecretEncryptionKey))
+	if err != nil {
+		return "", "", err
+	}
+
+	// the user id is authenticated so secrets can't be swapped between users
+	encrypted, err := crypt.Encrypt(key, []byte(secret), []byte(u.Id))
+	if err != nil {
+		return "", "", err
+	}
+
+	u.TOTPSecret = encrypted
+	u.TOTPCounter = 0
+
+	return secret, totp.URI(viper.GetString(config.AppName), account, secret), nil
+}
+
+// ConfirmTOTP enables TOTP if code is valid and returns new recovery codes.
+// Only their argon2 hashes are kept so they can't be shown again.
+func (u *User) ConfirmTOTP(code string) ([]string, error) {
+	if u.IsMFAEnabled() {
+		return nil, NewError(ErrTOTPExist, Conflict)
+	}
+
+	if u.TOTPSecret == "" {
+		return nil, NewError(ErrTOTPNotEnrolled, Conflict)
+	}
+
+	secret, err := u.totpSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	counter, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, NewError(ErrMFACodeInvalid, Invalid)
+	}
+
+	codes := make([]string, 0, RecoveryCodesCount)
+	hashes := make([]string, 0, RecoveryCodesCount)
+	for i := 0; i < RecoveryCodesCount; i++ {
+		b, err := rand.GenerateRandomBytes(recoveryCodeSize)
+		if err != nil {
+			return nil, err
+		}
+
+		c := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		h, err := password.Hash([]byte(c))
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, c[:4]+"-"+c[4:8]+"-"+c[8:12]+"-"+c[12:])
+		hashes = append(hashes, h)
+	}
+
+	t := time.Now()
+	u.RecoveryCodes = hashes
+	u.TOTPCounter = counter
+	u.TOTPEnabledAt = &t
+
+	return codes, nil
+}
+
+// ValidateMFA checks code against the TOTP secret of u, falling back on the recovery codes.
+// Codes can only be used once.
+func (u *User) ValidateMFA(code string) error {
+	if !u.IsMFAEnabled() {
+		return NewError(ErrMFACodeInvalid, Invalid)
+	}
+
+	secret, err := u.totpSecret()
+	if err != nil {
+		return err
+	}
+
+	counter, ok := totp.Validate(secret, code, time.Now())
+	if ok && counter > u.TOTPCounter {
+		u.TOTPCounter = counter
+		return nil
+	}
+
+	// the hashes are only checked against what could be a recovery code,
+	// hashing TOTP codes against each of them would be wasted work
+	c := strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	if len(c) != base32.StdEncoding.EncodedLen(recoveryCodeSize) {
+		return NewError(ErrMFACodeInvalid, Invalid)
+	}
+
+	for i, rc := range u.RecoveryCodes {
+		if password.Verify([]byte(rc), []byte(c)) == nil {
+			u.RecoveryCodes = slices.Delete(u.RecoveryCodes, i, i+1)
+			return nil
+		}
+	}
+
+	return NewError(ErrMFACodeInvalid, Invalid)
+}
+
+// totpSecret decrypts the TOTP secret of u.
+func (u *User) totpSecret() (string, error) {
+	key, err := crypt.ParseKey(viper.GetString(config.MFASecretEncryptionKey))
+	if err != nil {
+		return "", err
+	}
+
+	b, err := crypt.Decrypt(key, u.TOTPSecret, []byte(u.Id))
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func (u *User) IsMFAEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// MFARequired reports whether u has to enable MFA before using the API.
+func (u *User) MFARequired() bool {
+	return viper.GetBool(config.MFARequireAdmin) && u.HasRoleOrHigher(AdminRole)
+}
+
 func (u *User) HasRoleOrHigher(role Role) bool {
 	if slices.Max(stringSliceToRolesSlice(u.Roles)) >= role {
 		return true
@@ -271,7 +559,8 @@ func (u *User) RemoveRole(user *User, role Role) error {
 	return nil
 }
 
-func (u *User) Ban(user *User) error {
+// Ban bans u for reason until the given time, or until it's lifted when until is nil.
+func (u *User) Ban(user *User, reason string, until *time.Time) error {
 	if slices.Max(stringSliceToRolesSlice(user.Roles)) < AdminRole {
 		return NewError(ErrAdminRoleRequired, Permission)
 	}
@@ -288,12 +577,26 @@ func (u *User) Ban(user *User) error {
 		return NewError(ErrBanExist, Conflict)
 	}
 
-	u.IsBanned = true
 	t := time.Now()
+	if until != nil && !until.After(t) {
+		return NewError(ErrUntilPast, Invalid)
+	}
+
+	// the ref is shared with the history so marking it with Act marks both
+	by := &Ref{Id: user.Id}
+	u.IsBanned = true
+	u.BanReason = reason
 	u.BannedAt = &t
-	u.BannedBy = &Ref{Id: user.Id}
+	u.BannedBy = by
+	u.BannedUntil = until
 	u.UnbannedAt = nil
 	u.UnbannedBy = nil
+	u.BanHistory = append(u.BanHistory, &Restriction{
+		CreatedAt: &t,
+		CreatedBy: by,
+		Reason:    reason,
+		Until:     until,
+	})
 
 	return nil
 }
@@ -315,17 +618,13 @@ func (u *User) Unban(user *User) error {
 		return NewError(ErrUnbanExist, Conflict)
 	}
 
-	u.IsBanned = false
-	t := time.Now()
-	u.BannedAt = nil
-	u.BannedBy = nil
-	u.UnbannedAt = &t
-	u.UnbannedBy = &Ref{Id: user.Id}
+	u.unban(time.Now(), &Ref{Id: user.Id})
 
 	return nil
 }
 
-func (u *User) Lock(user *User) error {
+// Lock locks u for reason until the given time, or until it's lifted when until is nil.
+func (u *User) Lock(user *User, reason string, until *time.Time) error {
 	if slices.Max(stringSliceToRolesSlice(user.Roles)) < AdminRole {
 		return NewError(ErrAdminRoleRequired, Permission)
 	}
@@ -342,12 +641,26 @@ func (u *User) Lock(user *User) error {
 		return NewError(ErrLockExist, Conflict)
 	}
 
-	u.IsLocked = true
 	t := time.Now()
+	if until != nil && !until.After(t) {
+		return NewError(ErrUntilPast, Invalid)
+	}
+
+	// the ref is shared with the history so marking it with Act marks both
+	by := &Ref{Id: user.Id}
+	u.IsLocked = true
+	u.LockReason = reason
 	u.LockedAt = &t
-	u.LockedBy = &Ref{Id: user.Id}
+	u.LockedBy = by
+	u.LockedUntil = until
 	u.UnlockedAt = nil
 	u.UnlockedBy = nil
+	u.LockHistory = append(u.LockHistory, &Restriction{
+		CreatedAt: &t,
+		CreatedBy: by,
+		Reason:    reason,
+		Until:     until,
+	})
 
 	return nil
 }
@@ -369,18 +682,187 @@ func (u *User) Unlock(user *User) error {
 		return NewError(ErrUnlockExist, Conflict)
 	}
 
+	u.unlock(time.Now(), &Ref{Id: user.Id})
+
+	return nil
+}
+
+func (u *User) unban(t time.Time, by *Ref) {
+	u.IsBanned = false
+	u.BanReason = ""
+	u.BannedAt = nil
+	u.BannedBy = nil
+	u.BannedUntil = nil
+	u.UnbannedAt = &t
+	u.UnbannedBy = nil
+	if by != nil {
+		u.UnbannedBy = by
+	}
+	liftLast(u.BanHistory, t, by)
+}
+
+func (u *User) unlock(t time.Time, by *Ref) {
 	u.IsLocked = false
-	t := time.Now()
+	u.LockReason = ""
 	u.LockedAt = nil
 	u.LockedBy = nil
+	u.LockedUntil = nil
 	u.UnlockedAt = &t
-	u.UnlockedBy = &Ref{Id: user.Id}
+	u.UnlockedBy = nil
+	if by != nil {
+		u.UnlockedBy = by
+	}
+	liftLast(u.LockHistory, t, by)
+}
+
+// CurrentBan returns the ban of u from its history, nil when it isn't banned
+// or was banned before history was kept.
+func (u *User) CurrentBan() *Restriction {
+	if !u.IsBanned {
+		return nil
+	}
+
+	return current(u.BanHistory)
+}
+
+// CurrentLock returns the lock of u from its history, nil when it isn't locked
+// or was locked before history was kept.
+func (u *User) CurrentLock() *Restriction {
+	if !u.IsLocked {
+		return nil
+	}
+
+	return current(u.LockHistory)
+}
+
+func current(history []*Restriction) *Restriction {
+	if len(history) == 0 || history[len(history)-1].LiftedAt != nil {
+		return nil
+	}
+
+	return history[len(history)-1]
+}
+
+// liftLast marks the current restriction of history as lifted,
+// users restricted before history was kept don't have one.
+func liftLast(history []*Restriction, t time.Time, by *Ref) {
+	r := current(history)
+	if r == nil {
+		return
+	}
+
+	r.LiftedAt = &t
+	r.LiftedBy = by
+}
+
+// LiftExpired lifts the ban and lock of u that reached their end date
+// and returns true if any was.
+func (u *User) LiftExpired() bool {
+	now := time.Now()
+	lifted := false
+
+	if u.IsBanned && u.BannedUntil != nil && !now.Before(*u.BannedUntil) {
+		u.unban(*u.BannedUntil, nil)
+		lifted = true
+	}
+
+	if u.IsLocked && u.LockedUntil != nil && !now.Before(*u.LockedUntil) {
+		u.unlock(*u.LockedUntil, nil)
+		lifted = true
+	}
+
+	return lifted
+}
+
+// RevokeSessions checks if user is allowed to revoke the sessions of u.
+func (u *User) RevokeSessions(user *User) error {
+	if user.Id == u.Id {
+		return nil
+	}
+
+	if slices.Max(stringSliceToRolesSlice(user.Roles)) < AdminRole {
+		return NewError(ErrAdminRoleRequired, Permission)
+	}
+
+	if u.compare(user) {
+		return NewError(ErrRevokeSessionsMorePrivileged, Permission)
+	}
 
 	return nil
 }
 
-func (u *User) Login() ([]byte, []byte, error) {
-	access, refresh, err := u.getTokens()
+// RevokePersonalAccessTokens checks if user is allowed to revoke the personal access tokens of u.
+func (u *User) RevokePersonalAccessTokens(user *User) error {
+	if user.Id == u.Id {
+		return nil
+	}
+
+	if slices.Max(stringSliceToRolesSlice(user.Roles)) < AdminRole {
+		return NewError(ErrAdminRoleRequired, Permission)
+	}
+
+	if u.compare(user) {
+		return NewError(ErrRevokePersonalAccessTokensMorePrivileged, Permission)
+	}
+
+	return nil
+}
+
+// ReadPersonalAccessTokens checks if user is allowed to read the personal access tokens of u.
+func (u *User) ReadPersonalAccessTokens(user *User) error {
+	if user.Id == u.Id {
+		return nil
+	}
+
+	if slices.Max(stringSliceToRolesSlice(user.Roles)) < AdminRole {
+		return NewError(ErrAdminRoleRequired, Permission)
+	}
+
+	if u.compare(user) {
+		return NewError(ErrReadPersonalAccessTokensMorePrivileged, Permission)
+	}
+
+	return nil
+}
+
+// NotOutrankedRoles returns the roles of the users u isn't more privileged than,
+// there are none for supers since they can act on each other.
+func (u *User) NotOutrankedRoles() []string {
+	highest := slices.Max(stringSliceToRolesSlice(u.Roles))
+	if highest == SuperRole {
+		return nil
+	}
+
+	var roles []string
+	for r := highest; r <= SuperRole; r++ {
+		roles = append(roles, r.String())
+	}
+	return roles
+}
+
+// Impersonate checks if user is allowed to impersonate u and returns an access
+// token for u recording user as the actor, it can't be refreshed. The token
+// belongs to session, which must be saved so the token can be revoked.
+func (u *User) Impersonate(user *User, session *Session) ([]byte, error) {
+	if !hasRoleOrHigher(user, SuperRole) {
+		return nil, NewError(ErrSuperRoleRequired, Permission)
+	}
+
+	if user.Id == u.Id {
+		return nil, NewError(ErrImpersonateSelf, Conflict)
+	}
+
+	if hasRoleOrHigher(u, SuperRole) {
+		return nil, NewError(ErrImpersonateSuper, Permission)
+	}
+
+	session.Impersonate(user.Id)
+
+	return jwt.GenerateImpersonationToken(u.Id, user.Id, session.Id)
+}
+
+func (u *User) Login(session *Session) ([]byte, []byte, error) {
+	access, refresh, err := u.getTokens(session)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -388,7 +870,7 @@ func (u *User) Login() ([]byte, []byte, error) {
 	t := time.Now()
 	u.LastLoginAt = &t
 
-	err = u.encryptRefreshToken(refresh)
+	err = session.Rotate(refresh)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -396,14 +878,14 @@ func (u *User) Login() ([]byte, []byte, error) {
 	return access, refresh, nil
 }
 
-func (u *User) Logout() {
+func (u *User) Logout(session *Session) {
 	t := time.Now()
 	u.LastLogoutAt = &t
-	u.RefreshToken = ""
+	session.Revoke(u.Id, SessionRevokedLogout)
 }
 
-func (u *User) Refresh() ([]byte, []byte, error) {
-	access, refresh, err := u.getTokens()
+func (u *User) Refresh(session *Session) ([]byte, []byte, error) {
+	access, refresh, err := u.getTokens(session)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -411,7 +893,7 @@ func (u *User) Refresh() ([]byte, []byte, error) {
 	t := time.Now()
 	u.LastRefreshAt = &t
 
-	err = u.encryptRefreshToken(refresh)
+	err = session.Rotate(refresh)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -419,28 +901,20 @@ func (u *User) Refresh() ([]byte, []byte, error) {
 	return access, refresh, nil
 }
 
-func (u *User) ValidateRefreshToken(s string) error {
-	return password.Verify([]byte(u.RefreshToken), []byte(s))
-}
+func (u *User) getTokens(session *Session) ([]byte, []byte, error) {
+	claims := map[string]any{"sid": session.Id}
 
-func (u *User) getTokens() ([]byte, []byte, error) {
-	access, refresh, err := jwt.GenerateTokens(u.Id, nil)
+	access, err := jwt.GenerateAccessToken(u.Id, claims)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return access, refresh, nil
-}
-
-func (u *User) encryptRefreshToken(token []byte) error {
-	b, err := password.Hash(token)
+	refresh, err := jwt.GenerateRefreshToken(u.Id, claims)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	u.RefreshToken = b
-
-	return nil
+	return access, refresh, nil
 }
 
 type Users []User
@@ -1,11 +1,19 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/crypt"
+	"github.com/alexferl/echo-boilerplate/util/totp"
 )
 
 func TestUser(t *testing.T) {
@@ -45,23 +53,28 @@ func TestUser(t *testing.T) {
 	assert.Equal(t, id, user.DeletedBy.(*Ref).Id)
 	assert.NotNil(t, user.DeletedAt)
 
-	access, refresh, err := user.Login()
+	session := NewSession(user.Id)
+	access, refresh, err := user.Login(session)
 	assert.NoError(t, err)
 	assert.NotEqual(t, "", string(access))
 	assert.NotEqual(t, "", string(refresh))
 	assert.NotNil(t, user.LastLoginAt)
 
-	err = user.ValidateRefreshToken(string(refresh))
+	err = session.ValidateRefreshToken(string(refresh))
 	assert.NoError(t, err)
 
-	access, refresh, err = user.Refresh()
+	oldRefresh := refresh
+	access, refresh, err = user.Refresh(session)
 	assert.NoError(t, err)
 	assert.NotEqual(t, "", string(access))
 	assert.NotEqual(t, "", string(refresh))
 	assert.NotNil(t, user.LastRefreshAt)
 
-	user.Logout()
-	assert.Equal(t, "", user.RefreshToken)
+	err = session.ValidateRefreshToken(string(oldRefresh))
+	assert.Error(t, err)
+
+	user.Logout(session)
+	assert.True(t, session.IsRevoked)
 	assert.NotNil(t, user.LastLogoutAt)
 
 	resp := user.Response()
@@ -73,6 +86,219 @@ func TestUser(t *testing.T) {
 	assert.False(t, admin.IsLocked)
 }
 
+func TestUser_Email(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	assert.False(t, user.IsEmailVerified())
+
+	user.VerifyEmail()
+	assert.True(t, user.IsEmailVerified())
+	assert.Equal(t, user.EmailVerifiedAt, user.Response().EmailVerifiedAt)
+
+	// same email keeps the verification
+	user.SetEmail("test@example.com")
+	assert.True(t, user.IsEmailVerified())
+
+	user.SetEmail("new@example.com")
+	assert.Equal(t, "new@example.com", user.Email)
+	assert.False(t, user.IsEmailVerified())
+}
+
+func TestUser_SetPassword_Policy(t *testing.T) {
+	user := NewUser("test@example.com", "tester")
+
+	err := user.SetPassword("tester")
+	var e *Error
+	assert.ErrorAs(t, err, &e)
+	if errors.As(err, &e) {
+		assert.Equal(t, ErrPasswordPolicy.Error(), e.Message)
+		assert.Equal(t, Invalid, e.Kind)
+		assert.Equal(t, []string{
+			"password must be at least 12 characters",
+			"password can't contain the username or email",
+		}, e.Errors)
+	}
+	assert.Empty(t, user.Password)
+}
+
+func TestUser_RehashPassword(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	assert.NoError(t, user.SetPassword("current-password"))
+	hash := user.Password
+
+	rehashed, err := user.RehashPassword("current-password")
+	assert.NoError(t, err)
+	assert.False(t, rehashed)
+	assert.Equal(t, hash, user.Password)
+
+	viper.Set(config.PasswordArgon2Time, 1)
+	defer viper.Set(config.PasswordArgon2Time, 3)
+
+	rehashed, err = user.RehashPassword("current-password")
+	assert.NoError(t, err)
+	assert.True(t, rehashed)
+	assert.NotEqual(t, hash, user.Password)
+	assert.Contains(t, user.Password, ",t=1,")
+	assert.NoError(t, user.ValidatePassword("current-password"))
+}
+
+func TestUser_ChangePassword(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	assert.NoError(t, user.SetPassword("current-password"))
+
+	err := user.ChangePassword("wrong-password", "correct-horse-staple-battery")
+	var e *Error
+	assert.ErrorAs(t, err, &e)
+	if errors.As(err, &e) {
+		assert.Equal(t, ErrPasswordInvalid.Error(), e.Message)
+		assert.Equal(t, Invalid, e.Kind)
+	}
+	assert.NoError(t, user.ValidatePassword("current-password"))
+
+	assert.NoError(t, user.ChangePassword("current-password", "correct-horse-staple-battery"))
+	assert.NoError(t, user.ValidatePassword("correct-horse-staple-battery"))
+}
+
+func TestUser_ChangeEmail(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	assert.NoError(t, user.SetPassword("current-password"))
+	user.VerifyEmail()
+
+	testCases := []struct {
+		name     string
+		password string
+		email    string
+		err      error
+		kind     Kind
+	}{
+		{"wrong password", "wrong-password", "new@example.com", ErrPasswordInvalid, Invalid},
+		{"same email", "current-password", "TEST@example.com", ErrEmailUnchanged, Conflict},
+		{"success", "current-password", "new@example.com", nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := user.ChangeEmail(tc.password, "", tc.email)
+			if tc.err != nil {
+				var e *Error
+				assert.ErrorAs(t, err, &e)
+				if errors.As(err, &e) {
+					assert.Equal(t, tc.err.Error(), e.Message)
+					assert.Equal(t, tc.kind, e.Kind)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.email, user.PendingEmail)
+				// the current email stays until the new one is verified
+				assert.Equal(t, "test@example.com", user.Email)
+				assert.True(t, user.IsEmailVerified())
+			}
+		})
+	}
+}
+
+func TestUser_ChangeEmail_MFA(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	assert.NoError(t, user.SetPassword("current-password"))
+	secret, _, err := user.EnrollTOTP()
+	assert.NoError(t, err)
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+	_, err = user.ConfirmTOTP(code)
+	assert.NoError(t, err)
+
+	err = user.ChangeEmail("current-password", "", "new@example.com")
+	var e *Error
+	assert.ErrorAs(t, err, &e)
+	if errors.As(err, &e) {
+		assert.Equal(t, ErrMFACodeInvalid.Error(), e.Message)
+	}
+	assert.Empty(t, user.PendingEmail)
+}
+
+func TestUser_TOTP(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	assert.False(t, user.IsMFAEnabled())
+
+	var me *Error
+
+	_, err := user.ConfirmTOTP("123456")
+	assert.ErrorAs(t, err, &me)
+	assert.Equal(t, ErrTOTPNotEnrolled.Error(), me.Message)
+
+	secret, uri, err := user.EnrollTOTP()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, user.TOTPSecret)
+	assert.NotContains(t, user.TOTPSecret, secret)
+	assert.Contains(t, uri, "otpauth://totp/")
+	assert.Contains(t, uri, "secret="+secret)
+	assert.False(t, user.IsMFAEnabled())
+
+	_, err = user.ConfirmTOTP("000000")
+	assert.ErrorAs(t, err, &me)
+	assert.Equal(t, Invalid, me.Kind)
+
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+	codes, err := user.ConfirmTOTP(code)
+	assert.NoError(t, err)
+	assert.Len(t, codes, RecoveryCodesCount)
+	assert.Len(t, user.RecoveryCodes, RecoveryCodesCount)
+	assert.Len(t, codes[0], 19)
+	assert.NotContains(t, user.RecoveryCodes, codes[0])
+	assert.True(t, strings.HasPrefix(user.RecoveryCodes[0], "$argon2id$"))
+	assert.True(t, user.IsMFAEnabled())
+
+	_, _, err = user.EnrollTOTP()
+	assert.ErrorAs(t, err, &me)
+	assert.Equal(t, Conflict, me.Kind)
+
+	// the code used to confirm can't be replayed
+	assert.Error(t, user.ValidateMFA(code))
+
+	next, _ := totp.Code(secret, totp.Counter(time.Now())+1)
+	assert.NoError(t, user.ValidateMFA(next))
+	assert.Error(t, user.ValidateMFA(next))
+
+	// recovery codes are single use
+	assert.NoError(t, user.ValidateMFA(strings.ToUpper(codes[0])))
+	assert.Len(t, user.RecoveryCodes, RecoveryCodesCount-1)
+	assert.Error(t, user.ValidateMFA(codes[0]))
+
+	assert.Error(t, user.ValidateMFA("wrong"))
+}
+
+func TestUser_TOTP_Secret_Encrypted(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	secret, _, err := user.EnrollTOTP()
+	assert.NoError(t, err)
+	code, _ := totp.Code(secret, totp.Counter(time.Now()))
+
+	// the secret is bound to the user it was enrolled for
+	other := NewUser("other@example.com", "other")
+	other.TOTPSecret = user.TOTPSecret
+	_, err = other.ConfirmTOTP(code)
+	assert.ErrorIs(t, err, crypt.ErrCiphertextInvalid)
+
+	key := viper.GetString(config.MFASecretEncryptionKey)
+	viper.Set(config.MFASecretEncryptionKey, "")
+	defer viper.Set(config.MFASecretEncryptionKey, key)
+
+	_, _, err = NewUser("new@example.com", "new").EnrollTOTP()
+	assert.ErrorIs(t, err, crypt.ErrKeyInvalid)
+}
+
+func TestUser_MFARequired(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+
+	assert.False(t, user.MFARequired())
+	assert.False(t, admin.MFARequired())
+
+	viper.Set(config.MFARequireAdmin, true)
+	defer viper.Set(config.MFARequireAdmin, false)
+
+	assert.False(t, user.MFARequired())
+	assert.True(t, admin.MFARequired())
+}
+
 func TestUsers(t *testing.T) {
 	user1 := NewUser("test1@example.com", "test1")
 	user2 := NewUser("test2@example.com", "test2")
@@ -91,6 +317,7 @@ func TestBan(t *testing.T) {
 	admin1 := NewUserWithRole("admin1@example.com", "admin1", AdminRole)
 	super := NewUserWithRole("super@example.com", "super", SuperRole)
 	super1 := NewUserWithRole("super1@example.com", "super1", SuperRole)
+	past := NewUser("past@example.com", "past")
 
 	testCases := []struct {
 		name   string
@@ -104,13 +331,18 @@ func TestBan(t *testing.T) {
 		{"target already banned", admin, bannedUser, ErrBanExist, Conflict},
 		{"target cannot be more privileged", admin, super, ErrBanMorePrivileged, Permission},
 		{"admin cannot ban admin", admin, admin1, ErrBanMorePrivileged, Permission},
+		{"until cannot be in the past", admin, past, ErrUntilPast, Invalid},
 		{"super can ban super", super, super1, nil, 0},
 		{"success", admin, user, nil, 0},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := tc.target.Ban(tc.user)
+			until := time.Now().Add(time.Hour)
+			if tc.target == past {
+				until = time.Now().Add(-time.Hour)
+			}
+			err := tc.target.Ban(tc.user, "spam", &until)
 			if tc.err != nil {
 				assert.Error(t, err)
 				var e *Error
@@ -123,6 +355,12 @@ func TestBan(t *testing.T) {
 				assert.NoError(t, err)
 				assert.NotNil(t, tc.target.BannedAt)
 				assert.Equal(t, tc.user.Id, tc.target.BannedBy.(*Ref).Id)
+				assert.Equal(t, "spam", tc.target.BanReason)
+				assert.Equal(t, &until, tc.target.BannedUntil)
+				assert.Len(t, tc.target.BanHistory, 1)
+				assert.Equal(t, "spam", tc.target.BanHistory[0].Reason)
+				assert.Equal(t, tc.user.Id, tc.target.BanHistory[0].CreatedBy.Id)
+				assert.Nil(t, tc.target.BanHistory[0].LiftedAt)
 			}
 		})
 	}
@@ -182,6 +420,7 @@ func TestLock(t *testing.T) {
 	admin1 := NewUserWithRole("admin1@example.com", "admin1", AdminRole)
 	super := NewUserWithRole("super@example.com", "super", SuperRole)
 	super1 := NewUserWithRole("super1@example.com", "super1", SuperRole)
+	past := NewUser("past@example.com", "past")
 
 	testCases := []struct {
 		name   string
@@ -195,13 +434,18 @@ func TestLock(t *testing.T) {
 		{"target already locked", admin, lockedUser, ErrLockExist, Conflict},
 		{"target cannot be more privileged", admin, super, ErrLockMorePrivileged, Permission},
 		{"admin cannot lock admin", admin, admin1, ErrLockMorePrivileged, Permission},
+		{"until cannot be in the past", admin, past, ErrUntilPast, Invalid},
 		{"super can lock super", super, super1, nil, 0},
 		{"success", admin, user, nil, 0},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := tc.target.Lock(tc.user)
+			until := time.Now().Add(time.Hour)
+			if tc.target == past {
+				until = time.Now().Add(-time.Hour)
+			}
+			err := tc.target.Lock(tc.user, "spam", &until)
 			if tc.err != nil {
 				assert.Error(t, err)
 				var e *Error
@@ -214,6 +458,12 @@ func TestLock(t *testing.T) {
 				assert.NoError(t, err)
 				assert.NotNil(t, tc.target.LockedAt)
 				assert.Equal(t, tc.user.Id, tc.target.LockedBy.(*Ref).Id)
+				assert.Equal(t, "spam", tc.target.LockReason)
+				assert.Equal(t, &until, tc.target.LockedUntil)
+				assert.Len(t, tc.target.LockHistory, 1)
+				assert.Equal(t, "spam", tc.target.LockHistory[0].Reason)
+				assert.Equal(t, tc.user.Id, tc.target.LockHistory[0].CreatedBy.Id)
+				assert.Nil(t, tc.target.LockHistory[0].LiftedAt)
 			}
 		})
 	}
@@ -265,6 +515,87 @@ func TestUnlock(t *testing.T) {
 	}
 }
 
+func TestUnban_History(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+
+	assert.NoError(t, user.Ban(admin, "spam", nil))
+	assert.NoError(t, user.Unban(admin))
+	assert.NoError(t, user.Ban(admin, "more spam", nil))
+
+	assert.Nil(t, user.UnbannedAt)
+	assert.Equal(t, "more spam", user.BanReason)
+	assert.Len(t, user.BanHistory, 2)
+	assert.NotNil(t, user.BanHistory[0].LiftedAt)
+	assert.Equal(t, admin.Id, user.BanHistory[0].LiftedBy.Id)
+	assert.Nil(t, user.BanHistory[1].LiftedAt)
+}
+
+func TestBan_Act(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+	ctx := WithActor(context.Background(), "3000")
+
+	assert.NoError(t, user.Ban(admin, "spam", nil))
+	Act(ctx, user.BannedBy)
+	assert.Equal(t, "3000", user.BanHistory[0].CreatedBy.Actor.Id)
+
+	assert.NoError(t, user.Unban(admin))
+	Act(ctx, user.UnbannedBy)
+	assert.Equal(t, "3000", user.BanHistory[0].LiftedBy.Actor.Id)
+
+	assert.NoError(t, user.Lock(admin, "spam", nil))
+	Act(ctx, user.LockedBy)
+	assert.Equal(t, "3000", user.LockHistory[0].CreatedBy.Actor.Id)
+}
+
+func TestCurrentBan(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+
+	assert.Nil(t, user.CurrentBan())
+	assert.Nil(t, user.CurrentLock())
+
+	assert.NoError(t, user.Ban(admin, "spam", nil))
+	assert.NoError(t, user.Lock(admin, "", nil))
+	assert.Equal(t, user.BanHistory[0], user.CurrentBan())
+	assert.Equal(t, user.LockHistory[0], user.CurrentLock())
+
+	assert.NoError(t, user.Unban(admin))
+	assert.Nil(t, user.CurrentBan())
+
+	// banned before history was kept
+	user.IsBanned = true
+	assert.Nil(t, user.CurrentBan())
+}
+
+func TestLiftExpired(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+
+	until := time.Now().Add(time.Hour)
+	assert.NoError(t, user.Ban(admin, "spam", &until))
+	assert.NoError(t, user.Lock(admin, "", nil))
+	assert.False(t, user.LiftExpired())
+
+	past := time.Now().Add(-time.Minute)
+	user.BannedUntil = &past
+	user.BanHistory[0].Until = &past
+	assert.True(t, user.LiftExpired())
+
+	assert.False(t, user.IsBanned)
+	assert.Empty(t, user.BanReason)
+	assert.Nil(t, user.BannedUntil)
+	assert.Equal(t, &past, user.UnbannedAt)
+	assert.Nil(t, user.UnbannedBy)
+	assert.Equal(t, &past, user.BanHistory[0].LiftedAt)
+	assert.Nil(t, user.BanHistory[0].LiftedBy)
+
+	// locks without an end date stay until they're lifted
+	assert.True(t, user.IsLocked)
+	assert.False(t, user.LiftExpired())
+}
+
 func TestAddRole(t *testing.T) {
 	user := NewUser("test@example.com", "test")
 	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
@@ -340,3 +671,178 @@ func TestRemoveRole(t *testing.T) {
 		})
 	}
 }
+
+func TestRevokeSessions(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	user1 := NewUser("test1@example.com", "test1")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+	admin1 := NewUserWithRole("admin1@example.com", "admin1", AdminRole)
+	super := NewUserWithRole("super@example.com", "super", SuperRole)
+	super1 := NewUserWithRole("super1@example.com", "super1", SuperRole)
+
+	testCases := []struct {
+		name   string
+		user   *User
+		target *User
+		err    error
+		kind   Kind
+	}{
+		{"need admin or higher role", user, user1, ErrAdminRoleRequired, Permission},
+		{"target cannot be more privileged", admin, super, ErrRevokeSessionsMorePrivileged, Permission},
+		{"admin cannot revoke admin", admin, admin1, ErrRevokeSessionsMorePrivileged, Permission},
+		{"self", user, user, nil, 0},
+		{"super can revoke super", super, super1, nil, 0},
+		{"success", admin, user, nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.target.RevokeSessions(tc.user)
+			if tc.err != nil {
+				assert.Error(t, err)
+				var e *Error
+				assert.ErrorAs(t, err, &e)
+				if errors.As(err, &e) {
+					assert.Equal(t, tc.err.Error(), e.Message)
+					assert.Equal(t, tc.kind, e.Kind)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRevokePersonalAccessTokens(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	user1 := NewUser("test1@example.com", "test1")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+	admin1 := NewUserWithRole("admin1@example.com", "admin1", AdminRole)
+	super := NewUserWithRole("super@example.com", "super", SuperRole)
+	super1 := NewUserWithRole("super1@example.com", "super1", SuperRole)
+
+	testCases := []struct {
+		name   string
+		user   *User
+		target *User
+		err    error
+		kind   Kind
+	}{
+		{"need admin or higher role", user, user1, ErrAdminRoleRequired, Permission},
+		{"target cannot be more privileged", admin, super, ErrRevokePersonalAccessTokensMorePrivileged, Permission},
+		{"admin cannot revoke admin", admin, admin1, ErrRevokePersonalAccessTokensMorePrivileged, Permission},
+		{"self", user, user, nil, 0},
+		{"super can revoke super", super, super1, nil, 0},
+		{"success", admin, user, nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.target.RevokePersonalAccessTokens(tc.user)
+			if tc.err != nil {
+				assert.Error(t, err)
+				var e *Error
+				assert.ErrorAs(t, err, &e)
+				if errors.As(err, &e) {
+					assert.Equal(t, tc.err.Error(), e.Message)
+					assert.Equal(t, tc.kind, e.Kind)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestReadPersonalAccessTokens(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	user1 := NewUser("test1@example.com", "test1")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+	admin1 := NewUserWithRole("admin1@example.com", "admin1", AdminRole)
+	super := NewUserWithRole("super@example.com", "super", SuperRole)
+	super1 := NewUserWithRole("super1@example.com", "super1", SuperRole)
+
+	testCases := []struct {
+		name   string
+		user   *User
+		target *User
+		err    error
+		kind   Kind
+	}{
+		{"need admin or higher role", user, user1, ErrAdminRoleRequired, Permission},
+		{"target cannot be more privileged", admin, super, ErrReadPersonalAccessTokensMorePrivileged, Permission},
+		{"admin cannot read admin", admin, admin1, ErrReadPersonalAccessTokensMorePrivileged, Permission},
+		{"self", admin, admin, nil, 0},
+		{"super can read super", super, super1, nil, 0},
+		{"success", admin, user, nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.target.ReadPersonalAccessTokens(tc.user)
+			if tc.err != nil {
+				assert.Error(t, err)
+				var e *Error
+				assert.ErrorAs(t, err, &e)
+				if errors.As(err, &e) {
+					assert.Equal(t, tc.err.Error(), e.Message)
+					assert.Equal(t, tc.kind, e.Kind)
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNotOutrankedRoles(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+	super := NewUserWithRole("super@example.com", "super", SuperRole)
+
+	assert.Equal(t, []string{"user", "admin", "super"}, user.NotOutrankedRoles())
+	assert.Equal(t, []string{"admin", "super"}, admin.NotOutrankedRoles())
+	assert.Empty(t, super.NotOutrankedRoles())
+}
+
+func TestImpersonate(t *testing.T) {
+	user := NewUser("test@example.com", "test")
+	admin := NewUserWithRole("admin@example.com", "admin", AdminRole)
+	super := NewUserWithRole("super@example.com", "super", SuperRole)
+	super1 := NewUserWithRole("super1@example.com", "super1", SuperRole)
+
+	testCases := []struct {
+		name   string
+		user   *User
+		target *User
+		err    error
+		kind   Kind
+	}{
+		{"need super role", admin, user, ErrSuperRoleRequired, Permission},
+		{"self", super, super, ErrImpersonateSelf, Conflict},
+		{"target cannot be super", super, super1, ErrImpersonateSuper, Permission},
+		{"success", super, admin, nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			session := NewSession(tc.target.Id)
+			token, err := tc.target.Impersonate(tc.user, session)
+			if tc.err != nil {
+				assert.Error(t, err)
+				var e *Error
+				assert.ErrorAs(t, err, &e)
+				if errors.As(err, &e) {
+					assert.Equal(t, tc.err.Error(), e.Message)
+					assert.Equal(t, tc.kind, e.Kind)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, token)
+				assert.Equal(t, tc.user.Id, session.Actor.Id)
+				assert.NotNil(t, session.ExpiresAt)
+				assert.Empty(t, session.RefreshToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/rs/xid"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+)
+
+const (
+	WebAuthnChallengeLogin        = "login"
+	WebAuthnChallengeRegistration = "registration"
+)
+
+var (
+	ErrWebAuthnChallengeExpired = errors.New("webauthn challenge expired")
+	ErrWebAuthnChallengeInvalid = errors.New("webauthn challenge invalid")
+	ErrWebAuthnChallengeUsed    = errors.New("webauthn challenge already used")
+	ErrWebAuthnCloned           = errors.New("webauthn authenticator may be cloned")
+)
+
+// WebAuthnCredential is a public key credential, such as a passkey, registered by a user.
+type WebAuthnCredential struct {
+	Id              string     `bson:"id"`
+	AAGUID          []byte     `bson:"aaguid"`
+	AttestationType string     `bson:"attestation_type"`
+	BackupEligible  bool       `bson:"backup_eligible"`
+	BackupState     bool       `bson:"backup_state"`
+	CreatedAt       *time.Time `bson:"created_at"`
+	CredentialId    []byte     `bson:"credential_id"`
+	DeletedAt       *time.Time `bson:"deleted_at"`
+	LastUsedAt      *time.Time `bson:"last_used_at"`
+	Name            string     `bson:"name"`
+	PublicKey       []byte     `bson:"public_key"`
+	SignCount       uint32     `bson:"sign_count"`
+	Transports      []string   `bson:"transports"`
+	UserId          string     `bson:"user_id"`
+}
+
+type WebAuthnCredentialResponse struct {
+	Id         string     `json:"id"`
+	CreatedAt  *time.Time `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Name       string     `json:"name"`
+}
+
+func NewWebAuthnCredential(userId string, name string, credential *webauthn.Credential) *WebAuthnCredential {
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	now := time.Now()
+	return &WebAuthnCredential{
+		Id:              xid.New().String(),
+		AAGUID:          credential.Authenticator.AAGUID,
+		AttestationType: credential.AttestationType,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       &now,
+		CredentialId:    credential.ID,
+		Name:            name,
+		PublicKey:       credential.PublicKey,
+		SignCount:       credential.Authenticator.SignCount,
+		Transports:      transports,
+		UserId:          userId,
+	}
+}
+
+func (c *WebAuthnCredential) Response() *WebAuthnCredentialResponse {
+	return &WebAuthnCredentialResponse{
+		Id:         c.Id,
+		CreatedAt:  c.CreatedAt,
+		LastUsedAt: c.LastUsedAt,
+		Name:       c.Name,
+	}
+}
+
+// Credential converts c to the type used by the webauthn library.
+func (c *WebAuthnCredential) Credential() webauthn.Credential {
+	transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+	for _, t := range c.Transports {
+		transports = append(transports, protocol.AuthenticatorTransport(t))
+	}
+
+	return webauthn.Credential{
+		ID:              c.CredentialId,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    c.AAGUID,
+			SignCount: c.SignCount,
+		},
+	}
+}
+
+// Use records a successful login with credential, which is the result of
+// validating an assertion against c. A signature counter that didn't
+// increase means the private key may have been copied to another authenticator.
+func (c *WebAuthnCredential) Use(credential *webauthn.Credential) error {
+	if credential.Authenticator.CloneWarning {
+		return ErrWebAuthnCloned
+	}
+
+	t := time.Now()
+	c.BackupState = credential.Flags.BackupState
+	c.LastUsedAt = &t
+	c.SignCount = credential.Authenticator.SignCount
+
+	return nil
+}
+
+func (c *WebAuthnCredential) Delete() {
+	t := time.Now()
+	c.DeletedAt = &t
+}
+
+type WebAuthnCredentials []WebAuthnCredential
+
+type WebAuthnCredentialsResponse struct {
+	Credentials []WebAuthnCredentialResponse `json:"credentials"`
+}
+
+func (cs WebAuthnCredentials) Response() *WebAuthnCredentialsResponse {
+	res := make([]WebAuthnCredentialResponse, 0)
+	for _, c := range cs {
+		res = append(res, *c.Response())
+	}
+	return &WebAuthnCredentialsResponse{Credentials: res}
+}
+
+// WebAuthnUser adapts a User and its credentials to the webauthn.User interface.
+type WebAuthnUser struct {
+	*User
+	Credentials WebAuthnCredentials
+}
+
+func (u *WebAuthnUser) WebAuthnID() []byte {
+	return []byte(u.Id)
+}
+
+func (u *WebAuthnUser) WebAuthnName() string {
+	if u.Username != "" {
+		return u.Username
+	}
+	return u.Email
+}
+
+func (u *WebAuthnUser) WebAuthnDisplayName() string {
+	if u.Name != "" {
+		return u.Name
+	}
+	return u.WebAuthnName()
+}
+
+func (u *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	res := make([]webauthn.Credential, 0, len(u.Credentials))
+	for _, c := range u.Credentials {
+		res = append(res, c.Credential())
+	}
+	return res
+}
+
+func (u *WebAuthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// WebAuthnChallenge keeps the state of a registration or login
+// ceremony between its begin and finish steps. It can only be used once.
+type WebAuthnChallenge struct {
+	Id        string               `bson:"id"`
+	CreatedAt *time.Time           `bson:"created_at"`
+	ExpiresAt *time.Time           `bson:"expires_at"`
+	Kind      string               `bson:"kind"`
+	Session   webauthn.SessionData `bson:"session"`
+	UsedAt    *time.Time           `bson:"used_at"`
+	UserId    string               `bson:"user_id"`
+}
+
+// NewWebAuthnChallenge creates a challenge of kind for userId, which
+// is empty for logins where the user isn't known yet.
+func NewWebAuthnChallenge(kind string, userId string, session *webauthn.SessionData) *WebAuthnChallenge {
+	now := time.Now()
+	expiresAt := now.Add(viper.GetDuration(config.WebAuthnTimeout))
+	return &WebAuthnChallenge{
+		Id:        xid.New().String(),
+		CreatedAt: &now,
+		ExpiresAt: &expiresAt,
+		Kind:      kind,
+		Session:   *session,
+		UserId:    userId,
+	}
+}
+
+// Validate checks that c can still be used to finish a ceremony of kind.
+func (c *WebAuthnChallenge) Validate(kind string) error {
+	if c.Kind != kind {
+		return ErrWebAuthnChallengeInvalid
+	}
+
+	if c.UsedAt != nil {
+		return ErrWebAuthnChallengeUsed
+	}
+
+	if time.Now().After(*c.ExpiresAt) {
+		return ErrWebAuthnChallengeExpired
+	}
+
+	return nil
+}
+
+func (c *WebAuthnChallenge) Use() {
+	t := time.Now()
+	c.UsedAt = &t
+}
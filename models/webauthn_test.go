@@ -0,0 +1,66 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebAuthnCredential(t *testing.T) {
+	c := NewWebAuthnCredential("1", "My Phone", &webauthn.Credential{
+		ID:        []byte("abc"),
+		PublicKey: []byte("key"),
+		Transport: []protocol.AuthenticatorTransport{protocol.Internal},
+		Authenticator: webauthn.Authenticator{
+			SignCount: 1,
+		},
+	})
+	assert.Equal(t, "1", c.UserId)
+	assert.Equal(t, []string{"internal"}, c.Transports)
+
+	credential := c.Credential()
+	assert.Equal(t, []byte("abc"), credential.ID)
+	assert.Equal(t, []protocol.AuthenticatorTransport{protocol.Internal}, credential.Transport)
+
+	credential.Authenticator.UpdateCounter(2)
+	assert.NoError(t, c.Use(&credential))
+	assert.Equal(t, uint32(2), c.SignCount)
+	assert.NotNil(t, c.LastUsedAt)
+
+	credential = c.Credential()
+	credential.Authenticator.UpdateCounter(2)
+	assert.ErrorIs(t, c.Use(&credential), ErrWebAuthnCloned)
+	assert.Equal(t, uint32(2), c.SignCount)
+
+	c.Delete()
+	assert.NotNil(t, c.DeletedAt)
+}
+
+func TestWebAuthnUser(t *testing.T) {
+	user := NewUser("test@email.com", "test")
+	u := &WebAuthnUser{User: user}
+	assert.Equal(t, []byte(user.Id), u.WebAuthnID())
+	assert.Equal(t, "test", u.WebAuthnName())
+	assert.Equal(t, "test", u.WebAuthnDisplayName())
+
+	user.Name = "Test User"
+	assert.Equal(t, "Test User", u.WebAuthnDisplayName())
+}
+
+func TestWebAuthnChallenge(t *testing.T) {
+	c := NewWebAuthnChallenge(WebAuthnChallengeLogin, "", &webauthn.SessionData{Challenge: "abc"})
+	assert.True(t, c.ExpiresAt.After(time.Now()))
+
+	assert.NoError(t, c.Validate(WebAuthnChallengeLogin))
+	assert.ErrorIs(t, c.Validate(WebAuthnChallengeRegistration), ErrWebAuthnChallengeInvalid)
+
+	past := time.Now().Add(-time.Minute)
+	c.ExpiresAt = &past
+	assert.ErrorIs(t, c.Validate(WebAuthnChallengeLogin), ErrWebAuthnChallengeExpired)
+
+	c.Use()
+	assert.ErrorIs(t, c.Validate(WebAuthnChallengeLogin), ErrWebAuthnChallengeUsed)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+// keysRefreshInterval is how often the signing keys are reloaded, which is
+// also how long before it signs tokens a new key is published.
+const keysRefreshInterval = time.Minute
+
+// rotateKeys rotates the signing keys stored in the database at now and
+// loads them, the private key file is stored as the first one.
+func rotateKeys(svc *services.SigningKey, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	raw, err := jwt.LoadPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	initial, err := models.NewSigningKeyFromRaw(raw)
+	if err != nil {
+		return err
+	}
+
+	active, others, err := svc.Rotate(
+		ctx,
+		now,
+		viper.GetDuration(config.JWTKeyRotationInterval),
+		viper.GetDuration(config.JWTKeyRetention),
+		keysRefreshInterval,
+		initial,
+	)
+	if err != nil {
+		return err
+	}
+
+	signing, err := active.Key()
+	if err != nil {
+		return err
+	}
+
+	var keys []jwk.Key
+	for _, k := range others {
+		key, err := k.Key()
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	return jwt.SetKeys(signing, keys)
+}
+
+// watchKeys rotates the signing keys and picks up the
+// keys rotated by other instances until the program exits.
+func watchKeys(svc *services.SigningKey) {
+	ticker := time.NewTicker(keysRefreshInterval)
+	defer ticker.Stop()
+
+	for t := range ticker.C {
+		if err := rotateKeys(svc, t); err != nil {
+			log.Error().Err(err).Msg("failed rotating signing keys")
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexferl/echo-openapi"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
+)
+
+// signingKeys stores the signing keys in memory, Find only
+// returns the keys not expired at the time of its filter.
+type signingKeys struct {
+	keys models.SigningKeys
+}
+
+func (m *signingKeys) Create(_ context.Context, model *models.SigningKey) (*models.SigningKey, error) {
+	m.keys = append(m.keys, *model)
+	return model, nil
+}
+
+func (m *signingKeys) Find(_ context.Context, filter any) (models.SigningKeys, error) {
+	or := filter.(bson.D).Map()["$or"].(bson.A)
+	t := or[1].(bson.D).Map()["expires_at"].(bson.D).Map()["$gt"].(time.Time)
+
+	var keys models.SigningKeys
+	for _, k := range m.keys {
+		if k.ExpiresAt == nil || k.ExpiresAt.After(t) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (m *signingKeys) Update(_ context.Context, model *models.SigningKey) (*models.SigningKey, error) {
+	for i := range m.keys {
+		if m.keys[i].Id == model.Id {
+			m.keys[i] = *model
+		}
+	}
+	return model, nil
+}
+
+func TestRotateKeys(t *testing.T) {
+	interval := 90 * 24 * time.Hour
+	retention := viper.GetDuration(config.JWTRefreshTokenExpiry)
+	viper.Set(config.JWTKeyEncryptionKey, "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U=")
+	viper.Set(config.JWTKeyRotationInterval, interval)
+	viper.Set(config.JWTKeyRetention, retention)
+	t.Cleanup(func() {
+		viper.Set(config.JWTKeyEncryptionKey, "")
+		viper.Set(config.JWTKeyRotationInterval, time.Duration(0))
+		viper.Set(config.JWTKeyRetention, time.Duration(0))
+
+		raw, _ := jwt.LoadPrivateKey()
+		key, _ := jwt.Key(raw)
+		_ = jwt.SetKeys(key, nil)
+	})
+
+	mapper := &signingKeys{}
+	svc := services.NewSigningKey(mapper)
+	server := NewTestServer(
+		handlers.NewMockUserService(t),
+		handlers.NewMockPersonalAccessTokenService(t),
+		handlers.NewMockServiceAccountService(t),
+		handlers.NewMockSessionService(t),
+		handlers.NewJWKSHandler(openapi.NewHandler()),
+	)
+
+	published := func() []string {
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		resp := httptest.NewRecorder()
+		server.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusOK, resp.Code)
+
+		var result struct {
+			Keys []struct {
+				Kid string `json:"kid"`
+			} `json:"keys"`
+		}
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &result))
+
+		var kids []string
+		for _, k := range result.Keys {
+			kids = append(kids, k.Kid)
+		}
+		return kids
+	}
+
+	sign := func() ([]byte, string) {
+		token, err := jwt.GenerateAccessToken("123", nil)
+		require.NoError(t, err)
+		msg, err := jws.Parse(token)
+		require.NoError(t, err)
+		return token, msg.Signatures()[0].ProtectedHeaders().KeyID()
+	}
+
+	start := time.Now()
+	require.NoError(t, rotateKeys(svc, start))
+	require.Len(t, mapper.keys, 1)
+	initial := mapper.keys[0].Id
+	old, kid := sign()
+	assert.Equal(t, initial, kid)
+	assert.Equal(t, []string{initial}, published())
+
+	// the next key is published a refresh before it activates
+	require.NoError(t, rotateKeys(svc, start.Add(interval-keysRefreshInterval)))
+	require.Len(t, mapper.keys, 2)
+	next := mapper.keys[1].Id
+	assert.ElementsMatch(t, []string{initial, next}, published())
+	_, kid = sign()
+	assert.Equal(t, initial, kid)
+
+	// and signs once it's active, the key it replaced still verifies
+	rotatedAt := start.Add(interval)
+	require.NoError(t, rotateKeys(svc, rotatedAt))
+	_, kid = sign()
+	assert.Equal(t, next, kid)
+	assert.ElementsMatch(t, []string{initial, next}, published())
+	_, err := jwt.ParseEncoded(old)
+	assert.NoError(t, err)
+
+	// until the tokens it signed expired
+	require.NoError(t, rotateKeys(svc, rotatedAt.Add(retention-keysRefreshInterval)))
+	assert.ElementsMatch(t, []string{initial, next}, published())
+	_, err = jwt.ParseEncoded(old)
+	assert.NoError(t, err)
+
+	require.NoError(t, rotateKeys(svc, rotatedAt.Add(retention)))
+	assert.Equal(t, []string{next}, published())
+	_, err = jwt.ParseEncoded(old)
+	assert.Error(t, err)
+}
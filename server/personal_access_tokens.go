@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+	"github.com/alexferl/echo-boilerplate/util/mailer"
+)
+
+// cleanupPersonalAccessTokens emails the owners of the tokens about to expire,
+// marks the expired tokens and deletes the ones kept past their retention.
+func cleanupPersonalAccessTokens(svc *services.PersonalAccessToken, userSvc *services.User, m mailer.Mailer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if reminder := viper.GetDuration(config.PersonalAccessTokensExpiryReminder); reminder > 0 {
+		pats, err := svc.FindExpiring(ctx, reminder)
+		if err != nil {
+			return err
+		}
+
+		for _, pat := range pats {
+			if err = remindPersonalAccessToken(ctx, svc, userSvc, m, &pat); err != nil {
+				log.Error().Err(err).Str("pat_id", pat.Id).Msg("failed reminding personal access token expiry")
+			}
+		}
+	}
+
+	expired, err := svc.Expire(ctx)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := svc.Purge(ctx, viper.GetDuration(config.PersonalAccessTokensRetention))
+	if err != nil {
+		return err
+	}
+
+	log.Info().
+		Int64("expired", expired).
+		Int64("deleted", deleted).
+		Msg("cleaned up personal access tokens")
+
+	return nil
+}
+
+func remindPersonalAccessToken(
+	ctx context.Context,
+	svc *services.PersonalAccessToken,
+	userSvc *services.User,
+	m mailer.Mailer,
+	pat *models.PersonalAccessToken,
+) error {
+	user, err := userSvc.Read(ctx, pat.UserId)
+	if err != nil {
+		// nobody is left to remind, revoking the token stops finding it again
+		var se *services.Error
+		if errors.As(err, &se) && (se.Kind == services.NotExist || se.Kind == services.Deleted) {
+			log.Info().Str("pat_id", pat.Id).Msg("revoking personal access token of deleted user")
+			return svc.Revoke(ctx, "", pat)
+		}
+		return err
+	}
+
+	if user.Email == "" {
+		return nil
+	}
+
+	ok, err := svc.Remind(ctx, pat)
+	if err != nil || !ok {
+		return err
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Your personal access token is about to expire",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\n"+
+				"Your personal access token '%s' expires on %s. "+
+				"Create a new one before then if you still need it.\n",
+			user.Username, pat.Name, pat.ExpiresAt.Format(time.DateOnly),
+		),
+	}
+
+	// the reminder is only kept once sent, so the next run tries again
+	if err = m.Send(ctx, msg); err != nil {
+		if err := svc.Unremind(ctx, pat); err != nil {
+			log.Error().Err(err).Str("pat_id", pat.Id).Msg("failed clearing personal access token reminder")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// watchPersonalAccessTokens cleans up the personal access
+// tokens every interval until the program exits.
+func watchPersonalAccessTokens(
+	interval time.Duration,
+	svc *services.PersonalAccessToken,
+	userSvc *services.User,
+	m mailer.Mailer,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := cleanupPersonalAccessTokens(svc, userSvc, m); err != nil {
+			log.Error().Err(err).Msg("failed cleaning up personal access tokens")
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/handlers"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+// updates returns a matcher of the updates setting or unsetting field.
+func updates(op string, field string) any {
+	return mock.MatchedBy(func(update bson.D) bool {
+		fields, ok := update.Map()[op].(bson.D)
+		if !ok {
+			return false
+		}
+		_, ok = fields.Map()[field]
+		return ok
+	})
+}
+
+func TestCleanupPersonalAccessTokens(t *testing.T) {
+	viper.Set(config.PersonalAccessTokensExpiryReminder, 7*24*time.Hour)
+	defer viper.Set(config.PersonalAccessTokensExpiryReminder, time.Duration(0))
+
+	deleted := models.NewUser("deleted@example.com", "deleted")
+	deleted.Delete(deleted.Id)
+
+	testCases := []struct {
+		name     string
+		expiring bool
+		user     *models.User
+		userErr  error
+		sendErr  error
+		expired  int64
+	}{
+		{"expire", false, nil, nil, nil, 2},
+		{"remind", true, models.NewUser("test@example.com", "test"), nil, nil, 0},
+		{"send failure", true, models.NewUser("test@example.com", "test"), nil, errors.New("error"), 0},
+		{"deleted owner", true, deleted, nil, nil, 0},
+		{"missing owner", true, nil, data.ErrNoDocuments, nil, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			patMapper := services.NewMockPersonalAccessTokenMapper(t)
+			userMapper := services.NewMockUserMapper(t)
+			m := handlers.NewMockMailer(t)
+
+			expiresAt := time.Now().Add(24 * time.Hour)
+			pat := models.PersonalAccessToken{Id: "1", Name: "ci", ExpiresAt: &expiresAt, UserId: "2"}
+
+			var pats models.PersonalAccessTokens
+			if tc.expiring {
+				pats = append(pats, pat)
+			}
+			patMapper.EXPECT().
+				Find(mock.Anything, mock.Anything).
+				Return(pats, nil).Once()
+
+			if tc.expiring {
+				userMapper.EXPECT().
+					FindOne(mock.Anything, mock.Anything).
+					Return(tc.user, tc.userErr).Once()
+			}
+
+			if tc.user != nil && tc.user.DeletedBy == nil {
+				patMapper.EXPECT().
+					UpdateMany(mock.Anything, mock.Anything, updates("$set", "reminded_at")).
+					Return(int64(1), nil).Once()
+
+				m.EXPECT().
+					Send(mock.Anything, mock.Anything).
+					Return(tc.sendErr).Once()
+			}
+
+			if tc.sendErr != nil {
+				patMapper.EXPECT().
+					UpdateMany(mock.Anything, mock.Anything, updates("$unset", "reminded_at")).
+					Return(int64(1), nil).Once()
+			}
+
+			if tc.userErr != nil || (tc.user != nil && tc.user.DeletedBy != nil) {
+				patMapper.EXPECT().
+					Update(mock.Anything, mock.MatchedBy(func(p *models.PersonalAccessToken) bool {
+						return p.Id == pat.Id && p.IsRevoked
+					})).
+					Return(&pat, nil).Once()
+			}
+
+			patMapper.EXPECT().
+				UpdateMany(mock.Anything, mock.Anything, updates("$set", "is_expired")).
+				Return(tc.expired, nil).Once()
+
+			patMapper.EXPECT().
+				DeleteMany(mock.Anything, mock.Anything).
+				Return(int64(0), nil).Once()
+
+			err := cleanupPersonalAccessTokens(
+				services.NewPersonalAccessToken(patMapper),
+				services.NewUser(userMapper),
+				m,
+			)
+			assert.NoError(t, err)
+		})
+	}
+}
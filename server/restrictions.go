@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+// liftRestrictions lifts the bans and locks past their end date.
+func liftRestrictions(svc *services.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	lifted, err := svc.LiftExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	if lifted > 0 {
+		log.Info().Int64("lifted", lifted).Msg("lifted expired bans and locks")
+	}
+
+	return nil
+}
+
+// watchRestrictions lifts the expired bans and locks
+// every interval until the program exits.
+func watchRestrictions(interval time.Duration, svc *services.User) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := liftRestrictions(svc); err != nil {
+			log.Error().Err(err).Msg("failed lifting expired bans and locks")
+		}
+	}
+}
+
+// restrictedMessage tells banned or locked users why and until when they are.
+func restrictedMessage(err error, reason string, until *time.Time) string {
+	msg := err.Error()
+	if until != nil {
+		msg += " until " + until.UTC().Format(time.RFC3339)
+	}
+	if reason != "" {
+		msg += ": " + reason
+	}
+
+	return msg
+}
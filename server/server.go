@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	casbinMw "github.com/alexferl/echo-casbin"
@@ -21,14 +23,19 @@ import (
 	"github.com/alexferl/echo-boilerplate/data"
 	"github.com/alexferl/echo-boilerplate/handlers"
 	"github.com/alexferl/echo-boilerplate/mappers"
+	"github.com/alexferl/echo-boilerplate/models"
 	"github.com/alexferl/echo-boilerplate/services"
 	"github.com/alexferl/echo-boilerplate/util/hash"
+	"github.com/alexferl/echo-boilerplate/util/idp"
 	"github.com/alexferl/echo-boilerplate/util/jwt"
+	"github.com/alexferl/echo-boilerplate/util/mailer"
+	"github.com/alexferl/echo-boilerplate/util/password"
 )
 
 var (
 	ErrBanned            = errors.New("account banned")
 	ErrLocked            = errors.New("account locked")
+	ErrMFARequired       = errors.New("mfa enrollment required")
 	ErrCookieMissing     = errors.New("missing access token cookie")
 	ErrCSRFHeaderMissing = errors.New("missing CSRF token header")
 	ErrCSRFInvalid       = errors.New("invalid CSRF token")
@@ -36,6 +43,9 @@ var (
 	ErrTokenMismatch     = errors.New("token mismatch")
 	ErrTokenRevoked      = errors.New("token is revoked")
 	ErrTokenExpired      = errors.New("token is expired")
+	ErrScopeInsufficient = errors.New("token scopes don't allow this")
+	ErrServiceAccount    = errors.New("service accounts can't use this route")
+	ErrImpersonation     = errors.New("cannot change credentials while impersonating")
 )
 
 func New() *server.Server {
@@ -46,53 +56,161 @@ func New() *server.Server {
 
 	openapi := openapiMw.NewHandler()
 
+	emailVerificationMapper := mappers.NewEmailVerification(client)
+	emailVerificationSvc := services.NewEmailVerification(emailVerificationMapper)
+
+	loginAttemptMapper := mappers.NewLoginAttempt(client)
+	loginAttemptSvc := services.NewLoginAttempt(loginAttemptMapper)
+
+	magicLinkMapper := mappers.NewMagicLink(client)
+	magicLinkSvc := services.NewMagicLink(magicLinkMapper)
+
+	passwordResetMapper := mappers.NewPasswordReset(client)
+	passwordResetSvc := services.NewPasswordReset(passwordResetMapper)
+
 	patMapper := mappers.NewPersonalAccessToken(client)
 	patSvc := services.NewPersonalAccessToken(patMapper)
 
+	serviceAccountMapper := mappers.NewServiceAccount(client)
+	serviceAccountSvc := services.NewServiceAccount(serviceAccountMapper)
+
+	sessionMapper := mappers.NewSession(client)
+	sessionSvc := services.NewSession(sessionMapper)
+
+	signingKeyMapper := mappers.NewSigningKey(client)
+	signingKeySvc := services.NewSigningKey(signingKeyMapper)
+
 	taskMapper := mappers.NewTask(client)
 	taskSvc := services.NewTask(taskMapper)
 
 	userMapper := mappers.NewUser(client)
 	userSvc := services.NewUser(userMapper)
 
-	return newServer(userSvc, patSvc, []handlers.Handler{
+	webAuthnChallengeMapper := mappers.NewWebAuthnChallenge(client)
+	webAuthnChallengeSvc := services.NewWebAuthnChallenge(webAuthnChallengeMapper)
+
+	webAuthnCredentialMapper := mappers.NewWebAuthnCredential(client)
+	webAuthnCredentialSvc := services.NewWebAuthnCredential(webAuthnCredentialMapper)
+
+	mailSvc := mailer.New()
+
+	providers, err := idp.NewRegistry()
+	if err != nil {
+		log.Panic().Err(err).Msg("failed creating oauth2 providers")
+	}
+
+	if path := viper.GetString(config.PasswordBreachedFile); path != "" {
+		breached, err := password.LoadBreached(path)
+		if err != nil {
+			log.Panic().Err(err).Msg("failed loading breached passwords")
+		}
+		password.SetBreached(breached)
+		log.Info().Int("count", breached.Len()).Msg("loaded breached passwords")
+	}
+
+	if viper.GetDuration(config.JWTKeyRotationInterval) > 0 {
+		if err = rotateKeys(signingKeySvc, time.Now()); err != nil {
+			log.Panic().Err(err).Msg("failed rotating signing keys")
+		}
+		go watchKeys(signingKeySvc)
+	}
+
+	if interval := viper.GetDuration(config.PersonalAccessTokensCleanupInterval); interval > 0 {
+		go watchPersonalAccessTokens(interval, patSvc, userSvc, mailSvc)
+	}
+
+	if interval := viper.GetDuration(config.RestrictionsSweepInterval); interval > 0 {
+		go watchRestrictions(interval, userSvc)
+	}
+
+	return newServer(userSvc, patSvc, serviceAccountSvc, sessionSvc, []handlers.Handler{
 		handlers.NewRootHandler(openapi),
-		handlers.NewAuthHandler(openapi, userSvc),
-		handlers.NewPersonalAccessTokenHandler(openapi, patSvc),
+		handlers.NewJWKSHandler(openapi),
+		handlers.NewAuthHandler(openapi, userSvc, sessionSvc, emailVerificationSvc, loginAttemptSvc, mailSvc),
+		handlers.NewEmailVerificationHandler(openapi, emailVerificationSvc, userSvc, loginAttemptSvc, mailSvc),
+		handlers.NewIdentityHandler(openapi, providers, userSvc, webAuthnCredentialSvc),
+		handlers.NewMagicLinkHandler(openapi, magicLinkSvc, userSvc, sessionSvc, mailSvc),
+		handlers.NewMFAHandler(openapi, userSvc),
+		handlers.NewOAuth2Handler(openapi, providers, userSvc, sessionSvc, emailVerificationSvc, mailSvc),
+		handlers.NewOAuth2TokenHandler(openapi, userSvc, sessionSvc, patSvc, serviceAccountSvc),
+		handlers.NewPasswordResetHandler(openapi, passwordResetSvc, userSvc, sessionSvc, loginAttemptSvc, mailSvc),
+		handlers.NewPersonalAccessTokenHandler(openapi, patSvc, userSvc),
+		handlers.NewServiceAccountHandler(openapi, serviceAccountSvc),
+		handlers.NewSessionHandler(openapi, sessionSvc, userSvc),
 		handlers.NewTaskHandler(openapi, taskSvc),
-		handlers.NewUserHandler(openapi, userSvc),
+		handlers.NewUserHandler(openapi, userSvc, patSvc, sessionSvc),
+		handlers.NewWebAuthnHandler(openapi, webAuthnCredentialSvc, webAuthnChallengeSvc, userSvc, sessionSvc),
 	}...)
 }
 
-func NewTestServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessTokenService, handler ...handlers.Handler) *server.Server {
+func NewTestServer(
+	userSvc handlers.UserService,
+	patSvc handlers.PersonalAccessTokenService,
+	saSvc handlers.ServiceAccountService,
+	sessionSvc handlers.SessionService,
+	handler ...handlers.Handler,
+) *server.Server {
 	c := config.New()
 	c.BindFlags()
 
 	viper.Set(config.CookiesEnabled, true)
 	viper.Set(config.CSRFEnabled, true)
 
-	return newServer(userSvc, patSvc, handler...)
+	return newServer(userSvc, patSvc, saSvc, sessionSvc, handler...)
 }
 
-func newServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessTokenService, handler ...handlers.Handler) *server.Server {
+func newServer(
+	userSvc handlers.UserService,
+	patSvc handlers.PersonalAccessTokenService,
+	saSvc handlers.ServiceAccountService,
+	sessionSvc handlers.SessionService,
+	handler ...handlers.Handler,
+) *server.Server {
 	jwtConfig := jwtMw.Config{
-		Key:             jwt.PrivateKey,
 		UseRefreshToken: true,
+		// tokens are verified with every key of the set, not only the signing key
+		ParseTokenFunc: func(encodedToken string, _ []jwx.ParseOption) (jwx.Token, error) {
+			return jwt.ParseEncoded([]byte(encodedToken))
+		},
 		ExemptRoutes: map[string][]string{
-			"/":                       {http.MethodGet},
-			"/readyz":                 {http.MethodGet},
-			"/livez":                  {http.MethodGet},
-			"/docs":                   {http.MethodGet},
-			"/openapi/*":              {http.MethodGet},
-			"/auth/login":             {http.MethodPost},
-			"/auth/signup":            {http.MethodPost},
-			"/oauth2/google/callback": {http.MethodGet},
-			"/oauth2/google/login":    {http.MethodGet},
+			"/":                           {http.MethodGet},
+			"/readyz":                     {http.MethodGet},
+			"/livez":                      {http.MethodGet},
+			"/docs":                       {http.MethodGet},
+			"/openapi/*":                  {http.MethodGet},
+			"/.well-known/jwks.json":      {http.MethodGet},
+			"/auth/login":                 {http.MethodPost},
+			"/auth/login/mfa":             {http.MethodPost},
+			"/auth/magic-link":            {http.MethodPost},
+			"/auth/magic-link/callback":   {http.MethodGet},
+			"/auth/password/forgot":       {http.MethodPost},
+			"/auth/password/reset":        {http.MethodPost},
+			"/auth/signup":                {http.MethodPost},
+			"/auth/verify-email":          {http.MethodPost},
+			"/auth/verify-email/resend":   {http.MethodPost},
+			"/auth/webauthn/login/begin":  {http.MethodPost},
+			"/auth/webauthn/login/finish": {http.MethodPost},
+			"/oauth2/introspect":          {http.MethodPost},
+			"/oauth2/revoke":              {http.MethodPost},
+			"/oauth2/token":               {http.MethodPost},
+			"/oauth2/:provider/callback":  {http.MethodGet},
+			"/oauth2/:provider/login":     {http.MethodGet},
 		},
 		AfterParseFunc: func(c echo.Context, t jwx.Token, encodedToken string, src jwtMw.TokenSource) *echo.HTTPError {
+			// MFA tokens can only be exchanged on /auth/login/mfa
+			// and OAuth2 state tokens are only used by the callbacks
+			switch t.PrivateClaims()["type"] {
+			case jwt.MFAToken.String(), jwt.OAuth2StateToken.String():
+				return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenInvalid)
+			}
+
 			ctx, cancel := context.WithTimeout(c.Request().Context(), 10*time.Second)
 			defer cancel()
 
+			if t.PrivateClaims()["type"] == jwt.ServiceToken.String() {
+				return authenticateServiceAccount(ctx, c, saSvc, t)
+			}
+
 			user, err := userSvc.Read(ctx, t.Subject())
 			if err != nil {
 				log.Error().Err(err).Msg("failed getting user")
@@ -101,13 +219,27 @@ func newServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessToken
 
 			c.Set("user", user)
 			// set roles for casbin
-			c.Set("roles", user.Roles)
+			if viper.GetString(config.EmailVerificationPolicy) == config.EmailVerificationPolicyRoles &&
+				!user.IsEmailVerified() {
+				// unverified users only get the default 'any' role
+				c.Set("roles", []string{})
+			} else {
+				c.Set("roles", user.Roles)
+			}
 
 			if user.IsBanned {
-				return echo.NewHTTPError(http.StatusForbidden, ErrBanned.Error())
+				return echo.NewHTTPError(http.StatusForbidden, restrictedMessage(ErrBanned, user.BanReason, user.BannedUntil))
 			}
 			if user.IsLocked {
-				return echo.NewHTTPError(http.StatusForbidden, ErrLocked.Error())
+				return echo.NewHTTPError(http.StatusForbidden, restrictedMessage(ErrLocked, user.LockReason, user.LockedUntil))
+			}
+
+			// users who must use MFA can only enroll until they do
+			if user.MFARequired() && !user.IsMFAEnabled() {
+				path := c.Path()
+				if !strings.HasPrefix(path, "/me/mfa/") && !strings.HasPrefix(path, "/auth/") {
+					return echo.NewHTTPError(http.StatusForbidden, ErrMFARequired.Error())
+				}
 			}
 
 			// CSRF
@@ -132,11 +264,17 @@ func newServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessToken
 					}
 				}
 			}
-			// Personal Access Tokens
+			// Sessions, access tokens stop working as soon as their session is revoked
 			claims := t.PrivateClaims()
 			typ := claims["type"]
-			if typ == jwt.PersonalToken.String() {
-				pat, err := patSvc.FindOne(ctx, t.Subject(), "")
+			// access tokens issued before sessions can't be revoked, they're rejected
+			if typ == jwt.AccessToken.String() {
+				sid, ok := claims["sid"].(string)
+				if !ok {
+					return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenInvalid)
+				}
+
+				session, err := sessionSvc.Read(ctx, t.Subject(), sid)
 				if err != nil {
 					var se *services.Error
 					if errors.As(err, &se) {
@@ -147,8 +285,41 @@ func newServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessToken
 					return echo.NewHTTPError(http.StatusServiceUnavailable)
 				}
 
-				if err = pat.Validate(encodedToken); err != nil {
-					return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenMismatch)
+				if session.IsRevoked {
+					return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenRevoked)
+				}
+			}
+
+			// Personal Access Tokens
+			if typ == jwt.PersonalToken.String() {
+				var pat *models.PersonalAccessToken
+				if patId, ok := claims["pat_id"].(string); ok {
+					var err error
+					pat, err = patSvc.Read(ctx, t.Subject(), patId)
+					if err != nil {
+						var se *services.Error
+						if errors.As(err, &se) {
+							if se.Kind == services.NotExist {
+								return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenInvalid)
+							}
+						}
+						return echo.NewHTTPError(http.StatusServiceUnavailable)
+					}
+
+					if err = pat.Validate(encodedToken); err != nil {
+						return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenMismatch)
+					}
+				} else {
+					// tokens created before scopes have no pat_id, they're matched by hash
+					pats, err := patSvc.FindUnscoped(ctx, t.Subject())
+					if err != nil {
+						return echo.NewHTTPError(http.StatusServiceUnavailable)
+					}
+
+					pat = pats.Match(encodedToken)
+					if pat == nil {
+						return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenInvalid)
+					}
 				}
 
 				if pat.IsRevoked {
@@ -158,6 +329,35 @@ func newServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessToken
 				if time.Now().After(*pat.ExpiresAt) {
 					return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenExpired)
 				}
+
+				// failing to record the usage shouldn't fail the request
+				if pat.Use(c.RealIP(), c.Request().UserAgent()) {
+					if err = patSvc.Use(ctx, pat); err != nil {
+						log.Error().Err(err).Msg("failed updating personal access token usage")
+					}
+				}
+
+				// nor failing to upgrade a hash made with older argon2 parameters
+				if rehashed, err := pat.Rehash(encodedToken); err != nil {
+					log.Error().Err(err).Msg("failed rehashing personal access token")
+				} else if rehashed {
+					if err = patSvc.Rehash(ctx, pat); err != nil {
+						log.Error().Err(err).Msg("failed updating personal access token hash")
+					}
+				}
+
+				// set scopes for casbin, tokens created before scopes aren't limited
+				if scope, ok := claims["scope"].(string); ok {
+					c.Set("scopes", strings.Fields(scope))
+				}
+			}
+
+			// Impersonation
+			if act, ok := claims["act"].(map[string]any); ok {
+				actorId, _ := act["sub"].(string)
+				if err := authenticateActor(ctx, c, userSvc, actorId); err != nil {
+					return err
+				}
 			}
 
 			// set token_id globally
@@ -172,16 +372,30 @@ func newServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessToken
 		log.Panic().Err(err).Msg("failed creating enforcer")
 	}
 
+	scopeEnforcer, err := casbin.NewEnforcer(viper.GetString(config.CasbinModel), viper.GetString(config.CasbinScopePolicy))
+	if err != nil {
+		log.Panic().Err(err).Msg("failed creating scope enforcer")
+	}
+
+	// personal access tokens can only do what both their
+	// scopes and the roles of their owner allow
+	scopeConfig := casbinMw.Config{
+		Enforcer:         scopeEnforcer,
+		ContextKey:       "scopes",
+		ForbiddenMessage: ErrScopeInsufficient.Error(),
+		Skipper: func(c echo.Context) bool {
+			return c.Get("scopes") == nil
+		},
+	}
+
 	openAPIConfig := openapiMw.Config{
 		Schema: viper.GetString(config.OpenAPISchema),
 		ExemptRoutes: map[string][]string{
-			"/":                       {http.MethodGet},
-			"/readyz":                 {http.MethodGet},
-			"/livez":                  {http.MethodGet},
-			"/docs":                   {http.MethodGet},
-			"/openapi/*":              {http.MethodGet},
-			"/oauth2/google/callback": {http.MethodGet},
-			"/oauth2/google/login":    {http.MethodGet},
+			"/":          {http.MethodGet},
+			"/readyz":    {http.MethodGet},
+			"/livez":     {http.MethodGet},
+			"/docs":      {http.MethodGet},
+			"/openapi/*": {http.MethodGet},
 		},
 	}
 
@@ -190,6 +404,7 @@ func newServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessToken
 	s.Use(
 		jwtMw.JWTWithConfig(jwtConfig),
 		casbinMw.Casbin(enforcer),
+		casbinMw.CasbinWithConfig(scopeConfig),
 		openapiMw.OpenAPIWithConfig(openAPIConfig),
 	)
 
@@ -202,3 +417,81 @@ func newServer(userSvc handlers.UserService, patSvc handlers.PersonalAccessToken
 
 	return s
 }
+
+// authenticateServiceAccount sets the user and roles service accounts act
+// as, as long as the credential their token was issued for still exists.
+// They have no account of their own so they can't use the /me routes.
+func authenticateServiceAccount(
+	ctx context.Context,
+	c echo.Context,
+	saSvc handlers.ServiceAccountService,
+	t jwx.Token,
+) *echo.HTTPError {
+	sa, err := saSvc.Read(ctx, t.Subject())
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenInvalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting service account")
+		return echo.NewHTTPError(http.StatusServiceUnavailable)
+	}
+
+	clientId, _ := t.PrivateClaims()["client_id"].(string)
+	if sa.Credential(clientId) == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenRevoked)
+	}
+
+	path := c.Path()
+	if path == "/me" || strings.HasPrefix(path, "/me/") {
+		return echo.NewHTTPError(http.StatusForbidden, ErrServiceAccount.Error())
+	}
+
+	c.Set("user", sa.User())
+	// set roles for casbin
+	c.Set("roles", sa.Roles)
+
+	// set token_id globally
+	log.Logger = log.Logger.With().Str("token_id", t.Subject()).Logger()
+
+	return nil
+}
+
+// authenticateActor checks that the super user impersonating the user of the
+// token still can and records them as the actor of what the request changes.
+func authenticateActor(ctx context.Context, c echo.Context, userSvc handlers.UserService, actorId string) *echo.HTTPError {
+	actor, err := userSvc.Read(ctx, actorId)
+	if err != nil {
+		var se *services.Error
+		if errors.As(err, &se) {
+			if se.Kind == services.NotExist || se.Kind == services.Deleted {
+				return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenInvalid)
+			}
+		}
+		log.Error().Err(err).Msg("failed getting actor")
+		return echo.NewHTTPError(http.StatusServiceUnavailable)
+	}
+
+	if !slices.Contains(actor.Roles, models.SuperRole.String()) || actor.IsBanned || actor.IsLocked {
+		return echo.NewHTTPError(http.StatusUnauthorized, ErrTokenInvalid)
+	}
+
+	// the credentials of the user stay theirs to change
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+	default:
+		path := c.Path()
+		if strings.HasPrefix(path, "/me/") || strings.HasPrefix(path, "/auth/") {
+			return echo.NewHTTPError(http.StatusForbidden, ErrImpersonation.Error())
+		}
+	}
+
+	// actor_id is only added to the logger of this request, log.Ctx returns it
+	logger := log.Logger.With().Str("actor_id", actor.Id).Logger()
+	reqCtx := logger.WithContext(models.WithActor(c.Request().Context(), actor.Id))
+	c.SetRequest(c.Request().WithContext(reqCtx))
+
+	return nil
+}
@@ -1,31 +1,43 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/alexferl/echo-openapi"
 	api "github.com/alexferl/golib/http/api/server"
+	"github.com/casbin/casbin/v2"
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/alexferl/echo-boilerplate/config"
 	"github.com/alexferl/echo-boilerplate/handlers"
 	"github.com/alexferl/echo-boilerplate/models"
 	"github.com/alexferl/echo-boilerplate/services"
 	_ "github.com/alexferl/echo-boilerplate/testing"
 	"github.com/alexferl/echo-boilerplate/util/cookie"
+	"github.com/alexferl/echo-boilerplate/util/jwt"
 )
 
 type ServerTestSuite struct {
 	suite.Suite
 	svc         *handlers.MockUserService
 	patSvc      *handlers.MockPersonalAccessTokenService
+	saSvc       *handlers.MockServiceAccountService
+	sessionSvc  *handlers.MockSessionService
 	server      *api.Server
 	user        *models.User
 	accessToken []byte
@@ -35,17 +47,20 @@ type ServerTestSuite struct {
 func (s *ServerTestSuite) SetupTest() {
 	svc := handlers.NewMockUserService(s.T())
 	patSvc := handlers.NewMockPersonalAccessTokenService(s.T())
-	h := handlers.NewUserHandler(openapi.NewHandler(), svc)
+	saSvc := handlers.NewMockServiceAccountService(s.T())
+	h := handlers.NewUserHandler(openapi.NewHandler(), svc, patSvc, handlers.NewMockSessionService(s.T()))
 
 	admin := models.NewUserWithRole("test@example.com", "test", models.AdminRole)
 	user := models.NewUser("test@example.com", "test")
 	user.Id = "1000"
 	user.Create(user.Id)
-	access, _, _ := user.Login()
+	access, _, _ := user.Login(models.NewSession(user.Id))
 
 	s.svc = svc
 	s.patSvc = patSvc
-	s.server = NewTestServer(svc, patSvc, h)
+	s.saSvc = saSvc
+	s.sessionSvc = activeSessions()
+	s.server = NewTestServer(svc, patSvc, saSvc, s.sessionSvc, h)
 	s.user = user
 	s.accessToken = access
 	s.admin = admin
@@ -55,6 +70,19 @@ func TestServerTestSuite(t *testing.T) {
 	suite.Run(t, new(ServerTestSuite))
 }
 
+// activeSessions returns a SessionService where every session exists and isn't revoked.
+func activeSessions() *handlers.MockSessionService {
+	sessionSvc := &handlers.MockSessionService{}
+	sessionSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, mock.Anything).
+		RunAndReturn(func(ctx context.Context, userId string, id string) (*models.Session, error) {
+			session := models.NewSession(userId)
+			session.Id = id
+			return session, nil
+		}).Maybe()
+	return sessionSvc
+}
+
 func (s *ServerTestSuite) TestServer_503() {
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -71,7 +99,7 @@ func (s *ServerTestSuite) TestServer_503() {
 }
 
 func (s *ServerTestSuite) TestServer_403_Banned() {
-	_ = s.user.Ban(s.admin)
+	_ = s.user.Ban(s.admin, "", nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -91,8 +119,30 @@ func (s *ServerTestSuite) TestServer_403_Banned() {
 	s.Assert().Equal(ErrBanned.Error(), result.Message)
 }
 
+func (s *ServerTestSuite) TestServer_403_Banned_Reason() {
+	until := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	_ = s.user.Ban(s.admin, "spam", &until)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Equal("account banned until 2030-01-02T03:04:05Z: spam", result.Message)
+}
+
 func (s *ServerTestSuite) TestServer_403_Locked() {
-	_ = s.user.Lock(s.admin)
+	_ = s.user.Lock(s.admin, "", nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -112,6 +162,160 @@ func (s *ServerTestSuite) TestServer_403_Locked() {
 	s.Assert().Equal(ErrLocked.Error(), result.Message)
 }
 
+func (s *ServerTestSuite) TestServer_403_Email_Not_Verified() {
+	viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyRoles)
+	defer viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyNone)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_200_Email_Verified() {
+	viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyRoles)
+	defer viper.Set(config.EmailVerificationPolicy, config.EmailVerificationPolicyNone)
+
+	s.user.VerifyEmail()
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.accessToken))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Twice()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_401_Session_Revoked() {
+	session := models.NewSession(s.user.Id)
+	access, _, _ := s.user.Login(session)
+
+	testCases := []struct {
+		name    string
+		revoked bool
+		err     error
+		code    int
+	}{
+		{"active", false, nil, http.StatusOK},
+		{"revoked", true, nil, http.StatusUnauthorized},
+		{"not found", false, &services.Error{Kind: services.NotExist}, http.StatusUnauthorized},
+		{"error", false, errors.New("error"), http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		s.T().Run(tc.name, func(t *testing.T) {
+			sessionSvc := handlers.NewMockSessionService(t)
+			h := handlers.NewSessionHandler(openapi.NewHandler(), sessionSvc, s.svc)
+			server := NewTestServer(s.svc, s.patSvc, s.saSvc, sessionSvc, h)
+
+			req := httptest.NewRequest(http.MethodGet, "/me/sessions", nil)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access))
+			resp := httptest.NewRecorder()
+
+			s.svc.EXPECT().
+				Read(mock.Anything, s.user.Id).
+				Return(s.user, nil).Once()
+
+			if tc.revoked {
+				session.Revoke(s.user.Id, models.SessionRevokedUser)
+			}
+
+			var result *models.Session
+			if tc.err == nil {
+				result = session
+			}
+			sessionSvc.EXPECT().
+				Read(mock.Anything, s.user.Id, session.Id).
+				Return(result, tc.err).Once()
+
+			if tc.code == http.StatusOK {
+				sessionSvc.EXPECT().
+					Find(mock.Anything, s.user.Id).
+					Return(models.Sessions{*session}, nil).Once()
+			}
+
+			server.ServeHTTP(resp, req)
+
+			s.Assert().Equal(tc.code, resp.Code)
+		})
+	}
+}
+
+func (s *ServerTestSuite) TestServer_401_No_Session() {
+	access, _ := jwt.GenerateAccessToken(s.user.Id, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Equal(ErrTokenInvalid.Error(), result.Message)
+}
+
+func (s *ServerTestSuite) TestServer_401_MFA_Token() {
+	token, _ := jwt.GenerateMFAToken(s.user.Id, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_403_MFA_Required() {
+	viper.Set(config.MFARequireAdmin, true)
+	defer viper.Set(config.MFARequireAdmin, false)
+
+	admin := models.NewUserWithRole("admin@example.com", "admin", models.AdminRole)
+	admin.Id = "2000"
+	access, _, _ := admin.Login(models.NewSession(admin.Id))
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", access))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(admin, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Equal(ErrMFARequired.Error(), result.Message)
+}
+
 func (s *ServerTestSuite) TestServer_400_CSRF_Header_Missing() {
 	req := httptest.NewRequest(http.MethodPatch, "/me", nil)
 	req.Header.Set("Content-Type", "application/json")
@@ -156,6 +360,7 @@ func (s *ServerTestSuite) TestServer_PAT_401_Token_Invalid() {
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
 	)
 
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
@@ -170,7 +375,7 @@ func (s *ServerTestSuite) TestServer_PAT_401_Token_Invalid() {
 		Return(s.user, nil).Once()
 
 	s.patSvc.EXPECT().
-		FindOne(mock.Anything, mock.Anything, mock.Anything).
+		Read(mock.Anything, mock.Anything, pat.Id).
 		Return(nil, services.NewError(nil, services.NotExist, "")).Once()
 
 	s.server.ServeHTTP(resp, req)
@@ -187,6 +392,7 @@ func (s *ServerTestSuite) TestServer_PAT_401_Token_Mismatch() {
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
 	)
 
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
@@ -199,7 +405,7 @@ func (s *ServerTestSuite) TestServer_PAT_401_Token_Mismatch() {
 		Return(s.user, nil).Once()
 
 	s.patSvc.EXPECT().
-		FindOne(mock.Anything, mock.Anything, mock.Anything).
+		Read(mock.Anything, mock.Anything, pat.Id).
 		Return(pat, nil).Once()
 
 	s.server.ServeHTTP(resp, req)
@@ -216,6 +422,7 @@ func (s *ServerTestSuite) TestServer_PAT_401_Revoked() {
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
 	)
 
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
@@ -231,7 +438,7 @@ func (s *ServerTestSuite) TestServer_PAT_401_Revoked() {
 		Return(s.user, nil).Once()
 
 	s.patSvc.EXPECT().
-		FindOne(mock.Anything, mock.Anything, mock.Anything).
+		Read(mock.Anything, mock.Anything, pat.Id).
 		Return(pat, nil).Once()
 
 	s.server.ServeHTTP(resp, req)
@@ -248,6 +455,7 @@ func (s *ServerTestSuite) TestServer_PAT_401_Expired() {
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
 	)
 
 	past := time.Now().Add(-(7 * 24) * time.Hour)
@@ -265,7 +473,7 @@ func (s *ServerTestSuite) TestServer_PAT_401_Expired() {
 		Return(s.user, nil).Once()
 
 	s.patSvc.EXPECT().
-		FindOne(mock.Anything, mock.Anything, mock.Anything).
+		Read(mock.Anything, mock.Anything, pat.Id).
 		Return(pat, nil).Once()
 
 	s.server.ServeHTTP(resp, req)
@@ -282,6 +490,7 @@ func (s *ServerTestSuite) TestServer_PAT_503() {
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
 	)
 
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
@@ -294,7 +503,7 @@ func (s *ServerTestSuite) TestServer_PAT_503() {
 		Return(s.user, nil).Once()
 
 	s.patSvc.EXPECT().
-		FindOne(mock.Anything, mock.Anything, mock.Anything).
+		Read(mock.Anything, mock.Anything, pat.Id).
 		Return(nil, errors.New("")).Once()
 
 	s.server.ServeHTTP(resp, req)
@@ -307,6 +516,152 @@ func (s *ServerTestSuite) TestServer_PAT_200() {
 		s.user.Id,
 		fmt.Sprintf("my_token"),
 		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pat.Token))
+	resp := httptest.NewRecorder()
+
+	_ = pat.Encrypt()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.patSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, pat.Id).
+		Return(pat, nil).Once()
+
+	s.patSvc.EXPECT().
+		Use(mock.Anything, pat).
+		Return(nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_PAT_200_Rehash() {
+	pat, _ := models.NewPersonalAccessToken(
+		s.user.Id,
+		"my_token",
+		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pat.Token))
+	resp := httptest.NewRecorder()
+
+	viper.Set(config.PasswordArgon2Time, 1)
+	_ = pat.Encrypt()
+	viper.Set(config.PasswordArgon2Time, 3)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.patSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, pat.Id).
+		Return(pat, nil).Once()
+
+	s.patSvc.EXPECT().
+		Use(mock.Anything, pat).
+		Return(nil).Once()
+
+	s.patSvc.EXPECT().
+		Rehash(mock.Anything, mock.MatchedBy(func(p *models.PersonalAccessToken) bool {
+			return strings.Contains(p.Token, ",t=3,")
+		})).
+		Return(nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_PAT_200_Unscoped() {
+	// tokens created before scopes have neither a pat_id nor a scope claim
+	token, _ := jwt.GeneratePersonalToken(s.user.Id, (7*24)*time.Hour, nil)
+	expiresAt := time.Now().Add((7 * 24) * time.Hour)
+	other := models.PersonalAccessToken{Id: "other", ExpiresAt: &expiresAt, Token: "other", UserId: s.user.Id}
+	pat := models.PersonalAccessToken{Id: "legacy", ExpiresAt: &expiresAt, Token: string(token), UserId: s.user.Id}
+	_ = other.Encrypt()
+	_ = pat.Encrypt()
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.patSvc.EXPECT().
+		FindUnscoped(mock.Anything, s.user.Id).
+		Return(models.PersonalAccessTokens{other, pat}, nil).Once()
+
+	s.patSvc.EXPECT().
+		Use(mock.Anything, mock.MatchedBy(func(p *models.PersonalAccessToken) bool {
+			return p.Id == pat.Id
+		})).
+		Return(nil).Once()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_PAT_401_Unscoped_Token_Invalid() {
+	token, _ := jwt.GeneratePersonalToken(s.user.Id, (7*24)*time.Hour, nil)
+	expiresAt := time.Now().Add((7 * 24) * time.Hour)
+	other := models.PersonalAccessToken{Id: "other", ExpiresAt: &expiresAt, Token: "other", UserId: s.user.Id}
+	_ = other.Encrypt()
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Once()
+
+	s.patSvc.EXPECT().
+		FindUnscoped(mock.Anything, s.user.Id).
+		Return(models.PersonalAccessTokens{other}, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Equal(ErrTokenInvalid.Error(), result.Message)
+}
+
+func (s *ServerTestSuite) TestServer_PAT_403_Scope() {
+	pat, _ := models.NewPersonalAccessToken(
+		s.user.Id,
+		"my_token",
+		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeTasksRead},
 	)
 
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
@@ -321,14 +676,401 @@ func (s *ServerTestSuite) TestServer_PAT_200() {
 		Return(s.user, nil).Once()
 
 	s.patSvc.EXPECT().
-		FindOne(mock.Anything, mock.Anything, mock.Anything).
+		Read(mock.Anything, mock.Anything, pat.Id).
 		Return(pat, nil).Once()
 
+	s.patSvc.EXPECT().
+		Use(mock.Anything, pat).
+		Return(nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	var result echo.HTTPError
+	_ = json.Unmarshal(resp.Body.Bytes(), &result)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Equal(ErrScopeInsufficient.Error(), result.Message)
+}
+
+func (s *ServerTestSuite) TestServer_PAT_403_Admin_Scope_Roles() {
+	pat, _ := models.NewPersonalAccessToken(
+		s.user.Id,
+		"my_token",
+		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeAdmin},
+	)
+
+	// the admin scope doesn't go beyond the roles of the owner
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pat.Token))
+	resp := httptest.NewRecorder()
+
+	_ = pat.Encrypt()
+
 	s.svc.EXPECT().
 		Read(mock.Anything, mock.Anything).
 		Return(s.user, nil).Once()
 
+	s.patSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, pat.Id).
+		Return(pat, nil).Once()
+
+	s.patSvc.EXPECT().
+		Use(mock.Anything, pat).
+		Return(nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_PAT_200_Admin_Scope() {
+	pat, _ := models.NewPersonalAccessToken(
+		s.user.Id,
+		"my_token",
+		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeAdmin},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pat.Token))
+	resp := httptest.NewRecorder()
+
+	_ = pat.Encrypt()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Twice()
+
+	s.patSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, pat.Id).
+		Return(pat, nil).Once()
+
+	s.patSvc.EXPECT().
+		Use(mock.Anything, pat).
+		Return(nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_PAT_200_Usage_Throttled() {
+	pat, _ := models.NewPersonalAccessToken(
+		s.user.Id,
+		"my_token",
+		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
+	)
+	pat.Use("192.0.2.1", "curl/8.0")
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pat.Token))
+	resp := httptest.NewRecorder()
+
+	_ = pat.Encrypt()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Twice()
+
+	s.patSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, pat.Id).
+		Return(pat, nil).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().Equal("192.0.2.1", pat.LastUsedIP)
+}
+
+func (s *ServerTestSuite) TestServer_PAT_200_Usage_Err() {
+	pat, _ := models.NewPersonalAccessToken(
+		s.user.Id,
+		"my_token",
+		time.Now().Add((7*24)*time.Hour).Format("2006-01-02"),
+		[]string{models.ScopeUsersRead},
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", pat.Token))
+	req.Header.Set("User-Agent", "curl/8.0")
+	resp := httptest.NewRecorder()
+
+	_ = pat.Encrypt()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, mock.Anything).
+		Return(s.user, nil).Twice()
+
+	s.patSvc.EXPECT().
+		Read(mock.Anything, mock.Anything, pat.Id).
+		Return(pat, nil).Once()
+
+	s.patSvc.EXPECT().
+		Use(mock.Anything, pat).
+		Return(errors.New("error")).Once()
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+	s.Assert().NotNil(pat.LastUsedAt)
+	s.Assert().Equal("curl/8.0", pat.LastUsedUserAgent)
+}
+
+func TestScopePolicy(t *testing.T) {
+	e, err := casbin.NewEnforcer(viper.GetString(config.CasbinModel), viper.GetString(config.CasbinScopePolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		scope   string
+		path    string
+		method  string
+		allowed bool
+	}{
+		{models.ScopeTasksRead, "/tasks", http.MethodGet, true},
+		{models.ScopeTasksRead, "/tasks/:id", http.MethodGet, true},
+		{models.ScopeTasksRead, "/tasks", http.MethodPost, false},
+		{models.ScopeTasksWrite, "/tasks", http.MethodGet, true},
+		{models.ScopeTasksWrite, "/tasks/:id/transition", http.MethodPut, true},
+		{models.ScopeTasksWrite, "/users", http.MethodGet, false},
+		{models.ScopeUsersRead, "/users/:username", http.MethodGet, true},
+		{models.ScopeUsersRead, "/users/:username/ban", http.MethodPut, false},
+		{models.ScopeAdmin, "/users/:username/ban", http.MethodPut, true},
+		{models.ScopeAdmin, "/me/personal_access_tokens", http.MethodPost, true},
+	}
+
+	for _, tc := range testCases {
+		allowed, err := e.Enforce(tc.scope, tc.path, tc.method)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.allowed, allowed, "%s %s %s", tc.scope, tc.method, tc.path)
+	}
+}
+
+func (s *ServerTestSuite) serviceAccount() (*models.ServiceAccount, string) {
+	sa := models.NewServiceAccount("ci", "")
+	sa.Roles = []string{models.UserRole.String()}
+	cred, err := sa.AddCredential()
+	s.Require().NoError(err)
+
+	token, err := sa.Token(cred.ClientId, cred.ClientSecret)
+	s.Require().NoError(err)
+
+	return sa, string(token)
+}
+
+func (s *ServerTestSuite) TestServer_ServiceAccount_200() {
+	sa, token := s.serviceAccount()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1000", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.saSvc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusOK, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_ServiceAccount_403_Admin_Route() {
+	sa, token := s.serviceAccount()
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.saSvc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+}
+
+func (s *ServerTestSuite) TestServer_ServiceAccount_403_Me() {
+	sa, token := s.serviceAccount()
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.saSvc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Contains(resp.Body.String(), ErrServiceAccount.Error())
+}
+
+func (s *ServerTestSuite) TestServer_ServiceAccount_401_Credential_Removed() {
+	sa, token := s.serviceAccount()
+	s.Require().NoError(sa.RemoveCredential(sa.Credentials[0].ClientId))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1000", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.saSvc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(sa, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+	s.Assert().Contains(resp.Body.String(), ErrTokenRevoked.Error())
+}
+
+func (s *ServerTestSuite) TestServer_ServiceAccount_401_Deleted() {
+	sa, token := s.serviceAccount()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1000", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.saSvc.EXPECT().
+		Read(mock.Anything, sa.Id).
+		Return(nil, services.NewError(nil, services.Deleted, services.ErrServiceAccountDeleted.Error()))
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+}
+
+func (s *ServerTestSuite) impersonation() (*models.User, string) {
+	super := models.NewUserWithRole("super@example.com", "super", models.SuperRole)
+
+	token, err := s.user.Impersonate(super, models.NewSession(s.user.Id))
+	s.Require().NoError(err)
+
+	return super, string(token)
+}
+
+func (s *ServerTestSuite) TestServer_Impersonation_200() {
+	super, token := s.impersonation()
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, super.Id).
+		Return(super, nil)
+
 	s.server.ServeHTTP(resp, req)
 
 	s.Assert().Equal(http.StatusOK, resp.Code)
 }
+
+func (s *ServerTestSuite) TestServer_Impersonation_Logger() {
+	var buf bytes.Buffer
+	logger := log.Logger
+	log.Logger = zerolog.New(&buf)
+	defer func() { log.Logger = logger }()
+
+	super, token := s.impersonation()
+
+	var ctxs []context.Context
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Run(func(ctx context.Context, id string) { ctxs = append(ctxs, ctx) }).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, super.Id).
+		Return(super, nil)
+
+	for _, t := range []string{token, string(s.accessToken)} {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", t))
+		resp := httptest.NewRecorder()
+
+		s.server.ServeHTTP(resp, req)
+
+		s.Assert().Equal(http.StatusOK, resp.Code)
+	}
+
+	// the handlers of each request: impersonated, then not
+	s.Require().Len(ctxs, 4)
+
+	buf.Reset()
+	log.Ctx(ctxs[1]).Info().Msg("impersonated")
+	s.Assert().Contains(buf.String(), `"actor_id":"`+super.Id+`"`)
+
+	buf.Reset()
+	log.Ctx(ctxs[3]).Info().Msg("not impersonated")
+	log.Info().Msg("global")
+	s.Assert().NotContains(buf.String(), "actor_id")
+}
+
+func (s *ServerTestSuite) TestServer_Impersonation_403_Credentials() {
+	super, token := s.impersonation()
+	server := NewTestServer(s.svc, s.patSvc, s.saSvc, s.sessionSvc, handlers.NewPersonalAccessTokenHandler(openapi.NewHandler(), s.patSvc, s.svc))
+
+	b, _ := json.Marshal(map[string]any{"name": "test", "expires_at": time.Now().Add(24 * time.Hour)})
+	req := httptest.NewRequest(http.MethodPost, "/me/personal_access_tokens", bytes.NewBuffer(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, super.Id).
+		Return(super, nil)
+
+	server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusForbidden, resp.Code)
+	s.Assert().Contains(resp.Body.String(), ErrImpersonation.Error())
+}
+
+func (s *ServerTestSuite) TestServer_Impersonation_401_Not_Super() {
+	super, token := s.impersonation()
+	super.Roles = []string{models.UserRole.String(), models.AdminRole.String()}
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	resp := httptest.NewRecorder()
+
+	s.svc.EXPECT().
+		Read(mock.Anything, s.user.Id).
+		Return(s.user, nil)
+
+	s.svc.EXPECT().
+		Read(mock.Anything, super.Id).
+		Return(super, nil)
+
+	s.server.ServeHTTP(resp, req)
+
+	s.Assert().Equal(http.StatusUnauthorized, resp.Code)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// EmailVerificationMapper defines the datastore handling persisting EmailVerification documents.
+type EmailVerificationMapper interface {
+	Create(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error)
+	FindOne(ctx context.Context, filter any) (*models.EmailVerification, error)
+	Update(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error)
+	UpdateOne(ctx context.Context, filter any, update any) (int64, error)
+}
+
+var ErrEmailVerificationNotFound = errors.New("email verification not found")
+
+// EmailVerification defines the application service in charge of interacting with EmailVerifications.
+type EmailVerification struct {
+	mapper EmailVerificationMapper
+}
+
+func NewEmailVerification(mapper EmailVerificationMapper) *EmailVerification {
+	return &EmailVerification{mapper: mapper}
+}
+
+func (e *EmailVerification) Create(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error) {
+	ev, err := e.mapper.Create(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return ev, nil
+}
+
+func (e *EmailVerification) Read(ctx context.Context, id string) (*models.EmailVerification, error) {
+	filter := bson.D{{"id", id}}
+	ev, err := e.mapper.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return nil, NewError(err, NotExist, ErrEmailVerificationNotFound.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return ev, nil
+}
+
+func (e *EmailVerification) Update(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error) {
+	ev, err := e.mapper.Update(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return ev, nil
+}
+
+// Use consumes model, it fails with a Conflict error if it was already
+// used, including by another request since it was read.
+func (e *EmailVerification) Use(ctx context.Context, model *models.EmailVerification) error {
+	model.Use()
+	filter := bson.D{{"id", model.Id}, {"used_at", nil}}
+	update := bson.D{{"$set", bson.D{{"used_at", model.UsedAt}}}}
+	matched, err := e.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	if matched == 0 {
+		return NewError(models.ErrEmailVerificationUsed, Conflict, models.ErrEmailVerificationUsed.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type EmailVerificationTestSuite struct {
+	suite.Suite
+	mapper *services.MockEmailVerificationMapper
+	svc    *services.EmailVerification
+}
+
+func (s *EmailVerificationTestSuite) SetupTest() {
+	s.mapper = services.NewMockEmailVerificationMapper(s.T())
+	s.svc = services.NewEmailVerification(s.mapper)
+}
+
+func TestEmailVerificationTestSuite(t *testing.T) {
+	suite.Run(t, new(EmailVerificationTestSuite))
+}
+
+func (s *EmailVerificationTestSuite) TestEmailVerification_Create() {
+	m, _ := models.NewEmailVerification("100", "test@email.com")
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ev, err := s.svc.Create(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Equal("100", ev.UserId)
+}
+
+func (s *EmailVerificationTestSuite) TestEmailVerification_Read() {
+	m, _ := models.NewEmailVerification("100", "test@email.com")
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ev, err := s.svc.Read(context.Background(), m.Id)
+	s.Assert().NoError(err)
+	s.Assert().Equal(m.Id, ev.Id)
+}
+
+func (s *EmailVerificationTestSuite) TestEmailVerification_Read_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, data.ErrNoDocuments)
+
+	_, err := s.svc.Read(context.Background(), "123")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.NotExist, se.Kind)
+	}
+}
+
+func (s *EmailVerificationTestSuite) TestEmailVerification_Update() {
+	m, _ := models.NewEmailVerification("100", "test@email.com")
+	_ = m.Verify(models.NewUser("test@email.com", "test"))
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ev, err := s.svc.Update(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(ev.UsedAt)
+}
+
+func (s *EmailVerificationTestSuite) TestEmailVerification_Use() {
+	m, _ := models.NewEmailVerification("100", "test@email.com")
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, bson.D{{"id", m.Id}, {"used_at", nil}}, mock.Anything).
+		Return(1, nil)
+
+	err := s.svc.Use(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(m.UsedAt)
+}
+
+func (s *EmailVerificationTestSuite) TestEmailVerification_Use_Conflict() {
+	m, _ := models.NewEmailVerification("100", "test@email.com")
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, mock.Anything, mock.Anything).
+		Return(0, nil)
+
+	err := s.svc.Use(context.Background(), m)
+	var se *services.Error
+	if s.Assert().ErrorAs(err, &se) {
+		s.Assert().Equal(services.Conflict, se.Kind)
+	}
+}
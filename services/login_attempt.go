@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// LoginAttemptMapper defines the datastore handling persisting LoginAttempt documents.
+type LoginAttemptMapper interface {
+	FindOne(ctx context.Context, filter any) (*models.LoginAttempt, error)
+	Upsert(ctx context.Context, filter any, update any) (*models.LoginAttempt, error)
+	UpdateOne(ctx context.Context, filter any, update any) error
+}
+
+// LoginAttempt defines the application service in charge of interacting with LoginAttempts.
+type LoginAttempt struct {
+	mapper LoginAttemptMapper
+}
+
+func NewLoginAttempt(mapper LoginAttemptMapper) *LoginAttempt {
+	return &LoginAttempt{mapper: mapper}
+}
+
+// Read returns the LoginAttempt of value, or a new one if there were no recent failures.
+func (l *LoginAttempt) Read(ctx context.Context, kind string, value string) (*models.LoginAttempt, error) {
+	filter := bson.D{{"id", models.LoginAttemptId(kind, value)}}
+	attempt, err := l.mapper.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return models.NewLoginAttempt(kind, value), nil
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return attempt, nil
+}
+
+// Fail counts a failed attempt of model and returns it as updated. The failures
+// are incremented by the datastore so concurrent ones are all counted, once the
+// previous failures are forgotten or their lock ended they start over.
+func (l *LoginAttempt) Fail(ctx context.Context, model *models.LoginAttempt) (*models.LoginAttempt, error) {
+	now := time.Now()
+	window := model.Window()
+
+	filter := bson.D{{"id", model.Id}, {"$or", bson.A{
+		bson.D{{"last_failure_at", bson.D{{"$lt", now.Add(-window)}}}},
+		bson.D{{"locked_until", bson.D{{"$lte", now}}}},
+	}}}
+	update := bson.D{{"$set", bson.D{{"failures", 0}, {"locked_until", nil}}}}
+	err := l.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	filter = bson.D{{"id", model.Id}}
+	update = bson.D{
+		{"$inc", bson.D{{"failures", 1}}},
+		{"$set", bson.D{{"kind", model.Kind}, {"last_failure_at", now}}},
+		{"$max", bson.D{{"expires_at", now.Add(window)}}},
+	}
+	attempt, err := l.mapper.Upsert(ctx, filter, update)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	if attempt.Lock() {
+		// only the first failure reaching the maximum sets the lock
+		filter = bson.D{{"id", attempt.Id}, {"locked_until", nil}}
+		update = bson.D{
+			{"$set", bson.D{{"locked_until", attempt.LockedUntil}}},
+			{"$max", bson.D{{"expires_at", attempt.ExpiresAt}}},
+		}
+		err = l.mapper.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return nil, NewError(err, Other, "other")
+		}
+	}
+
+	return attempt, nil
+}
+
+// Reset forgets the failures of model.
+func (l *LoginAttempt) Reset(ctx context.Context, model *models.LoginAttempt) error {
+	model.Reset()
+	filter := bson.D{{"id", model.Id}}
+	update := bson.D{{"$set", bson.D{{"failures", 0}, {"last_failure_at", nil}, {"locked_until", nil}}}}
+	err := l.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/config"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type LoginAttemptTestSuite struct {
+	suite.Suite
+	mapper *services.MockLoginAttemptMapper
+	svc    *services.LoginAttempt
+}
+
+func (s *LoginAttemptTestSuite) SetupTest() {
+	s.mapper = services.NewMockLoginAttemptMapper(s.T())
+	s.svc = services.NewLoginAttempt(s.mapper)
+}
+
+func TestLoginAttemptTestSuite(t *testing.T) {
+	suite.Run(t, new(LoginAttemptTestSuite))
+}
+
+func (s *LoginAttemptTestSuite) TestLoginAttempt_Read() {
+	m := models.NewLoginAttempt(models.LoginAttemptAccount, "100")
+	m.Failures = 1
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	a, err := s.svc.Read(context.Background(), models.LoginAttemptAccount, "100")
+	s.Assert().NoError(err)
+	s.Assert().Equal(1, a.Failures)
+}
+
+func (s *LoginAttemptTestSuite) TestLoginAttempt_Read_New() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, data.ErrNoDocuments)
+
+	a, err := s.svc.Read(context.Background(), models.LoginAttemptIP, "192.0.2.1")
+	s.Assert().NoError(err)
+	s.Assert().Equal("ip:192.0.2.1", a.Id)
+	s.Assert().Equal(0, a.Failures)
+}
+
+func (s *LoginAttemptTestSuite) TestLoginAttempt_Read_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, errors.New("error"))
+
+	_, err := s.svc.Read(context.Background(), models.LoginAttemptIP, "192.0.2.1")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.Other, se.Kind)
+	}
+}
+
+func (s *LoginAttemptTestSuite) TestLoginAttempt_Fail() {
+	m := models.NewLoginAttempt(models.LoginAttemptAccount, "100")
+	now := time.Now()
+	counted := &models.LoginAttempt{Id: m.Id, Kind: m.Kind, Failures: 2, LastFailureAt: &now}
+
+	// forgotten failures and ended locks start over
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, mock.Anything, bson.D{{"$set", bson.D{{"failures", 0}, {"locked_until", nil}}}}).
+		Return(nil).Once()
+
+	s.mapper.EXPECT().
+		Upsert(mock.Anything, bson.D{{"id", m.Id}}, mock.MatchedBy(func(update bson.D) bool {
+			return update[0].Key == "$inc"
+		})).
+		Return(counted, nil).Once()
+
+	a, err := s.svc.Fail(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Equal(2, a.Failures)
+	s.Assert().Nil(a.LockedUntil)
+}
+
+func (s *LoginAttemptTestSuite) TestLoginAttempt_Fail_Lock() {
+	m := models.NewLoginAttempt(models.LoginAttemptAccount, "100")
+	now := time.Now()
+	counted := &models.LoginAttempt{
+		Id:            m.Id,
+		Kind:          m.Kind,
+		Failures:      viper.GetInt(config.LoginThrottleAccountMaxAttempts),
+		LastFailureAt: &now,
+	}
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, mock.Anything, mock.Anything).
+		Return(nil).Once()
+
+	s.mapper.EXPECT().
+		Upsert(mock.Anything, mock.Anything, mock.Anything).
+		Return(counted, nil).Once()
+
+	// the lock is computed from the failures counted by the datastore
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, bson.D{{"id", m.Id}, {"locked_until", nil}}, mock.Anything).
+		Return(nil).Once()
+
+	a, err := s.svc.Fail(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(a.LockedUntil)
+	s.Assert().Greater(a.RetryAfter(), viper.GetDuration(config.LoginThrottleLockDuration)-time.Minute)
+}
+
+func (s *LoginAttemptTestSuite) TestLoginAttempt_Reset() {
+	m := models.NewLoginAttempt(models.LoginAttemptAccount, "100")
+	m.Failures = 3
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, bson.D{{"id", m.Id}}, mock.Anything).
+		Return(nil).Once()
+
+	err := s.svc.Reset(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Equal(0, m.Failures)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// MagicLinkMapper defines the datastore handling persisting MagicLink documents.
+type MagicLinkMapper interface {
+	Create(ctx context.Context, model *models.MagicLink) (*models.MagicLink, error)
+	Count(ctx context.Context, filter any) (int64, error)
+	FindOne(ctx context.Context, filter any) (*models.MagicLink, error)
+	Update(ctx context.Context, model *models.MagicLink) (*models.MagicLink, error)
+	UpdateOne(ctx context.Context, filter any, update any) (int64, error)
+}
+
+var ErrMagicLinkNotFound = errors.New("magic link not found")
+
+// MagicLink defines the application service in charge of interacting with MagicLinks.
+type MagicLink struct {
+	mapper MagicLinkMapper
+}
+
+func NewMagicLink(mapper MagicLinkMapper) *MagicLink {
+	return &MagicLink{mapper: mapper}
+}
+
+func (m *MagicLink) Create(ctx context.Context, model *models.MagicLink) (*models.MagicLink, error) {
+	ml, err := m.mapper.Create(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return ml, nil
+}
+
+// CountSince returns the number of magic links created for the user since t.
+func (m *MagicLink) CountSince(ctx context.Context, userId string, t time.Time) (int64, error) {
+	filter := bson.D{{"user_id", userId}, {"created_at", bson.D{{"$gte", t}}}}
+	count, err := m.mapper.Count(ctx, filter)
+	if err != nil {
+		return 0, NewError(err, Other, "other")
+	}
+
+	return count, nil
+}
+
+func (m *MagicLink) Read(ctx context.Context, id string) (*models.MagicLink, error) {
+	filter := bson.D{{"id", id}}
+	ml, err := m.mapper.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return nil, NewError(err, NotExist, ErrMagicLinkNotFound.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return ml, nil
+}
+
+func (m *MagicLink) Update(ctx context.Context, model *models.MagicLink) (*models.MagicLink, error) {
+	ml, err := m.mapper.Update(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return ml, nil
+}
+
+// Use consumes model, it fails with a Conflict error if it was already
+// used, including by another request since it was read.
+func (m *MagicLink) Use(ctx context.Context, model *models.MagicLink) error {
+	model.Use()
+	filter := bson.D{{"id", model.Id}, {"used_at", nil}}
+	update := bson.D{{"$set", bson.D{{"used_at", model.UsedAt}}}}
+	matched, err := m.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	if matched == 0 {
+		return NewError(models.ErrMagicLinkUsed, Conflict, models.ErrMagicLinkUsed.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type MagicLinkTestSuite struct {
+	suite.Suite
+	mapper *services.MockMagicLinkMapper
+	svc    *services.MagicLink
+}
+
+func (s *MagicLinkTestSuite) SetupTest() {
+	s.mapper = services.NewMockMagicLinkMapper(s.T())
+	s.svc = services.NewMagicLink(s.mapper)
+}
+
+func TestMagicLinkTestSuite(t *testing.T) {
+	suite.Run(t, new(MagicLinkTestSuite))
+}
+
+func (s *MagicLinkTestSuite) TestMagicLink_Create() {
+	m, _ := models.NewMagicLink("100", "nonce", "")
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ml, err := s.svc.Create(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Equal("100", ml.UserId)
+}
+
+func (s *MagicLinkTestSuite) TestMagicLink_CountSince() {
+	since := time.Now().Add(-time.Hour)
+	filter := bson.D{{"user_id", "100"}, {"created_at", bson.D{{"$gte", since}}}}
+
+	s.mapper.EXPECT().
+		Count(mock.Anything, filter).
+		Return(2, nil)
+
+	count, err := s.svc.CountSince(context.Background(), "100", since)
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(2), count)
+}
+
+func (s *MagicLinkTestSuite) TestMagicLink_Read() {
+	m, _ := models.NewMagicLink("100", "nonce", "")
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ml, err := s.svc.Read(context.Background(), m.Id)
+	s.Assert().NoError(err)
+	s.Assert().Equal(m.Id, ml.Id)
+}
+
+func (s *MagicLinkTestSuite) TestMagicLink_Read_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, data.ErrNoDocuments)
+
+	_, err := s.svc.Read(context.Background(), "123")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.NotExist, se.Kind)
+	}
+}
+
+func (s *MagicLinkTestSuite) TestMagicLink_Update() {
+	m, _ := models.NewMagicLink("100", "nonce", "")
+	m.Use()
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ml, err := s.svc.Update(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(ml.UsedAt)
+}
+
+func (s *MagicLinkTestSuite) TestMagicLink_Use() {
+	m, _ := models.NewMagicLink("100", "nonce", "")
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, bson.D{{"id", m.Id}, {"used_at", nil}}, mock.Anything).
+		Return(1, nil)
+
+	err := s.svc.Use(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(m.UsedAt)
+}
+
+func (s *MagicLinkTestSuite) TestMagicLink_Use_Conflict() {
+	m, _ := models.NewMagicLink("100", "nonce", "")
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, mock.Anything, mock.Anything).
+		Return(0, nil)
+
+	err := s.svc.Use(context.Background(), m)
+	var se *services.Error
+	if s.Assert().ErrorAs(err, &se) {
+		s.Assert().Equal(services.Conflict, se.Kind)
+	}
+}
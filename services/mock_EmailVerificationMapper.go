@@ -0,0 +1,272 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package services
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockEmailVerificationMapper is an autogenerated mock type for the EmailVerificationMapper type
+type MockEmailVerificationMapper struct {
+	mock.Mock
+}
+
+type MockEmailVerificationMapper_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockEmailVerificationMapper) EXPECT() *MockEmailVerificationMapper_Expecter {
+	return &MockEmailVerificationMapper_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockEmailVerificationMapper) Create(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.EmailVerification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.EmailVerification) (*models.EmailVerification, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.EmailVerification) *models.EmailVerification); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.EmailVerification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.EmailVerification) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockEmailVerificationMapper_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockEmailVerificationMapper_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.EmailVerification
+func (_e *MockEmailVerificationMapper_Expecter) Create(ctx interface{}, model interface{}) *MockEmailVerificationMapper_Create_Call {
+	return &MockEmailVerificationMapper_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockEmailVerificationMapper_Create_Call) Run(run func(ctx context.Context, model *models.EmailVerification)) *MockEmailVerificationMapper_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.EmailVerification))
+	})
+	return _c
+}
+
+func (_c *MockEmailVerificationMapper_Create_Call) Return(_a0 *models.EmailVerification, _a1 error) *MockEmailVerificationMapper_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockEmailVerificationMapper_Create_Call) RunAndReturn(run func(context.Context, *models.EmailVerification) (*models.EmailVerification, error)) *MockEmailVerificationMapper_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindOne provides a mock function with given fields: ctx, filter
+func (_m *MockEmailVerificationMapper) FindOne(ctx context.Context, filter interface{}) (*models.EmailVerification, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOne")
+	}
+
+	var r0 *models.EmailVerification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*models.EmailVerification, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *models.EmailVerification); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.EmailVerification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockEmailVerificationMapper_FindOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOne'
+type MockEmailVerificationMapper_FindOne_Call struct {
+	*mock.Call
+}
+
+// FindOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockEmailVerificationMapper_Expecter) FindOne(ctx interface{}, filter interface{}) *MockEmailVerificationMapper_FindOne_Call {
+	return &MockEmailVerificationMapper_FindOne_Call{Call: _e.mock.On("FindOne", ctx, filter)}
+}
+
+func (_c *MockEmailVerificationMapper_FindOne_Call) Run(run func(ctx context.Context, filter interface{})) *MockEmailVerificationMapper_FindOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockEmailVerificationMapper_FindOne_Call) Return(_a0 *models.EmailVerification, _a1 error) *MockEmailVerificationMapper_FindOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockEmailVerificationMapper_FindOne_Call) RunAndReturn(run func(context.Context, interface{}) (*models.EmailVerification, error)) *MockEmailVerificationMapper_FindOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockEmailVerificationMapper) Update(ctx context.Context, model *models.EmailVerification) (*models.EmailVerification, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.EmailVerification
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.EmailVerification) (*models.EmailVerification, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.EmailVerification) *models.EmailVerification); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.EmailVerification)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.EmailVerification) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockEmailVerificationMapper_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockEmailVerificationMapper_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.EmailVerification
+func (_e *MockEmailVerificationMapper_Expecter) Update(ctx interface{}, model interface{}) *MockEmailVerificationMapper_Update_Call {
+	return &MockEmailVerificationMapper_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockEmailVerificationMapper_Update_Call) Run(run func(ctx context.Context, model *models.EmailVerification)) *MockEmailVerificationMapper_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.EmailVerification))
+	})
+	return _c
+}
+
+func (_c *MockEmailVerificationMapper_Update_Call) Return(_a0 *models.EmailVerification, _a1 error) *MockEmailVerificationMapper_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockEmailVerificationMapper_Update_Call) RunAndReturn(run func(context.Context, *models.EmailVerification) (*models.EmailVerification, error)) *MockEmailVerificationMapper_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateOne provides a mock function with given fields: ctx, filter, update
+func (_m *MockEmailVerificationMapper) UpdateOne(ctx context.Context, filter interface{}, update interface{}) (int64, error) {
+	ret := _m.Called(ctx, filter, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateOne")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) (int64, error)); ok {
+		return rf(ctx, filter, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) int64); ok {
+		r0 = rf(ctx, filter, update)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, interface{}) error); ok {
+		r1 = rf(ctx, filter, update)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockEmailVerificationMapper_UpdateOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateOne'
+type MockEmailVerificationMapper_UpdateOne_Call struct {
+	*mock.Call
+}
+
+// UpdateOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+//   - update interface{}
+func (_e *MockEmailVerificationMapper_Expecter) UpdateOne(ctx interface{}, filter interface{}, update interface{}) *MockEmailVerificationMapper_UpdateOne_Call {
+	return &MockEmailVerificationMapper_UpdateOne_Call{Call: _e.mock.On("UpdateOne", ctx, filter, update)}
+}
+
+func (_c *MockEmailVerificationMapper_UpdateOne_Call) Run(run func(ctx context.Context, filter interface{}, update interface{})) *MockEmailVerificationMapper_UpdateOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}), args[2].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockEmailVerificationMapper_UpdateOne_Call) Return(_a0 int64, _a1 error) *MockEmailVerificationMapper_UpdateOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockEmailVerificationMapper_UpdateOne_Call) RunAndReturn(run func(context.Context, interface{}, interface{}) (int64, error)) *MockEmailVerificationMapper_UpdateOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockEmailVerificationMapper creates a new instance of MockEmailVerificationMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockEmailVerificationMapper(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockEmailVerificationMapper {
+	mock := &MockEmailVerificationMapper{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,204 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package services
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockLoginAttemptMapper is an autogenerated mock type for the LoginAttemptMapper type
+type MockLoginAttemptMapper struct {
+	mock.Mock
+}
+
+type MockLoginAttemptMapper_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockLoginAttemptMapper) EXPECT() *MockLoginAttemptMapper_Expecter {
+	return &MockLoginAttemptMapper_Expecter{mock: &_m.Mock}
+}
+
+// FindOne provides a mock function with given fields: ctx, filter
+func (_m *MockLoginAttemptMapper) FindOne(ctx context.Context, filter interface{}) (*models.LoginAttempt, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOne")
+	}
+
+	var r0 *models.LoginAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*models.LoginAttempt, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *models.LoginAttempt); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginAttempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLoginAttemptMapper_FindOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOne'
+type MockLoginAttemptMapper_FindOne_Call struct {
+	*mock.Call
+}
+
+// FindOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockLoginAttemptMapper_Expecter) FindOne(ctx interface{}, filter interface{}) *MockLoginAttemptMapper_FindOne_Call {
+	return &MockLoginAttemptMapper_FindOne_Call{Call: _e.mock.On("FindOne", ctx, filter)}
+}
+
+func (_c *MockLoginAttemptMapper_FindOne_Call) Run(run func(ctx context.Context, filter interface{})) *MockLoginAttemptMapper_FindOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptMapper_FindOne_Call) Return(_a0 *models.LoginAttempt, _a1 error) *MockLoginAttemptMapper_FindOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLoginAttemptMapper_FindOne_Call) RunAndReturn(run func(context.Context, interface{}) (*models.LoginAttempt, error)) *MockLoginAttemptMapper_FindOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateOne provides a mock function with given fields: ctx, filter, update
+func (_m *MockLoginAttemptMapper) UpdateOne(ctx context.Context, filter interface{}, update interface{}) error {
+	ret := _m.Called(ctx, filter, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateOne")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) error); ok {
+		r0 = rf(ctx, filter, update)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockLoginAttemptMapper_UpdateOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateOne'
+type MockLoginAttemptMapper_UpdateOne_Call struct {
+	*mock.Call
+}
+
+// UpdateOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+//   - update interface{}
+func (_e *MockLoginAttemptMapper_Expecter) UpdateOne(ctx interface{}, filter interface{}, update interface{}) *MockLoginAttemptMapper_UpdateOne_Call {
+	return &MockLoginAttemptMapper_UpdateOne_Call{Call: _e.mock.On("UpdateOne", ctx, filter, update)}
+}
+
+func (_c *MockLoginAttemptMapper_UpdateOne_Call) Run(run func(ctx context.Context, filter interface{}, update interface{})) *MockLoginAttemptMapper_UpdateOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}), args[2].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptMapper_UpdateOne_Call) Return(_a0 error) *MockLoginAttemptMapper_UpdateOne_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockLoginAttemptMapper_UpdateOne_Call) RunAndReturn(run func(context.Context, interface{}, interface{}) error) *MockLoginAttemptMapper_UpdateOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Upsert provides a mock function with given fields: ctx, filter, update
+func (_m *MockLoginAttemptMapper) Upsert(ctx context.Context, filter interface{}, update interface{}) (*models.LoginAttempt, error) {
+	ret := _m.Called(ctx, filter, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Upsert")
+	}
+
+	var r0 *models.LoginAttempt
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) (*models.LoginAttempt, error)); ok {
+		return rf(ctx, filter, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) *models.LoginAttempt); ok {
+		r0 = rf(ctx, filter, update)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginAttempt)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, interface{}) error); ok {
+		r1 = rf(ctx, filter, update)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockLoginAttemptMapper_Upsert_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Upsert'
+type MockLoginAttemptMapper_Upsert_Call struct {
+	*mock.Call
+}
+
+// Upsert is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+//   - update interface{}
+func (_e *MockLoginAttemptMapper_Expecter) Upsert(ctx interface{}, filter interface{}, update interface{}) *MockLoginAttemptMapper_Upsert_Call {
+	return &MockLoginAttemptMapper_Upsert_Call{Call: _e.mock.On("Upsert", ctx, filter, update)}
+}
+
+func (_c *MockLoginAttemptMapper_Upsert_Call) Run(run func(ctx context.Context, filter interface{}, update interface{})) *MockLoginAttemptMapper_Upsert_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}), args[2].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockLoginAttemptMapper_Upsert_Call) Return(_a0 *models.LoginAttempt, _a1 error) *MockLoginAttemptMapper_Upsert_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockLoginAttemptMapper_Upsert_Call) RunAndReturn(run func(context.Context, interface{}, interface{}) (*models.LoginAttempt, error)) *MockLoginAttemptMapper_Upsert_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockLoginAttemptMapper creates a new instance of MockLoginAttemptMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockLoginAttemptMapper(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockLoginAttemptMapper {
+	mock := &MockLoginAttemptMapper{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
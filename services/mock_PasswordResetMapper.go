@@ -0,0 +1,329 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package services
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockPasswordResetMapper is an autogenerated mock type for the PasswordResetMapper type
+type MockPasswordResetMapper struct {
+	mock.Mock
+}
+
+type MockPasswordResetMapper_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockPasswordResetMapper) EXPECT() *MockPasswordResetMapper_Expecter {
+	return &MockPasswordResetMapper_Expecter{mock: &_m.Mock}
+}
+
+// Count provides a mock function with given fields: ctx, filter
+func (_m *MockPasswordResetMapper) Count(ctx context.Context, filter interface{}) (int64, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Count")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (int64, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) int64); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPasswordResetMapper_Count_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Count'
+type MockPasswordResetMapper_Count_Call struct {
+	*mock.Call
+}
+
+// Count is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockPasswordResetMapper_Expecter) Count(ctx interface{}, filter interface{}) *MockPasswordResetMapper_Count_Call {
+	return &MockPasswordResetMapper_Count_Call{Call: _e.mock.On("Count", ctx, filter)}
+}
+
+func (_c *MockPasswordResetMapper_Count_Call) Run(run func(ctx context.Context, filter interface{})) *MockPasswordResetMapper_Count_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_Count_Call) Return(_a0 int64, _a1 error) *MockPasswordResetMapper_Count_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_Count_Call) RunAndReturn(run func(context.Context, interface{}) (int64, error)) *MockPasswordResetMapper_Count_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockPasswordResetMapper) Create(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.PasswordReset
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PasswordReset) (*models.PasswordReset, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PasswordReset) *models.PasswordReset); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PasswordReset)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.PasswordReset) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPasswordResetMapper_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockPasswordResetMapper_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.PasswordReset
+func (_e *MockPasswordResetMapper_Expecter) Create(ctx interface{}, model interface{}) *MockPasswordResetMapper_Create_Call {
+	return &MockPasswordResetMapper_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockPasswordResetMapper_Create_Call) Run(run func(ctx context.Context, model *models.PasswordReset)) *MockPasswordResetMapper_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.PasswordReset))
+	})
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_Create_Call) Return(_a0 *models.PasswordReset, _a1 error) *MockPasswordResetMapper_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_Create_Call) RunAndReturn(run func(context.Context, *models.PasswordReset) (*models.PasswordReset, error)) *MockPasswordResetMapper_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindOne provides a mock function with given fields: ctx, filter
+func (_m *MockPasswordResetMapper) FindOne(ctx context.Context, filter interface{}) (*models.PasswordReset, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOne")
+	}
+
+	var r0 *models.PasswordReset
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*models.PasswordReset, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *models.PasswordReset); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PasswordReset)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPasswordResetMapper_FindOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOne'
+type MockPasswordResetMapper_FindOne_Call struct {
+	*mock.Call
+}
+
+// FindOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockPasswordResetMapper_Expecter) FindOne(ctx interface{}, filter interface{}) *MockPasswordResetMapper_FindOne_Call {
+	return &MockPasswordResetMapper_FindOne_Call{Call: _e.mock.On("FindOne", ctx, filter)}
+}
+
+func (_c *MockPasswordResetMapper_FindOne_Call) Run(run func(ctx context.Context, filter interface{})) *MockPasswordResetMapper_FindOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_FindOne_Call) Return(_a0 *models.PasswordReset, _a1 error) *MockPasswordResetMapper_FindOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_FindOne_Call) RunAndReturn(run func(context.Context, interface{}) (*models.PasswordReset, error)) *MockPasswordResetMapper_FindOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockPasswordResetMapper) Update(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.PasswordReset
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PasswordReset) (*models.PasswordReset, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PasswordReset) *models.PasswordReset); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.PasswordReset)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.PasswordReset) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPasswordResetMapper_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockPasswordResetMapper_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.PasswordReset
+func (_e *MockPasswordResetMapper_Expecter) Update(ctx interface{}, model interface{}) *MockPasswordResetMapper_Update_Call {
+	return &MockPasswordResetMapper_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockPasswordResetMapper_Update_Call) Run(run func(ctx context.Context, model *models.PasswordReset)) *MockPasswordResetMapper_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.PasswordReset))
+	})
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_Update_Call) Return(_a0 *models.PasswordReset, _a1 error) *MockPasswordResetMapper_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_Update_Call) RunAndReturn(run func(context.Context, *models.PasswordReset) (*models.PasswordReset, error)) *MockPasswordResetMapper_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateOne provides a mock function with given fields: ctx, filter, update
+func (_m *MockPasswordResetMapper) UpdateOne(ctx context.Context, filter interface{}, update interface{}) (int64, error) {
+	ret := _m.Called(ctx, filter, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateOne")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) (int64, error)); ok {
+		return rf(ctx, filter, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) int64); ok {
+		r0 = rf(ctx, filter, update)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, interface{}) error); ok {
+		r1 = rf(ctx, filter, update)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPasswordResetMapper_UpdateOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateOne'
+type MockPasswordResetMapper_UpdateOne_Call struct {
+	*mock.Call
+}
+
+// UpdateOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+//   - update interface{}
+func (_e *MockPasswordResetMapper_Expecter) UpdateOne(ctx interface{}, filter interface{}, update interface{}) *MockPasswordResetMapper_UpdateOne_Call {
+	return &MockPasswordResetMapper_UpdateOne_Call{Call: _e.mock.On("UpdateOne", ctx, filter, update)}
+}
+
+func (_c *MockPasswordResetMapper_UpdateOne_Call) Run(run func(ctx context.Context, filter interface{}, update interface{})) *MockPasswordResetMapper_UpdateOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}), args[2].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_UpdateOne_Call) Return(_a0 int64, _a1 error) *MockPasswordResetMapper_UpdateOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPasswordResetMapper_UpdateOne_Call) RunAndReturn(run func(context.Context, interface{}, interface{}) (int64, error)) *MockPasswordResetMapper_UpdateOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockPasswordResetMapper creates a new instance of MockPasswordResetMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockPasswordResetMapper(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockPasswordResetMapper {
+	mock := &MockPasswordResetMapper{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
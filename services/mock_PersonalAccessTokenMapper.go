@@ -7,6 +7,8 @@ import (
 
 	models "github.com/alexferl/echo-boilerplate/models"
 	mock "github.com/stretchr/testify/mock"
+
+	options "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MockPersonalAccessTokenMapper is an autogenerated mock type for the PersonalAccessTokenMapper type
@@ -22,6 +24,63 @@ func (_m *MockPersonalAccessTokenMapper) EXPECT() *MockPersonalAccessTokenMapper
 	return &MockPersonalAccessTokenMapper_Expecter{mock: &_m.Mock}
 }
 
+// Count provides a mock function with given fields: ctx, filter
+func (_m *MockPersonalAccessTokenMapper) Count(ctx context.Context, filter interface{}) (int64, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Count")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (int64, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) int64); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPersonalAccessTokenMapper_Count_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Count'
+type MockPersonalAccessTokenMapper_Count_Call struct {
+	*mock.Call
+}
+
+// Count is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockPersonalAccessTokenMapper_Expecter) Count(ctx interface{}, filter interface{}) *MockPersonalAccessTokenMapper_Count_Call {
+	return &MockPersonalAccessTokenMapper_Count_Call{Call: _e.mock.On("Count", ctx, filter)}
+}
+
+func (_c *MockPersonalAccessTokenMapper_Count_Call) Run(run func(ctx context.Context, filter interface{})) *MockPersonalAccessTokenMapper_Count_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_Count_Call) Return(_a0 int64, _a1 error) *MockPersonalAccessTokenMapper_Count_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_Count_Call) RunAndReturn(run func(context.Context, interface{}) (int64, error)) *MockPersonalAccessTokenMapper_Count_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Create provides a mock function with given fields: ctx, model
 func (_m *MockPersonalAccessTokenMapper) Create(ctx context.Context, model *models.PersonalAccessToken) (*models.PersonalAccessToken, error) {
 	ret := _m.Called(ctx, model)
@@ -81,29 +140,93 @@ func (_c *MockPersonalAccessTokenMapper_Create_Call) RunAndReturn(run func(conte
 	return _c
 }
 
-// Find provides a mock function with given fields: ctx, filter
-func (_m *MockPersonalAccessTokenMapper) Find(ctx context.Context, filter interface{}) (models.PersonalAccessTokens, error) {
+// DeleteMany provides a mock function with given fields: ctx, filter
+func (_m *MockPersonalAccessTokenMapper) DeleteMany(ctx context.Context, filter interface{}) (int64, error) {
 	ret := _m.Called(ctx, filter)
 
 	if len(ret) == 0 {
-		panic("no return value specified for Find")
+		panic("no return value specified for DeleteMany")
 	}
 
-	var r0 models.PersonalAccessTokens
+	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (models.PersonalAccessTokens, error)); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (int64, error)); ok {
 		return rf(ctx, filter)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, interface{}) models.PersonalAccessTokens); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) int64); ok {
 		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPersonalAccessTokenMapper_DeleteMany_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteMany'
+type MockPersonalAccessTokenMapper_DeleteMany_Call struct {
+	*mock.Call
+}
+
+// DeleteMany is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockPersonalAccessTokenMapper_Expecter) DeleteMany(ctx interface{}, filter interface{}) *MockPersonalAccessTokenMapper_DeleteMany_Call {
+	return &MockPersonalAccessTokenMapper_DeleteMany_Call{Call: _e.mock.On("DeleteMany", ctx, filter)}
+}
+
+func (_c *MockPersonalAccessTokenMapper_DeleteMany_Call) Run(run func(ctx context.Context, filter interface{})) *MockPersonalAccessTokenMapper_DeleteMany_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_DeleteMany_Call) Return(_a0 int64, _a1 error) *MockPersonalAccessTokenMapper_DeleteMany_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_DeleteMany_Call) RunAndReturn(run func(context.Context, interface{}) (int64, error)) *MockPersonalAccessTokenMapper_DeleteMany_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Find provides a mock function with given fields: ctx, filter, opts
+func (_m *MockPersonalAccessTokenMapper) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (models.PersonalAccessTokens, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, filter)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Find")
+	}
+
+	var r0 models.PersonalAccessTokens
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, ...*options.FindOptions) (models.PersonalAccessTokens, error)); ok {
+		return rf(ctx, filter, opts...)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, ...*options.FindOptions) models.PersonalAccessTokens); ok {
+		r0 = rf(ctx, filter, opts...)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(models.PersonalAccessTokens)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
-		r1 = rf(ctx, filter)
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, ...*options.FindOptions) error); ok {
+		r1 = rf(ctx, filter, opts...)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -119,13 +242,21 @@ type MockPersonalAccessTokenMapper_Find_Call struct {
 // Find is a helper method to define mock.On call
 //   - ctx context.Context
 //   - filter interface{}
-func (_e *MockPersonalAccessTokenMapper_Expecter) Find(ctx interface{}, filter interface{}) *MockPersonalAccessTokenMapper_Find_Call {
-	return &MockPersonalAccessTokenMapper_Find_Call{Call: _e.mock.On("Find", ctx, filter)}
+//   - opts ...*options.FindOptions
+func (_e *MockPersonalAccessTokenMapper_Expecter) Find(ctx interface{}, filter interface{}, opts ...interface{}) *MockPersonalAccessTokenMapper_Find_Call {
+	return &MockPersonalAccessTokenMapper_Find_Call{Call: _e.mock.On("Find",
+		append([]interface{}{ctx, filter}, opts...)...)}
 }
 
-func (_c *MockPersonalAccessTokenMapper_Find_Call) Run(run func(ctx context.Context, filter interface{})) *MockPersonalAccessTokenMapper_Find_Call {
+func (_c *MockPersonalAccessTokenMapper_Find_Call) Run(run func(ctx context.Context, filter interface{}, opts ...*options.FindOptions)) *MockPersonalAccessTokenMapper_Find_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].(interface{}))
+		variadicArgs := make([]*options.FindOptions, len(args)-2)
+		for i, a := range args[2:] {
+			if a != nil {
+				variadicArgs[i] = a.(*options.FindOptions)
+			}
+		}
+		run(args[0].(context.Context), args[1].(interface{}), variadicArgs...)
 	})
 	return _c
 }
@@ -135,7 +266,7 @@ func (_c *MockPersonalAccessTokenMapper_Find_Call) Return(_a0 models.PersonalAcc
 	return _c
 }
 
-func (_c *MockPersonalAccessTokenMapper_Find_Call) RunAndReturn(run func(context.Context, interface{}) (models.PersonalAccessTokens, error)) *MockPersonalAccessTokenMapper_Find_Call {
+func (_c *MockPersonalAccessTokenMapper_Find_Call) RunAndReturn(run func(context.Context, interface{}, ...*options.FindOptions) (models.PersonalAccessTokens, error)) *MockPersonalAccessTokenMapper_Find_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -258,6 +389,158 @@ func (_c *MockPersonalAccessTokenMapper_Update_Call) RunAndReturn(run func(conte
 	return _c
 }
 
+// UpdateMany provides a mock function with given fields: ctx, filter, update
+func (_m *MockPersonalAccessTokenMapper) UpdateMany(ctx context.Context, filter interface{}, update interface{}) (int64, error) {
+	ret := _m.Called(ctx, filter, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateMany")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) (int64, error)); ok {
+		return rf(ctx, filter, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) int64); ok {
+		r0 = rf(ctx, filter, update)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, interface{}) error); ok {
+		r1 = rf(ctx, filter, update)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockPersonalAccessTokenMapper_UpdateMany_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateMany'
+type MockPersonalAccessTokenMapper_UpdateMany_Call struct {
+	*mock.Call
+}
+
+// UpdateMany is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+//   - update interface{}
+func (_e *MockPersonalAccessTokenMapper_Expecter) UpdateMany(ctx interface{}, filter interface{}, update interface{}) *MockPersonalAccessTokenMapper_UpdateMany_Call {
+	return &MockPersonalAccessTokenMapper_UpdateMany_Call{Call: _e.mock.On("UpdateMany", ctx, filter, update)}
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateMany_Call) Run(run func(ctx context.Context, filter interface{}, update interface{})) *MockPersonalAccessTokenMapper_UpdateMany_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}), args[2].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateMany_Call) Return(_a0 int64, _a1 error) *MockPersonalAccessTokenMapper_UpdateMany_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateMany_Call) RunAndReturn(run func(context.Context, interface{}, interface{}) (int64, error)) *MockPersonalAccessTokenMapper_UpdateMany_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateToken provides a mock function with given fields: ctx, model
+func (_m *MockPersonalAccessTokenMapper) UpdateToken(ctx context.Context, model *models.PersonalAccessToken) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateToken")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PersonalAccessToken) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPersonalAccessTokenMapper_UpdateToken_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateToken'
+type MockPersonalAccessTokenMapper_UpdateToken_Call struct {
+	*mock.Call
+}
+
+// UpdateToken is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.PersonalAccessToken
+func (_e *MockPersonalAccessTokenMapper_Expecter) UpdateToken(ctx interface{}, model interface{}) *MockPersonalAccessTokenMapper_UpdateToken_Call {
+	return &MockPersonalAccessTokenMapper_UpdateToken_Call{Call: _e.mock.On("UpdateToken", ctx, model)}
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateToken_Call) Run(run func(ctx context.Context, model *models.PersonalAccessToken)) *MockPersonalAccessTokenMapper_UpdateToken_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.PersonalAccessToken))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateToken_Call) Return(_a0 error) *MockPersonalAccessTokenMapper_UpdateToken_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateToken_Call) RunAndReturn(run func(context.Context, *models.PersonalAccessToken) error) *MockPersonalAccessTokenMapper_UpdateToken_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateUsage provides a mock function with given fields: ctx, model
+func (_m *MockPersonalAccessTokenMapper) UpdateUsage(ctx context.Context, model *models.PersonalAccessToken) error {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateUsage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.PersonalAccessToken) error); ok {
+		r0 = rf(ctx, model)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockPersonalAccessTokenMapper_UpdateUsage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateUsage'
+type MockPersonalAccessTokenMapper_UpdateUsage_Call struct {
+	*mock.Call
+}
+
+// UpdateUsage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.PersonalAccessToken
+func (_e *MockPersonalAccessTokenMapper_Expecter) UpdateUsage(ctx interface{}, model interface{}) *MockPersonalAccessTokenMapper_UpdateUsage_Call {
+	return &MockPersonalAccessTokenMapper_UpdateUsage_Call{Call: _e.mock.On("UpdateUsage", ctx, model)}
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateUsage_Call) Run(run func(ctx context.Context, model *models.PersonalAccessToken)) *MockPersonalAccessTokenMapper_UpdateUsage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.PersonalAccessToken))
+	})
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateUsage_Call) Return(_a0 error) *MockPersonalAccessTokenMapper_UpdateUsage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockPersonalAccessTokenMapper_UpdateUsage_Call) RunAndReturn(run func(context.Context, *models.PersonalAccessToken) error) *MockPersonalAccessTokenMapper_UpdateUsage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockPersonalAccessTokenMapper creates a new instance of MockPersonalAccessTokenMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockPersonalAccessTokenMapper(t interface {
@@ -0,0 +1,330 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package services
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockServiceAccountMapper is an autogenerated mock type for the ServiceAccountMapper type
+type MockServiceAccountMapper struct {
+	mock.Mock
+}
+
+type MockServiceAccountMapper_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockServiceAccountMapper) EXPECT() *MockServiceAccountMapper_Expecter {
+	return &MockServiceAccountMapper_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockServiceAccountMapper) Create(ctx context.Context, model *models.ServiceAccount) (*models.ServiceAccount, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.ServiceAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ServiceAccount) (*models.ServiceAccount, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ServiceAccount) *models.ServiceAccount); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ServiceAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ServiceAccount) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceAccountMapper_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockServiceAccountMapper_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.ServiceAccount
+func (_e *MockServiceAccountMapper_Expecter) Create(ctx interface{}, model interface{}) *MockServiceAccountMapper_Create_Call {
+	return &MockServiceAccountMapper_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockServiceAccountMapper_Create_Call) Run(run func(ctx context.Context, model *models.ServiceAccount)) *MockServiceAccountMapper_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.ServiceAccount))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_Create_Call) Return(_a0 *models.ServiceAccount, _a1 error) *MockServiceAccountMapper_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_Create_Call) RunAndReturn(run func(context.Context, *models.ServiceAccount) (*models.ServiceAccount, error)) *MockServiceAccountMapper_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Find provides a mock function with given fields: ctx, filter, limit, skip
+func (_m *MockServiceAccountMapper) Find(ctx context.Context, filter interface{}, limit int, skip int) (int64, models.ServiceAccounts, error) {
+	ret := _m.Called(ctx, filter, limit, skip)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Find")
+	}
+
+	var r0 int64
+	var r1 models.ServiceAccounts
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, int, int) (int64, models.ServiceAccounts, error)); ok {
+		return rf(ctx, filter, limit, skip)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, int, int) int64); ok {
+		r0 = rf(ctx, filter, limit, skip)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, int, int) models.ServiceAccounts); ok {
+		r1 = rf(ctx, filter, limit, skip)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(models.ServiceAccounts)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, interface{}, int, int) error); ok {
+		r2 = rf(ctx, filter, limit, skip)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// MockServiceAccountMapper_Find_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Find'
+type MockServiceAccountMapper_Find_Call struct {
+	*mock.Call
+}
+
+// Find is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+//   - limit int
+//   - skip int
+func (_e *MockServiceAccountMapper_Expecter) Find(ctx interface{}, filter interface{}, limit interface{}, skip interface{}) *MockServiceAccountMapper_Find_Call {
+	return &MockServiceAccountMapper_Find_Call{Call: _e.mock.On("Find", ctx, filter, limit, skip)}
+}
+
+func (_c *MockServiceAccountMapper_Find_Call) Run(run func(ctx context.Context, filter interface{}, limit int, skip int)) *MockServiceAccountMapper_Find_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}), args[2].(int), args[3].(int))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_Find_Call) Return(_a0 int64, _a1 models.ServiceAccounts, _a2 error) *MockServiceAccountMapper_Find_Call {
+	_c.Call.Return(_a0, _a1, _a2)
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_Find_Call) RunAndReturn(run func(context.Context, interface{}, int, int) (int64, models.ServiceAccounts, error)) *MockServiceAccountMapper_Find_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindOne provides a mock function with given fields: ctx, filter
+func (_m *MockServiceAccountMapper) FindOne(ctx context.Context, filter interface{}) (*models.ServiceAccount, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOne")
+	}
+
+	var r0 *models.ServiceAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*models.ServiceAccount, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *models.ServiceAccount); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ServiceAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceAccountMapper_FindOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOne'
+type MockServiceAccountMapper_FindOne_Call struct {
+	*mock.Call
+}
+
+// FindOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockServiceAccountMapper_Expecter) FindOne(ctx interface{}, filter interface{}) *MockServiceAccountMapper_FindOne_Call {
+	return &MockServiceAccountMapper_FindOne_Call{Call: _e.mock.On("FindOne", ctx, filter)}
+}
+
+func (_c *MockServiceAccountMapper_FindOne_Call) Run(run func(ctx context.Context, filter interface{})) *MockServiceAccountMapper_FindOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_FindOne_Call) Return(_a0 *models.ServiceAccount, _a1 error) *MockServiceAccountMapper_FindOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_FindOne_Call) RunAndReturn(run func(context.Context, interface{}) (*models.ServiceAccount, error)) *MockServiceAccountMapper_FindOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockServiceAccountMapper) Update(ctx context.Context, model *models.ServiceAccount) (*models.ServiceAccount, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.ServiceAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ServiceAccount) (*models.ServiceAccount, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ServiceAccount) *models.ServiceAccount); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.ServiceAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ServiceAccount) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockServiceAccountMapper_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockServiceAccountMapper_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.ServiceAccount
+func (_e *MockServiceAccountMapper_Expecter) Update(ctx interface{}, model interface{}) *MockServiceAccountMapper_Update_Call {
+	return &MockServiceAccountMapper_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockServiceAccountMapper_Update_Call) Run(run func(ctx context.Context, model *models.ServiceAccount)) *MockServiceAccountMapper_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.ServiceAccount))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_Update_Call) Return(_a0 *models.ServiceAccount, _a1 error) *MockServiceAccountMapper_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_Update_Call) RunAndReturn(run func(context.Context, *models.ServiceAccount) (*models.ServiceAccount, error)) *MockServiceAccountMapper_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateUsage provides a mock function with given fields: ctx, model, cred
+func (_m *MockServiceAccountMapper) UpdateUsage(ctx context.Context, model *models.ServiceAccount, cred *models.ServiceAccountCredential) error {
+	ret := _m.Called(ctx, model, cred)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateUsage")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ServiceAccount, *models.ServiceAccountCredential) error); ok {
+		r0 = rf(ctx, model, cred)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockServiceAccountMapper_UpdateUsage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateUsage'
+type MockServiceAccountMapper_UpdateUsage_Call struct {
+	*mock.Call
+}
+
+// UpdateUsage is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.ServiceAccount
+//   - cred *models.ServiceAccountCredential
+func (_e *MockServiceAccountMapper_Expecter) UpdateUsage(ctx interface{}, model interface{}, cred interface{}) *MockServiceAccountMapper_UpdateUsage_Call {
+	return &MockServiceAccountMapper_UpdateUsage_Call{Call: _e.mock.On("UpdateUsage", ctx, model, cred)}
+}
+
+func (_c *MockServiceAccountMapper_UpdateUsage_Call) Run(run func(ctx context.Context, model *models.ServiceAccount, cred *models.ServiceAccountCredential)) *MockServiceAccountMapper_UpdateUsage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.ServiceAccount), args[2].(*models.ServiceAccountCredential))
+	})
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_UpdateUsage_Call) Return(_a0 error) *MockServiceAccountMapper_UpdateUsage_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockServiceAccountMapper_UpdateUsage_Call) RunAndReturn(run func(context.Context, *models.ServiceAccount, *models.ServiceAccountCredential) error) *MockServiceAccountMapper_UpdateUsage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockServiceAccountMapper creates a new instance of MockServiceAccountMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockServiceAccountMapper(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockServiceAccountMapper {
+	mock := &MockServiceAccountMapper{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,333 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package services
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockSessionMapper is an autogenerated mock type for the SessionMapper type
+type MockSessionMapper struct {
+	mock.Mock
+}
+
+type MockSessionMapper_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockSessionMapper) EXPECT() *MockSessionMapper_Expecter {
+	return &MockSessionMapper_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockSessionMapper) Create(ctx context.Context, model *models.Session) (*models.Session, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) (*models.Session, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) *models.Session); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Session) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionMapper_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockSessionMapper_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.Session
+func (_e *MockSessionMapper_Expecter) Create(ctx interface{}, model interface{}) *MockSessionMapper_Create_Call {
+	return &MockSessionMapper_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockSessionMapper_Create_Call) Run(run func(ctx context.Context, model *models.Session)) *MockSessionMapper_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.Session))
+	})
+	return _c
+}
+
+func (_c *MockSessionMapper_Create_Call) Return(_a0 *models.Session, _a1 error) *MockSessionMapper_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionMapper_Create_Call) RunAndReturn(run func(context.Context, *models.Session) (*models.Session, error)) *MockSessionMapper_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Find provides a mock function with given fields: ctx, filter
+func (_m *MockSessionMapper) Find(ctx context.Context, filter interface{}) (models.Sessions, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Find")
+	}
+
+	var r0 models.Sessions
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (models.Sessions, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) models.Sessions); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(models.Sessions)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionMapper_Find_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Find'
+type MockSessionMapper_Find_Call struct {
+	*mock.Call
+}
+
+// Find is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockSessionMapper_Expecter) Find(ctx interface{}, filter interface{}) *MockSessionMapper_Find_Call {
+	return &MockSessionMapper_Find_Call{Call: _e.mock.On("Find", ctx, filter)}
+}
+
+func (_c *MockSessionMapper_Find_Call) Run(run func(ctx context.Context, filter interface{})) *MockSessionMapper_Find_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockSessionMapper_Find_Call) Return(_a0 models.Sessions, _a1 error) *MockSessionMapper_Find_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionMapper_Find_Call) RunAndReturn(run func(context.Context, interface{}) (models.Sessions, error)) *MockSessionMapper_Find_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindOne provides a mock function with given fields: ctx, filter
+func (_m *MockSessionMapper) FindOne(ctx context.Context, filter interface{}) (*models.Session, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOne")
+	}
+
+	var r0 *models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*models.Session, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *models.Session); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionMapper_FindOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOne'
+type MockSessionMapper_FindOne_Call struct {
+	*mock.Call
+}
+
+// FindOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockSessionMapper_Expecter) FindOne(ctx interface{}, filter interface{}) *MockSessionMapper_FindOne_Call {
+	return &MockSessionMapper_FindOne_Call{Call: _e.mock.On("FindOne", ctx, filter)}
+}
+
+func (_c *MockSessionMapper_FindOne_Call) Run(run func(ctx context.Context, filter interface{})) *MockSessionMapper_FindOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockSessionMapper_FindOne_Call) Return(_a0 *models.Session, _a1 error) *MockSessionMapper_FindOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionMapper_FindOne_Call) RunAndReturn(run func(context.Context, interface{}) (*models.Session, error)) *MockSessionMapper_FindOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockSessionMapper) Update(ctx context.Context, model *models.Session) (*models.Session, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) (*models.Session, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session) *models.Session); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Session) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionMapper_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockSessionMapper_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.Session
+func (_e *MockSessionMapper_Expecter) Update(ctx interface{}, model interface{}) *MockSessionMapper_Update_Call {
+	return &MockSessionMapper_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockSessionMapper_Update_Call) Run(run func(ctx context.Context, model *models.Session)) *MockSessionMapper_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.Session))
+	})
+	return _c
+}
+
+func (_c *MockSessionMapper_Update_Call) Return(_a0 *models.Session, _a1 error) *MockSessionMapper_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionMapper_Update_Call) RunAndReturn(run func(context.Context, *models.Session) (*models.Session, error)) *MockSessionMapper_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// UpdateIf provides a mock function with given fields: ctx, filter, model
+func (_m *MockSessionMapper) UpdateIf(ctx context.Context, filter interface{}, model *models.Session) (*models.Session, error) {
+	ret := _m.Called(ctx, filter, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateIf")
+	}
+
+	var r0 *models.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, *models.Session) (*models.Session, error)); ok {
+		return rf(ctx, filter, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, *models.Session) *models.Session); ok {
+		r0 = rf(ctx, filter, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Session)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, *models.Session) error); ok {
+		r1 = rf(ctx, filter, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSessionMapper_UpdateIf_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateIf'
+type MockSessionMapper_UpdateIf_Call struct {
+	*mock.Call
+}
+
+// UpdateIf is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+//   - model *models.Session
+func (_e *MockSessionMapper_Expecter) UpdateIf(ctx interface{}, filter interface{}, model interface{}) *MockSessionMapper_UpdateIf_Call {
+	return &MockSessionMapper_UpdateIf_Call{Call: _e.mock.On("UpdateIf", ctx, filter, model)}
+}
+
+func (_c *MockSessionMapper_UpdateIf_Call) Run(run func(ctx context.Context, filter interface{}, model *models.Session)) *MockSessionMapper_UpdateIf_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}), args[2].(*models.Session))
+	})
+	return _c
+}
+
+func (_c *MockSessionMapper_UpdateIf_Call) Return(_a0 *models.Session, _a1 error) *MockSessionMapper_UpdateIf_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSessionMapper_UpdateIf_Call) RunAndReturn(run func(context.Context, interface{}, *models.Session) (*models.Session, error)) *MockSessionMapper_UpdateIf_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockSessionMapper creates a new instance of MockSessionMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSessionMapper(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSessionMapper {
+	mock := &MockSessionMapper{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
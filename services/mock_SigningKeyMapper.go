@@ -0,0 +1,214 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package services
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockSigningKeyMapper is an autogenerated mock type for the SigningKeyMapper type
+type MockSigningKeyMapper struct {
+	mock.Mock
+}
+
+type MockSigningKeyMapper_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockSigningKeyMapper) EXPECT() *MockSigningKeyMapper_Expecter {
+	return &MockSigningKeyMapper_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockSigningKeyMapper) Create(ctx context.Context, model *models.SigningKey) (*models.SigningKey, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.SigningKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SigningKey) (*models.SigningKey, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SigningKey) *models.SigningKey); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.SigningKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.SigningKey) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSigningKeyMapper_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockSigningKeyMapper_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.SigningKey
+func (_e *MockSigningKeyMapper_Expecter) Create(ctx interface{}, model interface{}) *MockSigningKeyMapper_Create_Call {
+	return &MockSigningKeyMapper_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockSigningKeyMapper_Create_Call) Run(run func(ctx context.Context, model *models.SigningKey)) *MockSigningKeyMapper_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.SigningKey))
+	})
+	return _c
+}
+
+func (_c *MockSigningKeyMapper_Create_Call) Return(_a0 *models.SigningKey, _a1 error) *MockSigningKeyMapper_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSigningKeyMapper_Create_Call) RunAndReturn(run func(context.Context, *models.SigningKey) (*models.SigningKey, error)) *MockSigningKeyMapper_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Find provides a mock function with given fields: ctx, filter
+func (_m *MockSigningKeyMapper) Find(ctx context.Context, filter interface{}) (models.SigningKeys, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Find")
+	}
+
+	var r0 models.SigningKeys
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (models.SigningKeys, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) models.SigningKeys); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(models.SigningKeys)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSigningKeyMapper_Find_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Find'
+type MockSigningKeyMapper_Find_Call struct {
+	*mock.Call
+}
+
+// Find is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockSigningKeyMapper_Expecter) Find(ctx interface{}, filter interface{}) *MockSigningKeyMapper_Find_Call {
+	return &MockSigningKeyMapper_Find_Call{Call: _e.mock.On("Find", ctx, filter)}
+}
+
+func (_c *MockSigningKeyMapper_Find_Call) Run(run func(ctx context.Context, filter interface{})) *MockSigningKeyMapper_Find_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockSigningKeyMapper_Find_Call) Return(_a0 models.SigningKeys, _a1 error) *MockSigningKeyMapper_Find_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSigningKeyMapper_Find_Call) RunAndReturn(run func(context.Context, interface{}) (models.SigningKeys, error)) *MockSigningKeyMapper_Find_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockSigningKeyMapper) Update(ctx context.Context, model *models.SigningKey) (*models.SigningKey, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.SigningKey
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SigningKey) (*models.SigningKey, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SigningKey) *models.SigningKey); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.SigningKey)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.SigningKey) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockSigningKeyMapper_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockSigningKeyMapper_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.SigningKey
+func (_e *MockSigningKeyMapper_Expecter) Update(ctx interface{}, model interface{}) *MockSigningKeyMapper_Update_Call {
+	return &MockSigningKeyMapper_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockSigningKeyMapper_Update_Call) Run(run func(ctx context.Context, model *models.SigningKey)) *MockSigningKeyMapper_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.SigningKey))
+	})
+	return _c
+}
+
+func (_c *MockSigningKeyMapper_Update_Call) Return(_a0 *models.SigningKey, _a1 error) *MockSigningKeyMapper_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockSigningKeyMapper_Update_Call) RunAndReturn(run func(context.Context, *models.SigningKey) (*models.SigningKey, error)) *MockSigningKeyMapper_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockSigningKeyMapper creates a new instance of MockSigningKeyMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockSigningKeyMapper(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockSigningKeyMapper {
+	mock := &MockSigningKeyMapper{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
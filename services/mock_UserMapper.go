@@ -208,6 +208,65 @@ func (_c *MockUserMapper_FindOne_Call) RunAndReturn(run func(context.Context, in
 	return _c
 }
 
+// FindOneByEmailOrUsername provides a mock function with given fields: ctx, filter
+func (_m *MockUserMapper) FindOneByEmailOrUsername(ctx context.Context, filter interface{}) (*models.User, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOneByEmailOrUsername")
+	}
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*models.User, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *models.User); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockUserMapper_FindOneByEmailOrUsername_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOneByEmailOrUsername'
+type MockUserMapper_FindOneByEmailOrUsername_Call struct {
+	*mock.Call
+}
+
+// FindOneByEmailOrUsername is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockUserMapper_Expecter) FindOneByEmailOrUsername(ctx interface{}, filter interface{}) *MockUserMapper_FindOneByEmailOrUsername_Call {
+	return &MockUserMapper_FindOneByEmailOrUsername_Call{Call: _e.mock.On("FindOneByEmailOrUsername", ctx, filter)}
+}
+
+func (_c *MockUserMapper_FindOneByEmailOrUsername_Call) Run(run func(ctx context.Context, filter interface{})) *MockUserMapper_FindOneByEmailOrUsername_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockUserMapper_FindOneByEmailOrUsername_Call) Return(_a0 *models.User, _a1 error) *MockUserMapper_FindOneByEmailOrUsername_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockUserMapper_FindOneByEmailOrUsername_Call) RunAndReturn(run func(context.Context, interface{}) (*models.User, error)) *MockUserMapper_FindOneByEmailOrUsername_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Update provides a mock function with given fields: ctx, model
 func (_m *MockUserMapper) Update(ctx context.Context, model *models.User) (*models.User, error) {
 	ret := _m.Called(ctx, model)
@@ -267,6 +326,111 @@ func (_c *MockUserMapper_Update_Call) RunAndReturn(run func(context.Context, *mo
 	return _c
 }
 
+// UpdateOne provides a mock function with given fields: ctx, filter, update
+func (_m *MockUserMapper) UpdateOne(ctx context.Context, filter interface{}, update interface{}) (int64, error) {
+	ret := _m.Called(ctx, filter, update)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateOne")
+	}
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) (int64, error)); ok {
+		return rf(ctx, filter, update)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) int64); ok {
+		r0 = rf(ctx, filter, update)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, interface{}) error); ok {
+		r1 = rf(ctx, filter, update)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockUserMapper_UpdateOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateOne'
+type MockUserMapper_UpdateOne_Call struct {
+	*mock.Call
+}
+
+// UpdateOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+//   - update interface{}
+func (_e *MockUserMapper_Expecter) UpdateOne(ctx interface{}, filter interface{}, update interface{}) *MockUserMapper_UpdateOne_Call {
+	return &MockUserMapper_UpdateOne_Call{Call: _e.mock.On("UpdateOne", ctx, filter, update)}
+}
+
+func (_c *MockUserMapper_UpdateOne_Call) Run(run func(ctx context.Context, filter interface{}, update interface{})) *MockUserMapper_UpdateOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}), args[2].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockUserMapper_UpdateOne_Call) Return(_a0 int64, _a1 error) *MockUserMapper_UpdateOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockUserMapper_UpdateOne_Call) RunAndReturn(run func(context.Context, interface{}, interface{}) (int64, error)) *MockUserMapper_UpdateOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WithTransaction provides a mock function with given fields: ctx, fn
+func (_m *MockUserMapper) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	ret := _m.Called(ctx, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WithTransaction")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(context.Context) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MockUserMapper_WithTransaction_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WithTransaction'
+type MockUserMapper_WithTransaction_Call struct {
+	*mock.Call
+}
+
+// WithTransaction is a helper method to define mock.On call
+//   - ctx context.Context
+//   - fn func(context.Context) error
+func (_e *MockUserMapper_Expecter) WithTransaction(ctx interface{}, fn interface{}) *MockUserMapper_WithTransaction_Call {
+	return &MockUserMapper_WithTransaction_Call{Call: _e.mock.On("WithTransaction", ctx, fn)}
+}
+
+func (_c *MockUserMapper_WithTransaction_Call) Run(run func(ctx context.Context, fn func(context.Context) error)) *MockUserMapper_WithTransaction_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(func(context.Context) error))
+	})
+	return _c
+}
+
+func (_c *MockUserMapper_WithTransaction_Call) Return(_a0 error) *MockUserMapper_WithTransaction_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *MockUserMapper_WithTransaction_Call) RunAndReturn(run func(context.Context, func(context.Context) error) error) *MockUserMapper_WithTransaction_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockUserMapper creates a new instance of MockUserMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockUserMapper(t interface {
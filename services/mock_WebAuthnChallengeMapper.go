@@ -0,0 +1,214 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package services
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockWebAuthnChallengeMapper is an autogenerated mock type for the WebAuthnChallengeMapper type
+type MockWebAuthnChallengeMapper struct {
+	mock.Mock
+}
+
+type MockWebAuthnChallengeMapper_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockWebAuthnChallengeMapper) EXPECT() *MockWebAuthnChallengeMapper_Expecter {
+	return &MockWebAuthnChallengeMapper_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnChallengeMapper) Create(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.WebAuthnChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnChallenge) *models.WebAuthnChallenge); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnChallenge)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebAuthnChallenge) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnChallengeMapper_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockWebAuthnChallengeMapper_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnChallenge
+func (_e *MockWebAuthnChallengeMapper_Expecter) Create(ctx interface{}, model interface{}) *MockWebAuthnChallengeMapper_Create_Call {
+	return &MockWebAuthnChallengeMapper_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockWebAuthnChallengeMapper_Create_Call) Run(run func(ctx context.Context, model *models.WebAuthnChallenge)) *MockWebAuthnChallengeMapper_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnChallenge))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeMapper_Create_Call) Return(_a0 *models.WebAuthnChallenge, _a1 error) *MockWebAuthnChallengeMapper_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeMapper_Create_Call) RunAndReturn(run func(context.Context, *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)) *MockWebAuthnChallengeMapper_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindOne provides a mock function with given fields: ctx, filter
+func (_m *MockWebAuthnChallengeMapper) FindOne(ctx context.Context, filter interface{}) (*models.WebAuthnChallenge, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOne")
+	}
+
+	var r0 *models.WebAuthnChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*models.WebAuthnChallenge, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *models.WebAuthnChallenge); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnChallenge)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnChallengeMapper_FindOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOne'
+type MockWebAuthnChallengeMapper_FindOne_Call struct {
+	*mock.Call
+}
+
+// FindOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockWebAuthnChallengeMapper_Expecter) FindOne(ctx interface{}, filter interface{}) *MockWebAuthnChallengeMapper_FindOne_Call {
+	return &MockWebAuthnChallengeMapper_FindOne_Call{Call: _e.mock.On("FindOne", ctx, filter)}
+}
+
+func (_c *MockWebAuthnChallengeMapper_FindOne_Call) Run(run func(ctx context.Context, filter interface{})) *MockWebAuthnChallengeMapper_FindOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeMapper_FindOne_Call) Return(_a0 *models.WebAuthnChallenge, _a1 error) *MockWebAuthnChallengeMapper_FindOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeMapper_FindOne_Call) RunAndReturn(run func(context.Context, interface{}) (*models.WebAuthnChallenge, error)) *MockWebAuthnChallengeMapper_FindOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnChallengeMapper) Update(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.WebAuthnChallenge
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnChallenge) *models.WebAuthnChallenge); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnChallenge)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebAuthnChallenge) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnChallengeMapper_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockWebAuthnChallengeMapper_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnChallenge
+func (_e *MockWebAuthnChallengeMapper_Expecter) Update(ctx interface{}, model interface{}) *MockWebAuthnChallengeMapper_Update_Call {
+	return &MockWebAuthnChallengeMapper_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockWebAuthnChallengeMapper_Update_Call) Run(run func(ctx context.Context, model *models.WebAuthnChallenge)) *MockWebAuthnChallengeMapper_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnChallenge))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeMapper_Update_Call) Return(_a0 *models.WebAuthnChallenge, _a1 error) *MockWebAuthnChallengeMapper_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnChallengeMapper_Update_Call) RunAndReturn(run func(context.Context, *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)) *MockWebAuthnChallengeMapper_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockWebAuthnChallengeMapper creates a new instance of MockWebAuthnChallengeMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockWebAuthnChallengeMapper(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockWebAuthnChallengeMapper {
+	mock := &MockWebAuthnChallengeMapper{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
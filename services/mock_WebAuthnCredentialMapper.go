@@ -0,0 +1,273 @@
+// Code generated by mockery v2.42.0. DO NOT EDIT.
+
+package services
+
+import (
+	context "context"
+
+	models "github.com/alexferl/echo-boilerplate/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MockWebAuthnCredentialMapper is an autogenerated mock type for the WebAuthnCredentialMapper type
+type MockWebAuthnCredentialMapper struct {
+	mock.Mock
+}
+
+type MockWebAuthnCredentialMapper_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockWebAuthnCredentialMapper) EXPECT() *MockWebAuthnCredentialMapper_Expecter {
+	return &MockWebAuthnCredentialMapper_Expecter{mock: &_m.Mock}
+}
+
+// Create provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnCredentialMapper) Create(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Create")
+	}
+
+	var r0 *models.WebAuthnCredential
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) (*models.WebAuthnCredential, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) *models.WebAuthnCredential); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnCredential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebAuthnCredential) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnCredentialMapper_Create_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Create'
+type MockWebAuthnCredentialMapper_Create_Call struct {
+	*mock.Call
+}
+
+// Create is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnCredential
+func (_e *MockWebAuthnCredentialMapper_Expecter) Create(ctx interface{}, model interface{}) *MockWebAuthnCredentialMapper_Create_Call {
+	return &MockWebAuthnCredentialMapper_Create_Call{Call: _e.mock.On("Create", ctx, model)}
+}
+
+func (_c *MockWebAuthnCredentialMapper_Create_Call) Run(run func(ctx context.Context, model *models.WebAuthnCredential)) *MockWebAuthnCredentialMapper_Create_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnCredential))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialMapper_Create_Call) Return(_a0 *models.WebAuthnCredential, _a1 error) *MockWebAuthnCredentialMapper_Create_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialMapper_Create_Call) RunAndReturn(run func(context.Context, *models.WebAuthnCredential) (*models.WebAuthnCredential, error)) *MockWebAuthnCredentialMapper_Create_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Find provides a mock function with given fields: ctx, filter
+func (_m *MockWebAuthnCredentialMapper) Find(ctx context.Context, filter interface{}) (models.WebAuthnCredentials, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Find")
+	}
+
+	var r0 models.WebAuthnCredentials
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (models.WebAuthnCredentials, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) models.WebAuthnCredentials); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(models.WebAuthnCredentials)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnCredentialMapper_Find_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Find'
+type MockWebAuthnCredentialMapper_Find_Call struct {
+	*mock.Call
+}
+
+// Find is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockWebAuthnCredentialMapper_Expecter) Find(ctx interface{}, filter interface{}) *MockWebAuthnCredentialMapper_Find_Call {
+	return &MockWebAuthnCredentialMapper_Find_Call{Call: _e.mock.On("Find", ctx, filter)}
+}
+
+func (_c *MockWebAuthnCredentialMapper_Find_Call) Run(run func(ctx context.Context, filter interface{})) *MockWebAuthnCredentialMapper_Find_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialMapper_Find_Call) Return(_a0 models.WebAuthnCredentials, _a1 error) *MockWebAuthnCredentialMapper_Find_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialMapper_Find_Call) RunAndReturn(run func(context.Context, interface{}) (models.WebAuthnCredentials, error)) *MockWebAuthnCredentialMapper_Find_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// FindOne provides a mock function with given fields: ctx, filter
+func (_m *MockWebAuthnCredentialMapper) FindOne(ctx context.Context, filter interface{}) (*models.WebAuthnCredential, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for FindOne")
+	}
+
+	var r0 *models.WebAuthnCredential
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) (*models.WebAuthnCredential, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) *models.WebAuthnCredential); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnCredential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnCredentialMapper_FindOne_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'FindOne'
+type MockWebAuthnCredentialMapper_FindOne_Call struct {
+	*mock.Call
+}
+
+// FindOne is a helper method to define mock.On call
+//   - ctx context.Context
+//   - filter interface{}
+func (_e *MockWebAuthnCredentialMapper_Expecter) FindOne(ctx interface{}, filter interface{}) *MockWebAuthnCredentialMapper_FindOne_Call {
+	return &MockWebAuthnCredentialMapper_FindOne_Call{Call: _e.mock.On("FindOne", ctx, filter)}
+}
+
+func (_c *MockWebAuthnCredentialMapper_FindOne_Call) Run(run func(ctx context.Context, filter interface{})) *MockWebAuthnCredentialMapper_FindOne_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(interface{}))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialMapper_FindOne_Call) Return(_a0 *models.WebAuthnCredential, _a1 error) *MockWebAuthnCredentialMapper_FindOne_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialMapper_FindOne_Call) RunAndReturn(run func(context.Context, interface{}) (*models.WebAuthnCredential, error)) *MockWebAuthnCredentialMapper_FindOne_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Update provides a mock function with given fields: ctx, model
+func (_m *MockWebAuthnCredentialMapper) Update(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+	ret := _m.Called(ctx, model)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Update")
+	}
+
+	var r0 *models.WebAuthnCredential
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) (*models.WebAuthnCredential, error)); ok {
+		return rf(ctx, model)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WebAuthnCredential) *models.WebAuthnCredential); ok {
+		r0 = rf(ctx, model)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnCredential)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WebAuthnCredential) error); ok {
+		r1 = rf(ctx, model)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MockWebAuthnCredentialMapper_Update_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Update'
+type MockWebAuthnCredentialMapper_Update_Call struct {
+	*mock.Call
+}
+
+// Update is a helper method to define mock.On call
+//   - ctx context.Context
+//   - model *models.WebAuthnCredential
+func (_e *MockWebAuthnCredentialMapper_Expecter) Update(ctx interface{}, model interface{}) *MockWebAuthnCredentialMapper_Update_Call {
+	return &MockWebAuthnCredentialMapper_Update_Call{Call: _e.mock.On("Update", ctx, model)}
+}
+
+func (_c *MockWebAuthnCredentialMapper_Update_Call) Run(run func(ctx context.Context, model *models.WebAuthnCredential)) *MockWebAuthnCredentialMapper_Update_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*models.WebAuthnCredential))
+	})
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialMapper_Update_Call) Return(_a0 *models.WebAuthnCredential, _a1 error) *MockWebAuthnCredentialMapper_Update_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *MockWebAuthnCredentialMapper_Update_Call) RunAndReturn(run func(context.Context, *models.WebAuthnCredential) (*models.WebAuthnCredential, error)) *MockWebAuthnCredentialMapper_Update_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockWebAuthnCredentialMapper creates a new instance of MockWebAuthnCredentialMapper. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockWebAuthnCredentialMapper(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockWebAuthnCredentialMapper {
+	mock := &MockWebAuthnCredentialMapper{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
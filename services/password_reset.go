@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// PasswordResetMapper defines the datastore handling persisting PasswordReset documents.
+type PasswordResetMapper interface {
+	Create(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error)
+	Count(ctx context.Context, filter any) (int64, error)
+	FindOne(ctx context.Context, filter any) (*models.PasswordReset, error)
+	Update(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error)
+	UpdateOne(ctx context.Context, filter any, update any) (int64, error)
+}
+
+var ErrPasswordResetNotFound = errors.New("password reset not found")
+
+// PasswordReset defines the application service in charge of interacting with PasswordResets.
+type PasswordReset struct {
+	mapper PasswordResetMapper
+}
+
+func NewPasswordReset(mapper PasswordResetMapper) *PasswordReset {
+	return &PasswordReset{mapper: mapper}
+}
+
+func (p *PasswordReset) Create(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error) {
+	pr, err := p.mapper.Create(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return pr, nil
+}
+
+// CountSince returns the number of password resets created for the user since t.
+func (p *PasswordReset) CountSince(ctx context.Context, userId string, t time.Time) (int64, error) {
+	filter := bson.D{{"user_id", userId}, {"created_at", bson.D{{"$gte", t}}}}
+	count, err := p.mapper.Count(ctx, filter)
+	if err != nil {
+		return 0, NewError(err, Other, "other")
+	}
+
+	return count, nil
+}
+
+func (p *PasswordReset) Read(ctx context.Context, id string) (*models.PasswordReset, error) {
+	filter := bson.D{{"id", id}}
+	pr, err := p.mapper.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return nil, NewError(err, NotExist, ErrPasswordResetNotFound.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return pr, nil
+}
+
+func (p *PasswordReset) Update(ctx context.Context, model *models.PasswordReset) (*models.PasswordReset, error) {
+	pr, err := p.mapper.Update(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return pr, nil
+}
+
+// Use consumes model, it fails with a Conflict error if it was already
+// used, including by another request since it was read.
+func (p *PasswordReset) Use(ctx context.Context, model *models.PasswordReset) error {
+	model.Use()
+	filter := bson.D{{"id", model.Id}, {"used_at", nil}}
+	update := bson.D{{"$set", bson.D{{"used_at", model.UsedAt}}}}
+	matched, err := p.mapper.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	if matched == 0 {
+		return NewError(models.ErrPasswordResetUsed, Conflict, models.ErrPasswordResetUsed.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type PasswordResetTestSuite struct {
+	suite.Suite
+	mapper *services.MockPasswordResetMapper
+	svc    *services.PasswordReset
+}
+
+func (s *PasswordResetTestSuite) SetupTest() {
+	s.mapper = services.NewMockPasswordResetMapper(s.T())
+	s.svc = services.NewPasswordReset(s.mapper)
+}
+
+func TestPasswordResetTestSuite(t *testing.T) {
+	suite.Run(t, new(PasswordResetTestSuite))
+}
+
+func (s *PasswordResetTestSuite) TestPasswordReset_Create() {
+	m, _ := models.NewPasswordReset("100")
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	pr, err := s.svc.Create(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Equal("100", pr.UserId)
+}
+
+func (s *PasswordResetTestSuite) TestPasswordReset_CountSince() {
+	since := time.Now().Add(-time.Hour)
+	filter := bson.D{{"user_id", "100"}, {"created_at", bson.D{{"$gte", since}}}}
+
+	s.mapper.EXPECT().
+		Count(mock.Anything, filter).
+		Return(2, nil)
+
+	count, err := s.svc.CountSince(context.Background(), "100", since)
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(2), count)
+}
+
+func (s *PasswordResetTestSuite) TestPasswordReset_Read() {
+	m, _ := models.NewPasswordReset("100")
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	pr, err := s.svc.Read(context.Background(), m.Id)
+	s.Assert().NoError(err)
+	s.Assert().Equal(m.Id, pr.Id)
+}
+
+func (s *PasswordResetTestSuite) TestPasswordReset_Read_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, data.ErrNoDocuments)
+
+	_, err := s.svc.Read(context.Background(), "123")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.NotExist, se.Kind)
+	}
+}
+
+func (s *PasswordResetTestSuite) TestPasswordReset_Update() {
+	m, _ := models.NewPasswordReset("100")
+	m.Use()
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	pr, err := s.svc.Update(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(pr.UsedAt)
+}
+
+func (s *PasswordResetTestSuite) TestPasswordReset_Use() {
+	m, _ := models.NewPasswordReset("100")
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, bson.D{{"id", m.Id}, {"used_at", nil}}, mock.Anything).
+		Return(1, nil)
+
+	err := s.svc.Use(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(m.UsedAt)
+}
+
+func (s *PasswordResetTestSuite) TestPasswordReset_Use_Conflict() {
+	m, _ := models.NewPasswordReset("100")
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, mock.Anything, mock.Anything).
+		Return(0, nil)
+
+	err := s.svc.Use(context.Background(), m)
+	var se *services.Error
+	if s.Assert().ErrorAs(err, &se) {
+		s.Assert().Equal(services.Conflict, se.Kind)
+	}
+}
@@ -3,8 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/alexferl/echo-boilerplate/data"
 	"github.com/alexferl/echo-boilerplate/models"
@@ -13,9 +16,14 @@ import (
 // PersonalAccessTokenMapper defines the datastore handling persisting User documents.
 type PersonalAccessTokenMapper interface {
 	Create(ctx context.Context, model *models.PersonalAccessToken) (*models.PersonalAccessToken, error)
-	Find(ctx context.Context, filter any) (models.PersonalAccessTokens, error)
+	Count(ctx context.Context, filter any) (int64, error)
+	DeleteMany(ctx context.Context, filter any) (int64, error)
+	Find(ctx context.Context, filter any, opts ...*options.FindOptions) (models.PersonalAccessTokens, error)
 	FindOne(ctx context.Context, filter any) (*models.PersonalAccessToken, error)
 	Update(ctx context.Context, model *models.PersonalAccessToken) (*models.PersonalAccessToken, error)
+	UpdateMany(ctx context.Context, filter any, update any) (int64, error)
+	UpdateToken(ctx context.Context, model *models.PersonalAccessToken) error
+	UpdateUsage(ctx context.Context, model *models.PersonalAccessToken) error
 }
 
 var ErrPersonalAccessTokenNotFound = errors.New("personal access token not found")
@@ -51,8 +59,9 @@ func (t *PersonalAccessToken) Read(ctx context.Context, userId string, id string
 	return token, nil
 }
 
-func (t *PersonalAccessToken) Revoke(ctx context.Context, model *models.PersonalAccessToken) error {
-	model.IsRevoked = true
+func (t *PersonalAccessToken) Revoke(ctx context.Context, id string, model *models.PersonalAccessToken) error {
+	model.Revoke(id)
+	models.Act(ctx, model.RevokedBy)
 	_, err := t.mapper.Update(ctx, model)
 	if err != nil {
 		return NewError(err, Other, "other")
@@ -61,14 +70,84 @@ func (t *PersonalAccessToken) Revoke(ctx context.Context, model *models.Personal
 	return nil
 }
 
-func (t *PersonalAccessToken) Find(ctx context.Context, userId string) (models.PersonalAccessTokens, error) {
-	filter := bson.D{{"user_id", userId}}
+// RevokeAll revokes every personal access token of the user that isn't already.
+func (t *PersonalAccessToken) RevokeAll(ctx context.Context, id string, userId string) error {
+	filter := bson.D{{"user_id", userId}, {"is_revoked", false}}
 	tokens, err := t.mapper.Find(ctx, filter)
 	if err != nil {
-		return nil, NewError(err, Other, "other")
+		return NewError(err, Other, "other")
 	}
 
-	return tokens, nil
+	for _, token := range tokens {
+		err = t.Revoke(ctx, id, &token)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *PersonalAccessToken) Use(ctx context.Context, model *models.PersonalAccessToken) error {
+	err := t.mapper.UpdateUsage(ctx, model)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
+
+// Rehash saves the token hash of model after it was hashed again with the current argon2 parameters.
+func (t *PersonalAccessToken) Rehash(ctx context.Context, model *models.PersonalAccessToken) error {
+	err := t.mapper.UpdateToken(ctx, model)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
+
+func (t *PersonalAccessToken) Find(ctx context.Context, params *models.PersonalAccessTokenSearchParams) (int64, models.PersonalAccessTokens, error) {
+	filter := bson.M{}
+	if params.UserId != "" {
+		filter["user_id"] = params.UserId
+	} else if len(params.ExcludeUserIds) > 0 {
+		filter["user_id"] = bson.M{"$nin": params.ExcludeUserIds}
+	}
+	expiresAt := bson.M{}
+	if params.ExpiresAfter != nil {
+		expiresAt["$gte"] = params.ExpiresAfter
+	}
+	if params.ExpiresBefore != nil {
+		expiresAt["$lt"] = params.ExpiresBefore
+	}
+	if len(expiresAt) > 0 {
+		filter["expires_at"] = expiresAt
+	}
+	if params.IsRevoked != nil {
+		filter["is_revoked"] = *params.IsRevoked
+	}
+
+	count, err := t.mapper.Count(ctx, filter)
+	if err != nil {
+		return 0, nil, NewError(err, Other, "other")
+	}
+
+	opts := options.Find().SetLimit(int64(params.Limit)).SetSkip(int64(params.Skip))
+	if params.Sort != "" {
+		field, order := strings.TrimPrefix(params.Sort, "-"), 1
+		if strings.HasPrefix(params.Sort, "-") {
+			order = -1
+		}
+		opts.SetSort(bson.D{{field, order}, {"_id", order}})
+	}
+
+	tokens, err := t.mapper.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, nil, NewError(err, Other, "other")
+	}
+
+	return count, tokens, nil
 }
 
 func (t *PersonalAccessToken) FindOne(ctx context.Context, userId string, name string) (*models.PersonalAccessToken, error) {
@@ -83,3 +162,93 @@ func (t *PersonalAccessToken) FindOne(ctx context.Context, userId string, name s
 
 	return user, nil
 }
+
+// FindUnscoped returns the tokens of userId created before tokens had scopes,
+// which were issued without a pat_id claim and can only be matched by hash.
+func (t *PersonalAccessToken) FindUnscoped(ctx context.Context, userId string) (models.PersonalAccessTokens, error) {
+	filter := bson.D{{"user_id", userId}, {"scopes", nil}}
+	tokens, err := t.mapper.Find(ctx, filter)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return tokens, nil
+}
+
+// FindExpiring returns the tokens expiring within d whose owners weren't reminded yet.
+func (t *PersonalAccessToken) FindExpiring(ctx context.Context, d time.Duration) (models.PersonalAccessTokens, error) {
+	now := time.Now()
+	filter := bson.D{
+		{"is_revoked", false},
+		{"reminded_at", nil},
+		{"expires_at", bson.D{{"$gt", now}, {"$lte", now.Add(d)}}},
+	}
+	tokens, err := t.mapper.Find(ctx, filter)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return tokens, nil
+}
+
+// Remind marks the owner of model as reminded of its expiry, it returns
+// false when another instance already did so only one reminder is sent.
+// The mark must be cleared with Unremind if the reminder can't be sent.
+func (t *PersonalAccessToken) Remind(ctx context.Context, model *models.PersonalAccessToken) (bool, error) {
+	now := time.Now()
+	filter := bson.D{{"id", model.Id}, {"reminded_at", nil}}
+	update := bson.D{{"$set", bson.D{{"reminded_at", now}}}}
+	n, err := t.mapper.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return false, NewError(err, Other, "other")
+	}
+
+	model.RemindedAt = &now
+
+	return n == 1, nil
+}
+
+// Unremind clears the reminder mark of model so it's sent again later.
+func (t *PersonalAccessToken) Unremind(ctx context.Context, model *models.PersonalAccessToken) error {
+	filter := bson.D{{"id", model.Id}}
+	update := bson.D{{"$unset", bson.D{{"reminded_at", ""}}}}
+	_, err := t.mapper.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	model.RemindedAt = nil
+
+	return nil
+}
+
+// Expire marks the tokens past their expiry as expired and returns how many were.
+func (t *PersonalAccessToken) Expire(ctx context.Context) (int64, error) {
+	filter := bson.D{
+		{"is_expired", bson.D{{"$ne", true}}},
+		{"expires_at", bson.D{{"$lte", time.Now()}}},
+	}
+	update := bson.D{{"$set", bson.D{{"is_expired", true}}}}
+	n, err := t.mapper.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, NewError(err, Other, "other")
+	}
+
+	return n, nil
+}
+
+// Purge deletes the tokens revoked or expired for longer than retention, which
+// frees their names. It returns how many were deleted.
+func (t *PersonalAccessToken) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	before := time.Now().Add(-retention)
+	filter := bson.D{{"$or", bson.A{
+		bson.D{{"is_revoked", true}, {"revoked_at", bson.D{{"$lte", before}}}},
+		bson.D{{"expires_at", bson.D{{"$lte", before}}}},
+	}}}
+	n, err := t.mapper.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, NewError(err, Other, "other")
+	}
+
+	return n, nil
+}
@@ -8,6 +8,8 @@ import (
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/alexferl/echo-boilerplate/data"
 	"github.com/alexferl/echo-boilerplate/models"
@@ -36,7 +38,7 @@ func TestPersonalAccessToken(t *testing.T) {
 func (s *PersonalAccessTokenTestSuite) TestPersonalAccessToken_Create() {
 	name := "my_token"
 	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
-	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt)
+	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt, []string{models.ScopeTasksRead})
 	s.Assert().NoError(err)
 
 	s.mapper.EXPECT().
@@ -52,7 +54,7 @@ func (s *PersonalAccessTokenTestSuite) TestPersonalAccessToken_Create() {
 func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Read() {
 	name := "my_token"
 	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
-	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt)
+	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt, []string{models.ScopeTasksRead})
 	s.Assert().NoError(err)
 	id := "123"
 	m.Id = id
@@ -71,7 +73,7 @@ func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Read() {
 func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Read_Err() {
 	name := "my_token"
 	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
-	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt)
+	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt, []string{models.ScopeTasksRead})
 	s.Assert().NoError(err)
 	id := "123"
 	m.Id = id
@@ -92,7 +94,7 @@ func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Read_Err
 func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Revoke() {
 	name := "my_token"
 	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
-	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt)
+	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt, []string{models.ScopeTasksRead})
 	s.Assert().NoError(err)
 	id := "123"
 	m.Id = id
@@ -101,7 +103,7 @@ func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Revoke()
 		Update(mock.Anything, mock.Anything).
 		Return(m, nil)
 
-	err = s.svc.Revoke(context.Background(), m)
+	err = s.svc.Revoke(context.Background(), s.user.Id, m)
 	s.Assert().NoError(err)
 
 	s.mapper.EXPECT().
@@ -117,19 +119,147 @@ func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Revoke()
 }
 
 func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Find() {
+	filter := bson.M{"user_id": "123"}
+
+	s.mapper.EXPECT().
+		Count(mock.Anything, filter).
+		Return(int64(0), nil)
+
 	s.mapper.EXPECT().
-		Find(mock.Anything, mock.Anything).
+		Find(mock.Anything, filter, options.Find().SetLimit(0).SetSkip(0)).
 		Return(models.PersonalAccessTokens{}, nil)
 
-	pats, err := s.svc.Find(context.Background(), "123")
+	count, pats, err := s.svc.Find(context.Background(), &models.PersonalAccessTokenSearchParams{UserId: "123"})
 	s.Assert().NoError(err)
+	s.Assert().Equal(int64(0), count)
 	s.Assert().Equal(models.PersonalAccessTokens{}, pats)
 }
 
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Find_Filter() {
+	after := time.Now()
+	before := after.Add(7 * 24 * time.Hour)
+	revoked := true
+	filter := bson.M{
+		"expires_at": bson.M{"$gte": &after, "$lt": &before},
+		"is_revoked": true,
+	}
+
+	s.mapper.EXPECT().
+		Count(mock.Anything, filter).
+		Return(int64(20), nil)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, filter, options.Find().SetLimit(10).SetSkip(10)).
+		Return(models.PersonalAccessTokens{}, nil)
+
+	params := &models.PersonalAccessTokenSearchParams{
+		ExpiresAfter:  &after,
+		ExpiresBefore: &before,
+		IsRevoked:     &revoked,
+		Limit:         10,
+		Skip:          10,
+	}
+	count, _, err := s.svc.Find(context.Background(), params)
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(20), count)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Find_Exclude() {
+	filter := bson.M{"user_id": bson.M{"$nin": []string{"1", "2"}}}
+
+	s.mapper.EXPECT().
+		Count(mock.Anything, filter).
+		Return(int64(0), nil)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, filter, options.Find().SetLimit(0).SetSkip(0)).
+		Return(models.PersonalAccessTokens{}, nil)
+
+	params := &models.PersonalAccessTokenSearchParams{ExcludeUserIds: []string{"1", "2"}}
+	_, _, err := s.svc.Find(context.Background(), params)
+	s.Assert().NoError(err)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Find_Sort() {
+	opts := options.Find().SetLimit(0).SetSkip(0).SetSort(bson.D{{"last_used_at", -1}, {"_id", -1}})
+
+	s.mapper.EXPECT().
+		Count(mock.Anything, mock.Anything).
+		Return(int64(0), nil)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything, opts).
+		Return(models.PersonalAccessTokens{}, nil)
+
+	params := &models.PersonalAccessTokenSearchParams{UserId: "123", Sort: "-last_used_at"}
+	_, _, err := s.svc.Find(context.Background(), params)
+	s.Assert().NoError(err)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_RevokeAll() {
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+	m1, _ := models.NewPersonalAccessToken(s.user.Id, "token1", expiresAt, []string{models.ScopeTasksRead})
+	m2, _ := models.NewPersonalAccessToken(s.user.Id, "token2", expiresAt, []string{models.ScopeTasksRead})
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, bson.D{{"user_id", s.user.Id}, {"is_revoked", false}}).
+		Return(models.PersonalAccessTokens{*m1, *m2}, nil)
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.MatchedBy(func(m *models.PersonalAccessToken) bool {
+			return m.IsRevoked
+		})).
+		Return(nil, nil).Twice()
+
+	err := s.svc.RevokeAll(context.Background(), s.user.Id, s.user.Id)
+	s.Assert().NoError(err)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Revoke_Actor() {
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+	m, _ := models.NewPersonalAccessToken(s.user.Id, "my_token", expiresAt, []string{models.ScopeTasksRead})
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ctx := models.WithActor(context.Background(), "3000")
+	err := s.svc.Revoke(ctx, s.user.Id, m)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.user.Id, m.RevokedBy.Id)
+	s.Assert().Equal("3000", m.RevokedBy.Actor.Id)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Use() {
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+	m, err := models.NewPersonalAccessToken(s.user.Id, "my_token", expiresAt, []string{models.ScopeTasksRead})
+	s.Assert().NoError(err)
+
+	s.mapper.EXPECT().
+		UpdateUsage(mock.Anything, m).
+		Return(nil)
+
+	err = s.svc.Use(context.Background(), m)
+	s.Assert().NoError(err)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Rehash() {
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+	m, err := models.NewPersonalAccessToken(s.user.Id, "my_token", expiresAt, []string{models.ScopeTasksRead})
+	s.Assert().NoError(err)
+
+	s.mapper.EXPECT().
+		UpdateToken(mock.Anything, m).
+		Return(nil)
+
+	err = s.svc.Rehash(context.Background(), m)
+	s.Assert().NoError(err)
+}
+
 func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_FindOne() {
 	name := "my_token"
 	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
-	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt)
+	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt, []string{models.ScopeTasksRead})
 	s.Assert().NoError(err)
 	id := "123"
 	userId := "456"
@@ -151,7 +281,7 @@ func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_FindOne(
 func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_FindOne_Err() {
 	name := "my_token"
 	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
-	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt)
+	m, err := models.NewPersonalAccessToken(s.user.Id, name, expiresAt, []string{models.ScopeTasksRead})
 	s.Assert().NoError(err)
 	id := "123"
 	userId := "456"
@@ -170,3 +300,96 @@ func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_FindOne_
 		s.Assert().Equal(services.NotExist, se.Kind)
 	}
 }
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_FindUnscoped() {
+	filter := bson.D{{"user_id", s.user.Id}, {"scopes", nil}}
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, filter).
+		Return(models.PersonalAccessTokens{}, nil)
+
+	_, err := s.svc.FindUnscoped(context.Background(), s.user.Id)
+	s.Assert().NoError(err)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_FindExpiring() {
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.MatchedBy(func(filter bson.D) bool {
+			m := filter.Map()
+			expiresAt := m["expires_at"].(bson.D).Map()
+			return m["is_revoked"] == false &&
+				m["reminded_at"] == nil &&
+				expiresAt["$lte"].(time.Time).Sub(expiresAt["$gt"].(time.Time)) == 7*24*time.Hour
+		})).
+		Return(models.PersonalAccessTokens{}, nil)
+
+	_, err := s.svc.FindExpiring(context.Background(), 7*24*time.Hour)
+	s.Assert().NoError(err)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Remind() {
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+	m, _ := models.NewPersonalAccessToken(s.user.Id, "my_token", expiresAt, []string{models.ScopeTasksRead})
+
+	filter := bson.D{{"id", m.Id}, {"reminded_at", nil}}
+
+	s.mapper.EXPECT().
+		UpdateMany(mock.Anything, filter, mock.Anything).
+		Return(int64(1), nil).Once()
+
+	ok, err := s.svc.Remind(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().True(ok)
+	s.Assert().NotNil(m.RemindedAt)
+
+	// already reminded by another instance
+	s.mapper.EXPECT().
+		UpdateMany(mock.Anything, filter, mock.Anything).
+		Return(int64(0), nil).Once()
+
+	ok, err = s.svc.Remind(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().False(ok)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Unremind() {
+	expiresAt := time.Now().Add((7 * 24) * time.Hour).Format("2006-01-02")
+	m, _ := models.NewPersonalAccessToken(s.user.Id, "my_token", expiresAt, []string{models.ScopeTasksRead})
+	now := time.Now()
+	m.RemindedAt = &now
+
+	s.mapper.EXPECT().
+		UpdateMany(mock.Anything, bson.D{{"id", m.Id}}, bson.D{{"$unset", bson.D{{"reminded_at", ""}}}}).
+		Return(int64(1), nil).Once()
+
+	err := s.svc.Unremind(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Nil(m.RemindedAt)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Expire() {
+	s.mapper.EXPECT().
+		UpdateMany(mock.Anything, mock.Anything, bson.D{{"$set", bson.D{{"is_expired", true}}}}).
+		Return(int64(2), nil)
+
+	n, err := s.svc.Expire(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(2), n)
+}
+
+func (s *PersonalAccessTokenTestSuite) TestPersonalAccessTokenTestSuite_Purge() {
+	s.mapper.EXPECT().
+		DeleteMany(mock.Anything, mock.Anything).
+		Return(int64(3), nil).Once()
+
+	n, err := s.svc.Purge(context.Background(), 30*24*time.Hour)
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(3), n)
+
+	s.mapper.EXPECT().
+		DeleteMany(mock.Anything, mock.Anything).
+		Return(int64(0), errors.New("error")).Once()
+
+	_, err = s.svc.Purge(context.Background(), 30*24*time.Hour)
+	s.Assert().Error(err)
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// ServiceAccountMapper defines the datastore handling persisting ServiceAccount documents.
+type ServiceAccountMapper interface {
+	Create(ctx context.Context, model *models.ServiceAccount) (*models.ServiceAccount, error)
+	Find(ctx context.Context, filter any, limit int, skip int) (int64, models.ServiceAccounts, error)
+	FindOne(ctx context.Context, filter any) (*models.ServiceAccount, error)
+	Update(ctx context.Context, model *models.ServiceAccount) (*models.ServiceAccount, error)
+	UpdateUsage(ctx context.Context, model *models.ServiceAccount, cred *models.ServiceAccountCredential) error
+}
+
+var (
+	ErrServiceAccountDeleted  = errors.New("service account was deleted")
+	ErrServiceAccountExist    = errors.New("name already in-use")
+	ErrServiceAccountNotFound = errors.New("service account not found")
+)
+
+// ServiceAccount defines the application service in charge of interacting with ServiceAccounts.
+type ServiceAccount struct {
+	mapper ServiceAccountMapper
+}
+
+func NewServiceAccount(mapper ServiceAccountMapper) *ServiceAccount {
+	return &ServiceAccount{mapper: mapper}
+}
+
+func (s *ServiceAccount) Create(ctx context.Context, id string, model *models.ServiceAccount) (*models.ServiceAccount, error) {
+	model.Create(id)
+	models.Act(ctx, model.CreatedBy)
+	res, err := s.mapper.Create(ctx, model)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, NewError(err, Exist, ErrServiceAccountExist.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return res, nil
+}
+
+func (s *ServiceAccount) Read(ctx context.Context, id string) (*models.ServiceAccount, error) {
+	return s.findOne(ctx, bson.D{{"id", id}})
+}
+
+// ReadByClientId returns the service account owning the credential of clientId.
+func (s *ServiceAccount) ReadByClientId(ctx context.Context, clientId string) (*models.ServiceAccount, error) {
+	return s.findOne(ctx, bson.D{{"credentials.client_id", clientId}})
+}
+
+func (s *ServiceAccount) Update(ctx context.Context, id string, model *models.ServiceAccount) (*models.ServiceAccount, error) {
+	model.Update(id)
+	models.Act(ctx, model.UpdatedBy)
+	res, err := s.mapper.Update(ctx, model)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, NewError(err, Exist, ErrServiceAccountExist.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return res, nil
+}
+
+// Delete deletes model and its credentials, which ends the access tokens they were exchanged for.
+func (s *ServiceAccount) Delete(ctx context.Context, id string, model *models.ServiceAccount) error {
+	model.Delete(id)
+	models.Act(ctx, model.DeletedBy)
+	model.Credentials = []models.ServiceAccountCredential{}
+	_, err := s.mapper.Update(ctx, model)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
+
+// Use records the last use of the credential of clientId.
+func (s *ServiceAccount) Use(ctx context.Context, model *models.ServiceAccount, clientId string) error {
+	cred := model.Credential(clientId)
+	if cred == nil {
+		return NewError(nil, NotExist, models.ErrServiceAccountCredentialNotFound.Error())
+	}
+
+	if err := s.mapper.UpdateUsage(ctx, model, cred); err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
+
+func (s *ServiceAccount) Find(ctx context.Context, params *models.ServiceAccountSearchParams) (int64, models.ServiceAccounts, error) {
+	filter := bson.M{"deleted_at": bson.M{"$eq": nil}}
+	count, sas, err := s.mapper.Find(ctx, filter, params.Limit, params.Skip)
+	if err != nil {
+		return 0, nil, NewError(err, Other, "other")
+	}
+
+	return count, sas, nil
+}
+
+func (s *ServiceAccount) findOne(ctx context.Context, filter any) (*models.ServiceAccount, error) {
+	sa, err := s.mapper.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return nil, NewError(err, NotExist, ErrServiceAccountNotFound.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	if sa.DeletedBy != nil {
+		return nil, NewError(err, Deleted, ErrServiceAccountDeleted.Error())
+	}
+
+	return sa, nil
+}
@@ -0,0 +1,126 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type ServiceAccountTestSuite struct {
+	suite.Suite
+	mapper *services.MockServiceAccountMapper
+	svc    *services.ServiceAccount
+}
+
+func (s *ServiceAccountTestSuite) SetupTest() {
+	s.mapper = services.NewMockServiceAccountMapper(s.T())
+	s.svc = services.NewServiceAccount(s.mapper)
+}
+
+func TestServiceAccountTestSuite(t *testing.T) {
+	suite.Run(t, new(ServiceAccountTestSuite))
+}
+
+func (s *ServiceAccountTestSuite) TestServiceAccount_Create() {
+	m := models.NewServiceAccount("ci", "")
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, m).
+		Return(m, nil)
+
+	sa, err := s.svc.Create(context.Background(), "1", m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(sa.CreatedBy)
+}
+
+func (s *ServiceAccountTestSuite) TestServiceAccount_Create_Exist() {
+	m := models.NewServiceAccount("ci", "")
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, m).
+		Return(nil, &mongo.WriteError{Code: 11000})
+
+	_, err := s.svc.Create(context.Background(), "1", m)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.Exist, se.Kind)
+	}
+}
+
+func (s *ServiceAccountTestSuite) TestServiceAccount_Read_Deleted() {
+	m := models.NewServiceAccount("ci", "")
+	m.Delete("1")
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, bson.D{{"id", m.Id}}).
+		Return(m, nil)
+
+	_, err := s.svc.Read(context.Background(), m.Id)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.Deleted, se.Kind)
+	}
+}
+
+func (s *ServiceAccountTestSuite) TestServiceAccount_ReadByClientId_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, bson.D{{"credentials.client_id", "sa_1"}}).
+		Return(nil, data.ErrNoDocuments)
+
+	_, err := s.svc.ReadByClientId(context.Background(), "sa_1")
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.NotExist, se.Kind)
+	}
+}
+
+func (s *ServiceAccountTestSuite) TestServiceAccount_Delete() {
+	m := models.NewServiceAccount("ci", "")
+	_, _ = m.AddCredential()
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, m).
+		Return(m, nil)
+
+	err := s.svc.Delete(context.Background(), "1", m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(m.DeletedAt)
+	s.Assert().Empty(m.Credentials)
+}
+
+func (s *ServiceAccountTestSuite) TestServiceAccount_Use() {
+	m := models.NewServiceAccount("ci", "")
+	cred, _ := m.AddCredential()
+
+	s.mapper.EXPECT().
+		UpdateUsage(mock.Anything, m, m.Credential(cred.ClientId)).
+		Return(nil)
+
+	err := s.svc.Use(context.Background(), m, cred.ClientId)
+	s.Assert().NoError(err)
+}
+
+func (s *ServiceAccountTestSuite) TestServiceAccount_Find() {
+	sas := models.ServiceAccounts{*models.NewServiceAccount("ci", "")}
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything, 10, 0).
+		Return(1, sas, nil)
+
+	count, res, err := s.svc.Find(context.Background(), &models.ServiceAccountSearchParams{Limit: 10})
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(1), count)
+	s.Assert().Len(res, 1)
+}
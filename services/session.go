@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// SessionMapper defines the datastore handling persisting Session documents.
+type SessionMapper interface {
+	Create(ctx context.Context, model *models.Session) (*models.Session, error)
+	Find(ctx context.Context, filter any) (models.Sessions, error)
+	FindOne(ctx context.Context, filter any) (*models.Session, error)
+	Update(ctx context.Context, model *models.Session) (*models.Session, error)
+	UpdateIf(ctx context.Context, filter any, model *models.Session) (*models.Session, error)
+}
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionChanged  = errors.New("session refresh token already used")
+)
+
+// Session defines the application service in charge of interacting with Sessions.
+type Session struct {
+	mapper SessionMapper
+}
+
+func NewSession(mapper SessionMapper) *Session {
+	return &Session{mapper: mapper}
+}
+
+func (s *Session) Create(ctx context.Context, model *models.Session) (*models.Session, error) {
+	session, err := s.mapper.Create(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return session, nil
+}
+
+func (s *Session) Read(ctx context.Context, userId string, id string) (*models.Session, error) {
+	filter := bson.D{{"user_id", userId}, {"id", id}}
+	session, err := s.mapper.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return nil, NewError(err, NotExist, ErrSessionNotFound.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return session, nil
+}
+
+func (s *Session) Update(ctx context.Context, model *models.Session) (*models.Session, error) {
+	session, err := s.mapper.Update(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return session, nil
+}
+
+// CompareAndSwap saves model, whose refresh token was rotated or revoked, only if the
+// session is still active with the refresh token hash previous. Otherwise the refresh
+// token was used concurrently and it returns a Conflict error.
+func (s *Session) CompareAndSwap(ctx context.Context, model *models.Session, previous string) error {
+	filter := bson.D{
+		{"id", model.Id},
+		{"is_revoked", false},
+		{"refresh_token", previous},
+	}
+	// logging out revokes the session
+	models.Act(ctx, model.RevokedBy)
+	_, err := s.mapper.UpdateIf(ctx, filter, model)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return NewError(err, Conflict, ErrSessionChanged.Error())
+		}
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
+
+func (s *Session) Revoke(ctx context.Context, id string, model *models.Session, reason string) error {
+	model.Revoke(id, reason)
+	models.Act(ctx, model.RevokedBy)
+	_, err := s.mapper.Update(ctx, model)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
+
+// RevokeAll revokes every active session of the user except exceptId, which can be empty.
+func (s *Session) RevokeAll(ctx context.Context, id string, userId string, exceptId string, reason string) error {
+	sessions, err := s.Find(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		if session.Id == exceptId {
+			continue
+		}
+
+		err = s.Revoke(ctx, id, &session, reason)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find returns the active sessions of the user.
+func (s *Session) Find(ctx context.Context, userId string) (models.Sessions, error) {
+	filter := bson.D{
+		{"user_id", userId},
+		{"is_revoked", false},
+		{"expires_at", bson.D{{"$gt", time.Now()}}},
+	}
+	sessions, err := s.mapper.Find(ctx, filter)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,172 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type SessionTestSuite struct {
+	suite.Suite
+	mapper *services.MockSessionMapper
+	svc    *services.Session
+	user   *models.User
+}
+
+func (s *SessionTestSuite) SetupTest() {
+	s.mapper = services.NewMockSessionMapper(s.T())
+	s.svc = services.NewSession(s.mapper)
+	user := models.NewUser("test@email.com", "test")
+	user.Id = "100"
+	s.user = user
+}
+
+func TestSessionTestSuite(t *testing.T) {
+	suite.Run(t, new(SessionTestSuite))
+}
+
+func (s *SessionTestSuite) TestSession_Create() {
+	m := models.NewSession(s.user.Id)
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	session, err := s.svc.Create(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.user.Id, session.UserId)
+}
+
+func (s *SessionTestSuite) TestSession_Read() {
+	m := models.NewSession(s.user.Id)
+	id := "123"
+	m.Id = id
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	session, err := s.svc.Read(context.Background(), s.user.Id, id)
+	s.Assert().NoError(err)
+	s.Assert().Equal(id, session.Id)
+	s.Assert().Equal(s.user.Id, session.UserId)
+}
+
+func (s *SessionTestSuite) TestSession_Read_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, data.ErrNoDocuments)
+
+	_, err := s.svc.Read(context.Background(), s.user.Id, "123")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.NotExist, se.Kind)
+	}
+}
+
+func (s *SessionTestSuite) TestSession_Update() {
+	m := models.NewSession(s.user.Id)
+	m.Revoke(s.user.Id, models.SessionRevokedLogout)
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	session, err := s.svc.Update(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().True(session.IsRevoked)
+}
+
+func (s *SessionTestSuite) TestSession_CompareAndSwap() {
+	m := models.NewSession(s.user.Id)
+
+	s.mapper.EXPECT().
+		UpdateIf(mock.Anything, bson.D{
+			{"id", m.Id},
+			{"is_revoked", false},
+			{"refresh_token", "previous"},
+		}, m).
+		Return(m, nil)
+
+	err := s.svc.CompareAndSwap(context.Background(), m, "previous")
+	s.Assert().NoError(err)
+}
+
+func (s *SessionTestSuite) TestSession_CompareAndSwap_Conflict() {
+	m := models.NewSession(s.user.Id)
+
+	s.mapper.EXPECT().
+		UpdateIf(mock.Anything, mock.Anything, m).
+		Return(nil, data.ErrNoDocuments)
+
+	err := s.svc.CompareAndSwap(context.Background(), m, "previous")
+	var se *services.Error
+	if s.Assert().ErrorAs(err, &se) {
+		s.Assert().Equal(services.Conflict, se.Kind)
+	}
+}
+
+func (s *SessionTestSuite) TestSession_Revoke() {
+	m := models.NewSession(s.user.Id)
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	err := s.svc.Revoke(context.Background(), s.user.Id, m, models.SessionRevokedUser)
+	s.Assert().NoError(err)
+	s.Assert().True(m.IsRevoked)
+	s.Assert().Equal(models.SessionRevokedUser, m.RevokedReason)
+}
+
+func (s *SessionTestSuite) TestSession_Revoke_Actor() {
+	m := models.NewSession(s.user.Id)
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ctx := models.WithActor(context.Background(), "3000")
+	err := s.svc.Revoke(ctx, s.user.Id, m, models.SessionRevokedUser)
+	s.Assert().NoError(err)
+	s.Assert().Equal(s.user.Id, m.RevokedBy.Id)
+	s.Assert().Equal("3000", m.RevokedBy.Actor.Id)
+}
+
+func (s *SessionTestSuite) TestSession_RevokeAll() {
+	current := models.NewSession(s.user.Id)
+	other := models.NewSession(s.user.Id)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.Sessions{*current, *other}, nil)
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.MatchedBy(func(m *models.Session) bool {
+			return m.Id == other.Id && m.IsRevoked
+		})).
+		Return(other, nil).Once()
+
+	err := s.svc.RevokeAll(context.Background(), s.user.Id, s.user.Id, current.Id, models.SessionRevokedUser)
+	s.Assert().NoError(err)
+}
+
+func (s *SessionTestSuite) TestSession_Find() {
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.Sessions{}, nil)
+
+	sessions, err := s.svc.Find(context.Background(), s.user.Id)
+	s.Assert().NoError(err)
+	s.Assert().Equal(models.Sessions{}, sessions)
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// SigningKeyMapper defines the datastore handling persisting SigningKey documents.
+type SigningKeyMapper interface {
+	Create(ctx context.Context, model *models.SigningKey) (*models.SigningKey, error)
+	Find(ctx context.Context, filter any) (models.SigningKeys, error)
+	Update(ctx context.Context, model *models.SigningKey) (*models.SigningKey, error)
+}
+
+// SigningKey defines the application service in charge of interacting with SigningKeys.
+type SigningKey struct {
+	mapper SigningKeyMapper
+}
+
+func NewSigningKey(mapper SigningKeyMapper) *SigningKey {
+	return &SigningKey{mapper: mapper}
+}
+
+// Find returns the keys that still verify tokens at t.
+func (s *SigningKey) Find(ctx context.Context, t time.Time) (models.SigningKeys, error) {
+	filter := bson.D{{"$or", bson.A{
+		bson.D{{"expires_at", nil}},
+		bson.D{{"expires_at", bson.D{{"$gt", t}}}},
+	}}}
+	keys, err := s.mapper.Find(ctx, filter)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return keys, nil
+}
+
+// Rotate replaces the active key at now once it's older than interval and retires the
+// keys it replaced for retention. The next key is created publish before, and
+// activates publish after it's created at the earliest, so the instances and
+// services reloading the keys every publish verify its tokens by then.
+// initial is stored as the first key so tokens signed before rotation was
+// enabled stay valid, new keys use its algorithm. The key that activated last
+// is the active one, which lets instances rotating at the same time settle on
+// the same key. It returns the active key and the other keys verifying tokens,
+// the retired ones and the next one.
+func (s *SigningKey) Rotate(ctx context.Context, now time.Time, interval time.Duration, retention time.Duration,
+	publish time.Duration, initial *models.SigningKey,
+) (*models.SigningKey, models.SigningKeys, error) {
+	keys, err := s.Find(ctx, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(keys) == 0 {
+		initial.CreatedAt = &now
+		key, err := s.mapper.Create(ctx, initial)
+		if err != nil {
+			return nil, nil, NewError(err, Other, "other")
+		}
+		keys = append(keys, *key)
+	}
+
+	var active, next *models.SigningKey
+	for i := range keys {
+		k := &keys[i]
+		switch {
+		case k.IsRetired():
+		case k.IsPending(now):
+			next = k
+		case active == nil || k.ActiveSince().After(active.ActiveSince()):
+			active = k
+		}
+	}
+
+	// none of the keys signs tokens, like when they were all retired
+	if active == nil {
+		active, err = s.create(ctx, initial.Algorithm, now)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	rotateAt := active.ActiveSince().Add(interval)
+	if next == nil && !now.Before(rotateAt.Add(-publish)) {
+		activatesAt := now.Add(publish)
+		if rotateAt.After(activatesAt) {
+			activatesAt = rotateAt
+		}
+
+		next, err = s.create(ctx, initial.Algorithm, activatesAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, *next)
+	}
+
+	var others models.SigningKeys
+	for _, k := range keys {
+		if k.Id == active.Id {
+			continue
+		}
+
+		if !k.IsRetired() && !k.IsPending(now) {
+			k.Retire(now, retention)
+			if _, err = s.mapper.Update(ctx, &k); err != nil {
+				return nil, nil, NewError(err, Other, "other")
+			}
+		}
+		others = append(others, k)
+	}
+
+	return active, others, nil
+}
+
+// create stores a new key of algorithm activating at activatesAt.
+func (s *SigningKey) create(ctx context.Context, algorithm string, activatesAt time.Time) (*models.SigningKey, error) {
+	key, err := models.NewSigningKey(algorithm)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+	key.Activate(activatesAt)
+
+	key, err = s.mapper.Create(ctx, key)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,181 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type SigningKeyTestSuite struct {
+	suite.Suite
+	mapper  *services.MockSigningKeyMapper
+	svc     *services.SigningKey
+	initial *models.SigningKey
+}
+
+func (s *SigningKeyTestSuite) SetupTest() {
+	viper.Set(config.JWTKeyEncryptionKey, "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U=")
+	s.mapper = services.NewMockSigningKeyMapper(s.T())
+	s.svc = services.NewSigningKey(s.mapper)
+	initial := s.newKey(0)
+	s.initial = &initial
+}
+
+func TestSigningKeyTestSuite(t *testing.T) {
+	suite.Run(t, new(SigningKeyTestSuite))
+}
+
+func (s *SigningKeyTestSuite) newKey(age time.Duration) models.SigningKey {
+	key, err := models.NewSigningKey("RS256")
+	s.Require().NoError(err)
+	createdAt := time.Now().Add(-age)
+	key.CreatedAt = &createdAt
+	return *key
+}
+
+func (s *SigningKeyTestSuite) TestSigningKey_Rotate_Initial() {
+	initial := s.newKey(0)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.SigningKeys{}, nil)
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, &initial).
+		Return(&initial, nil)
+
+	active, retired, err := s.svc.Rotate(context.Background(), time.Now(), time.Hour, time.Hour, time.Minute, &initial)
+	s.Assert().NoError(err)
+	s.Assert().Equal(initial.Id, active.Id)
+	s.Assert().Empty(retired)
+}
+
+func (s *SigningKeyTestSuite) TestSigningKey_Rotate_Active() {
+	current := s.newKey(time.Minute)
+	old := s.newKey(2 * time.Hour)
+	old.Retire(time.Now(), time.Hour)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.SigningKeys{old, current}, nil)
+
+	active, retired, err := s.svc.Rotate(context.Background(), time.Now(), time.Hour, time.Hour, time.Minute, s.initial)
+	s.Assert().NoError(err)
+	s.Assert().Equal(current.Id, active.Id)
+	s.Assert().Len(retired, 1)
+	s.Assert().Equal(old.Id, retired[0].Id)
+}
+
+func (s *SigningKeyTestSuite) TestSigningKey_Rotate_Next() {
+	current := s.newKey(time.Hour - 30*time.Second)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.SigningKeys{current}, nil)
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, m *models.SigningKey) (*models.SigningKey, error) {
+			return m, nil
+		})
+
+	// the next key is published but current keeps signing until it activates
+	active, others, err := s.svc.Rotate(context.Background(), time.Now(), time.Hour, time.Hour, time.Minute, s.initial)
+	s.Assert().NoError(err)
+	s.Assert().Equal(current.Id, active.Id)
+	s.Assert().Len(others, 1)
+	s.Assert().Equal(s.initial.Algorithm, others[0].Algorithm)
+	s.Assert().True(others[0].IsPending(time.Now()))
+	s.Assert().WithinDuration(time.Now().Add(time.Minute), *others[0].ActivatesAt, time.Second)
+}
+
+func (s *SigningKeyTestSuite) TestSigningKey_Rotate_Next_Scheduled() {
+	current := s.newKey(time.Hour - time.Minute)
+	next := s.newKey(0)
+	next.Activate(time.Now().Add(time.Minute))
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.SigningKeys{current, next}, nil)
+
+	active, others, err := s.svc.Rotate(context.Background(), time.Now(), time.Hour, time.Hour, time.Minute, s.initial)
+	s.Assert().NoError(err)
+	s.Assert().Equal(current.Id, active.Id)
+	s.Assert().Len(others, 1)
+	s.Assert().Equal(next.Id, others[0].Id)
+	s.Assert().False(others[0].IsRetired())
+}
+
+func (s *SigningKeyTestSuite) TestSigningKey_Rotate_Next_Activated() {
+	current := s.newKey(time.Hour + time.Minute)
+	next := s.newKey(time.Minute)
+	next.Activate(time.Now().Add(-time.Second))
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.SigningKeys{current, next}, nil)
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.MatchedBy(func(m *models.SigningKey) bool {
+			return m.Id == current.Id && m.IsRetired()
+		})).
+		RunAndReturn(func(_ context.Context, m *models.SigningKey) (*models.SigningKey, error) {
+			return m, nil
+		})
+
+	active, others, err := s.svc.Rotate(context.Background(), time.Now(), time.Hour, time.Hour, time.Minute, s.initial)
+	s.Assert().NoError(err)
+	s.Assert().Equal(next.Id, active.Id)
+	s.Assert().Len(others, 1)
+	s.Assert().True(others[0].IsRetired())
+}
+
+func (s *SigningKeyTestSuite) TestSigningKey_Rotate_Expired() {
+	current := s.newKey(2 * time.Hour)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.SigningKeys{current}, nil)
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, m *models.SigningKey) (*models.SigningKey, error) {
+			return m, nil
+		})
+
+	// a late rotation still publishes the next key before it signs
+	active, others, err := s.svc.Rotate(context.Background(), time.Now(), time.Hour, time.Hour, time.Minute, s.initial)
+	s.Assert().NoError(err)
+	s.Assert().Equal(current.Id, active.Id)
+	s.Assert().Len(others, 1)
+	s.Assert().WithinDuration(time.Now().Add(time.Minute), *others[0].ActivatesAt, time.Second)
+}
+
+func (s *SigningKeyTestSuite) TestSigningKey_Rotate_Concurrent() {
+	older := s.newKey(2 * time.Minute)
+	newer := s.newKey(time.Minute)
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.SigningKeys{newer, older}, nil)
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, m *models.SigningKey) (*models.SigningKey, error) {
+			return m, nil
+		})
+
+	active, retired, err := s.svc.Rotate(context.Background(), time.Now(), time.Hour, time.Hour, time.Minute, s.initial)
+	s.Assert().NoError(err)
+	s.Assert().Equal(newer.Id, active.Id)
+	s.Assert().Len(retired, 1)
+	s.Assert().Equal(older.Id, retired[0].Id)
+}
@@ -35,6 +35,7 @@ func NewTask(mapper TaskMapper) *Task {
 
 func (t *Task) Create(ctx context.Context, id string, model *models.Task) (*models.Task, error) {
 	model.Create(id)
+	models.Act(ctx, model.CreatedBy)
 	task, err := t.mapper.Create(ctx, model)
 	if err != nil {
 		return nil, NewError(err, Other, "other")
@@ -61,6 +62,7 @@ func (t *Task) Read(ctx context.Context, id string) (*models.Task, error) {
 
 func (t *Task) Update(ctx context.Context, id string, model *models.Task) (*models.Task, error) {
 	model.Update(id)
+	models.Act(ctx, model.UpdatedBy)
 	task, err := t.mapper.Update(ctx, model)
 	if err != nil {
 		return nil, NewError(err, Other, "other")
@@ -71,6 +73,7 @@ func (t *Task) Update(ctx context.Context, id string, model *models.Task) (*mode
 
 func (t *Task) Delete(ctx context.Context, id string, model *models.Task) error {
 	model.Delete(id)
+	models.Act(ctx, model.DeletedBy)
 	_, err := t.mapper.Update(ctx, model)
 	if err != nil {
 		return NewError(err, Other, "other")
@@ -88,6 +88,20 @@ func (s *TaskTestSuite) TestTask_Update() {
 	s.Assert().NotNil(task.UpdatedBy)
 }
 
+func (s *TaskTestSuite) TestTask_Update_Actor() {
+	m := models.NewTask()
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	ctx := models.WithActor(context.Background(), "3000")
+	task, err := s.svc.Update(ctx, "123", m)
+	s.Assert().NoError(err)
+	s.Assert().Equal("123", task.UpdatedBy.(*models.Ref).Id)
+	s.Assert().Equal("3000", task.UpdatedBy.(*models.Ref).Actor.Id)
+}
+
 func (s *TaskTestSuite) TestTask_Delete() {
 	m := models.NewTask()
 	id := "123"
@@ -3,6 +3,8 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -16,7 +18,10 @@ type UserMapper interface {
 	Create(ctx context.Context, model *models.User) (*models.User, error)
 	Find(ctx context.Context, filter any, limit int, skip int) (int64, models.Users, error)
 	FindOne(ctx context.Context, filter any) (*models.User, error)
+	FindOneByEmailOrUsername(ctx context.Context, filter any) (*models.User, error)
 	Update(ctx context.Context, model *models.User) (*models.User, error)
+	UpdateOne(ctx context.Context, filter any, update any) (int64, error)
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 var (
@@ -64,6 +69,10 @@ func (u *User) Read(ctx context.Context, id string) (*models.User, error) {
 		return nil, NewError(err, Deleted, ErrUserDeleted.Error())
 	}
 
+	if err = u.liftExpired(ctx, user); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
 
@@ -72,9 +81,13 @@ func (u *User) Update(ctx context.Context, id string, model *models.User) (*mode
 	// and shouldn't update the UpdateAt timestamp
 	if id != "" {
 		model.Update(id)
+		models.Act(ctx, model.UpdatedBy)
 	}
 	res, err := u.mapper.Update(ctx, model)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, NewError(err, Exist, ErrUserExist.Error())
+		}
 		return nil, NewError(err, Other, "other")
 	}
 
@@ -83,6 +96,7 @@ func (u *User) Update(ctx context.Context, id string, model *models.User) (*mode
 
 func (u *User) Delete(ctx context.Context, id string, model *models.User) error {
 	model.Delete(id)
+	models.Act(ctx, model.DeletedBy)
 	_, err := u.mapper.Update(ctx, model)
 	if err != nil {
 		return NewError(err, Other, "other")
@@ -98,14 +112,70 @@ func (u *User) Find(ctx context.Context, params *models.UserSearchParams) (int64
 		return 0, nil, NewError(err, Other, "other")
 	}
 
+	for i := range users {
+		if err = u.liftExpired(ctx, &users[i]); err != nil {
+			return 0, nil, err
+		}
+	}
+
 	return count, users, nil
 }
 
+// FindIdsByRoles returns the ids of the users, deleted or not, having any of roles.
+func (u *User) FindIdsByRoles(ctx context.Context, roles []string) ([]string, error) {
+	filter := bson.D{{"roles", bson.D{{"$in", roles}}}}
+	_, users, err := u.mapper.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.Id)
+	}
+
+	return ids, nil
+}
+
 func (u *User) FindOneByEmailOrUsername(ctx context.Context, email string, username string) (*models.User, error) {
-	filter := bson.D{{"$or", bson.A{
-		bson.D{{"email", email}},
-		bson.D{{"username", username}},
-	}}}
+	// empty values are left out, they would match users without an email or username
+	or := bson.A{}
+	if email != "" {
+		or = append(or, bson.D{{"email", email}})
+	}
+	if username != "" {
+		or = append(or, bson.D{{"username", username}})
+	}
+	if len(or) == 0 {
+		return nil, NewError(data.ErrNoDocuments, NotExist, ErrUserNotFound.Error())
+	}
+
+	filter := bson.D{{"$or", or}}
+	user, err := u.mapper.FindOneByEmailOrUsername(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return nil, NewError(err, NotExist, ErrUserNotFound.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	if user.DeletedBy != nil {
+		return nil, NewError(err, Deleted, ErrUserDeleted.Error())
+	}
+
+	if err = u.liftExpired(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// FindOneByIdentity returns the user the account with subject at provider is linked to.
+func (u *User) FindOneByIdentity(ctx context.Context, provider string, subject string) (*models.User, error) {
+	filter := bson.D{{"identities", bson.D{{"$elemMatch", bson.D{
+		{"provider", provider},
+		{"subject", subject},
+	}}}}}
 	user, err := u.mapper.FindOne(ctx, filter)
 	if err != nil {
 		if errors.Is(err, data.ErrNoDocuments) {
@@ -114,5 +184,104 @@ func (u *User) FindOneByEmailOrUsername(ctx context.Context, email string, usern
 		return nil, NewError(err, Other, "other")
 	}
 
+	if user.DeletedBy != nil {
+		return nil, NewError(err, Deleted, ErrUserDeleted.Error())
+	}
+
+	if err = u.liftExpired(ctx, user); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
+
+// LiftExpired lifts the bans and locks that reached their end date
+// and returns how many users had one lifted.
+func (u *User) LiftExpired(ctx context.Context) (int64, error) {
+	now := time.Now()
+	filter := bson.D{{"$or", bson.A{
+		bson.D{{"is_banned", true}, {"banned_until", bson.D{{"$lte", now}}}},
+		bson.D{{"is_locked", true}, {"locked_until", bson.D{{"$lte", now}}}},
+	}}}
+	_, users, err := u.mapper.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return 0, NewError(err, Other, "other")
+	}
+
+	var n int64
+	for i := range users {
+		if err = u.liftExpired(ctx, &users[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// liftExpired lifts the bans and locks of user that reached their end date
+// as soon as it's read rather than when swept. Only the fields of those are
+// written, on the condition they're still expired, so a read doesn't overwrite
+// changes made to the user since.
+func (u *User) liftExpired(ctx context.Context, user *models.User) error {
+	banned, ban := user.IsBanned, user.CurrentBan()
+	locked, lock := user.IsLocked, user.CurrentLock()
+	if !user.LiftExpired() {
+		return nil
+	}
+	now := time.Now()
+
+	if banned && !user.IsBanned {
+		filter := bson.D{{"id", user.Id}, {"is_banned", true}, {"banned_until", bson.D{{"$lte", now}}}}
+		set := bson.D{{"is_banned", false}, {"unbanned_at", user.UnbannedAt}}
+		if ban != nil {
+			key := fmt.Sprintf("ban_history.%d.lifted_at", len(user.BanHistory)-1)
+			set = append(set, bson.E{Key: key, Value: user.UnbannedAt})
+		}
+		unset := bson.D{{"ban_reason", ""}, {"banned_at", ""}, {"banned_by", ""}, {"banned_until", ""}, {"unbanned_by", ""}}
+		if err := u.lift(ctx, filter, set, unset); err != nil {
+			return err
+		}
+	}
+
+	if locked && !user.IsLocked {
+		filter := bson.D{{"id", user.Id}, {"is_locked", true}, {"locked_until", bson.D{{"$lte", now}}}}
+		set := bson.D{{"is_locked", false}, {"unlocked_at", user.UnlockedAt}}
+		if lock != nil {
+			key := fmt.Sprintf("lock_history.%d.lifted_at", len(user.LockHistory)-1)
+			set = append(set, bson.E{Key: key, Value: user.UnlockedAt})
+		}
+		unset := bson.D{{"lock_reason", ""}, {"locked_at", ""}, {"locked_by", ""}, {"locked_until", ""}, {"unlocked_by", ""}}
+		if err := u.lift(ctx, filter, set, unset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lift applies set and unset to the user matching filter, nothing is
+// written when it was lifted or restricted again in the meantime.
+func (u *User) lift(ctx context.Context, filter bson.D, set bson.D, unset bson.D) error {
+	_, err := u.mapper.UpdateOne(ctx, filter, bson.D{{"$set", set}, {"$unset", unset}})
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
+
+// WithTransaction runs fn in a transaction, the services called
+// with the ctx passed to fn write as part of it.
+func (u *User) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := u.mapper.WithTransaction(ctx, fn)
+	if err != nil {
+		var se *Error
+		if errors.As(err, &se) {
+			return err
+		}
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
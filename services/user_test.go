@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/alexferl/echo-boilerplate/data"
@@ -85,6 +87,32 @@ func (s *UserTestSuite) TestUser_Read() {
 	s.Assert().Equal(username, user.Username)
 }
 
+func (s *UserTestSuite) TestUser_Read_LiftExpired() {
+	m := models.NewUser("test@example.com", "test")
+	admin := models.NewUserWithRole("admin@example.com", "admin", models.AdminRole)
+	until := time.Now().Add(time.Hour)
+	_ = m.Ban(admin, "spam", &until)
+	past := time.Now().Add(-time.Minute)
+	m.BannedUntil = &past
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, mock.Anything, mock.MatchedBy(func(update bson.D) bool {
+			set := update.Map()["$set"].(bson.D).Map()
+			unset := update.Map()["$unset"].(bson.D).Map()
+			_, reason := unset["ban_reason"]
+			return set["is_banned"] == false && set["ban_history.0.lifted_at"] != nil && reason
+		})).
+		Return(1, nil)
+
+	user, err := s.svc.Read(context.Background(), m.Id)
+	s.Assert().NoError(err)
+	s.Assert().False(user.IsBanned)
+}
+
 func (s *UserTestSuite) TestUser_Read_Err() {
 	email := "test@example.com"
 	username := "test"
@@ -121,6 +149,22 @@ func (s *UserTestSuite) TestUser_Update() {
 	s.Assert().NotNil(task.UpdatedBy)
 }
 
+func (s *UserTestSuite) TestUser_Update_Exist() {
+	m := models.NewUser("test@example.com", "test")
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(nil, &mongo.WriteError{Code: 11000})
+
+	_, err := s.svc.Update(context.Background(), "", m)
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.Exist, se.Kind)
+	}
+}
+
 func (s *UserTestSuite) TestUser_Delete() {
 	email := "test@example.com"
 	username := "test"
@@ -162,6 +206,85 @@ func (s *UserTestSuite) TestUser_Find() {
 	s.Assert().Equal(models.Users{}, tasks)
 }
 
+func (s *UserTestSuite) TestUser_FindIdsByRoles() {
+	admin := models.NewUserWithRole("admin@example.com", "admin", models.AdminRole)
+	roles := []string{models.AdminRole.String(), models.SuperRole.String()}
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, bson.D{{"roles", bson.D{{"$in", roles}}}}, 0, 0).
+		Return(1, models.Users{*admin}, nil)
+
+	ids, err := s.svc.FindIdsByRoles(context.Background(), roles)
+	s.Assert().NoError(err)
+	s.Assert().Equal([]string{admin.Id}, ids)
+}
+
+func (s *UserTestSuite) TestUser_LiftExpired() {
+	m := models.NewUser("test@example.com", "test")
+	admin := models.NewUserWithRole("admin@example.com", "admin", models.AdminRole)
+	until := time.Now().Add(time.Hour)
+	_ = m.Lock(admin, "", &until)
+	past := time.Now().Add(-time.Minute)
+	m.LockedUntil = &past
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything, 0, 0).
+		Return(1, models.Users{*m}, nil)
+
+	s.mapper.EXPECT().
+		UpdateOne(mock.Anything, mock.MatchedBy(func(filter bson.D) bool {
+			return filter.Map()["id"] == m.Id && filter.Map()["is_locked"] == true
+		}), mock.MatchedBy(func(update bson.D) bool {
+			set := update.Map()["$set"].(bson.D).Map()
+			return set["is_locked"] == false && set["unlocked_at"].(*time.Time).Equal(past)
+		})).
+		Return(1, nil)
+
+	n, err := s.svc.LiftExpired(context.Background())
+	s.Assert().NoError(err)
+	s.Assert().Equal(int64(1), n)
+}
+
+func (s *UserTestSuite) TestUser_FindOneByIdentity_Deleted() {
+	m := models.NewUser("test@example.com", "test")
+	_ = m.LinkIdentity("github", "123", "test@example.com")
+	m.Delete("123")
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	_, err := s.svc.FindOneByIdentity(context.Background(), "github", "123")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.Deleted, se.Kind)
+	}
+}
+
+func (s *UserTestSuite) TestUser_WithTransaction_Err() {
+	s.mapper.EXPECT().
+		WithTransaction(mock.Anything, mock.Anything).
+		Return(errors.New("")).Once()
+
+	err := s.svc.WithTransaction(context.Background(), func(ctx context.Context) error { return nil })
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.Other, se.Kind)
+	}
+
+	// errors of the services called in the transaction are kept
+	notFound := services.NewError(data.ErrNoDocuments, services.NotExist, services.ErrUserNotFound.Error())
+	s.mapper.EXPECT().
+		WithTransaction(mock.Anything, mock.Anything).
+		Return(notFound).Once()
+
+	err = s.svc.WithTransaction(context.Background(), func(ctx context.Context) error { return nil })
+	s.Assert().Equal(notFound, err)
+}
+
 func (s *UserTestSuite) TestUser_FindOneByEmailOrUsername() {
 	email := "test@example.com"
 	username := "test"
@@ -170,7 +293,7 @@ func (s *UserTestSuite) TestUser_FindOneByEmailOrUsername() {
 	m.Id = id
 
 	s.mapper.EXPECT().
-		FindOne(mock.Anything, mock.Anything).
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything).
 		Return(m, nil)
 
 	user, err := s.svc.FindOneByEmailOrUsername(context.Background(), email, username)
@@ -188,7 +311,7 @@ func (s *UserTestSuite) TestUser_FindOneByEmailOrUsername_Err() {
 	m.Id = id
 
 	s.mapper.EXPECT().
-		FindOne(mock.Anything, mock.Anything).
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything).
 		Return(nil, data.ErrNoDocuments)
 
 	_, err := s.svc.FindOneByEmailOrUsername(context.Background(), email, username)
@@ -199,3 +322,47 @@ func (s *UserTestSuite) TestUser_FindOneByEmailOrUsername_Err() {
 		s.Assert().Equal(services.NotExist, se.Kind)
 	}
 }
+
+func (s *UserTestSuite) TestUser_FindOneByEmailOrUsername_Deleted() {
+	m := models.NewUser("test@example.com", "test")
+	m.Delete("123")
+
+	s.mapper.EXPECT().
+		FindOneByEmailOrUsername(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	_, err := s.svc.FindOneByEmailOrUsername(context.Background(), m.Email, "")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.Deleted, se.Kind)
+	}
+}
+
+func (s *UserTestSuite) TestUser_FindOneByIdentity() {
+	m := models.NewUser("test@example.com", "test")
+	_ = m.LinkIdentity("github", "123", "test@example.com")
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	user, err := s.svc.FindOneByIdentity(context.Background(), "github", "123")
+	s.Assert().NoError(err)
+	s.Assert().Equal(m.Id, user.Id)
+}
+
+func (s *UserTestSuite) TestUser_FindOneByIdentity_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, data.ErrNoDocuments)
+
+	_, err := s.svc.FindOneByIdentity(context.Background(), "github", "123")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.NotExist, se.Kind)
+	}
+}
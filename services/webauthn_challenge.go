@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// WebAuthnChallengeMapper defines the datastore handling persisting WebAuthnChallenge documents.
+type WebAuthnChallengeMapper interface {
+	Create(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)
+	FindOne(ctx context.Context, filter any) (*models.WebAuthnChallenge, error)
+	Update(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error)
+}
+
+var ErrWebAuthnChallengeNotFound = errors.New("webauthn challenge not found")
+
+// WebAuthnChallenge defines the application service in charge of interacting with WebAuthnChallenges.
+type WebAuthnChallenge struct {
+	mapper WebAuthnChallengeMapper
+}
+
+func NewWebAuthnChallenge(mapper WebAuthnChallengeMapper) *WebAuthnChallenge {
+	return &WebAuthnChallenge{mapper: mapper}
+}
+
+func (w *WebAuthnChallenge) Create(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error) {
+	challenge, err := w.mapper.Create(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return challenge, nil
+}
+
+func (w *WebAuthnChallenge) Read(ctx context.Context, id string) (*models.WebAuthnChallenge, error) {
+	filter := bson.D{{"id", id}}
+	challenge, err := w.mapper.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return nil, NewError(err, NotExist, ErrWebAuthnChallengeNotFound.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return challenge, nil
+}
+
+func (w *WebAuthnChallenge) Update(ctx context.Context, model *models.WebAuthnChallenge) (*models.WebAuthnChallenge, error) {
+	challenge, err := w.mapper.Update(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return challenge, nil
+}
@@ -0,0 +1,69 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type WebAuthnChallengeTestSuite struct {
+	suite.Suite
+	mapper *services.MockWebAuthnChallengeMapper
+	svc    *services.WebAuthnChallenge
+}
+
+func (s *WebAuthnChallengeTestSuite) SetupTest() {
+	s.mapper = services.NewMockWebAuthnChallengeMapper(s.T())
+	s.svc = services.NewWebAuthnChallenge(s.mapper)
+}
+
+func TestWebAuthnChallengeTestSuite(t *testing.T) {
+	suite.Run(t, new(WebAuthnChallengeTestSuite))
+}
+
+func (s *WebAuthnChallengeTestSuite) TestWebAuthnChallenge_Create() {
+	m := models.NewWebAuthnChallenge(models.WebAuthnChallengeLogin, "100", &webauthn.SessionData{})
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	c, err := s.svc.Create(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Equal("100", c.UserId)
+}
+
+func (s *WebAuthnChallengeTestSuite) TestWebAuthnChallenge_Read_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, data.ErrNoDocuments)
+
+	_, err := s.svc.Read(context.Background(), "123")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.NotExist, se.Kind)
+	}
+}
+
+func (s *WebAuthnChallengeTestSuite) TestWebAuthnChallenge_Update() {
+	m := models.NewWebAuthnChallenge(models.WebAuthnChallengeLogin, "100", &webauthn.SessionData{})
+	m.Use()
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	c, err := s.svc.Update(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(c.UsedAt)
+}
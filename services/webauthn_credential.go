@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+)
+
+// WebAuthnCredentialMapper defines the datastore handling persisting WebAuthnCredential documents.
+type WebAuthnCredentialMapper interface {
+	Create(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error)
+	Find(ctx context.Context, filter any) (models.WebAuthnCredentials, error)
+	FindOne(ctx context.Context, filter any) (*models.WebAuthnCredential, error)
+	Update(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error)
+}
+
+var ErrWebAuthnCredentialNotFound = errors.New("webauthn credential not found")
+
+// WebAuthnCredential defines the application service in charge of interacting with WebAuthnCredentials.
+type WebAuthnCredential struct {
+	mapper WebAuthnCredentialMapper
+}
+
+func NewWebAuthnCredential(mapper WebAuthnCredentialMapper) *WebAuthnCredential {
+	return &WebAuthnCredential{mapper: mapper}
+}
+
+func (w *WebAuthnCredential) Create(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+	credential, err := w.mapper.Create(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return credential, nil
+}
+
+func (w *WebAuthnCredential) Read(ctx context.Context, userId string, id string) (*models.WebAuthnCredential, error) {
+	filter := bson.D{{"user_id", userId}, {"id", id}, {"deleted_at", nil}}
+	credential, err := w.mapper.FindOne(ctx, filter)
+	if err != nil {
+		if errors.Is(err, data.ErrNoDocuments) {
+			return nil, NewError(err, NotExist, ErrWebAuthnCredentialNotFound.Error())
+		}
+		return nil, NewError(err, Other, "other")
+	}
+
+	return credential, nil
+}
+
+func (w *WebAuthnCredential) Update(ctx context.Context, model *models.WebAuthnCredential) (*models.WebAuthnCredential, error) {
+	credential, err := w.mapper.Update(ctx, model)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return credential, nil
+}
+
+func (w *WebAuthnCredential) Delete(ctx context.Context, model *models.WebAuthnCredential) error {
+	model.Delete()
+	_, err := w.mapper.Update(ctx, model)
+	if err != nil {
+		return NewError(err, Other, "other")
+	}
+
+	return nil
+}
+
+// Find returns the credentials of the user that weren't deleted.
+func (w *WebAuthnCredential) Find(ctx context.Context, userId string) (models.WebAuthnCredentials, error) {
+	filter := bson.D{{"user_id", userId}, {"deleted_at", nil}}
+	credentials, err := w.mapper.Find(ctx, filter)
+	if err != nil {
+		return nil, NewError(err, Other, "other")
+	}
+
+	return credentials, nil
+}
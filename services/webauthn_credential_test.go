@@ -0,0 +1,92 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/alexferl/echo-boilerplate/data"
+	"github.com/alexferl/echo-boilerplate/models"
+	"github.com/alexferl/echo-boilerplate/services"
+)
+
+type WebAuthnCredentialTestSuite struct {
+	suite.Suite
+	mapper *services.MockWebAuthnCredentialMapper
+	svc    *services.WebAuthnCredential
+}
+
+func (s *WebAuthnCredentialTestSuite) SetupTest() {
+	s.mapper = services.NewMockWebAuthnCredentialMapper(s.T())
+	s.svc = services.NewWebAuthnCredential(s.mapper)
+}
+
+func TestWebAuthnCredentialTestSuite(t *testing.T) {
+	suite.Run(t, new(WebAuthnCredentialTestSuite))
+}
+
+func (s *WebAuthnCredentialTestSuite) TestWebAuthnCredential_Create() {
+	m := models.NewWebAuthnCredential("100", "My Phone", &webauthn.Credential{ID: []byte("abc")})
+
+	s.mapper.EXPECT().
+		Create(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	c, err := s.svc.Create(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().Equal("100", c.UserId)
+}
+
+func (s *WebAuthnCredentialTestSuite) TestWebAuthnCredential_Read() {
+	m := models.NewWebAuthnCredential("100", "My Phone", &webauthn.Credential{ID: []byte("abc")})
+
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	c, err := s.svc.Read(context.Background(), "100", m.Id)
+	s.Assert().NoError(err)
+	s.Assert().Equal(m.Id, c.Id)
+}
+
+func (s *WebAuthnCredentialTestSuite) TestWebAuthnCredential_Read_Err() {
+	s.mapper.EXPECT().
+		FindOne(mock.Anything, mock.Anything).
+		Return(nil, data.ErrNoDocuments)
+
+	_, err := s.svc.Read(context.Background(), "100", "123")
+	s.Assert().Error(err)
+	var se *services.Error
+	s.Assert().ErrorAs(err, &se)
+	if errors.As(err, &se) {
+		s.Assert().Equal(services.NotExist, se.Kind)
+	}
+}
+
+func (s *WebAuthnCredentialTestSuite) TestWebAuthnCredential_Delete() {
+	m := models.NewWebAuthnCredential("100", "My Phone", &webauthn.Credential{ID: []byte("abc")})
+
+	s.mapper.EXPECT().
+		Update(mock.Anything, mock.Anything).
+		Return(m, nil)
+
+	err := s.svc.Delete(context.Background(), m)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(m.DeletedAt)
+}
+
+func (s *WebAuthnCredentialTestSuite) TestWebAuthnCredential_Find() {
+	m := models.NewWebAuthnCredential("100", "My Phone", &webauthn.Credential{ID: []byte("abc")})
+
+	s.mapper.EXPECT().
+		Find(mock.Anything, mock.Anything).
+		Return(models.WebAuthnCredentials{*m}, nil)
+
+	res, err := s.svc.Find(context.Background(), "100")
+	s.Assert().NoError(err)
+	s.Assert().Len(res, 1)
+}
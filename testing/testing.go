@@ -5,6 +5,8 @@ import (
 	"path"
 	"runtime"
 
+	"github.com/spf13/viper"
+
 	"github.com/alexferl/echo-boilerplate/config"
 )
 
@@ -19,4 +21,7 @@ func init() {
 
 	c := config.New()
 	c.BindFlags()
+
+	// TOTP secrets can't be stored without it
+	viper.Set(config.MFASecretEncryptionKey, "bWZhbWZhbWZhbWZhbWZhbWZhbWZhbWZhbWZhbWZhbWY=")
 }
@@ -0,0 +1,82 @@
+// Package crypt encrypts the secrets stored in the database with AES-256-GCM.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+
+	"github.com/alexferl/echo-boilerplate/util/rand"
+)
+
+// KeySize is the size of the keys in bytes.
+const KeySize = 32
+
+var (
+	ErrKeyInvalid        = errors.New("key must be 32 base64 encoded bytes")
+	ErrCiphertextInvalid = errors.New("ciphertext invalid")
+)
+
+// ParseKey decodes a base64 encoded key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(key) != KeySize {
+		return nil, ErrKeyInvalid
+	}
+
+	return key, nil
+}
+
+// Encrypt encrypts plaintext with key and returns it base64 encoded, prefixed
+// with its nonce. Decrypting it requires the same additionalData, which
+// binds the ciphertext to what it's stored with.
+func Encrypt(key []byte, plaintext []byte, additionalData []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := rand.GenerateRandomBytes(aead.NonceSize())
+	if err != nil {
+		return "", err
+	}
+
+	b := aead.Seal(nonce, nonce, plaintext, additionalData)
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Decrypt decrypts ciphertext returned by Encrypt.
+func Decrypt(key []byte, ciphertext string, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(b) < aead.NonceSize() {
+		return nil, ErrCiphertextInvalid
+	}
+
+	nonce, b := b[:aead.NonceSize()], b[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, b, additionalData)
+	if err != nil {
+		return nil, ErrCiphertextInvalid
+	}
+
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, ErrKeyInvalid
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,53 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKey(t *testing.T) {
+	key, err := ParseKey(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), KeySize)))
+	assert.NoError(t, err)
+	assert.Len(t, key, KeySize)
+
+	for _, s := range []string{"", "not base64", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		_, err = ParseKey(s)
+		assert.ErrorIs(t, err, ErrKeyInvalid)
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), KeySize)
+
+	ciphertext, err := Encrypt(key, []byte("secret"), []byte("id"))
+	assert.NoError(t, err)
+	assert.NotContains(t, ciphertext, "secret")
+
+	other, err := Encrypt(key, []byte("secret"), []byte("id"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, ciphertext, other)
+
+	plaintext, err := Decrypt(key, ciphertext, []byte("id"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+}
+
+func TestDecrypt_Invalid(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), KeySize)
+	ciphertext, _ := Encrypt(key, []byte("secret"), []byte("id"))
+
+	_, err := Decrypt(bytes.Repeat([]byte("o"), KeySize), ciphertext, []byte("id"))
+	assert.ErrorIs(t, err, ErrCiphertextInvalid)
+
+	_, err = Decrypt(key, ciphertext, []byte("other"))
+	assert.ErrorIs(t, err, ErrCiphertextInvalid)
+
+	_, err = Decrypt(key, "short", []byte("id"))
+	assert.ErrorIs(t, err, ErrCiphertextInvalid)
+
+	_, err = Decrypt([]byte("short"), ciphertext, []byte("id"))
+	assert.ErrorIs(t, err, ErrKeyInvalid)
+}
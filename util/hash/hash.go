@@ -17,3 +17,10 @@ func ValidMAC(message []byte, messageMAC []byte, key []byte) bool {
 	expectedMAC := NewHMAC(message, key)
 	return hmac.Equal(messageMAC, []byte(expectedMAC))
 }
+
+// SHA256 returns the hex encoded SHA-256 digest of message.
+// It's only suitable for high entropy secrets, passwords should use the password package.
+func SHA256(message []byte) string {
+	sum := sha256.Sum256(message)
+	return hex.EncodeToString(sum[:])
+}
@@ -14,3 +14,8 @@ func TestHash(t *testing.T) {
 
 	assert.True(t, b)
 }
+
+func TestSHA256(t *testing.T) {
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", SHA256([]byte("")))
+	assert.NotEqual(t, SHA256([]byte("a")), SHA256([]byte("b")))
+}
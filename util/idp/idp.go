@@ -0,0 +1,478 @@
+// Package idp logs users in with external identity providers using OAuth2 or OpenID Connect.
+package idp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	jwx "github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+
+	"github.com/alexferl/echo-boilerplate/config"
+)
+
+const (
+	// requestTimeout bounds the requests made to providers,
+	// so one that hangs doesn't hold up logins indefinitely.
+	requestTimeout = 10 * time.Second
+
+	// keysRefetchInterval is the minimum time between fetching the keys of a provider again
+	// for ID tokens signed with an unknown key, so junk tokens can't be used to flood it.
+	keysRefetchInterval = time.Minute
+)
+
+var httpClient = &http.Client{Timeout: requestTimeout}
+
+var (
+	ErrEmailMissing   = errors.New("identity provider didn't return an email")
+	ErrIDTokenInvalid = errors.New("id token invalid")
+	ErrIDTokenMissing = errors.New("id token missing")
+	ErrNonceInvalid   = errors.New("id token nonce invalid")
+	ErrSubjectInvalid = errors.New("userinfo subject doesn't match the id token")
+	ErrSubjectMissing = errors.New("identity provider didn't return a subject")
+)
+
+// Identity is a user as known by an identity provider.
+type Identity struct {
+	Email         string
+	EmailVerified bool
+	Name          string
+	Provider      string
+	Subject       string
+}
+
+// Config configures a Provider, empty endpoints are taken from
+// the OpenID Connect discovery document when DiscoveryURL is set.
+type Config struct {
+	AuthURL            string
+	ClientId           string
+	ClientSecret       string
+	DiscoveryURL       string
+	EmailClaim         string
+	EmailsURL          string
+	EmailVerifiedClaim string
+	NameClaim          string
+	RedirectURL        string
+	Scopes             []string
+	SubjectClaim       string
+	TokenURL           string
+	UserInfoURL        string
+}
+
+// Provider is an identity provider users can log in with.
+type Provider struct {
+	Name   string
+	config Config
+
+	mu          sync.Mutex
+	discovered  bool
+	issuer      string
+	jwksURL     string
+	keys        jwk.Set
+	refetchedAt time.Time
+	oauth2      *oauth2.Config
+}
+
+func NewProvider(name string, config Config) *Provider {
+	return &Provider{Name: name, config: config}
+}
+
+// AuthCodeURL returns the URL of the provider's consent page. The verifier is used
+// for PKCE and the nonce ends up in the ID token, both have to be kept for Exchange.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, verifier string, nonce string) (string, error) {
+	if err := p.discover(ctx); err != nil {
+		return "", err
+	}
+
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if p.issuer != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+
+	return p.oauth2.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange converts an authorization code into the identity of the user. The ID token of
+// OpenID Connect providers is validated with the provider's keys and must contain nonce.
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (*Identity, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed exchanging authorization code: %v", err)
+	}
+
+	claims := map[string]any{}
+	if p.issuer != "" {
+		raw, ok := token.Extra("id_token").(string)
+		if !ok || raw == "" {
+			return nil, ErrIDTokenMissing
+		}
+
+		idToken, err := p.verifyIDToken(ctx, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		claims, err = idToken.AsMap(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// the ID token has to be the one issued for this login
+		if n, ok := claims["nonce"].(string); !ok || nonce == "" || n != nonce {
+			return nil, ErrNonceInvalid
+		}
+	}
+
+	client := p.oauth2.Client(ctx, token)
+	if p.config.UserInfoURL != "" {
+		userInfo := map[string]any{}
+		if err = getJSON(ctx, client, p.config.UserInfoURL, &userInfo); err != nil {
+			return nil, fmt.Errorf("failed getting user info: %v", err)
+		}
+
+		// the userinfo response has to be about the user of the ID token
+		if sub, ok := claims["sub"]; ok && userInfo["sub"] != nil && claimString(userInfo["sub"]) != claimString(sub) {
+			return nil, ErrSubjectInvalid
+		}
+
+		for k, v := range userInfo {
+			if _, ok := claims[k]; !ok {
+				claims[k] = v
+			}
+		}
+	}
+
+	identity := p.identity(claims)
+	if identity.Subject == "" {
+		return nil, ErrSubjectMissing
+	}
+
+	if (identity.Email == "" || !identity.EmailVerified) && p.config.EmailsURL != "" {
+		if err = p.primaryEmail(ctx, client, identity); err != nil {
+			return nil, fmt.Errorf("failed getting emails: %v", err)
+		}
+	}
+
+	if identity.Email == "" {
+		return nil, ErrEmailMissing
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) identity(claims map[string]any) *Identity {
+	return &Identity{
+		Email:         claimString(claims[p.config.EmailClaim]),
+		EmailVerified: claimBool(claims[p.config.EmailVerifiedClaim]),
+		Name:          claimString(claims[p.config.NameClaim]),
+		Provider:      p.Name,
+		Subject:       claimString(claims[p.config.SubjectClaim]),
+	}
+}
+
+type email struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// primaryEmail sets the primary email of identity from the provider's list
+// of emails, for providers like GitHub that may not return it otherwise.
+func (p *Provider) primaryEmail(ctx context.Context, client *http.Client, identity *Identity) error {
+	var emails []email
+	if err := getJSON(ctx, client, p.config.EmailsURL, &emails); err != nil {
+		return err
+	}
+
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+		}
+	}
+
+	return nil
+}
+
+type discoveryDocument struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	Issuer                string `json:"issuer"`
+	JWKSURI               string `json:"jwks_uri"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// discover gets the endpoints and keys of OpenID Connect providers the
+// first time they're needed, so the app can start while a provider is down.
+// The lock isn't held while fetching them, concurrent logins may each fetch
+// them until one succeeds.
+func (p *Provider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	discovered, c := p.discovered, p.config
+	p.mu.Unlock()
+
+	if discovered {
+		return nil
+	}
+
+	var (
+		issuer  string
+		jwksURL string
+		keys    jwk.Set
+	)
+	if c.DiscoveryURL != "" {
+		doc := &discoveryDocument{}
+		if err := getJSON(ctx, httpClient, c.DiscoveryURL, doc); err != nil {
+			return fmt.Errorf("failed getting discovery document: %v", err)
+		}
+
+		if doc.Issuer == "" || doc.JWKSURI == "" {
+			return fmt.Errorf("discovery document is missing the issuer or jwks_uri")
+		}
+
+		issuer = doc.Issuer
+		jwksURL = doc.JWKSURI
+		c.AuthURL = or(c.AuthURL, doc.AuthorizationEndpoint)
+		c.TokenURL = or(c.TokenURL, doc.TokenEndpoint)
+		c.UserInfoURL = or(c.UserInfoURL, doc.UserInfoEndpoint)
+
+		var err error
+		keys, err = jwk.Fetch(ctx, jwksURL, jwk.WithHTTPClient(httpClient))
+		if err != nil {
+			return fmt.Errorf("failed getting jwks: %v", err)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovered {
+		return nil
+	}
+
+	p.config = c
+	p.issuer = issuer
+	p.jwksURL = jwksURL
+	p.keys = keys
+	p.oauth2 = &oauth2.Config{
+		ClientID:     c.ClientId,
+		ClientSecret: c.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  c.AuthURL,
+			TokenURL: c.TokenURL,
+		},
+		RedirectURL: c.RedirectURL,
+		Scopes:      c.Scopes,
+	}
+	p.discovered = true
+
+	return nil
+}
+
+// verifyIDToken validates the signature and claims of raw. The keys are fetched
+// again when it's signed with one that isn't known in case the provider rotated
+// them, at most once per keysRefetchInterval.
+func (p *Provider) verifyIDToken(ctx context.Context, raw string) (jwx.Token, error) {
+	msg, err := jws.Parse([]byte(raw))
+	if err != nil || len(msg.Signatures()) == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrIDTokenInvalid, err)
+	}
+	kid := msg.Signatures()[0].ProtectedHeaders().KeyID()
+
+	keys, err := p.keySet(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwx.Parse(
+		[]byte(raw),
+		jwx.WithKeySet(keys, jws.WithInferAlgorithmFromKey(true)),
+		jwx.WithValidate(true),
+		jwx.WithIssuer(p.issuer),
+		jwx.WithAudience(p.config.ClientId),
+		jwx.WithAcceptableSkew(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIDTokenInvalid, err)
+	}
+
+	return token, nil
+}
+
+// keySet returns the keys of the provider, fetched again
+// if kid isn't one of them and they weren't recently.
+func (p *Provider) keySet(ctx context.Context, kid string) (jwk.Set, error) {
+	p.mu.Lock()
+	keys := p.keys
+	if _, ok := keys.LookupKeyID(kid); ok || time.Since(p.refetchedAt) < keysRefetchInterval {
+		p.mu.Unlock()
+		return keys, nil
+	}
+	// set before fetching so concurrent logins don't fetch them as well
+	p.refetchedAt = time.Now()
+	p.mu.Unlock()
+
+	keys, err := jwk.Fetch(ctx, p.jwksURL, jwk.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("failed getting jwks: %v", err)
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	return keys, nil
+}
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// NewRegistry creates the providers listed in the config.
+func NewRegistry() (*Registry, error) {
+	r := &Registry{providers: map[string]*Provider{}}
+	for _, name := range viper.GetStringSlice(config.OAuth2Providers) {
+		if name == "" {
+			continue
+		}
+
+		c, err := newConfig(name)
+		if err != nil {
+			return nil, err
+		}
+		r.providers[name] = NewProvider(name, c)
+	}
+
+	return r, nil
+}
+
+// Get returns the provider called name, if it's configured.
+func (r *Registry) Get(name string) (*Provider, bool) {
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+func (r *Registry) Len() int {
+	return len(r.providers)
+}
+
+// newConfig reads the settings of the provider called name,
+// falling back to the preset of the same name if there's one.
+func newConfig(name string) (Config, error) {
+	preset := presets[name]
+	get := func(setting string, def string) string {
+		return or(viper.GetString(config.OAuth2ProviderKey(name, setting)), def)
+	}
+
+	scopes := viper.GetStringSlice(config.OAuth2ProviderKey(name, config.OAuth2ProviderScopes))
+	if len(scopes) == 0 {
+		scopes = preset.Scopes
+	}
+
+	c := Config{
+		AuthURL:            get(config.OAuth2ProviderAuthURL, preset.AuthURL),
+		ClientId:           get(config.OAuth2ProviderClientId, ""),
+		ClientSecret:       get(config.OAuth2ProviderClientSecret, ""),
+		DiscoveryURL:       get(config.OAuth2ProviderDiscoveryURL, preset.DiscoveryURL),
+		EmailClaim:         get(config.OAuth2ProviderEmailClaim, or(preset.EmailClaim, "email")),
+		EmailsURL:          get(config.OAuth2ProviderEmailsURL, preset.EmailsURL),
+		EmailVerifiedClaim: get(config.OAuth2ProviderEmailVerifiedClaim, or(preset.EmailVerifiedClaim, "email_verified")),
+		NameClaim:          get(config.OAuth2ProviderNameClaim, or(preset.NameClaim, "name")),
+		RedirectURL:        fmt.Sprintf("%s/oauth2/%s/callback", viper.GetString(config.BaseURL), name),
+		Scopes:             scopes,
+		SubjectClaim:       get(config.OAuth2ProviderSubjectClaim, or(preset.SubjectClaim, "sub")),
+		TokenURL:           get(config.OAuth2ProviderTokenURL, preset.TokenURL),
+		UserInfoURL:        get(config.OAuth2ProviderUserInfoURL, preset.UserInfoURL),
+	}
+
+	if c.ClientId == "" {
+		return c, fmt.Errorf("oauth2 provider '%s': client id is unset", name)
+	}
+
+	if c.DiscoveryURL == "" && (c.AuthURL == "" || c.TokenURL == "" || c.UserInfoURL == "") {
+		return c, fmt.Errorf("oauth2 provider '%s': either the discovery url or the auth, token and userinfo urls must be set", name)
+	}
+
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return c, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("response code was: %d body: %s", resp.StatusCode, b)
+	}
+
+	// numbers are kept as is so large ids don't get formatted as floats
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	return d.Decode(v)
+}
+
+func claimString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// claimBool handles providers that send booleans as strings.
+func claimBool(v any) bool {
+	switch t := v.(type) {
+	case bool:
+		return t
+	case string:
+		b, _ := strconv.ParseBool(t)
+		return b
+	default:
+		return false
+	}
+}
+
+func or(s string, def string) string {
+	if s != "" {
+		return s
+	}
+	return def
+}
@@ -0,0 +1,290 @@
+package idp_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+
+	"github.com/alexferl/echo-boilerplate/config"
+	"github.com/alexferl/echo-boilerplate/util/idp"
+	"github.com/alexferl/echo-boilerplate/util/idp/idptest"
+)
+
+func setProvider(name string, settings map[string]any) func() {
+	for k, v := range settings {
+		viper.Set(config.OAuth2ProviderKey(name, k), v)
+	}
+	viper.Set(config.OAuth2Providers, []string{name})
+
+	return func() {
+		for k := range settings {
+			viper.Set(config.OAuth2ProviderKey(name, k), "")
+		}
+		viper.Set(config.OAuth2Providers, []string{""})
+	}
+}
+
+func TestNewRegistry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider string
+		settings map[string]any
+		err      bool
+	}{
+		{"preset", "github", map[string]any{config.OAuth2ProviderClientId: "id"}, false},
+		{"discovery", "custom", map[string]any{
+			config.OAuth2ProviderClientId:     "id",
+			config.OAuth2ProviderDiscoveryURL: "http://localhost/.well-known/openid-configuration",
+		}, false},
+		{"endpoints", "custom", map[string]any{
+			config.OAuth2ProviderClientId:    "id",
+			config.OAuth2ProviderAuthURL:     "http://localhost/authorize",
+			config.OAuth2ProviderTokenURL:    "http://localhost/token",
+			config.OAuth2ProviderUserInfoURL: "http://localhost/userinfo",
+		}, false},
+		{"client id missing", "google", map[string]any{}, true},
+		{"endpoints missing", "custom", map[string]any{
+			config.OAuth2ProviderClientId: "id",
+			config.OAuth2ProviderAuthURL:  "http://localhost/authorize",
+		}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reset := setProvider(tc.provider, tc.settings)
+			defer reset()
+
+			r, err := idp.NewRegistry()
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			p, ok := r.Get(tc.provider)
+			assert.True(t, ok)
+			assert.Equal(t, tc.provider, p.Name)
+			_, ok = r.Get("other")
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestNewRegistry_Empty(t *testing.T) {
+	r, err := idp.NewRegistry()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, r.Len())
+}
+
+func login(t *testing.T, s *idptest.Server, p *idp.Provider) (*idp.Identity, error) {
+	verifier := oauth2.GenerateVerifier()
+	code, err := authorize(t, s, p, verifier, "nonce")
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Exchange(context.Background(), code, verifier, "nonce")
+}
+
+// authorize logs in to s and returns the authorization code.
+func authorize(t *testing.T, s *idptest.Server, p *idp.Provider, verifier string, nonce string) (string, error) {
+	authURL, err := p.AuthCodeURL(context.Background(), "state", verifier, nonce)
+	if !assert.NoError(t, err) {
+		return "", err
+	}
+
+	callback, err := s.Authorize(authURL)
+	if !assert.NoError(t, err) {
+		return "", err
+	}
+	assert.Equal(t, "state", callback.Query().Get("state"))
+
+	return callback.Query().Get("code"), nil
+}
+
+func TestProvider_OIDC(t *testing.T) {
+	s := idptest.NewServer()
+	defer s.Close()
+
+	reset := setProvider("fake", map[string]any{
+		config.OAuth2ProviderClientId:     idptest.ClientId,
+		config.OAuth2ProviderClientSecret: idptest.ClientSecret,
+		config.OAuth2ProviderDiscoveryURL: s.DiscoveryURL(),
+	})
+	defer reset()
+
+	r, err := idp.NewRegistry()
+	assert.NoError(t, err)
+	p, _ := r.Get("fake")
+
+	identity, err := login(t, s, p)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "fake", identity.Provider)
+		assert.Equal(t, "1234567890", identity.Subject)
+		assert.Equal(t, "test@example.com", identity.Email)
+		assert.True(t, identity.EmailVerified)
+		assert.Equal(t, "Test User", identity.Name)
+	}
+
+	// some providers send booleans as strings
+	s.Claims["email_verified"] = "false"
+	identity, err = login(t, s, p)
+	if assert.NoError(t, err) {
+		assert.False(t, identity.EmailVerified)
+	}
+
+	s.Claims["aud"] = "other"
+	_, err = login(t, s, p)
+	assert.True(t, errors.Is(err, idp.ErrIDTokenInvalid))
+	delete(s.Claims, "aud")
+
+	delete(s.Claims, "email")
+	_, err = login(t, s, p)
+	assert.ErrorIs(t, err, idp.ErrEmailMissing)
+}
+
+func TestProvider_OAuth2(t *testing.T) {
+	s := idptest.NewServer()
+	defer s.Close()
+
+	// like GitHub, without OpenID Connect and with the emails in their own endpoint
+	s.Claims = map[string]any{"id": 12345678901, "login": "test", "name": "Test User"}
+	s.Emails = []map[string]any{
+		{"email": "other@example.com", "primary": false, "verified": true},
+		{"email": "test@example.com", "primary": true, "verified": true},
+	}
+
+	p := idp.NewProvider("fake", idp.Config{
+		AuthURL:            s.URL + "/authorize",
+		ClientId:           idptest.ClientId,
+		ClientSecret:       idptest.ClientSecret,
+		EmailClaim:         "email",
+		EmailsURL:          s.URL + "/emails",
+		EmailVerifiedClaim: "email_verified",
+		NameClaim:          "name",
+		RedirectURL:        "http://localhost:1323/oauth2/fake/callback",
+		SubjectClaim:       "id",
+		TokenURL:           s.URL + "/token",
+		UserInfoURL:        s.URL + "/userinfo",
+	})
+
+	identity, err := login(t, s, p)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "12345678901", identity.Subject)
+		assert.Equal(t, "test@example.com", identity.Email)
+		assert.True(t, identity.EmailVerified)
+		assert.Equal(t, "Test User", identity.Name)
+	}
+}
+
+func TestProvider_Exchange_Invalid_Code(t *testing.T) {
+	s := idptest.NewServer()
+	defer s.Close()
+
+	p := idp.NewProvider("fake", idp.Config{
+		ClientId:     idptest.ClientId,
+		ClientSecret: idptest.ClientSecret,
+		DiscoveryURL: s.DiscoveryURL(),
+		SubjectClaim: "sub",
+	})
+
+	_, err := p.Exchange(context.Background(), "invalid", oauth2.GenerateVerifier(), "nonce")
+	assert.Error(t, err)
+}
+
+func TestProvider_Exchange_PKCE_Nonce(t *testing.T) {
+	s := idptest.NewServer()
+	defer s.Close()
+
+	reset := setProvider("fake", map[string]any{
+		config.OAuth2ProviderClientId:     idptest.ClientId,
+		config.OAuth2ProviderClientSecret: idptest.ClientSecret,
+		config.OAuth2ProviderDiscoveryURL: s.DiscoveryURL(),
+	})
+	defer reset()
+
+	r, err := idp.NewRegistry()
+	assert.NoError(t, err)
+	p, _ := r.Get("fake")
+
+	verifier := oauth2.GenerateVerifier()
+	code, err := authorize(t, s, p, verifier, "nonce")
+	if assert.NoError(t, err) {
+		_, err = p.Exchange(context.Background(), code, oauth2.GenerateVerifier(), "nonce")
+		assert.Error(t, err)
+	}
+
+	code, err = authorize(t, s, p, verifier, "nonce")
+	if assert.NoError(t, err) {
+		_, err = p.Exchange(context.Background(), code, verifier, "other")
+		assert.ErrorIs(t, err, idp.ErrNonceInvalid)
+	}
+}
+
+func TestProvider_Keys_Refetch(t *testing.T) {
+	s := idptest.NewServer()
+	defer s.Close()
+
+	reset := setProvider("fake", map[string]any{
+		config.OAuth2ProviderClientId:     idptest.ClientId,
+		config.OAuth2ProviderClientSecret: idptest.ClientSecret,
+		config.OAuth2ProviderDiscoveryURL: s.DiscoveryURL(),
+	})
+	defer reset()
+
+	r, err := idp.NewRegistry()
+	assert.NoError(t, err)
+	p, _ := r.Get("fake")
+
+	_, err = login(t, s, p)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.JWKSRequests())
+
+	// invalid tokens signed with a known key don't fetch the keys again
+	s.Claims["aud"] = "other"
+	_, err = login(t, s, p)
+	assert.ErrorIs(t, err, idp.ErrIDTokenInvalid)
+	assert.Equal(t, 1, s.JWKSRequests())
+	delete(s.Claims, "aud")
+
+	// the provider rotated its key
+	s.RotateKey("rotated")
+	_, err = login(t, s, p)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, s.JWKSRequests())
+
+	// unknown keys only fetch them once a minute
+	s.RotateKey("unknown")
+	_, err = login(t, s, p)
+	assert.ErrorIs(t, err, idp.ErrIDTokenInvalid)
+	assert.Equal(t, 2, s.JWKSRequests())
+}
+
+func TestProvider_Discover_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer s.Close()
+	defer close(done)
+
+	p := idp.NewProvider("fake", idp.Config{
+		ClientId:     idptest.ClientId,
+		DiscoveryURL: s.URL + "/.well-known/openid-configuration",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := p.AuthCodeURL(ctx, "state", oauth2.GenerateVerifier(), "nonce")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,281 @@
+// Package idptest provides a fake OpenID Connect provider for tests.
+package idptest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	jwx "github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+const (
+	ClientId     = "client-id"
+	ClientSecret = "client-secret"
+	accessToken  = "access-token"
+)
+
+// Server is an OpenID Connect provider issuing ID tokens with Claims for the user who logs in.
+// Its authorization endpoint logs the user in without asking and redirects back with a code.
+type Server struct {
+	*httptest.Server
+
+	// Claims are the claims of the user, put in ID tokens and returned by the userinfo endpoint.
+	Claims map[string]any
+
+	// Emails are returned by the emails endpoint, which works like GitHub's.
+	Emails []map[string]any
+
+	// Key signs the ID tokens, its public key is served by the jwks endpoint.
+	Key jwk.Key
+
+	mu           sync.Mutex
+	codes        map[string]url.Values
+	jwksRequests int
+}
+
+func NewServer() *Server {
+	s := &Server{
+		Claims: map[string]any{
+			"sub":            "1234567890",
+			"email":          "test@example.com",
+			"email_verified": true,
+			"name":           "Test User",
+		},
+		Key:   newKey("test"),
+		codes: map[string]url.Values{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("/authorize", s.authorize)
+	mux.HandleFunc("/emails", s.emails)
+	mux.HandleFunc("/jwks", s.jwks)
+	mux.HandleFunc("/token", s.token)
+	mux.HandleFunc("/userinfo", s.userInfo)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+func newKey(kid string) jwk.Key {
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	key, _ := jwk.FromRaw(raw)
+	_ = key.Set(jwk.KeyIDKey, kid)
+	_ = key.Set(jwk.AlgorithmKey, jwa.RS256)
+
+	return key
+}
+
+// RotateKey replaces Key with a new one identified by kid.
+func (s *Server) RotateKey(kid string) {
+	key := newKey(kid)
+	s.mu.Lock()
+	s.Key = key
+	s.mu.Unlock()
+}
+
+// JWKSRequests returns how many times the jwks endpoint was requested.
+func (s *Server) JWKSRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jwksRequests
+}
+
+// DiscoveryURL is the URL of the discovery document.
+func (s *Server) DiscoveryURL() string {
+	return s.URL + "/.well-known/openid-configuration"
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"userinfo_endpoint":      s.URL + "/userinfo",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+// authorize redirects to redirect_uri with a code, the parameters
+// of the request are kept to be checked when it's exchanged.
+// Like most providers, PKCE is only enforced when a challenge is sent.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	if params.Get("client_id") != ClientId {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	code := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	s.codes[code] = params
+	s.mu.Unlock()
+
+	u, err := url.Parse(params.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	q := u.Query()
+	q.Set("code", code)
+	q.Set("state", params.Get("state"))
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// Authorize visits authURL like a browser would and returns the callback URL it redirects to.
+func (s *Server) Authorize(authURL string) (*url.URL, error) {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		return nil, fmt.Errorf("authorize returned %d", resp.StatusCode)
+	}
+
+	return resp.Location()
+}
+
+func (s *Server) emails(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") != accessToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.Emails)
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.jwksRequests++
+	key := s.Key
+	s.mu.Unlock()
+
+	pub, _ := key.PublicKey()
+	set := jwk.NewSet()
+	_ = set.AddKey(pub)
+	writeJSON(w, http.StatusOK, set)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientId, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientId, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	if clientId != ClientId || clientSecret != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	params, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	if !ok || params.Get("redirect_uri") != r.PostForm.Get("redirect_uri") {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	if challenge := params.Get("code_challenge"); challenge != "" {
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if params.Get("code_challenge_method") != "S256" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+	}
+
+	idToken, err := s.IDToken(params.Get("nonce"))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"expires_in":   3600,
+		"id_token":     string(idToken),
+		"token_type":   "Bearer",
+	})
+}
+
+// IDToken returns an ID token for the user signed with Key.
+func (s *Server) IDToken(nonce string) ([]byte, error) {
+	now := time.Now()
+	b := jwx.NewBuilder().
+		Issuer(s.URL).
+		Audience([]string{ClientId}).
+		IssuedAt(now).
+		Expiration(now.Add(time.Hour))
+
+	for k, v := range s.Claims {
+		b = b.Claim(k, v)
+	}
+
+	if nonce != "" {
+		b = b.Claim("nonce", nonce)
+	}
+
+	token, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	key := s.Key
+	s.mu.Unlock()
+
+	return jwx.Sign(token, jwx.WithKey(jwa.RS256, key))
+}
+
+func (s *Server) userInfo(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ") != accessToken {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.Claims)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
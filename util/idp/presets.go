@@ -0,0 +1,32 @@
+package idp
+
+import (
+	"golang.org/x/oauth2/endpoints"
+)
+
+// presets are the settings of well known providers, so only
+// the client id and secret have to be configured for them.
+var presets = map[string]Config{
+	"github": {
+		AuthURL: endpoints.GitHub.AuthURL,
+		// GitHub doesn't say if the email of the user is verified, the emails endpoint does
+		EmailsURL:    "https://api.github.com/user/emails",
+		Scopes:       []string{"read:user", "user:email"},
+		SubjectClaim: "id",
+		TokenURL:     endpoints.GitHub.TokenURL,
+		UserInfoURL:  "https://api.github.com/user",
+	},
+	"gitlab": {
+		DiscoveryURL: "https://gitlab.com/.well-known/openid-configuration",
+	},
+	"google": {
+		DiscoveryURL: "https://accounts.google.com/.well-known/openid-configuration",
+	},
+	// the issuer of Microsoft's common endpoint depends on the tenant of each user so
+	// ID tokens can't be validated, set the discovery URL of a tenant to use OpenID Connect
+	"microsoft": {
+		AuthURL:     endpoints.AzureAD("common").AuthURL,
+		TokenURL:    endpoints.AzureAD("common").TokenURL,
+		UserInfoURL: "https://graph.microsoft.com/oidc/userinfo",
+	},
+}
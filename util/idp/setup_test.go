@@ -0,0 +1,3 @@
+package idp_test
+
+import _ "github.com/alexferl/echo-boilerplate/testing"
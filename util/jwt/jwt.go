@@ -1,32 +1,116 @@
 package jwt
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	jwx "github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/rs/xid"
 	"github.com/spf13/viper"
 
 	"github.com/alexferl/echo-boilerplate/config"
 )
 
-var PrivateKey *rsa.PrivateKey = nil
+var ErrKeyUnsupported = errors.New("unsupported private key")
+
+var (
+	mu         sync.RWMutex
+	signingKey jwk.Key
+	publicKeys jwk.Set
+)
 
 func init() {
 	c := config.New()
 	c.BindFlags()
 
-	key, err := loadPrivateKey()
+	raw, err := LoadPrivateKey()
+	if err != nil {
+		panic(err)
+	}
+
+	key, err := Key(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	err = SetKeys(key, nil)
 	if err != nil {
 		panic(err)
 	}
-	PrivateKey = key
+}
+
+// Key converts the private key raw to a signing key identified
+// by the thumbprint of its public key, which ends up in the kid
+// header of the tokens it signs.
+func Key(raw any) (jwk.Key, error) {
+	alg, err := Algorithm(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := jwk.FromRaw(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwk: %v", err)
+	}
+
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute thumbprint: %v", err)
+	}
+
+	_ = key.Set(jwk.KeyIDKey, base64.RawURLEncoding.EncodeToString(thumbprint))
+	_ = key.Set(jwk.AlgorithmKey, alg)
+	_ = key.Set(jwk.KeyUsageKey, jwk.ForSignature)
+
+	return key, nil
+}
+
+// SetKeys sets the key new tokens are signed with and the other keys
+// verifying tokens, signing verifies tokens too.
+func SetKeys(signing jwk.Key, others []jwk.Key) error {
+	set := jwk.NewSet()
+	for _, key := range append([]jwk.Key{signing}, others...) {
+		pub, err := key.PublicKey()
+		if err != nil {
+			return fmt.Errorf("failed to get public key: %v", err)
+		}
+
+		if err = set.AddKey(pub); err != nil {
+			return fmt.Errorf("failed to add public key: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	signingKey = signing
+	publicKeys = set
+
+	return nil
+}
+
+// PublicKeys returns the keys tokens are verified with.
+func PublicKeys() jwk.Set {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return publicKeys
 }
 
 type Type int8
@@ -35,10 +119,13 @@ const (
 	AccessToken Type = iota + 1
 	RefreshToken
 	PersonalToken
+	MFAToken
+	OAuth2StateToken
+	ServiceToken
 )
 
 func (t Type) String() string {
-	return [...]string{"access", "refresh", "personal"}[t-1]
+	return [...]string{"access", "refresh", "personal", "mfa", "oauth2_state", "service"}[t-1]
 }
 
 func GenerateTokens(sub string, claims map[string]any) ([]byte, []byte, error) {
@@ -47,7 +134,7 @@ func GenerateTokens(sub string, claims map[string]any) ([]byte, []byte, error) {
 		return nil, nil, err
 	}
 
-	refresh, err := GenerateRefreshToken(sub)
+	refresh, err := GenerateRefreshToken(sub, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -60,17 +147,47 @@ func GenerateAccessToken(sub string, claims map[string]any) ([]byte, error) {
 	return generateToken(AccessToken, expiry, sub, claims)
 }
 
-func GenerateRefreshToken(sub string) ([]byte, error) {
+func GenerateRefreshToken(sub string, claims map[string]any) ([]byte, error) {
 	expiry := viper.GetDuration(config.JWTRefreshTokenExpiry)
-	return generateToken(RefreshToken, expiry, sub, map[string]any{})
+	return generateToken(RefreshToken, expiry, sub, claims)
 }
 
 func GeneratePersonalToken(sub string, expiry time.Duration, claims map[string]any) ([]byte, error) {
 	return generateToken(PersonalToken, expiry, sub, claims)
 }
 
+// GenerateMFAToken generates the token proving the first login step
+// succeeded, it can only be exchanged for tokens with a valid MFA code.
+func GenerateMFAToken(sub string, claims map[string]any) ([]byte, error) {
+	expiry := viper.GetDuration(config.MFAChallengeExpiry)
+	return generateToken(MFAToken, expiry, sub, claims)
+}
+
+// GenerateOAuth2StateToken generates the token keeping the state of
+// a login with an OAuth2 provider until the provider redirects back.
+func GenerateOAuth2StateToken(sub string, claims map[string]any) ([]byte, error) {
+	expiry := viper.GetDuration(config.OAuth2StateExpiry)
+	return generateToken(OAuth2StateToken, expiry, sub, claims)
+}
+
+// GenerateImpersonationToken generates an access token of sub for the user
+// actor impersonating them, see RFC 8693. Its session sid can't be refreshed.
+func GenerateImpersonationToken(sub string, actor string, sid string) ([]byte, error) {
+	expiry := viper.GetDuration(config.ImpersonationExpiry)
+	claims := map[string]any{"act": map[string]any{"sub": actor}, "sid": sid}
+	return generateToken(AccessToken, expiry, sub, claims)
+}
+
+// GenerateServiceToken generates the access token of a service account,
+// it expires like access tokens but has no refresh token or session.
+func GenerateServiceToken(sub string, claims map[string]any) ([]byte, error) {
+	expiry := viper.GetDuration(config.JWTAccessTokenExpiry)
+	return generateToken(ServiceToken, expiry, sub, claims)
+}
+
 func generateToken(typ Type, expiry time.Duration, sub string, claims map[string]any) ([]byte, error) {
 	builder := jwx.NewBuilder().
+		JwtID(xid.New().String()).
 		Subject(sub).
 		Issuer(viper.GetString(config.JWTIssuer)).
 		IssuedAt(time.Now()).
@@ -89,7 +206,11 @@ func generateToken(typ Type, expiry time.Duration, sub string, claims map[string
 		return nil, fmt.Errorf("failed to build %s token: %v\n", typ.String(), err)
 	}
 
-	signed, err := jwx.Sign(token, jwx.WithKey(jwa.RS256, PrivateKey))
+	mu.RLock()
+	key := signingKey
+	mu.RUnlock()
+
+	signed, err := jwx.Sign(token, jwx.WithKey(key.Algorithm(), key))
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign %s token: %v\n", typ.String(), err)
 	}
@@ -97,8 +218,11 @@ func generateToken(typ Type, expiry time.Duration, sub string, claims map[string
 	return signed, nil
 }
 
+// ParseEncoded verifies encodedToken with the current and retired keys and parses it,
+// tokens signed before keys had ids are verified with each of them.
 func ParseEncoded(encodedToken []byte) (jwx.Token, error) {
-	token, err := jwx.Parse(encodedToken, jwx.WithValidate(true), jwx.WithKey(jwa.RS256, PrivateKey))
+	keys := jwx.WithKeySet(PublicKeys(), jws.WithInferAlgorithmFromKey(true), jws.WithRequireKid(false))
+	token, err := jwx.Parse(encodedToken, jwx.WithValidate(true), keys)
 	if err != nil {
 		return nil, err
 	}
@@ -106,37 +230,99 @@ func ParseEncoded(encodedToken []byte) (jwx.Token, error) {
 	return token, nil
 }
 
-func loadPrivateKey() (*rsa.PrivateKey, error) {
+// LoadPrivateKey loads the private key of jwt-private-key.
+func LoadPrivateKey() (crypto.Signer, error) {
 	f, err := os.Open(viper.GetString(config.JWTPrivateKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open private key: %v", err)
 	}
+	defer f.Close()
 
 	b, err := io.ReadAll(f)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read private key: %v", err)
 	}
 
+	return ParsePrivateKey(b)
+}
+
+// ParsePrivateKey parses a PEM encoded PKCS#1 RSA, SEC 1 EC or
+// PKCS#8 RSA, ECDSA or Ed25519 private key.
+func ParsePrivateKey(b []byte) (crypto.Signer, error) {
 	block, _ := pem.Decode(b)
 	if block == nil {
-		return nil, fmt.Errorf("failed to parse PEM block: %v", err)
+		return nil, fmt.Errorf("failed to parse PEM block")
 	}
 
-	var key *rsa.PrivateKey
+	var (
+		key any
+		err error
+	)
 	switch block.Type {
 	case "RSA PRIVATE KEY": // PKCS#1
 		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
-		if err != nil {
-			return nil, err
-		}
+	case "EC PRIVATE KEY": // SEC 1
+		key, err = x509.ParseECPrivateKey(block.Bytes)
 	case "PRIVATE KEY": // PKCS#8
-		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-		if err != nil {
-			return nil, err
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: PEM block type %s", ErrKeyUnsupported, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	if _, err = Algorithm(key); err != nil {
+		return nil, err
+	}
+
+	return key.(crypto.Signer), nil
+}
+
+// Algorithm returns the algorithm the private key raw signs tokens with:
+// RS256 for RSA, ES256 and ES384 for ECDSA P-256 and P-384 and EdDSA for Ed25519.
+func Algorithm(raw any) (jwa.SignatureAlgorithm, error) {
+	switch k := raw.(type) {
+	case *rsa.PrivateKey:
+		return jwa.RS256, nil
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwa.ES256, nil
+		case elliptic.P384():
+			return jwa.ES384, nil
 		}
+		return "", fmt.Errorf("%w: ECDSA curve %s", ErrKeyUnsupported, k.Curve.Params().Name)
+	case ed25519.PrivateKey:
+		return jwa.EdDSA, nil
+	}
 
-		key = privateKey.(*rsa.PrivateKey)
+	return "", fmt.Errorf("%w: %T", ErrKeyUnsupported, raw)
+}
+
+// GenerateKey generates a private key signing tokens with algorithm.
+func GenerateKey(algorithm jwa.SignatureAlgorithm) (crypto.Signer, error) {
+	switch algorithm {
+	case jwa.RS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case jwa.ES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwa.ES384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case jwa.EdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
 	}
 
-	return key, nil
+	return nil, fmt.Errorf("%w: algorithm %s", ErrKeyUnsupported, algorithm)
+}
+
+// EncodePrivateKey encodes key as a PEM encoded PKCS#8 private key.
+func EncodePrivateKey(key any) ([]byte, error) {
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}), nil
 }
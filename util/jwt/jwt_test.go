@@ -1,8 +1,21 @@
 package jwt
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
+	"time"
 
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	jwx "github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/alexferl/echo-boilerplate/config"
@@ -39,3 +52,226 @@ func TestParseEncoded(t *testing.T) {
 	_, ok = refreshToken.Get("claim")
 	assert.False(t, ok)
 }
+
+func TestGenerateRefreshToken(t *testing.T) {
+	c := config.New()
+	c.BindFlags()
+
+	sub := "123"
+	sid := "456"
+
+	refresh, err := GenerateRefreshToken(sub, map[string]any{"sid": sid})
+	assert.NoError(t, err)
+
+	token, err := ParseEncoded(refresh)
+	assert.NoError(t, err)
+	assert.Equal(t, sub, token.Subject())
+	assert.NotEqual(t, "", token.JwtID())
+	claim, ok := token.Get("sid")
+	assert.True(t, ok)
+	assert.Equal(t, sid, claim)
+
+	other, err := GenerateRefreshToken(sub, map[string]any{"sid": sid})
+	assert.NoError(t, err)
+	assert.NotEqual(t, string(refresh), string(other))
+}
+
+func TestGenerateMFAToken(t *testing.T) {
+	c := config.New()
+	c.BindFlags()
+
+	sub := "123"
+
+	mfa, err := GenerateMFAToken(sub, nil)
+	assert.NoError(t, err)
+
+	token, err := ParseEncoded(mfa)
+	assert.NoError(t, err)
+	assert.Equal(t, sub, token.Subject())
+	typ, ok := token.Get("type")
+	assert.True(t, ok)
+	assert.Equal(t, MFAToken.String(), typ)
+	assert.WithinDuration(t, time.Now().Add(viper.GetDuration(config.MFAChallengeExpiry)), token.Expiration(), time.Second*2)
+}
+
+func TestGenerateImpersonationToken(t *testing.T) {
+	c := config.New()
+	c.BindFlags()
+
+	token, err := GenerateImpersonationToken("123", "456", "789")
+	assert.NoError(t, err)
+
+	parsed, err := ParseEncoded(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "123", parsed.Subject())
+	assert.Equal(t, AccessToken.String(), parsed.PrivateClaims()["type"])
+	assert.Equal(t, map[string]any{"sub": "456"}, parsed.PrivateClaims()["act"])
+	assert.Equal(t, "789", parsed.PrivateClaims()["sid"])
+	assert.WithinDuration(t, time.Now().Add(viper.GetDuration(config.ImpersonationExpiry)), parsed.Expiration(), time.Second*2)
+}
+
+func TestGenerateServiceToken(t *testing.T) {
+	c := config.New()
+	c.BindFlags()
+
+	sub := "123"
+
+	service, err := GenerateServiceToken(sub, map[string]any{"client_id": "sa_123"})
+	assert.NoError(t, err)
+
+	token, err := ParseEncoded(service)
+	assert.NoError(t, err)
+	assert.Equal(t, sub, token.Subject())
+	assert.Equal(t, ServiceToken.String(), token.PrivateClaims()["type"])
+	assert.Equal(t, "sa_123", token.PrivateClaims()["client_id"])
+	assert.WithinDuration(t, time.Now().Add(viper.GetDuration(config.JWTAccessTokenExpiry)), token.Expiration(), time.Second*2)
+}
+
+func TestSetKeys(t *testing.T) {
+	c := config.New()
+	c.BindFlags()
+
+	before := PublicKeys()
+	defer func() {
+		raw, _ := LoadPrivateKey()
+		key, _ := Key(raw)
+		_ = SetKeys(key, nil)
+	}()
+
+	old, err := GenerateAccessToken("123", nil)
+	assert.NoError(t, err)
+
+	oldKey, err := LoadPrivateKey()
+	assert.NoError(t, err)
+	retired, err := Key(oldKey)
+	assert.NoError(t, err)
+
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key, err := Key(raw)
+	assert.NoError(t, err)
+
+	assert.NoError(t, SetKeys(key, []jwk.Key{retired}))
+	assert.Equal(t, 2, PublicKeys().Len())
+	assert.Equal(t, 1, before.Len())
+
+	token, err := GenerateAccessToken("123", nil)
+	assert.NoError(t, err)
+
+	msg, err := jws.Parse(token)
+	assert.NoError(t, err)
+	assert.Equal(t, key.KeyID(), msg.Signatures()[0].ProtectedHeaders().KeyID())
+
+	_, err = ParseEncoded(token)
+	assert.NoError(t, err)
+
+	// tokens of retired keys verify until the keys are dropped
+	_, err = ParseEncoded(old)
+	assert.NoError(t, err)
+
+	// tokens signed before keys had ids still verify
+	tok, err := jwx.NewBuilder().Subject("123").Expiration(time.Now().Add(time.Minute)).Build()
+	assert.NoError(t, err)
+	noKid, err := jwx.Sign(tok, jwx.WithKey(jwa.RS256, oldKey))
+	assert.NoError(t, err)
+	_, err = ParseEncoded(noKid)
+	assert.NoError(t, err)
+
+	assert.NoError(t, SetKeys(key, nil))
+	_, err = ParseEncoded(old)
+	assert.Error(t, err)
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	ec521Key, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	assert.NoError(t, err)
+
+	sec1, err := x509.MarshalECPrivateKey(ecKey)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name string
+		typ  string
+		key  any
+		der  []byte
+		alg  jwa.SignatureAlgorithm
+		err  bool
+	}{
+		{"PKCS#1 RSA", "RSA PRIVATE KEY", rsaKey, x509.MarshalPKCS1PrivateKey(rsaKey), jwa.RS256, false},
+		{"PKCS#8 RSA", "PRIVATE KEY", rsaKey, nil, jwa.RS256, false},
+		{"SEC 1 P-256", "EC PRIVATE KEY", ecKey, sec1, jwa.ES256, false},
+		{"PKCS#8 P-256", "PRIVATE KEY", ecKey, nil, jwa.ES256, false},
+		{"PKCS#8 P-384", "PRIVATE KEY", ec384Key, nil, jwa.ES384, false},
+		{"PKCS#8 Ed25519", "PRIVATE KEY", edKey, nil, jwa.EdDSA, false},
+		{"PKCS#8 P-521", "PRIVATE KEY", ec521Key, nil, "", true},
+		{"Unknown block", "OPENSSH PRIVATE KEY", rsaKey, []byte("key"), "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			der := tc.der
+			if der == nil {
+				der, err = x509.MarshalPKCS8PrivateKey(tc.key)
+				assert.NoError(t, err)
+			}
+
+			key, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: tc.typ, Bytes: der}))
+			if tc.err {
+				assert.ErrorIs(t, err, ErrKeyUnsupported)
+				return
+			}
+			assert.NoError(t, err)
+
+			alg, err := Algorithm(key)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.alg, alg)
+		})
+	}
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	assert.Error(t, err)
+}
+
+func TestSigningAlgorithms(t *testing.T) {
+	c := config.New()
+	c.BindFlags()
+
+	defer func() {
+		raw, _ := LoadPrivateKey()
+		key, _ := Key(raw)
+		_ = SetKeys(key, nil)
+	}()
+
+	for _, alg := range []jwa.SignatureAlgorithm{jwa.RS256, jwa.ES256, jwa.ES384, jwa.EdDSA} {
+		t.Run(alg.String(), func(t *testing.T) {
+			raw, err := GenerateKey(alg)
+			assert.NoError(t, err)
+
+			key, err := Key(raw)
+			assert.NoError(t, err)
+			assert.NoError(t, SetKeys(key, nil))
+
+			token, err := GenerateAccessToken("123", nil)
+			assert.NoError(t, err)
+
+			msg, err := jws.Parse(token)
+			assert.NoError(t, err)
+			assert.Equal(t, alg, msg.Signatures()[0].ProtectedHeaders().Algorithm())
+
+			parsed, err := ParseEncoded(token)
+			assert.NoError(t, err)
+			assert.Equal(t, "123", parsed.Subject())
+		})
+	}
+
+	_, err := GenerateKey(jwa.HS256)
+	assert.ErrorIs(t, err, ErrKeyUnsupported)
+}
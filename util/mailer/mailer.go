@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Bytes returns the message formatted as a plain text email.
+func (m *Message) Bytes(from string) []byte {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("From: %s\r\n", from))
+	sb.WriteString(fmt.Sprintf("To: %s\r\n", m.To))
+	sb.WriteString(fmt.Sprintf("Subject: %s\r\n", m.Subject))
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(m.Body)
+	return []byte(sb.String())
+}
+
+// Log writes emails to the logs instead of sending them.
+// It's meant to be used during development.
+type Log struct{}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+func (l *Log) Send(ctx context.Context, msg *Message) error {
+	log.Debug().
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("body", msg.Body).
+		Msg("sending email")
+	return nil
+}
+
+// SMTP sends emails through an SMTP server.
+type SMTP struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTP(host string, port int, username string, password string, from string) *SMTP {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTP{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, msg.Bytes(s.from))
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errCh:
+		return err
+	}
+}
+
+// Mailer is implemented by Log and SMTP.
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New returns an SMTP mailer if a host is configured
+// and a Log mailer otherwise.
+func New() Mailer {
+	host := viper.GetString(config.SMTPHost)
+	if host == "" {
+		return NewLog()
+	}
+
+	return NewSMTP(
+		host,
+		viper.GetInt(config.SMTPPort),
+		viper.GetString(config.SMTPUsername),
+		viper.GetString(config.SMTPPassword),
+		viper.GetString(config.SMTPFrom),
+	)
+}
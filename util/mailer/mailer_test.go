@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/config"
+)
+
+func TestMessage_Bytes(t *testing.T) {
+	msg := &Message{
+		To:      "test@example.com",
+		Subject: "Hello",
+		Body:    "World",
+	}
+
+	b := string(msg.Bytes("no-reply@example.com"))
+
+	assert.Contains(t, b, "From: no-reply@example.com\r\n")
+	assert.Contains(t, b, "To: test@example.com\r\n")
+	assert.Contains(t, b, "Subject: Hello\r\n")
+	assert.Contains(t, b, "\r\n\r\nWorld")
+}
+
+func TestNew(t *testing.T) {
+	viper.Set(config.SMTPHost, "")
+	assert.IsType(t, &Log{}, New())
+	assert.NoError(t, New().Send(context.Background(), &Message{}))
+
+	viper.Set(config.SMTPHost, "localhost")
+	viper.Set(config.SMTPPort, 25)
+	m := New()
+	assert.IsType(t, &SMTP{}, m)
+	assert.Equal(t, "localhost:25", m.(*SMTP).addr)
+	viper.Set(config.SMTPHost, "")
+}
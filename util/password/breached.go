@@ -0,0 +1,98 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const prefixLength = 5
+
+var (
+	mu       sync.RWMutex
+	breached *Breached
+)
+
+// Breached is a list of breached passwords, their SHA-1 hashes are indexed by their
+// first 5 characters like the k-anonymity range API of Have I Been Pwned, so the
+// files of its downloader can be used as is without sending any password to it.
+type Breached struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreached reads the breached passwords of path, which has one uppercase
+// or lowercase SHA-1 hash per line, optionally followed by ':' and a count.
+// Empty lines and lines starting with '#' are skipped.
+func LoadBreached(path string) (*Breached, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	b := &Breached{ranges: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		if _, err = hex.DecodeString(hash); err != nil || len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: invalid SHA-1 hash", path, n)
+		}
+
+		b.add(strings.ToUpper(hash))
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *Breached) add(hash string) {
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	if b.ranges[prefix] == nil {
+		b.ranges[prefix] = map[string]struct{}{}
+	}
+	b.ranges[prefix][suffix] = struct{}{}
+}
+
+// Contains reports whether password is one of the breached passwords.
+func (b *Breached) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	_, ok := b.ranges[hash[:prefixLength]][hash[prefixLength:]]
+	return ok
+}
+
+// Len returns the number of breached passwords.
+func (b *Breached) Len() int {
+	n := 0
+	for _, r := range b.ranges {
+		n += len(r)
+	}
+	return n
+}
+
+// SetBreached sets the breached passwords Check rejects, nil disables the check.
+func SetBreached(b *Breached) {
+	mu.Lock()
+	defer mu.Unlock()
+	breached = b
+}
+
+func isBreached(password string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return breached != nil && breached.Contains(password)
+}
@@ -0,0 +1,38 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeBreached(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadBreached(t *testing.T) {
+	// SHA-1 of 'password' and 'letmein'
+	path := writeBreached(t, "# breached\n"+
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:10437277\n"+
+		"\n"+
+		"b7a875fc1ea228b9061041b7cec4bd3c52ab3ce3\n")
+
+	b, err := LoadBreached(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, b.Len())
+	assert.True(t, b.Contains("password"))
+	assert.True(t, b.Contains("letmein"))
+	assert.False(t, b.Contains("correct-horse-staple-battery"))
+}
+
+func TestLoadBreached_Invalid(t *testing.T) {
+	_, err := LoadBreached(writeBreached(t, "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1\nnot-a-hash:2\n"))
+	assert.ErrorContains(t, err, ":2: invalid SHA-1 hash")
+
+	_, err = LoadBreached(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
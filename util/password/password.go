@@ -4,10 +4,14 @@ import (
 	"errors"
 
 	"github.com/matthewhartstonge/argon2"
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
 )
 
+// Hash hashes password with the configured argon2 parameters.
 func Hash(password []byte) (string, error) {
-	argon := argon2.DefaultConfig()
+	argon := argonConfig()
 	encoded, err := argon.HashEncoded(password)
 	if err != nil {
 		return "", err
@@ -16,8 +20,8 @@ func Hash(password []byte) (string, error) {
 	return string(encoded), nil
 }
 
-func Verify(password []byte, encoded []byte) error {
-	b, err := argon2.VerifyEncoded(encoded, password)
+func Verify(encoded []byte, password []byte) error {
+	b, err := argon2.VerifyEncoded(password, encoded)
 	if err != nil {
 		return err
 	}
@@ -28,3 +32,35 @@ func Verify(password []byte, encoded []byte) error {
 
 	return nil
 }
+
+// NeedsRehash reports whether encoded was hashed with other argon2 parameters
+// than the configured ones. Hashes that can't be decoded don't, they can't be
+// verified either.
+func NeedsRehash(encoded []byte) bool {
+	raw, err := argon2.Decode(encoded)
+	if err != nil {
+		return false
+	}
+
+	argon := argonConfig()
+	return raw.Config.MemoryCost != argon.MemoryCost ||
+		raw.Config.TimeCost != argon.TimeCost ||
+		raw.Config.Parallelism != argon.Parallelism ||
+		raw.Config.Mode != argon.Mode ||
+		raw.Config.Version != argon.Version
+}
+
+func argonConfig() argon2.Config {
+	argon := argon2.DefaultConfig()
+	if m := viper.GetUint32(config.PasswordArgon2Memory); m > 0 {
+		argon.MemoryCost = m
+	}
+	if t := viper.GetUint32(config.PasswordArgon2Time); t > 0 {
+		argon.TimeCost = t
+	}
+	if p := viper.GetUint(config.PasswordArgon2Parallelism); p > 0 {
+		argon.Parallelism = uint8(p)
+	}
+
+	return argon
+}
@@ -3,7 +3,10 @@ package password
 import (
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/config"
 )
 
 func TestPassword(t *testing.T) {
@@ -23,3 +26,22 @@ func TestWrongPassword(t *testing.T) {
 	err = Verify([]byte(enc), []byte("wrong"))
 	assert.Error(t, err)
 }
+
+func TestNeedsRehash(t *testing.T) {
+	enc, err := Hash([]byte("s3cret"))
+	assert.NoError(t, err)
+	assert.False(t, NeedsRehash([]byte(enc)))
+
+	viper.Set(config.PasswordArgon2Time, 1)
+	defer viper.Set(config.PasswordArgon2Time, 3)
+	assert.True(t, NeedsRehash([]byte(enc)))
+
+	enc, err = Hash([]byte("s3cret"))
+	assert.NoError(t, err)
+	assert.False(t, NeedsRehash([]byte(enc)))
+	assert.NoError(t, Verify([]byte(enc), []byte("s3cret")))
+}
+
+func TestNeedsRehash_Invalid(t *testing.T) {
+	assert.False(t, NeedsRehash([]byte("invalid")))
+}
@@ -0,0 +1,80 @@
+package password
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+
+	"github.com/alexferl/echo-boilerplate/config"
+)
+
+// userInfoMinLength is the minimum length of the usernames and
+// emails checked, shorter ones would reject too many passwords.
+const userInfoMinLength = 3
+
+// Check returns what's wrong with password according to the password policy,
+// nothing if it's allowed. userInfo are the username and email of its user.
+func Check(password string, userInfo ...string) []string {
+	var errs []string
+
+	if minLength := viper.GetInt(config.PasswordMinLength); utf8.RuneCountInString(password) < minLength {
+		errs = append(errs, fmt.Sprintf("password must be at least %d characters", minLength))
+	}
+
+	if minClasses := viper.GetInt(config.PasswordMinClasses); classes(password) < minClasses {
+		errs = append(errs, fmt.Sprintf(
+			"password must contain at least %d of lowercase letters, uppercase letters, digits and symbols",
+			minClasses,
+		))
+	}
+
+	if viper.GetBool(config.PasswordRejectUserInfo) && containsUserInfo(password, userInfo) {
+		errs = append(errs, "password can't contain the username or email")
+	}
+
+	if isBreached(password) {
+		errs = append(errs, "password was found in a data breach")
+	}
+
+	return errs
+}
+
+func classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+func containsUserInfo(password string, userInfo []string) bool {
+	password = strings.ToLower(password)
+	for _, info := range userInfo {
+		// only the local part of emails is checked,
+		// their domain is shared with other users
+		if addr, err := mail.ParseAddress(info); err == nil {
+			info, _, _ = strings.Cut(addr.Address, "@")
+		}
+
+		info = strings.ToLower(info)
+		if utf8.RuneCountInString(info) >= userInfoMinLength && strings.Contains(password, info) {
+			return true
+		}
+	}
+
+	return false
+}
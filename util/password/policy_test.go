@@ -0,0 +1,46 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/alexferl/echo-boilerplate/config"
+)
+
+func TestCheck(t *testing.T) {
+	viper.Set(config.PasswordMinClasses, 3)
+	defer viper.Set(config.PasswordMinClasses, 1)
+
+	// SHA-1 of 'Password123!'
+	b, err := LoadBreached(writeBreached(t, "49EFEF5F70D47ADC2DB2EB397FBEF5F7BC560E29:1\n"))
+	assert.NoError(t, err)
+	SetBreached(b)
+	defer SetBreached(nil)
+
+	testCases := []struct {
+		name     string
+		password string
+		errs     []string
+	}{
+		{"valid", "Correct-horse-staple-battery", nil},
+		{"too short", "Short-1", []string{"password must be at least 12 characters"}},
+		{"too few classes", "correcthorsestaple", []string{
+			"password must contain at least 3 of lowercase letters, uppercase letters, digits and symbols",
+		}},
+		{"username", "Correct-Alice-staple", []string{"password can't contain the username or email"}},
+		{"email", "Correct-horse-ALICE.B", []string{"password can't contain the username or email"}},
+		{"breached", "Password123!", []string{"password was found in a data breach"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.errs, Check(tc.password, "alice", "alice.b@example.com"))
+		})
+	}
+}
+
+func TestCheck_UserInfo_Too_Short(t *testing.T) {
+	assert.Empty(t, Check("correct-horse-staple-battery", "co", "or@example.com"))
+}
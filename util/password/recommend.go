@@ -0,0 +1,63 @@
+package password
+
+import (
+	"time"
+
+	"github.com/matthewhartstonge/argon2"
+)
+
+// Params are argon2 parameters, Memory is in KiB.
+type Params struct {
+	Memory      uint32
+	Parallelism uint8
+	Time        uint32
+}
+
+// Recommend benchmarks the host to find the number of passes over memory KiB
+// with parallelism threads that hashes a password in about target, it returns
+// the parameters with how long a hash took. Memory is what the host can spare
+// for each concurrent login and isn't lowered, even if a single pass is slower
+// than target.
+func Recommend(target time.Duration, memory uint32, parallelism uint8) (Params, time.Duration) {
+	params := Params{Memory: memory, Parallelism: parallelism, Time: 1}
+
+	one := benchmark(params)
+	if one >= target {
+		return params, one
+	}
+
+	// the duration grows linearly with the passes after the memory is
+	// allocated, so the second pass gives the cost of the others
+	params.Time = 2
+	two := benchmark(params)
+	if two >= target {
+		params.Time = 1
+		return params, one
+	}
+
+	if pass := two - one; pass > 0 {
+		params.Time = 1 + uint32((target-one)/pass)
+	}
+
+	return params, benchmark(params)
+}
+
+// benchmark returns the fastest of a few hashes so that
+// other work on the host doesn't skew the estimate.
+func benchmark(params Params) time.Duration {
+	argon := argon2.DefaultConfig()
+	argon.MemoryCost = params.Memory
+	argon.Parallelism = params.Parallelism
+	argon.TimeCost = params.Time
+
+	var fastest time.Duration
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		_, _ = argon.HashRaw([]byte("benchmark"))
+		if d := time.Since(start); fastest == 0 || d < fastest {
+			fastest = d
+		}
+	}
+
+	return fastest
+}
@@ -0,0 +1,22 @@
+package password
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommend(t *testing.T) {
+	params, d := Recommend(20*time.Millisecond, 1024, 1)
+	assert.Equal(t, uint32(1024), params.Memory)
+	assert.Equal(t, uint8(1), params.Parallelism)
+	assert.Greater(t, params.Time, uint32(1))
+	assert.Greater(t, d, time.Duration(0))
+}
+
+func TestRecommend_Slow(t *testing.T) {
+	params, d := Recommend(time.Nanosecond, 1024, 1)
+	assert.Equal(t, uint32(1), params.Time)
+	assert.Greater(t, d, time.Nanosecond)
+}
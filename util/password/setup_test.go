@@ -0,0 +1,3 @@
+package password
+
+import _ "github.com/alexferl/echo-boilerplate/testing"